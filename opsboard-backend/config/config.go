@@ -2,15 +2,17 @@
  * @file config.go
  * @description 负责从 .env 文件加载应用配置。
  * @modification
- *   - [Debug Fix]: 恢复并加强了对 `godotenv.Load()` 的错误处理。现在，如果 `.env` 文件不存在或无法读取，程序将明确地打印错误日志并退出，而不是静默失败。
- *   - [Reason]: 此举旨在解决配置未能成功加载，导致程序使用默认空值连接数据库的问题。
+ *   - [Default Change]: `JWT_ACCEPT_LEGACY_HS256` 默认改为关闭，升级时需要兼容旧令牌的部署需显式开启。
  */
 
 package config
 
 import (
-	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -19,6 +21,94 @@ type Config struct {
 	ServerPort         string
 	DBConnectionString string
 	JWTSecret          string
+	FormSigningSecret  string   // 表单令牌、OIDC 登录状态等交给客户端保管的数据的签名密钥，必填
+	RequireIfMatch     bool     // 修改/删除请求是否必须携带 If-Match 请求头
+	LogLevel           string   // 日志级别：debug|info|warn|error
+	LogFormat          string   // 日志格式：json|text
+	MetricsAddr        string   // /metrics 的独立监听地址，例如 127.0.0.1:9090
+	MetricsToken       string   // 访问 /metrics 所需的 Bearer 令牌
+	TracesExporter     string   // 链路追踪导出器：none|otlp|stdout|file
+	TracesFile         string   // TracesExporter 为 file 时的输出文件
+	ServiceName        string   // 链路追踪中上报的服务名
+	TracesSampleRatio  float64  // 根 span 的采样比例（0~1）
+	TrustedProxies     []string // 可信反向代理的 IP 或 CIDR
+
+	// JWT 签名密钥，零值表示使用默认值
+	JWTSigningAlg          string        // 新密钥的签名算法：RS256|EdDSA
+	JWTKeyRotationInterval time.Duration // 签名密钥的轮换周期
+	JWTKeyPrePublish       time.Duration // 新密钥在开始签名前提前多久发布到 JWKS
+	JWTAcceptLegacyHS256   bool          // 是否接受升级前用 JWT_SECRET 以 HS256 签发的令牌，默认不接受
+
+	// 本地账户密码策略，零值表示使用默认值
+	PasswordMinLength  int    // 密码最少字符数
+	PasswordMinClasses int    // 至少包含几类字符（1~4）
+	PasswordHistory    int    // 不能与最近多少个密码相同
+	PasswordBreachList string // 泄露密码列表文件，每行一个明文密码或 SHA-1 哈希
+
+	// 登录暴力破解防护，零值表示使用默认值
+	LoginMaxFailures     int           // 同一用户名连续失败多少次后锁定
+	LoginIPMaxFailures   int           // 同一客户端 IP 连续失败多少次后锁定
+	LoginFailureWindow   time.Duration // 超过该时长没有新的失败，计数清零
+	LoginLockoutDuration time.Duration // 锁定时长
+	LoginBackoffBase     time.Duration // 第一次失败后的等待时长，之后每次失败翻倍
+	LoginBackoffMax      time.Duration // 退避等待时长的上限
+	LoginAttemptStore    string        // 失败计数的存储：memory|database
+	LoginFormMinAge      time.Duration // 登录表单令牌签发后至少经过多久才允许提交
+	LoginFormMaxAge      time.Duration // 登录表单令牌的有效期
+
+	MFAIssuer string // 两步验证在验证器应用中显示的发行方名称
+
+	SLAEvaluationInterval time.Duration // 工单 SLA 评估任务的运行间隔，零值表示使用默认值
+
+	// Webhook 投递，零值表示使用默认值
+	WebhookTimeout     time.Duration // 每次投递的 HTTP 超时时间
+	WebhookMaxAttempts int           // 最多尝试的总次数
+	WebhookRetryBase   time.Duration // 第一次失败后的重试间隔，之后每次失败翻倍
+	WebhookRetryMax    time.Duration // 重试间隔的上限
+
+	// 邮件通知，SMTPHost 为空表示不发送邮件，其余零值表示使用默认值
+	SMTPHost               string
+	SMTPPort               int
+	SMTPUsername           string // 为空表示不认证
+	SMTPPassword           string
+	SMTPFrom               string        // 发件人，例如 OpsBoard <noreply@example.com>
+	SMTPSecurity           string        // 加密方式：none|starttls|tls
+	SMTPInsecureSkipVerify bool          // 跳过服务端证书校验，仅用于测试环境
+	SMTPTimeout            time.Duration // 连接和发送的超时时间
+	EmailMaxAttempts       int           // 最多尝试的总次数
+	EmailRetryBase         time.Duration // 第一次失败后的重试间隔，之后每次失败翻倍
+	EmailRetryMax          time.Duration // 重试间隔的上限
+	EmailDigestTime        time.Duration // 每日摘要的发送时间（距零点的时长），未设置时为 -1
+
+	// 登录认证源，按顺序尝试：local|ldap
+	AuthProviders []string
+
+	// LDAP / Active Directory 认证源
+	LDAPURL                string        // 例如 ldap://ldap.example.com:389 或 ldaps://ad.example.com:636
+	LDAPStartTLS           bool          // 对 ldap:// 连接执行 StartTLS
+	LDAPInsecureSkipVerify bool          // 跳过服务端证书校验，仅用于测试环境
+	LDAPBindDN             string        // 用于搜索用户的服务账号 DN
+	LDAPBindPassword       string        // 服务账号的密码
+	LDAPBaseDN             string        // 搜索用户的起点
+	LDAPUserFilter         string        // 搜索用户的过滤器，%s 为用户名
+	LDAPNicknameAttribute  string        // 作为昵称的属性
+	LDAPGroupAttribute     string        // 记录所属组 DN 的属性
+	LDAPGroupRoles         string        // 组到角色的映射，格式为 角色:组DN;角色:组DN
+	LDAPDefaultRole        string        // 不属于任何映射组时使用的角色，为空表示拒绝登录
+	LDAPTimeout            time.Duration // 连接和每个操作的超时时间
+
+	// OpenID Connect 单点登录，OIDCIssuerURL 为空表示不启用
+	OIDCIssuerURL     string   // IdP 的 Issuer
+	OIDCClientID      string   // 在 IdP 注册的客户端 ID
+	OIDCClientSecret  string   // 客户端密钥
+	OIDCRedirectURL   string   // /api/auth/oidc/callback 的完整外部 URL
+	OIDCScopes        []string // 请求的 scope
+	OIDCUsernameClaim string   // 作为用户名的声明
+	OIDCNicknameClaim string   // 作为昵称的声明
+	OIDCGroupsClaim   string   // 记录所属组的声明
+	OIDCGroupRoles    string   // 组到角色的映射，格式为 角色:组;角色:组
+	OIDCDefaultRole   string   // 不属于任何映射组时使用的角色，为空表示拒绝登录
+	OIDCPostLoginURL  string   // 回调完成后跳转的前端地址，为空时回调直接返回 JSON
 }
 
 // LoadConfig 从 .env 文件加载配置
@@ -28,19 +118,183 @@ func LoadConfig() (*Config, error) {
 	if err != nil {
 		// 如果错误不是 "file does not exist"，那么就是一个真实的 I/O 错误。
 		// 如果是 "file does not exist"，我们也将其视为一个需要注意的警告。
-		log.Printf("警告: 无法加载 .env 文件。将依赖系统环境变量。错误: %v", err)
+		slog.Warn("无法加载 .env 文件，将依赖系统环境变量", "error", err)
 	}
 
 	cfg := &Config{
 		ServerPort:         os.Getenv("SERVER_PORT"),
 		DBConnectionString: os.Getenv("DB_CONNECTION_STRING"),
 		JWTSecret:          os.Getenv("JWT_SECRET"),
+		FormSigningSecret:  os.Getenv("FORM_SIGNING_SECRET"),
+		LogLevel:           os.Getenv("LOG_LEVEL"),
+		LogFormat:          os.Getenv("LOG_FORMAT"),
+		MetricsAddr:        os.Getenv("METRICS_ADDR"),
+		MetricsToken:       os.Getenv("METRICS_TOKEN"),
+		TracesExporter:     os.Getenv("OTEL_TRACES_EXPORTER"),
+		TracesFile:         os.Getenv("OTEL_TRACES_FILE"),
+		ServiceName:        os.Getenv("OTEL_SERVICE_NAME"),
+		TracesSampleRatio:  1,
+		TrustedProxies:     []string{"127.0.0.1", "::1"},
+
+		JWTSigningAlg:          os.Getenv("JWT_SIGNING_ALG"),
+		JWTKeyRotationInterval: envDuration("JWT_KEY_ROTATION_INTERVAL"),
+		JWTKeyPrePublish:       envDuration("JWT_KEY_PREPUBLISH"),
+
+		PasswordMinLength:  envInt("PASSWORD_MIN_LENGTH"),
+		PasswordMinClasses: envInt("PASSWORD_MIN_CLASSES"),
+		PasswordHistory:    envInt("PASSWORD_HISTORY"),
+		PasswordBreachList: os.Getenv("PASSWORD_BREACH_LIST"),
+
+		LoginMaxFailures:     envInt("LOGIN_MAX_FAILURES"),
+		LoginIPMaxFailures:   envInt("LOGIN_IP_MAX_FAILURES"),
+		LoginFailureWindow:   envDuration("LOGIN_FAILURE_WINDOW"),
+		LoginLockoutDuration: envDuration("LOGIN_LOCKOUT_DURATION"),
+		LoginBackoffBase:     envDuration("LOGIN_BACKOFF_BASE"),
+		LoginBackoffMax:      envDuration("LOGIN_BACKOFF_MAX"),
+		LoginAttemptStore:    os.Getenv("LOGIN_ATTEMPT_STORE"),
+		LoginFormMinAge:      envDuration("LOGIN_FORM_MIN_AGE"),
+		LoginFormMaxAge:      envDuration("LOGIN_FORM_MAX_AGE"),
+
+		MFAIssuer: os.Getenv("MFA_ISSUER"),
+
+		SLAEvaluationInterval: envDuration("SLA_EVALUATION_INTERVAL"),
+
+		WebhookTimeout:     envDuration("WEBHOOK_TIMEOUT"),
+		WebhookMaxAttempts: envInt("WEBHOOK_MAX_ATTEMPTS"),
+		WebhookRetryBase:   envDuration("WEBHOOK_RETRY_BASE"),
+		WebhookRetryMax:    envDuration("WEBHOOK_RETRY_MAX"),
+
+		SMTPHost:         os.Getenv("SMTP_HOST"),
+		SMTPPort:         envInt("SMTP_PORT"),
+		SMTPUsername:     os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:     os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:         os.Getenv("SMTP_FROM"),
+		SMTPSecurity:     os.Getenv("SMTP_SECURITY"),
+		SMTPTimeout:      envDuration("SMTP_TIMEOUT"),
+		EmailMaxAttempts: envInt("EMAIL_MAX_ATTEMPTS"),
+		EmailRetryBase:   envDuration("EMAIL_RETRY_BASE"),
+		EmailRetryMax:    envDuration("EMAIL_RETRY_MAX"),
+		EmailDigestTime:  envClock("EMAIL_DIGEST_TIME"),
+
+		AuthProviders: envList("AUTH_PROVIDERS", []string{"local"}),
+
+		LDAPURL:               os.Getenv("LDAP_URL"),
+		LDAPBindDN:            os.Getenv("LDAP_BIND_DN"),
+		LDAPBindPassword:      os.Getenv("LDAP_BIND_PASSWORD"),
+		LDAPBaseDN:            os.Getenv("LDAP_BASE_DN"),
+		LDAPUserFilter:        envString("LDAP_USER_FILTER", "(uid=%s)"),
+		LDAPNicknameAttribute: envString("LDAP_NICKNAME_ATTRIBUTE", "displayName"),
+		LDAPGroupAttribute:    envString("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+		LDAPGroupRoles:        os.Getenv("LDAP_GROUP_ROLES"),
+		LDAPDefaultRole:       os.Getenv("LDAP_DEFAULT_ROLE"),
+		LDAPTimeout:           envDuration("LDAP_TIMEOUT"),
+
+		OIDCIssuerURL:     os.Getenv("OIDC_ISSUER_URL"),
+		OIDCClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCScopes:        envList("OIDC_SCOPES", []string{"openid", "profile", "email"}),
+		OIDCUsernameClaim: envString("OIDC_USERNAME_CLAIM", "preferred_username"),
+		OIDCNicknameClaim: envString("OIDC_NICKNAME_CLAIM", "name"),
+		OIDCGroupsClaim:   envString("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCGroupRoles:    os.Getenv("OIDC_GROUP_ROLES"),
+		OIDCDefaultRole:   os.Getenv("OIDC_DEFAULT_ROLE"),
+		OIDCPostLoginURL:  os.Getenv("OIDC_POST_LOGIN_URL"),
+	}
+	cfg.TrustedProxies = envList("TRUSTED_PROXIES", cfg.TrustedProxies)
+	cfg.RequireIfMatch, _ = strconv.ParseBool(os.Getenv("REQUIRE_IF_MATCH"))
+	cfg.JWTAcceptLegacyHS256, _ = strconv.ParseBool(os.Getenv("JWT_ACCEPT_LEGACY_HS256"))
+	cfg.SMTPInsecureSkipVerify, _ = strconv.ParseBool(os.Getenv("SMTP_INSECURE_SKIP_VERIFY"))
+	cfg.LDAPStartTLS, _ = strconv.ParseBool(os.Getenv("LDAP_START_TLS"))
+	cfg.LDAPInsecureSkipVerify, _ = strconv.ParseBool(os.Getenv("LDAP_INSECURE_SKIP_VERIFY"))
+	if v := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			slog.Warn("OTEL_TRACES_SAMPLER_ARG 无效，将使用默认值 1.0", "value", v)
+		} else {
+			cfg.TracesSampleRatio = ratio
+		}
+	}
+
+	switch cfg.SMTPSecurity {
+	case "", "none", "starttls", "tls":
+	default:
+		slog.Warn("SMTP_SECURITY 无效，将使用默认值 starttls", "value", cfg.SMTPSecurity)
+		cfg.SMTPSecurity = ""
+	}
+	if cfg.SMTPHost != "" && cfg.SMTPFrom == "" {
+		slog.Warn("已设置 SMTP_HOST 但 SMTP_FROM 为空，邮件将无法发送")
 	}
 
 	// 添加一个检查，如果关键配置为空，也进行提示
 	if cfg.DBConnectionString == "" {
-		log.Println("警告: 环境变量 DB_CONNECTION_STRING 为空。")
+		slog.Warn("环境变量 DB_CONNECTION_STRING 为空")
 	}
 
 	return cfg, nil
 }
+
+// envString 读取一个字符串环境变量，未设置时返回 def
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envList 读取一个逗号分隔的列表环境变量，忽略空白项；未设置时返回 def
+func envList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// envInt 读取一个正整数环境变量，未设置或无效时返回 0
+func envInt(key string) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		slog.Warn("环境变量不是有效的正整数，将使用默认值", "key", key, "value", v)
+		return 0
+	}
+	return n
+}
+
+// envDuration 读取一个时长环境变量（例如 15m、30s），未设置或无效时返回 0
+func envDuration(key string) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		slog.Warn("环境变量不是有效的时长，将使用默认值", "key", key, "value", v)
+		return 0
+	}
+	return d
+}
+
+// envClock 读取一个一天中的时间环境变量（HH:MM），返回距零点的时长；未设置或无效时返回 -1
+func envClock(key string) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return -1
+	}
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		slog.Warn("环境变量不是有效的时间（HH:MM），将使用默认值", "key", key, "value", v)
+		return -1
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
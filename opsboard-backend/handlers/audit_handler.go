@@ -0,0 +1,68 @@
+/**
+ * @file handlers/audit_handler.go
+ * @description 处理与审计日志相关的 HTTP 请求，支持分页查询和导出。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [链路追踪]：调用服务层时传入请求的 context（`c.Request.Context()`），使服务和数据库的 span 挂在请求的 span 之下。
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"opsboard-backend/models"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogList 处理获取审计日志列表的请求（支持分页和筛选）
+func GetAuditLogList(c *gin.Context) {
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	var filter services.AuditLogFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	result, err := services.GetPaginatedAuditLogs(c.Request.Context(), page, pageSize, filter)
+	if err != nil {
+		utils.RespondError(c, err, "获取审计日志列表失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// auditLogExportColumns 定义了审计日志导出文件的列
+var auditLogExportColumns = []utils.ExportColumn{
+	utils.Col("id", "ID", "ID"),
+	utils.Col("username", "用户名", "Username"),
+	utils.Col("userId", "用户 ID", "User ID"),
+	utils.Col("action", "操作类型", "Action"),
+	utils.Col("targetEntity", "目标实体", "Target Entity"),
+	utils.Col("targetId", "目标 ID", "Target ID"),
+	utils.Col("details", "详情", "Details"),
+	utils.Col("createdAt", "记录时间", "Created At"),
+}
+
+// ExportAuditLogs 处理审计日志导出的请求，筛选条件与 GetAuditLogList 相同，但不分页。
+func ExportAuditLogs(c *gin.Context) {
+	var filter services.AuditLogFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	streamExport(c, "audit-logs", auditLogExportColumns, func(e *utils.Exporter) error {
+		return services.StreamAuditLogs(c.Request.Context(), filter, func(l *models.AuditLog) error {
+			return e.Write(l.LogID, l.Username, l.UserID, l.Action, l.TargetEntity,
+				l.TargetID, l.Details, l.CreatedAt)
+		})
+	})
+}
@@ -2,24 +2,35 @@
  * @file opsboard-backend/handlers/auth_handler.go
  * @description 处理认证相关的 HTTP 请求，例如登录和刷新令牌。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [最终修复]：将响应结构体 `LoginResponse` 和 `RefreshTokenResponse` 的字段名改为首字母大写（例如 `AccessToken`）。
- *   - [原因]：此修改是解决“登录响应无效”问题的决定性方案。在 Go 中，只有导出的（首字母大写）结构体字段才能被 `encoding/json` 包序列化。通过将字段导出，并保留 `json:"..."` 标签，我们确保了后端能够生成包含正确 `accessToken` 和 `refreshToken` 字段的 JSON 响应，从而彻底修复了整个认证流程。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
  */
 
 package handlers
 
 import (
-	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"opsboard-backend/metrics"
+	"opsboard-backend/models"
 	"opsboard-backend/services"
 	"opsboard-backend/utils"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 )
 
+// errInvalidCredentials 是用户名不存在或密码错误时统一返回的错误，避免泄露用户名是否存在
+var errInvalidCredentials = utils.ErrUnauthorized("用户名或密码错误")
+
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username  string `json:"username" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	Website   string `json:"website"`   // 蜜罐字段，真实用户看不到，必须为空
+	FormToken string `json:"formToken"` // 由 GET /api/auth/form-token 签发的表单令牌
 }
 
 // [核心修复] 结构体字段名必须首字母大写才能被导出并序列化
@@ -28,6 +39,12 @@ type LoginResponse struct {
 	RefreshToken string `json:"refreshToken"`
 }
 
+// UnlockLoginRequest 是管理员解除登录锁定的请求体，用户名和 IP 至少填写一项
+type UnlockLoginRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip" binding:"omitempty,ip"`
+}
+
 type RefreshRequest struct {
 	RefreshToken string `json:"refreshToken" binding:"required"`
 }
@@ -39,72 +56,223 @@ type RefreshTokenResponse struct {
 
 func Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "无效的请求体"})
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if reason := services.CheckLoginForm(ctx, req.Website, req.FormToken); reason != "" {
+		metrics.LoginAttemptsTotal.WithLabelValues(metrics.ResultRejected).Inc()
+		services.CreateLog(ctx, "", string(services.UserLoginRejected), services.AuditTarget{Entity: "users"}, services.LogDetails{
+			"reason":     reason,
+			"username":   req.Username,
+			"ip_address": c.ClientIP(),
+			"user_agent": c.Request.UserAgent(),
+		})
+		utils.RespondError(c, loginFormError(reason), "")
+		return
+	}
+
+	block, err := services.CheckLoginAttempt(ctx, req.Username, c.ClientIP())
+	if err != nil {
+		utils.RespondError(c, err, "检查登录限制失败")
+		return
+	}
+	if block != nil {
+		metrics.LoginAttemptsTotal.WithLabelValues(metrics.ResultBlocked).Inc()
+		respondLoginBlocked(c, block)
 		return
 	}
 
-	user, err := services.GetUserByUsername(req.Username)
+	user, provider, err := services.AuthenticatePassword(ctx, req.Username, req.Password)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "用户名或密码错误"})
+		var failure *services.AuthFailure
+		if !errors.As(err, &failure) {
+			utils.RespondError(c, err, "认证服务暂时不可用")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "数据库查询失败"})
+		recordLoginFailure(c, req.Username, failure.UserID, failure.Reason)
+		// 以下两种原因只有密码正确时才会出现，可以明确告知用户需要联系管理员
+		switch failure.Reason {
+		case services.AuthFailureNoRole:
+			utils.RespondError(c, utils.ErrForbidden("您的目录账户未被授权访问本系统，请联系管理员"), "")
+			return
+		case services.AuthFailureDisabled:
+			utils.RespondError(c, services.ErrUserDisabled, "")
+			return
+		}
+		utils.RespondError(c, errInvalidCredentials, "")
 		return
 	}
 
-	if req.Password != user.Password {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "用户名或密码错误"})
+	mfa, err := services.GetMFAStatus(ctx, user.UserID.String(), user.Role)
+	if err != nil {
+		utils.RespondError(c, err, "获取两步验证状态失败")
+		return
+	}
+	if mfa.Enabled || mfa.Required {
+		respondMFAChallenge(c, user, !mfa.Enabled)
 		return
 	}
 
-	accessToken, err := utils.GenerateAccessToken(user.UserID.String())
+	resp, err := issueLoginTokens(c, user, provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "生成访问令牌失败"})
+		utils.RespondError(c, err, "生成令牌失败")
 		return
 	}
+	c.JSON(http.StatusOK, resp)
+}
 
-	refreshToken, err := utils.GenerateRefreshToken(user.UserID.String())
+// issueLoginTokens 在用户完成全部认证步骤后签发访问令牌和刷新令牌，清除登录失败计数并写入登录成功的审计日志。
+// method 记录本次使用的认证方式（local、ldap、totp、recovery_code 等）。
+func issueLoginTokens(c *gin.Context, user *models.User, method string) (*LoginResponse, error) {
+	ctx := c.Request.Context()
+	resp, err := newTokenPair(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "生成刷新令牌失败"})
-		return
+		return nil, err
+	}
+
+	if err := services.RecordLoginSuccess(ctx, user.Username); err != nil {
+		// 清除失败计数失败不影响本次登录，计数会在统计窗口结束后自然过期
+		slog.WarnContext(ctx, "清除登录失败计数失败", "error", err)
 	}
 
 	logDetails := services.LogDetails{
 		"ip_address": c.ClientIP(),
 		"user_agent": c.Request.UserAgent(),
+		"method":     method,
+	}
+	services.CreateLog(ctx, user.UserID.String(), string(services.UserLoginSuccess), services.Target("users", user.UserID), logDetails)
+	metrics.LoginAttemptsTotal.WithLabelValues(metrics.ResultSuccess).Inc()
+
+	return resp, nil
+}
+
+// newTokenPair 按用户当前的会话版本签发一对访问令牌和刷新令牌
+func newTokenPair(user *models.User) (*LoginResponse, error) {
+	accessToken, err := utils.GenerateAccessToken(user.UserID.String(), user.SessionVersion)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := utils.GenerateRefreshToken(user.UserID.String(), user.SessionVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResponse{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// GetFormToken 签发一个登录表单令牌，前端应在渲染登录表单时调用，并在每次提交失败后重新获取
+func GetFormToken(c *gin.Context) {
+	token, err := services.IssueFormToken()
+	if err != nil {
+		utils.RespondError(c, err, "生成表单令牌失败")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, token)
+}
+
+// loginFormError 返回机器人验证失败时的错误。
+// 令牌缺失、过期或已使用多半是页面停留过久或重复提交，提示用户刷新；其余情况只返回笼统的“请求无效”，不提示机器人如何绕过。
+func loginFormError(reason string) error {
+	switch reason {
+	case services.BotCheckMissing, services.BotCheckExpired, services.BotCheckReplayed:
+		return utils.ErrBadRequest("登录表单已失效，请刷新页面后重试")
+	default:
+		return utils.ErrBadRequest("请求无效")
 	}
-	services.CreateLog(user.UserID.String(), string(services.UserLoginSuccess), logDetails)
+}
 
-	c.JSON(http.StatusOK, LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+// recordLoginFailure 统计一次失败的登录并写入审计日志；记录失败只输出日志，不影响对客户端的响应
+func recordLoginFailure(c *gin.Context, username, userID, reason string) {
+	metrics.LoginAttemptsTotal.WithLabelValues(metrics.ResultFailure).Inc()
+	err := services.RecordLoginFailure(c.Request.Context(), services.LoginFailure{
+		Username:  username,
+		UserID:    userID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Reason:    reason,
 	})
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "记录登录失败次数失败", "error", err)
+	}
+}
+
+// respondLoginBlocked 返回 429 响应
+func respondLoginBlocked(c *gin.Context, block *services.LoginBlock) {
+	utils.RespondError(c, loginBlockedError(c, block), "")
+}
+
+// loginBlockedError 通过 Retry-After 告知客户端需要等待的秒数，并返回对应的 429 错误。
+// 错误不区分是用户名还是 IP 触发了限制，避免泄露用户名是否存在。
+func loginBlockedError(c *gin.Context, block *services.LoginBlock) error {
+	seconds := int(math.Ceil(block.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+
+	message := fmt.Sprintf("登录尝试过于频繁，请在 %d 秒后重试", seconds)
+	if block.Locked {
+		message = fmt.Sprintf("登录失败次数过多，已被临时锁定，请在 %d 秒后重试", seconds)
+	}
+	return utils.NewAppError(utils.CodeTooManyRequests, message)
+}
+
+// UnlockLogin 处理管理员解除登录锁定的请求
+func UnlockLogin(c *gin.Context) {
+	var req UnlockLoginRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	if req.Username == "" && req.IP == "" {
+		utils.RespondError(c, utils.ErrValidation(utils.FieldError{Field: "username", Message: "用户名和 IP 至少填写一项"}), "")
+		return
+	}
+
+	if err := services.UnlockLogin(c.Request.Context(), req.Username, req.IP); err != nil {
+		utils.RespondError(c, err, "解除登录锁定失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.UserLoginUnlocked), services.AuditTarget{Entity: "login_attempts"}, services.LogDetails{
+		"username":   req.Username,
+		"target_ip":  req.IP,
+		"ip_address": c.ClientIP(),
+	})
+	c.Status(http.StatusNoContent)
 }
 
 func RefreshToken(c *gin.Context) {
 	var req RefreshRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "无效的请求体"})
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
 		return
 	}
 
-	claims, err := utils.ValidateToken(req.RefreshToken)
+	claims, err := utils.ValidateTokenType(req.RefreshToken, utils.TokenTypeRefresh)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "无效或已过期的刷新令牌"})
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			utils.RespondError(c, utils.NewAppError(utils.CodeTokenExpired, "刷新令牌已过期"), "")
+		} else {
+			utils.RespondError(c, utils.ErrUnauthorized("无效的刷新令牌"), "")
+		}
 		return
 	}
 
 	userID, ok := claims["user_id"].(string)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "刷新令牌中缺少用户信息"})
+		utils.RespondError(c, utils.ErrUnauthorized("刷新令牌中缺少用户信息"), "")
+		return
+	}
+	user, err := services.CheckUserSession(c.Request.Context(), userID, utils.SessionVersion(claims))
+	if err != nil {
+		utils.RespondError(c, err, "校验用户状态失败")
 		return
 	}
 
-	newAccessToken, err := utils.GenerateAccessToken(userID)
+	newAccessToken, err := utils.GenerateAccessToken(userID, user.SessionVersion)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "生成新的访问令牌失败"})
+		utils.RespondError(c, err, "生成新的访问令牌失败")
 		return
 	}
 
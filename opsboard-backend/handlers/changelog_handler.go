@@ -2,42 +2,67 @@
  * @file handlers/changelog_handler.go
  * @description 处理与更新日志相关的 HTTP 请求，支持分页查询、删除和状态变更操作。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [最终修复]：将 `CompleteChangelog` 和 `UncompleteChangelog` 处理器成功时的响应状态码从 `200 OK` (带有隐式的空响应体) 更改为 `204 No Content`。
- *   - [原因]：此修改解决了因前端尝试解析空响应体而导致的 "Unexpected end of JSON input" 错误。`204 No Content` 是一个明确的信号，告知客户端操作已成功且无需解析任何响应内容，从而修复了状态变更功能的根本问题。
+ *   - [链路追踪]：调用服务层时传入请求的 context（`c.Request.Context()`），使服务和数据库的 span 挂在请求的 span 之下。
  */
 
 package handlers
 
 import (
 	"net/http"
+	"opsboard-backend/models"
 	"opsboard-backend/services"
-	"strconv"
+	"opsboard-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GetChangelogList ... (保持不变)
 func GetChangelogList(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
-	result, err := services.GetPaginatedChangelogs(page, pageSize)
+	page, pageSize, err := parsePagination(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "获取更新日志列表失败"})
+		utils.RespondError(c, err, "")
+		return
+	}
+	var filter services.ChangelogFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	result, err := services.GetPaginatedChangelogs(c.Request.Context(), page, pageSize, filter)
+	if err != nil {
+		utils.RespondError(c, err, "获取更新日志列表失败")
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
 
+// GetChangelogByID 处理根据 ID 获取单条更新日志详情的请求，并返回 ETag。
+func GetChangelogByID(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	changelog, err := services.GetChangelogByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "获取更新日志详情失败")
+		return
+	}
+
+	setETag(c, changelog.Version)
+	c.JSON(http.StatusOK, changelog)
+}
+
 // DeleteChangelog ... (保持不变)
 func DeleteChangelog(c *gin.Context) {
-	logID := c.Param("id")
-	if logID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "日志 ID 不能为空"})
+	logID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
 		return
 	}
-	err := services.DeleteChangelogByID(logID)
+	err = services.DeleteChangelogByID(c.Request.Context(), logID, expectedVersion(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "删除更新日志失败"})
+		utils.RespondError(c, err, "删除更新日志失败")
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -45,9 +70,13 @@ func DeleteChangelog(c *gin.Context) {
 
 // CompleteChangelog 处理将日志标记为“完成”的请求
 func CompleteChangelog(c *gin.Context) {
-	logID := c.Param("id")
-	if err := services.MarkChangelogAsCompleted(logID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "标记为完成失败"})
+	logID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	if err = services.MarkChangelogAsCompleted(c.Request.Context(), logID, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "标记为完成失败")
 		return
 	}
 	// [核心修复] 返回 204 No Content
@@ -56,11 +85,43 @@ func CompleteChangelog(c *gin.Context) {
 
 // UncompleteChangelog 处理将日志标记为“挂起”的请求
 func UncompleteChangelog(c *gin.Context) {
-	logID := c.Param("id")
-	if err := services.MarkChangelogAsPending(logID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "取消完成失败"})
+	logID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	if err = services.MarkChangelogAsPending(c.Request.Context(), logID, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "取消完成失败")
 		return
 	}
 	// [核心修复] 返回 204 No Content
 	c.Status(http.StatusNoContent)
 }
+
+// changelogExportColumns 定义了更新日志导出文件的列
+var changelogExportColumns = []utils.ExportColumn{
+	utils.Col("id", "ID", "ID"),
+	utils.Col("customerName", "客户名称", "Customer"),
+	utils.Col("updateTime", "更新时间", "Update Time"),
+	utils.Col("updateType", "更新类型", "Update Type"),
+	utils.Col("updateContent", "更新内容", "Content"),
+	utils.Col("status", "状态", "Status"),
+	utils.Col("completionTime", "完成时间", "Completion Time"),
+	utils.Col("createdAt", "创建时间", "Created At"),
+}
+
+// ExportChangelogs 处理更新日志导出的请求，筛选条件与 GetChangelogList 相同，但不分页。
+func ExportChangelogs(c *gin.Context) {
+	var filter services.ChangelogFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	streamExport(c, "changelogs", changelogExportColumns, func(e *utils.Exporter) error {
+		return services.StreamChangelogs(c.Request.Context(), filter, func(l *models.Changelog) error {
+			return e.Write(l.LogID, l.CustomerName, l.UpdateTime, l.UpdateType, l.UpdateContent,
+				l.Status, l.CompletionTime, l.CreatedAt)
+		})
+	})
+}
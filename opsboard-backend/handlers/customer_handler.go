@@ -0,0 +1,66 @@
+/**
+ * @file handlers/customer_handler.go
+ * @description 处理与客户相关的 HTTP 请求，支持分页查询和删除操作。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [链路追踪]：调用服务层时传入请求的 context（`c.Request.Context()`），使服务和数据库的 span 挂在请求的 span 之下。
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCustomerList 处理获取客户列表的请求（支持分页和按名称筛选）
+func GetCustomerList(c *gin.Context) {
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	result, err := services.GetPaginatedCustomers(c.Request.Context(), page, pageSize, c.Query("customerName"))
+	if err != nil {
+		utils.RespondError(c, err, "获取客户列表失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetCustomerByID 处理根据 ID 获取单个客户详情的请求，并返回 ETag。
+func GetCustomerByID(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	customer, err := services.GetCustomerByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "获取客户详情失败")
+		return
+	}
+
+	setETag(c, customer.Version)
+	c.JSON(http.StatusOK, customer)
+}
+
+// DeleteCustomer 处理删除客户的请求
+func DeleteCustomer(c *gin.Context) {
+	customerID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err = services.DeleteCustomerByID(c.Request.Context(), customerID, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "删除客户失败")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
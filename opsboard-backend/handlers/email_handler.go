@@ -0,0 +1,85 @@
+/**
+ * @file handlers/email_handler.go
+ * @description 处理邮件通知的 HTTP 请求：查询发件箱、手动重试邮件和发送测试邮件。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SendTestEmailRequest 是发送测试邮件的请求体
+type SendTestEmailRequest struct {
+	To string `json:"to" binding:"required,email,max=254"`
+}
+
+// GetEmailOutbox 处理分页查询发件箱的请求
+func GetEmailOutbox(c *gin.Context) {
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var filter services.EmailOutboxFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	result, err := services.GetEmailOutbox(c.Request.Context(), page, pageSize, filter)
+	if err != nil {
+		utils.RespondError(c, err, "获取发件箱失败")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RetryEmail 处理手动重试一封邮件的请求，返回重新放回发送队列的邮件
+func RetryEmail(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	email, err := services.RetryEmail(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "重试邮件失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.EmailRetried), services.Target("email_outbox", email.EmailID), services.LogDetails{
+		"email_id":   email.EmailID,
+		"template":   email.Template,
+		"ip_address": c.ClientIP(),
+	})
+	c.JSON(http.StatusAccepted, email)
+}
+
+// SendTestEmail 处理发送测试邮件的请求，发送成功返回 204
+func SendTestEmail(c *gin.Context) {
+	var req SendTestEmailRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	err := services.SendTestEmail(c.Request.Context(), req.To)
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.EmailTestSent), services.AuditTarget{}, services.LogDetails{
+		"to":         req.To,
+		"success":    err == nil,
+		"ip_address": c.ClientIP(),
+	})
+	if err != nil {
+		utils.RespondError(c, err, "发送测试邮件失败")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,47 @@
+/**
+ * @file handlers/export.go
+ * @description 提供各列表导出端点共用的 HTTP 处理逻辑：解析导出格式与表头语言、设置下载响应头，并驱动流式写入。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [错误处理]：不支持的 `format` 参数改为返回统一的 400 `VALIDATION_FAILED` 错误响应。
+ */
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"opsboard-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamExport 根据查询参数 `format`（csv|xlsx|ndjson，默认 csv）和 `lang`（zh|en，默认取 Accept-Language）
+// 创建导出写入器，设置附件下载响应头，然后调用 stream 逐行写入数据。
+// name 用于生成下载文件名，例如 "changelogs" 会生成 "changelogs-20250101-120000.csv"。
+func streamExport(c *gin.Context, name string, columns []utils.ExportColumn, stream func(e *utils.Exporter) error) {
+	format := c.DefaultQuery("format", utils.FormatCSV)
+	if !utils.IsExportFormat(format) {
+		utils.RespondError(c, utils.ErrValidation(utils.FieldError{Field: "format", Message: "仅支持 csv、xlsx 或 ndjson"}), "")
+		return
+	}
+	lang := utils.ResolveExportLang(c.Query("lang"), c.GetHeader("Accept-Language"))
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Content-Type", utils.ExportContentType(format))
+	c.Status(http.StatusOK)
+
+	exporter, err := utils.NewExporter(c.Writer, format, lang, columns)
+	if err == nil {
+		err = stream(exporter)
+		if closeErr := exporter.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		// 响应头和部分内容可能已经发送，此时只能记录错误并中断响应
+		c.Error(err)
+		c.Abort()
+	}
+}
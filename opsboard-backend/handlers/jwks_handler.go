@@ -0,0 +1,22 @@
+/**
+ * @file jwks_handler.go
+ * @description 提供 `/.well-known/jwks.json` 端点，公开校验 OpsBoard 访问令牌所需的公钥。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [重构]：缓存时长改用 `utils.JWKSMaxAge`，与签名密钥策略的校验共用同一个值。
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"opsboard-backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS 返回访问令牌密钥组中所有可用于校验的公钥，包括已发布但尚未开始签名的新密钥
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(utils.JWKSMaxAge.Seconds())))
+	c.JSON(http.StatusOK, gin.H{"keys": utils.JWKS()})
+}
@@ -2,41 +2,67 @@
  * @file handlers/maintenance_handler.go
  * @description 处理与维护任务相关的 HTTP 请求，支持分页查询、删除和状态变更操作。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [新增功能]：添加了 `CompleteMaintenanceTask` 和 `UncompleteMaintenanceTask` 两个处理器，分别用于处理将任务标记为“完成”和“挂起”的 `PUT` 请求。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
  */
 
 package handlers
 
 import (
 	"net/http"
+	"opsboard-backend/models"
 	"opsboard-backend/services"
-	"strconv"
+	"opsboard-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GetMaintenanceTaskList ... (保持不变)
 func GetMaintenanceTaskList(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
-	result, err := services.GetPaginatedMaintenanceTasks(page, pageSize)
+	page, pageSize, err := parsePagination(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "获取任务列表失败"})
+		utils.RespondError(c, err, "")
+		return
+	}
+	var filter services.MaintenanceTaskFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	result, err := services.GetPaginatedMaintenanceTasks(c.Request.Context(), page, pageSize, filter)
+	if err != nil {
+		utils.RespondError(c, err, "获取任务列表失败")
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
 
+// GetMaintenanceTaskByID 处理根据 ID 获取单个维护任务详情的请求，并返回 ETag。
+func GetMaintenanceTaskByID(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	task, err := services.GetMaintenanceTaskByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "获取任务详情失败")
+		return
+	}
+
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, task)
+}
+
 // DeleteMaintenanceTask ... (保持不变)
 func DeleteMaintenanceTask(c *gin.Context) {
-	taskID := c.Param("id")
-	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "任务 ID 不能为空"})
+	taskID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
 		return
 	}
-	err := services.DeleteMaintenanceTaskByID(taskID)
+	err = services.DeleteMaintenanceTaskByID(c.Request.Context(), taskID, expectedVersion(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "删除任务失败"})
+		utils.RespondError(c, err, "删除任务失败")
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -44,9 +70,13 @@ func DeleteMaintenanceTask(c *gin.Context) {
 
 // CompleteMaintenanceTask 处理将任务标记为“完成”的请求
 func CompleteMaintenanceTask(c *gin.Context) {
-	taskID := c.Param("id")
-	if err := services.MarkTaskAsCompleted(taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "标记为完成失败"})
+	taskID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	if err = services.MarkTaskAsCompleted(c.Request.Context(), taskID, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "标记为完成失败")
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -54,10 +84,75 @@ func CompleteMaintenanceTask(c *gin.Context) {
 
 // UncompleteMaintenanceTask 处理将任务标记为“挂起”的请求
 func UncompleteMaintenanceTask(c *gin.Context) {
-	taskID := c.Param("id")
-	if err := services.MarkTaskAsPending(taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "取消完成失败"})
+	taskID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	if err = services.MarkTaskAsPending(c.Request.Context(), taskID, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "取消完成失败")
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
+
+// FailMaintenanceTaskRequest 是将任务标记为“失败”的请求体，LogOutput 为空时保留原来的日志输出
+type FailMaintenanceTaskRequest struct {
+	LogOutput string `json:"logOutput" binding:"max=1000000"`
+}
+
+// FailMaintenanceTask 处理将任务标记为“失败”的请求
+func FailMaintenanceTask(c *gin.Context) {
+	taskID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req FailMaintenanceTaskRequest
+	if c.Request.ContentLength != 0 {
+		if err := bindJSON(c, &req); err != nil {
+			utils.RespondError(c, err, "")
+			return
+		}
+	}
+
+	if err = services.MarkTaskAsFailed(c.Request.Context(), taskID, req.LogOutput, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "标记为失败失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.MaintenanceTaskFailed), services.Target("maintenance", taskID), services.LogDetails{
+		"task_id":    taskID,
+		"ip_address": c.ClientIP(),
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// maintenanceExportColumns 定义了维护任务导出文件的列
+var maintenanceExportColumns = []utils.ExportColumn{
+	utils.Col("id", "ID", "ID"),
+	utils.Col("taskName", "任务名称", "Task Name"),
+	utils.Col("type", "任务类型", "Type"),
+	utils.Col("target", "目标服务器", "Target Server"),
+	utils.Col("status", "状态", "Status"),
+	utils.Col("publicationTime", "发布时间", "Publication Time"),
+	utils.Col("completionTime", "完成时间", "Completion Time"),
+	utils.Col("logOutput", "日志输出", "Log Output"),
+	utils.Col("createdAt", "创建时间", "Created At"),
+}
+
+// ExportMaintenanceTasks 处理维护任务导出的请求，筛选条件与 GetMaintenanceTaskList 相同，但不分页。
+func ExportMaintenanceTasks(c *gin.Context) {
+	var filter services.MaintenanceTaskFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	streamExport(c, "maintenance", maintenanceExportColumns, func(e *utils.Exporter) error {
+		return services.StreamMaintenanceTasks(c.Request.Context(), filter, func(t *models.MaintenanceTask) error {
+			return e.Write(t.TaskID, t.TaskName, t.TaskType, t.TargetServerName, t.Status,
+				t.PublicationTime, t.CompletionTime, t.LogOutput, t.CreatedAt)
+		})
+	})
+}
@@ -0,0 +1,416 @@
+/**
+ * @file mfa_handler.go
+ * @description 处理 TOTP 两步验证相关的 HTTP 请求：登录第二步、强制绑定、用户自助管理以及管理员的策略配置和重置。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [请求解析]：`ResetUserMFA` 改用 `parseUUIDParam` 解析路径中的用户 ID，校验错误与其他用户管理接口一致。
+ */
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"opsboard-backend/models"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MFAChallengeResponse 是密码校验或单点登录通过但还需要两步验证时 Login 和 OIDCCallback 的响应
+type MFAChallengeResponse struct {
+	MFARequired        bool   `json:"mfaRequired"`        // 恒为 true，便于前端区分于 LoginResponse
+	EnrollmentRequired bool   `json:"enrollmentRequired"` // 为 true 时用户的角色要求两步验证但尚未绑定，需要先完成绑定
+	MFAToken           string `json:"mfaToken"`
+	ExpiresIn          int    `json:"expiresIn"` // 挑战令牌的有效期（秒）
+}
+
+// MFAVerifyRequest 是登录第二步的请求体，code 和 recoveryCode 二选一
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfaToken" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// MFAChallengeRequest 是使用挑战令牌开始强制绑定的请求体
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+}
+
+// MFAChallengeConfirmRequest 是使用挑战令牌确认强制绑定的请求体
+type MFAChallengeConfirmRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFACodeRequest 是已登录用户提交 TOTP 验证码的请求体
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAEnrollmentLoginResponse 是通过挑战令牌完成强制绑定后的响应：登录令牌和只显示一次的恢复码
+type MFAEnrollmentLoginResponse struct {
+	LoginResponse
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// RecoveryCodesResponse 包含只显示一次的恢复码
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// MFAPolicyRequest 是设置按角色强制两步验证的请求体，也用作查询的响应
+type MFAPolicyRequest struct {
+	Roles []string `json:"roles" binding:"required"`
+}
+
+// respondMFAChallenge 为通过密码校验的用户签发两步验证挑战令牌
+func respondMFAChallenge(c *gin.Context, user *models.User, enrollmentRequired bool) {
+	challenge, err := newMFAChallenge(user, enrollmentRequired)
+	if err != nil {
+		utils.RespondError(c, err, "生成两步验证令牌失败")
+		return
+	}
+	c.JSON(http.StatusOK, challenge)
+}
+
+// newMFAChallenge 为已通过第一步认证（密码或单点登录）的用户生成两步验证挑战
+func newMFAChallenge(user *models.User, enrollmentRequired bool) (*MFAChallengeResponse, error) {
+	token, err := utils.GenerateMFAToken(user.UserID.String())
+	if err != nil {
+		return nil, err
+	}
+	return &MFAChallengeResponse{
+		MFARequired:        true,
+		EnrollmentRequired: enrollmentRequired,
+		MFAToken:           token,
+		ExpiresIn:          int(utils.MFATokenTTL.Seconds()),
+	}, nil
+}
+
+// mfaChallengeUser 校验挑战令牌并返回对应的用户
+func mfaChallengeUser(c *gin.Context, token string) (*models.User, error) {
+	claims, err := utils.ValidateTokenType(token, utils.TokenTypeMFA)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, utils.NewAppError(utils.CodeTokenExpired, "两步验证已超时，请重新登录")
+		}
+		return nil, utils.ErrUnauthorized("无效的两步验证令牌")
+	}
+	userID, _ := claims["user_id"].(string)
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, utils.ErrUnauthorized("无效的两步验证令牌")
+	}
+	user, err := services.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrUnauthorized("无效的两步验证令牌")
+		}
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return nil, services.ErrUserDisabled
+	}
+	return user, nil
+}
+
+// guardMFACode 在登录防护下执行需要验证码的操作：失败过多时直接拒绝；fn 返回 ErrInvalidMFACode 时计入失败次数
+func guardMFACode(c *gin.Context, user *models.User, fn func() error) error {
+	block, err := services.CheckLoginAttempt(c.Request.Context(), user.Username, c.ClientIP())
+	if err != nil {
+		return err
+	}
+	if block != nil {
+		return loginBlockedError(c, block)
+	}
+
+	err = fn()
+	if errors.Is(err, services.ErrInvalidMFACode) {
+		recordLoginFailure(c, user.Username, user.UserID.String(), "invalid_mfa_code")
+	}
+	return err
+}
+
+// VerifyMFA 处理登录第二步：校验 TOTP 验证码或恢复码，通过后签发登录令牌
+func VerifyMFA(c *gin.Context) {
+	var req MFAVerifyRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		utils.RespondError(c, utils.ErrValidation(utils.FieldError{Field: "code", Message: "请输入验证码或恢复码"}), "")
+		return
+	}
+
+	user, err := mfaChallengeUser(c, req.MFAToken)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	ctx := c.Request.Context()
+	method := "totp"
+	err = guardMFACode(c, user, func() error {
+		var ok bool
+		var err error
+		if req.Code != "" {
+			ok, err = services.VerifyMFACode(ctx, user.UserID.String(), req.Code)
+		} else {
+			method = "recovery_code"
+			ok, err = services.UseRecoveryCode(ctx, user.UserID.String(), req.RecoveryCode)
+		}
+		if err == nil && !ok {
+			err = services.ErrInvalidMFACode
+		}
+		return err
+	})
+	if errors.Is(err, services.ErrInvalidMFACode) {
+		err = utils.ErrUnauthorized("验证码错误或已被使用")
+	}
+	if err != nil {
+		utils.RespondError(c, err, "两步验证失败")
+		return
+	}
+
+	if method == "recovery_code" {
+		services.CreateLog(ctx, user.UserID.String(), string(services.MFARecoveryCodeUsed), services.Target("users", user.UserID), services.LogDetails{
+			"ip_address": c.ClientIP(),
+		})
+	}
+	resp, err := issueLoginTokens(c, user, method)
+	if err != nil {
+		utils.RespondError(c, err, "生成令牌失败")
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// BeginMFAChallengeEnrollment 为角色被要求启用两步验证但尚未绑定的用户开始绑定（使用登录时获得的挑战令牌）
+func BeginMFAChallengeEnrollment(c *gin.Context) {
+	var req MFAChallengeRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	user, err := mfaChallengeUser(c, req.MFAToken)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	enrollment, err := services.BeginMFAEnrollment(c.Request.Context(), user.UserID.String(), user.Username)
+	if err != nil {
+		utils.RespondError(c, err, "开始绑定两步验证失败")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmMFAChallengeEnrollment 确认强制绑定，成功后直接完成登录，并返回只显示一次的恢复码
+func ConfirmMFAChallengeEnrollment(c *gin.Context) {
+	var req MFAChallengeConfirmRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	user, err := mfaChallengeUser(c, req.MFAToken)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	var codes []string
+	err = guardMFACode(c, user, func() (err error) {
+		codes, err = services.ConfirmMFAEnrollment(c.Request.Context(), user.UserID.String(), req.Code)
+		return err
+	})
+	if err != nil {
+		utils.RespondError(c, err, "确认两步验证失败")
+		return
+	}
+	services.CreateLog(c.Request.Context(), user.UserID.String(), string(services.MFAEnabled), services.Target("users", user.UserID), services.LogDetails{
+		"ip_address": c.ClientIP(),
+	})
+
+	resp, err := issueLoginTokens(c, user, "totp")
+	if err != nil {
+		utils.RespondError(c, err, "生成令牌失败")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, MFAEnrollmentLoginResponse{LoginResponse: *resp, RecoveryCodes: codes})
+}
+
+// GetMyMFA 返回当前用户的两步验证状态
+func GetMyMFA(c *gin.Context) {
+	user, err := currentUser(c)
+	if err != nil {
+		utils.RespondError(c, err, "获取用户信息失败")
+		return
+	}
+	status, err := services.GetMFAStatus(c.Request.Context(), user.UserID.String(), user.Role)
+	if err != nil {
+		utils.RespondError(c, err, "获取两步验证状态失败")
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// BeginMyMFAEnrollment 为当前用户生成 TOTP 密钥，返回密钥和二维码内容
+func BeginMyMFAEnrollment(c *gin.Context) {
+	user, err := currentUser(c)
+	if err != nil {
+		utils.RespondError(c, err, "获取用户信息失败")
+		return
+	}
+	enrollment, err := services.BeginMFAEnrollment(c.Request.Context(), user.UserID.String(), user.Username)
+	if err != nil {
+		utils.RespondError(c, err, "开始绑定两步验证失败")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmMyMFAEnrollment 校验验证码后为当前用户启用两步验证，返回只显示一次的恢复码
+func ConfirmMyMFAEnrollment(c *gin.Context) {
+	var req MFACodeRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	user, err := currentUser(c)
+	if err != nil {
+		utils.RespondError(c, err, "获取用户信息失败")
+		return
+	}
+
+	var codes []string
+	err = guardMFACode(c, user, func() (err error) {
+		codes, err = services.ConfirmMFAEnrollment(c.Request.Context(), user.UserID.String(), req.Code)
+		return err
+	})
+	if err != nil {
+		utils.RespondError(c, err, "确认两步验证失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), user.UserID.String(), string(services.MFAEnabled), services.Target("users", user.UserID), services.LogDetails{
+		"ip_address": c.ClientIP(),
+	})
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateMyRecoveryCodes 校验验证码后为当前用户生成一组新的恢复码，旧的恢复码全部作废
+func RegenerateMyRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	user, err := currentUser(c)
+	if err != nil {
+		utils.RespondError(c, err, "获取用户信息失败")
+		return
+	}
+
+	var codes []string
+	err = guardMFACode(c, user, func() (err error) {
+		codes, err = services.RegenerateRecoveryCodes(c.Request.Context(), user.UserID.String(), req.Code)
+		return err
+	})
+	if err != nil {
+		utils.RespondError(c, err, "生成恢复码失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), user.UserID.String(), string(services.MFARecoveryCodesRegenerated), services.Target("users", user.UserID), services.LogDetails{
+		"ip_address": c.ClientIP(),
+	})
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMyMFA 校验验证码后关闭当前用户的两步验证
+func DisableMyMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	user, err := currentUser(c)
+	if err != nil {
+		utils.RespondError(c, err, "获取用户信息失败")
+		return
+	}
+
+	err = guardMFACode(c, user, func() error {
+		return services.DisableMFA(c.Request.Context(), user.UserID.String(), user.Role, req.Code)
+	})
+	if err != nil {
+		utils.RespondError(c, err, "关闭两步验证失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), user.UserID.String(), string(services.MFADisabled), services.Target("users", user.UserID), services.LogDetails{
+		"ip_address": c.ClientIP(),
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// ResetUserMFA 处理管理员重置指定用户两步验证的请求（例如用户丢失了手机）
+func ResetUserMFA(c *gin.Context) {
+	id, err := parseUUIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err := services.ResetMFA(c.Request.Context(), id.String()); err != nil {
+		utils.RespondError(c, err, "重置两步验证失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.MFAReset), services.Target("users", id), services.LogDetails{
+		"target_user_id": id.String(),
+		"ip_address":     c.ClientIP(),
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// GetMFAPolicy 返回被要求启用两步验证的角色列表
+func GetMFAPolicy(c *gin.Context) {
+	roles, err := services.GetMFAPolicy(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, err, "获取两步验证策略失败")
+		return
+	}
+	c.JSON(http.StatusOK, MFAPolicyRequest{Roles: roles})
+}
+
+// UpdateMFAPolicy 设置被要求启用两步验证的角色列表（整体替换）
+func UpdateMFAPolicy(c *gin.Context) {
+	var req MFAPolicyRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err := services.SetMFAPolicy(c.Request.Context(), req.Roles); err != nil {
+		utils.RespondError(c, err, "更新两步验证策略失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.MFAPolicyUpdated), services.AuditTarget{Entity: "mfa_role_policies"}, services.LogDetails{
+		"roles":      req.Roles,
+		"ip_address": c.ClientIP(),
+	})
+	c.JSON(http.StatusOK, req)
+}
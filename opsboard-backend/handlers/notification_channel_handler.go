@@ -0,0 +1,302 @@
+/**
+ * @file handlers/notification_channel_handler.go
+ * @description 处理群机器人通知配置的 HTTP 请求：通知渠道和路由规则的增删改查、发送测试消息，以及消息模板的查询、保存和恢复默认。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"opsboard-backend/models"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotificationChannels 处理获取通知渠道列表的请求
+func GetNotificationChannels(c *gin.Context) {
+	channels, err := services.GetNotificationChannels(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, err, "获取通知渠道列表失败")
+		return
+	}
+	c.JSON(http.StatusOK, channels)
+}
+
+// GetNotificationChannel 处理获取单个通知渠道的请求，并返回 ETag
+func GetNotificationChannel(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	channel, err := services.GetNotificationChannel(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "获取通知渠道失败")
+		return
+	}
+	setETag(c, channel.Version)
+	c.JSON(http.StatusOK, channel)
+}
+
+// CreateNotificationChannel 处理创建通知渠道的请求
+func CreateNotificationChannel(c *gin.Context) {
+	var input services.NotificationChannelInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	channel, err := services.CreateNotificationChannel(c.Request.Context(), input)
+	if err != nil {
+		utils.RespondError(c, err, "创建通知渠道失败")
+		return
+	}
+
+	logNotificationChange(c, services.NotificationChannelCreated, services.Target("notification_channels", channel.ChannelID), services.LogDetails{
+		"channel_id": channel.ChannelID,
+		"name":       channel.Name,
+		"type":       channel.Type,
+	})
+	setETag(c, channel.Version)
+	c.JSON(http.StatusCreated, channel)
+}
+
+// UpdateNotificationChannel 处理修改通知渠道的请求
+func UpdateNotificationChannel(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var input services.NotificationChannelInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	channel, err := services.UpdateNotificationChannel(c.Request.Context(), id, input, expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "修改通知渠道失败")
+		return
+	}
+
+	logNotificationChange(c, services.NotificationChannelUpdated, services.Target("notification_channels", channel.ChannelID), services.LogDetails{
+		"channel_id":     channel.ChannelID,
+		"name":           channel.Name,
+		"type":           channel.Type,
+		"enabled":        channel.Enabled,
+		"secret_changed": input.Secret != nil,
+	})
+	setETag(c, channel.Version)
+	c.JSON(http.StatusOK, channel)
+}
+
+// DeleteNotificationChannel 处理删除通知渠道的请求
+func DeleteNotificationChannel(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err := services.DeleteNotificationChannel(c.Request.Context(), id, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "删除通知渠道失败")
+		return
+	}
+
+	logNotificationChange(c, services.NotificationChannelDeleted, services.Target("notification_channels", id), services.LogDetails{"channel_id": id})
+	c.Status(http.StatusNoContent)
+}
+
+// TestNotificationChannel 处理向通知渠道发送测试消息的请求，发送成功返回 204
+func TestNotificationChannel(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	err = services.SendTestChatMessage(c.Request.Context(), id)
+	logNotificationChange(c, services.NotificationChannelTested, services.Target("notification_channels", id), services.LogDetails{
+		"channel_id": id,
+		"success":    err == nil,
+	})
+	if err != nil {
+		utils.RespondError(c, err, "发送测试消息失败")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetNotificationRules 处理获取路由规则列表的请求
+func GetNotificationRules(c *gin.Context) {
+	rules, err := services.GetNotificationRules(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, err, "获取路由规则列表失败")
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// GetNotificationRule 处理获取单条路由规则的请求，并返回 ETag
+func GetNotificationRule(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	rule, err := services.GetNotificationRule(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "获取路由规则失败")
+		return
+	}
+	setETag(c, rule.Version)
+	c.JSON(http.StatusOK, rule)
+}
+
+// CreateNotificationRule 处理创建路由规则的请求
+func CreateNotificationRule(c *gin.Context) {
+	var input services.NotificationRuleInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	rule, err := services.CreateNotificationRule(c.Request.Context(), input)
+	if err != nil {
+		utils.RespondError(c, err, "创建路由规则失败")
+		return
+	}
+
+	logNotificationChange(c, services.NotificationRuleCreated, services.Target("notification_rules", rule.RuleID), ruleLogDetails(rule))
+	setETag(c, rule.Version)
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateNotificationRule 处理修改路由规则的请求
+func UpdateNotificationRule(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var input services.NotificationRuleInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	rule, err := services.UpdateNotificationRule(c.Request.Context(), id, input, expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "修改路由规则失败")
+		return
+	}
+
+	details := ruleLogDetails(rule)
+	details["enabled"] = rule.Enabled
+	logNotificationChange(c, services.NotificationRuleUpdated, services.Target("notification_rules", rule.RuleID), details)
+	setETag(c, rule.Version)
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteNotificationRule 处理删除路由规则的请求
+func DeleteNotificationRule(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err := services.DeleteNotificationRule(c.Request.Context(), id, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "删除路由规则失败")
+		return
+	}
+
+	logNotificationChange(c, services.NotificationRuleDeleted, services.Target("notification_rules", id), services.LogDetails{"rule_id": id})
+	c.Status(http.StatusNoContent)
+}
+
+// GetNotificationTemplates 处理获取消息模板列表的请求，路由参数 kind 为模板种类
+func GetNotificationTemplates(c *gin.Context) {
+	templates, err := services.GetNotificationTemplates(c.Request.Context(), c.Param("kind"))
+	if err != nil {
+		utils.RespondError(c, err, "获取消息模板失败")
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetNotificationTemplate 处理获取单个消息模板的请求，并返回 ETag（未保存过的默认模板版本为 0）
+func GetNotificationTemplate(c *gin.Context) {
+	tmpl, err := services.GetNotificationTemplate(c.Request.Context(), c.Param("kind"), c.Param("event"))
+	if err != nil {
+		utils.RespondError(c, err, "获取消息模板失败")
+		return
+	}
+	setETag(c, tmpl.Version)
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// UpdateNotificationTemplate 处理保存消息模板的请求
+func UpdateNotificationTemplate(c *gin.Context) {
+	var input services.NotificationTemplateInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	tmpl, err := services.UpdateNotificationTemplate(c.Request.Context(), c.Param("kind"), c.Param("event"), input, c.GetString("user_id"), expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "保存消息模板失败")
+		return
+	}
+
+	logNotificationChange(c, services.NotificationTemplateUpdated, services.Target("notification_templates", tmpl.Kind+"/"+tmpl.EventType), services.LogDetails{
+		"kind":       tmpl.Kind,
+		"event_type": tmpl.EventType,
+	})
+	setETag(c, tmpl.Version)
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// ResetNotificationTemplate 处理把消息模板恢复为默认模板的请求，返回默认模板
+func ResetNotificationTemplate(c *gin.Context) {
+	tmpl, err := services.ResetNotificationTemplate(c.Request.Context(), c.Param("kind"), c.Param("event"), expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "恢复默认模板失败")
+		return
+	}
+
+	logNotificationChange(c, services.NotificationTemplateReset, services.Target("notification_templates", tmpl.Kind+"/"+tmpl.EventType), services.LogDetails{
+		"kind":       tmpl.Kind,
+		"event_type": tmpl.EventType,
+	})
+	setETag(c, tmpl.Version)
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// ruleLogDetails 返回路由规则审计日志的公共字段
+func ruleLogDetails(rule *models.NotificationRule) services.LogDetails {
+	details := services.LogDetails{
+		"rule_id":    rule.RuleID,
+		"name":       rule.Name,
+		"channel_id": rule.ChannelID,
+		"events":     rule.Events,
+	}
+	if rule.CustomerID.Valid {
+		details["customer_id"] = rule.CustomerID.Int64
+	}
+	return details
+}
+
+// logNotificationChange 记录管理员修改通知配置的审计日志
+func logNotificationChange(c *gin.Context, action services.LogAction, target services.AuditTarget, details services.LogDetails) {
+	details["ip_address"] = c.ClientIP()
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(action), target, details)
+}
@@ -0,0 +1,225 @@
+/**
+ * @file handlers/notification_handler.go
+ * @description 处理站内通知的 HTTP 请求：通知列表、未读数、标记已读，以及推送新通知的 SSE 连接。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [安全]：SSE 连接定期重新校验用户状态，用户被禁用、删除或会话被吊销，以及访问令牌过期时推送 `end` 事件并断开；
+ *     注明客户端需要用 fetch 携带 Bearer 请求头连接。
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"opsboard-backend/middleware"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// notificationStreamPoll 是 SSE 连接在没有被唤醒时检查新通知（其他实例生成的）、重新校验用户状态并发送心跳的间隔
+const notificationStreamPoll = 15 * time.Second
+
+// notificationStreamBatch 是 SSE 连接每次补发的最大通知数
+const notificationStreamBatch = 100
+
+// GetNotifications 处理分页查询当前用户通知的请求
+func GetNotifications(c *gin.Context) {
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var filter services.NotificationFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	result, err := services.GetNotifications(c.Request.Context(), c.GetString("user_id"), page, pageSize, filter)
+	if err != nil {
+		utils.RespondError(c, err, "获取通知列表失败")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetUnreadNotificationCount 处理获取当前用户未读通知数量的请求
+func GetUnreadNotificationCount(c *gin.Context) {
+	count, err := services.GetUnreadNotificationCount(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		utils.RespondError(c, err, "获取未读通知数量失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+// MarkNotificationRead 处理把一条通知标记为已读的请求，返回标记后的通知
+func MarkNotificationRead(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	notification, err := services.MarkNotificationRead(c.Request.Context(), c.GetString("user_id"), id)
+	if err != nil {
+		utils.RespondError(c, err, "标记通知已读失败")
+		return
+	}
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead 处理把当前用户的所有通知标记为已读的请求，返回标记的数量
+func MarkAllNotificationsRead(c *gin.Context) {
+	updated, err := services.MarkAllNotificationsRead(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		utils.RespondError(c, err, "标记全部通知已读失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// StreamNotifications 处理 SSE 推送连接：连接建立后先发送当前未读数，之后每有新通知或未读数变化时推送。
+// 没有 `Last-Event-ID` 请求头时只推送连接建立之后的新通知。
+//
+// 连接期间按 notificationStreamPoll 的间隔重新校验用户状态。访问令牌过期、用户被禁用或删除、会话被吊销（修改密码、
+// 管理员强制下线）时推送 `end` 事件后断开，data 中的 code 为错误码：TOKEN_EXPIRED 表示应刷新访问令牌后重连，
+// 其他错误码表示需要重新登录（INTERNAL_ERROR 除外，稍后重连即可）。
+//
+// 浏览器内置的 EventSource 不能设置请求头，客户端需要用 fetch 携带 `Authorization: Bearer <访问令牌>` 请求本接口，
+// 逐段读取响应体并按 SSE 格式解析；断开后以最后收到的事件 ID 作为 `Last-Event-ID` 请求头重连，补发断开期间的通知。
+func StreamNotifications(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	// 先注册唤醒通道再确定起点，避免遗漏两者之间生成的通知
+	wake, cancel := services.ListenNotifications(userID)
+	defer cancel()
+
+	lastID, err := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+	if err != nil {
+		if lastID, err = services.LatestNotificationID(ctx, userID); err != nil {
+			utils.RespondError(c, err, "建立通知推送连接失败")
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁止 nginx 缓冲响应
+	c.Status(http.StatusOK)
+
+	ticker := time.NewTicker(notificationStreamPoll)
+	defer ticker.Stop()
+	var expired <-chan time.Time
+	if exp, ok := c.Get(middleware.AuthExpiresAtKey); ok {
+		timer := time.NewTimer(time.Until(exp.(time.Time)))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	unread := int64(-1)
+	for {
+		sent, err := pushNotifications(c, userID, &lastID, &unread)
+		if err != nil {
+			return // 查询失败或客户端已断开，客户端会自动重连
+		}
+		if !sent {
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		}
+		c.Writer.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired:
+			endNotificationStream(c, utils.NewAppError(utils.CodeTokenExpired, "访问令牌已过期"))
+			return
+		case <-wake:
+		case <-ticker.C:
+			if err := checkStreamSession(c); err != nil {
+				endNotificationStream(c, err)
+				return
+			}
+		}
+	}
+}
+
+// checkStreamSession 重新执行 AuthMiddleware 对用户状态的校验：用户没有被禁用或删除，JWT 会话没有被吊销
+func checkStreamSession(c *gin.Context) error {
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	if version, ok := c.Get(middleware.SessionVersionKey); ok {
+		_, err := services.CheckUserSession(ctx, userID, version.(uint))
+		return err
+	}
+	_, err := services.CheckUserActive(ctx, userID)
+	return err
+}
+
+// endNotificationStream 推送 `end` 事件，告诉客户端连接因 err 被关闭；不是 AppError 的错误按 INTERNAL_ERROR 处理
+func endNotificationStream(c *gin.Context, err error) {
+	code := utils.CodeInternal
+	var appErr *utils.AppError
+	if errors.As(err, &appErr) {
+		code = appErr.Code
+	}
+	if writeSSE(c, "", "end", gin.H{"code": code}) == nil {
+		c.Writer.Flush()
+	}
+}
+
+// pushNotifications 推送 lastID 之后的新通知，未读数与 unread 不同时推送未读数，返回是否推送了任何事件
+func pushNotifications(c *gin.Context, userID string, lastID *uint64, unread *int64) (bool, error) {
+	ctx := c.Request.Context()
+	sent := false
+	for {
+		notifications, err := services.GetNotificationsAfter(ctx, userID, *lastID, notificationStreamBatch)
+		if err != nil {
+			return sent, err
+		}
+		for i := range notifications {
+			if err := writeSSE(c, strconv.FormatUint(notifications[i].NotificationID, 10), "notification", notifications[i]); err != nil {
+				return sent, err
+			}
+			*lastID = notifications[i].NotificationID
+			sent = true
+		}
+		if len(notifications) < notificationStreamBatch {
+			break
+		}
+	}
+
+	count, err := services.GetUnreadNotificationCount(ctx, userID)
+	if err != nil {
+		return sent, err
+	}
+	if count != *unread {
+		if err := writeSSE(c, "", "unread", gin.H{"count": count}); err != nil {
+			return sent, err
+		}
+		*unread = count
+		sent = true
+	}
+	return sent, nil
+}
+
+// writeSSE 写入一个 SSE 事件，data 序列化为单行 JSON；id 为空时不写 id 字段，不影响客户端记录的 Last-Event-ID
+func writeSSE(c *gin.Context, id, event string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(c.Writer, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, raw)
+	return err
+}
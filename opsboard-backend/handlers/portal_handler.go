@@ -0,0 +1,195 @@
+/**
+ * @file handlers/portal_handler.go
+ * @description 处理客户门户的 HTTP 请求：查看所属单位、提交和跟踪工单、确认解决、评价服务以及查看和回复公开评论。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitPortalTicketRequest 是客户提交工单的请求体，customerId 只在账户关联了多个单位时需要指定
+type SubmitPortalTicketRequest struct {
+	CustomerID       uint   `json:"customerId"`
+	OperationType    string `json:"operationType" binding:"max=100"`
+	OperationContent string `json:"operationContent" binding:"required,max=5000"`
+}
+
+// RatePortalTicketRequest 是客户评价工单服务的请求体
+type RatePortalTicketRequest struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=500"`
+}
+
+// AddPortalTicketCommentRequest 是客户回复工单的请求体
+type AddPortalTicketCommentRequest struct {
+	Content string `json:"content" binding:"required,max=5000"`
+}
+
+// GetPortalCustomers 处理获取当前用户所属单位的请求
+func GetPortalCustomers(c *gin.Context) {
+	customers, err := services.GetPortalCustomers(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, err, "获取所属单位失败")
+		return
+	}
+	c.JSON(http.StatusOK, customers)
+}
+
+// GetPortalTicketList 处理获取所属单位工单列表的请求（支持分页）
+func GetPortalTicketList(c *gin.Context) {
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	var filter services.PortalTicketFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	result, err := services.GetPortalTickets(c.Request.Context(), page, pageSize, filter)
+	if err != nil {
+		utils.RespondError(c, err, "获取工单列表失败")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetPortalTicket 处理获取所属单位单个工单详情的请求，并返回 ETag。
+func GetPortalTicket(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	ticket, err := services.GetPortalTicket(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "获取工单详情失败")
+		return
+	}
+
+	setETag(c, ticket.Version)
+	c.JSON(http.StatusOK, ticket)
+}
+
+// SubmitPortalTicket 处理客户提交工单的请求
+func SubmitPortalTicket(c *gin.Context) {
+	var req SubmitPortalTicketRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	userID := c.GetString("user_id")
+	ticket, err := services.SubmitPortalTicket(c.Request.Context(), userID, req.CustomerID, req.OperationType, req.OperationContent)
+	if err != nil {
+		utils.RespondError(c, err, "提交工单失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), userID, string(services.TicketSubmitted), services.Target("tickets", ticket.ID), services.LogDetails{
+		"ticket_id":   ticket.ID,
+		"customer_id": ticket.CustomerID,
+		"ip_address":  c.ClientIP(),
+	})
+	setETag(c, ticket.Version)
+	c.JSON(http.StatusCreated, ticket)
+}
+
+// ConfirmPortalTicket 处理客户确认工单已解决的请求（“待确认” → “完成”）
+func ConfirmPortalTicket(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	ticket, err := services.ConfirmTicketResolution(c.Request.Context(), id, expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "确认工单解决失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.TicketResolutionConfirmed), services.Target("tickets", ticket.ID), services.LogDetails{
+		"ticket_id":  ticket.ID,
+		"ip_address": c.ClientIP(),
+	})
+	setETag(c, ticket.Version)
+	c.JSON(http.StatusOK, ticket)
+}
+
+// RatePortalTicket 处理客户评价已完成工单的请求
+func RatePortalTicket(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req RatePortalTicketRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	ticket, err := services.RateTicket(c.Request.Context(), id, req.Rating, req.Comment, expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "评价工单失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.TicketRated), services.Target("tickets", ticket.ID), services.LogDetails{
+		"ticket_id":  ticket.ID,
+		"rating":     req.Rating,
+		"ip_address": c.ClientIP(),
+	})
+	setETag(c, ticket.Version)
+	c.JSON(http.StatusOK, ticket)
+}
+
+// GetPortalTicketComments 处理获取工单公开评论的请求，不包含内部备注
+func GetPortalTicketComments(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	comments, err := services.GetTicketComments(c.Request.Context(), id, false)
+	if err != nil {
+		utils.RespondError(c, err, "获取工单评论失败")
+		return
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+// AddPortalTicketComment 处理客户回复工单的请求，客户的回复总是公开的
+func AddPortalTicketComment(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req AddPortalTicketCommentRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	comment, err := services.AddPortalTicketComment(c.Request.Context(), id, c.GetString("user_id"), req.Content)
+	if err != nil {
+		utils.RespondError(c, err, "回复工单失败")
+		return
+	}
+	c.JSON(http.StatusCreated, comment)
+}
@@ -0,0 +1,31 @@
+/**
+ * @file handlers/precondition.go
+ * @description 提供乐观并发控制相关的处理器辅助函数：读取期望版本号和设置 ETag。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [重构]：移除 `respondMutationError`。版本冲突现在是带 `PRECONDITION_FAILED` 错误码的 `AppError`，由 `utils.RespondError` 统一转换为 412 响应。
+ */
+
+package handlers
+
+import (
+	"opsboard-backend/middleware"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// expectedVersion 返回 `middleware.IfMatch` 从 If-Match 请求头中解析出的期望版本号；
+// 请求未携带 If-Match（或值为 `*`）时返回 nil，表示不做版本校验。
+func expectedVersion(c *gin.Context) *uint {
+	v, ok := c.Get(middleware.IfMatchVersionKey)
+	if !ok {
+		return nil
+	}
+	version := v.(uint)
+	return &version
+}
+
+// setETag 根据实体版本号设置 ETag 响应头
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", utils.FormatETag(version))
+}
@@ -0,0 +1,77 @@
+/**
+ * @file handlers/request.go
+ * @description 提供处理器共用的请求解析辅助函数：路径 ID、分页参数以及查询/请求体的绑定与校验。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [UUID]：新增 `parseUUIDParam`，用于以 UUID 为主键的资源（例如用户）。
+ */
+
+package handlers
+
+import (
+	"strconv"
+
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 分页参数的默认值和上限
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// parseIDParam 解析并校验路径中的数字 ID（必须为正整数），返回规范化后的字符串形式
+func parseIDParam(c *gin.Context, name string) (string, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		return "", utils.ErrValidation(utils.FieldError{Field: name, Message: "必须是正整数"})
+	}
+	return strconv.FormatUint(id, 10), nil
+}
+
+// parseUUIDParam 解析并校验路径中的 UUID
+func parseUUIDParam(c *gin.Context, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		return uuid.Nil, utils.ErrValidation(utils.FieldError{Field: name, Message: "必须是有效的 UUID"})
+	}
+	return id, nil
+}
+
+// parsePagination 解析并校验 `page` 和 `pageSize` 查询参数
+func parsePagination(c *gin.Context) (int, int, error) {
+	var details []utils.FieldError
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		details = append(details, utils.FieldError{Field: "page", Message: "必须是大于 0 的整数"})
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		details = append(details, utils.FieldError{Field: "pageSize", Message: "必须是 1 到 " + strconv.Itoa(maxPageSize) + " 之间的整数"})
+	}
+
+	if len(details) > 0 {
+		return 0, 0, utils.ErrValidation(details...)
+	}
+	return page, pageSize, nil
+}
+
+// bindQuery 绑定并校验查询参数，失败时返回带字段详情的 AppError
+func bindQuery(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBindQuery(obj); err != nil {
+		return utils.BindingError(err)
+	}
+	return nil
+}
+
+// bindJSON 绑定并校验 JSON 请求体，失败时返回带字段详情的 AppError
+func bindJSON(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		return utils.BindingError(err)
+	}
+	return nil
+}
@@ -1,32 +1,39 @@
 // @file handlers/server_handler.go
 // @description 处理与服务器相关的 HTTP 请求，支持分页查询、按 ID 查询和删除操作。
 // @modification 本次提交中所做的具体修改摘要。
-//   - [功能新增]：新增了 `GetServerByID` Gin 处理器，用于处理 `GET /api/servers/:id` 请求。
-//   - [逻辑实现]：该处理器从 URL 路径中提取 ID，调用服务层的 `GetServerByID` 函数获取数据。
-//   - [错误处理]：能够区分 `gorm.ErrRecordNotFound`（返回 404）和其他数据库错误（返回 500），提供了更精确的 API 错误响应。
-//   - [数据转换]：在成功获取数据后，将 `models.Server` 数据库模型转换为 `models.ServerDetailResponse` API 响应模型，以匹配前端的精确需求。
+//   - [Webhook]：新增报告服务器离线的处理器 `ReportServerOffline`，供监控工具通过 API 令牌调用。
 
 package handlers
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"opsboard-backend/models"
 	"opsboard-backend/services"
+	"opsboard-backend/utils"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 // GetServerList 处理获取服务器列表的请求（支持分页）
 func GetServerList(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	var filter services.ServerFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
 
-	result, err := services.GetPaginatedServers(page, pageSize)
+	result, err := services.GetPaginatedServers(c.Request.Context(), page, pageSize, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "获取服务器列表失败"})
+		utils.RespondError(c, err, "获取服务器列表失败")
 		return
 	}
 
@@ -35,21 +42,16 @@ func GetServerList(c *gin.Context) {
 
 // GetServerByID 处理根据 ID 获取单个服务器详情的请求。
 func GetServerByID(c *gin.Context) {
-	serverID := c.Param("id")
-	if serverID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "服务器 ID 不能为空"})
+	serverID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
 		return
 	}
 
-	server, err := services.GetServerByID(serverID)
+	server, err := services.GetServerByID(c.Request.Context(), serverID)
 	if err != nil {
-		// 检查错误是否为 GORM 的 "记录未找到" 错误
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"message": "服务器未找到"})
-		} else {
-			// 对于所有其他类型的错误，返回内部服务器错误
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "获取服务器详情失败"})
-		}
+		// 服务器不存在时服务层返回 404 错误，其余数据库错误返回 500
+		utils.RespondError(c, err, "获取服务器详情失败")
 		return
 	}
 
@@ -67,24 +69,161 @@ func GetServerByID(c *gin.Context) {
 		UsageNote:      server.UsageNote,
 		CreatedAt:      server.CreatedAt,
 		UpdatedAt:      server.UpdatedAt,
+		Version:        server.Version,
 	}
 
+	setETag(c, server.Version)
 	c.JSON(http.StatusOK, response)
 }
 
 // DeleteServer 处理删除服务器的请求
 func DeleteServer(c *gin.Context) {
-	serverID := c.Param("id")
-	if serverID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "服务器 ID 不能为空"})
+	serverID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
 		return
 	}
 
-	err := services.DeleteServerByID(serverID)
+	err = services.DeleteServerByID(c.Request.Context(), serverID, expectedVersion(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "删除服务器失败"})
+		utils.RespondError(c, err, "删除服务器失败")
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// ReportServerOfflineRequest 是报告服务器离线的请求体
+type ReportServerOfflineRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ReportServerOffline 处理报告服务器离线的请求，服务器不记录在线状态，只发布 `server.offline` 事件
+func ReportServerOffline(c *gin.Context) {
+	serverID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req ReportServerOfflineRequest
+	if c.Request.ContentLength != 0 {
+		if err := bindJSON(c, &req); err != nil {
+			utils.RespondError(c, err, "")
+			return
+		}
+	}
+
+	if err := services.ReportServerOffline(c.Request.Context(), serverID, req.Reason); err != nil {
+		utils.RespondError(c, err, "报告服务器离线失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.ServerReportedOffline), services.Target("servers", serverID), services.LogDetails{
+		"server_id":  serverID,
+		"reason":     req.Reason,
+		"ip_address": c.ClientIP(),
+	})
+	c.Status(http.StatusAccepted)
+}
+
+// maxImportFileSize 限制导入文件的最大体积（10 MB）
+const maxImportFileSize = 10 << 20
+
+// ImportServers 处理服务器批量导入的请求。
+// 请求为 multipart/form-data，文件字段名为 `file`；查询参数 `dryRun=true` 时只校验不写入。
+func ImportServers(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.RespondError(c, utils.NewAppError(utils.CodePayloadTooLarge, "上传的文件不能超过 10 MB"), "")
+			return
+		}
+		utils.RespondError(c, utils.ErrValidation(utils.FieldError{Field: "file", Message: "请上传文件"}), "")
+		return
+	}
+
+	format, err := utils.DetectSpreadsheetFormat(fileHeader.Filename)
+	if err != nil {
+		utils.RespondError(c, utils.ErrValidation(utils.FieldError{Field: "file", Message: "仅支持 .csv 或 .xlsx 文件"}), "")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.RespondError(c, utils.ErrBadRequest("无法读取上传的文件"), "")
+		return
+	}
+	defer file.Close()
+
+	rows, err := utils.ReadSpreadsheet(file, format)
+	if err != nil {
+		utils.RespondError(c, utils.ErrBadRequest("文件解析失败，请检查文件格式"), "")
+		return
+	}
+
+	result, err := services.ImportServers(c.Request.Context(), rows, dryRun)
+	if err != nil {
+		utils.RespondError(c, err, "导入服务器失败")
+		return
+	}
+
+	if result.Applied {
+		services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.ServersImported), services.AuditTarget{Entity: "servers"}, services.LogDetails{
+			"file_name":  fileHeader.Filename,
+			"created":    result.Created,
+			"updated":    result.Updated,
+			"ip_address": c.ClientIP(),
+		})
+	}
+
+	// 非试运行且存在校验错误时，数据未被写入，返回 422 让前端明确感知导入失败。
+	// 每个行错误都会转换为一个字段详情，字段路径形如 `rows[3].ipAddress`（行号与文件中的行号一致）。
+	if !dryRun && len(result.Errors) > 0 {
+		utils.RespondError(c, importErrorToAppError(result), "")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// importErrorToAppError 将导入结果中的行错误转换为 422 `UNPROCESSABLE_ENTITY` 错误
+func importErrorToAppError(result *services.ServerImportResult) *utils.AppError {
+	details := make([]utils.FieldError, 0, len(result.Errors))
+	for _, rowErr := range result.Errors {
+		field := fmt.Sprintf("rows[%d]", rowErr.Row)
+		if rowErr.Field != "" {
+			field += "." + rowErr.Field
+		}
+		details = append(details, utils.FieldError{Field: field, Message: rowErr.Message})
+	}
+	return &utils.AppError{
+		Code:    utils.CodeUnprocessable,
+		Message: fmt.Sprintf("导入文件中有 %d 处错误，未写入任何数据", len(result.Errors)),
+		Details: details,
+	}
+}
+
+// serverExportColumns 定义了服务器导出文件的列：ID + 可导入的列 + 创建时间
+var serverExportColumns = append(append([]utils.ExportColumn{utils.Col("id", "ID", "ID")},
+	services.ServerImportColumns...),
+	utils.Col("createdAt", "创建时间", "Created At"),
+)
+
+// ExportServers 处理服务器导出的请求，筛选条件与 GetServerList 相同，但不分页。
+func ExportServers(c *gin.Context) {
+	var filter services.ServerFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	streamExport(c, "servers", serverExportColumns, func(e *utils.Exporter) error {
+		return services.StreamServers(c.Request.Context(), filter, func(s *models.Server) error {
+			return e.Write(s.ServerID, s.CustomerName, s.ServerName, s.IPAddress, s.Role,
+				s.DeploymentType, s.CustomerNote, s.UsageNote, s.CreatedAt)
+		})
+	})
+}
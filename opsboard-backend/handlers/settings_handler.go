@@ -0,0 +1,82 @@
+/**
+ * @file handlers/settings_handler.go
+ * @description 处理个人偏好设置和系统设置的 HTTP 请求。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMyPreferences 获取当前用户的偏好设置
+func GetMyPreferences(c *gin.Context) {
+	doc, err := services.GetUserPreferences(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		utils.RespondError(c, err, "获取偏好设置失败")
+		return
+	}
+
+	setETag(c, doc.Version)
+	c.JSON(http.StatusOK, doc)
+}
+
+// UpdateMyPreferences 保存当前用户的偏好设置（整体替换）
+func UpdateMyPreferences(c *gin.Context) {
+	prefs := services.DefaultUserPreferences()
+	if err := bindJSON(c, &prefs); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	doc, err := services.UpdateUserPreferences(c.Request.Context(), c.GetString("user_id"), prefs, expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "保存偏好设置失败")
+		return
+	}
+
+	setETag(c, doc.Version)
+	c.JSON(http.StatusOK, doc)
+}
+
+// GetSystemSettings 获取系统设置
+func GetSystemSettings(c *gin.Context) {
+	doc, err := services.GetSystemSettings(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, err, "获取系统设置失败")
+		return
+	}
+
+	setETag(c, doc.Version)
+	c.JSON(http.StatusOK, doc)
+}
+
+// UpdateSystemSettings 保存系统设置（整体替换），修改的会话时长只影响之后签发的令牌
+func UpdateSystemSettings(c *gin.Context) {
+	settings := services.DefaultSystemSettings()
+	if err := bindJSON(c, &settings); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	userID := c.GetString("user_id")
+	doc, err := services.UpdateSystemSettings(c.Request.Context(), settings, userID, expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "保存系统设置失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), userID, string(services.SettingsUpdated), services.AuditTarget{Entity: "system_settings"}, services.LogDetails{
+		"settings":   doc.Settings,
+		"version":    doc.Version,
+		"ip_address": c.ClientIP(),
+	})
+	setETag(c, doc.Version)
+	c.JSON(http.StatusOK, doc)
+}
@@ -0,0 +1,162 @@
+/**
+ * @file handlers/sla_handler.go
+ * @description 处理 SLA 策略和工作日历管理的 HTTP 请求。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBusinessCalendars 处理获取工作日历列表的请求
+func GetBusinessCalendars(c *gin.Context) {
+	calendars, err := services.GetBusinessCalendars(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, err, "获取工作日历失败")
+		return
+	}
+	c.JSON(http.StatusOK, calendars)
+}
+
+// CreateBusinessCalendar 处理创建工作日历的请求
+func CreateBusinessCalendar(c *gin.Context) {
+	var input services.BusinessCalendarInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	calendar, err := services.CreateBusinessCalendar(c.Request.Context(), input)
+	if err != nil {
+		utils.RespondError(c, err, "创建工作日历失败")
+		return
+	}
+
+	logSLAChange(c, services.BusinessCalendarCreated, services.Target("business_calendars", calendar.CalendarID), services.LogDetails{"calendar_id": calendar.CalendarID, "name": calendar.Name})
+	setETag(c, calendar.Version)
+	c.JSON(http.StatusCreated, calendar)
+}
+
+// UpdateBusinessCalendar 处理修改工作日历的请求（整体替换）
+func UpdateBusinessCalendar(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var input services.BusinessCalendarInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	calendar, err := services.UpdateBusinessCalendar(c.Request.Context(), id, input, expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "修改工作日历失败")
+		return
+	}
+
+	logSLAChange(c, services.BusinessCalendarUpdated, services.Target("business_calendars", calendar.CalendarID), services.LogDetails{"calendar_id": calendar.CalendarID, "name": calendar.Name})
+	setETag(c, calendar.Version)
+	c.JSON(http.StatusOK, calendar)
+}
+
+// DeleteBusinessCalendar 处理删除工作日历的请求
+func DeleteBusinessCalendar(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err := services.DeleteBusinessCalendar(c.Request.Context(), id, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "删除工作日历失败")
+		return
+	}
+
+	logSLAChange(c, services.BusinessCalendarDeleted, services.Target("business_calendars", id), services.LogDetails{"calendar_id": id})
+	c.Status(http.StatusNoContent)
+}
+
+// GetSLAPolicies 处理获取 SLA 策略列表的请求
+func GetSLAPolicies(c *gin.Context) {
+	policies, err := services.GetSLAPolicies(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, err, "获取 SLA 策略失败")
+		return
+	}
+	c.JSON(http.StatusOK, policies)
+}
+
+// CreateSLAPolicy 处理创建 SLA 策略的请求
+func CreateSLAPolicy(c *gin.Context) {
+	var input services.SLAPolicyInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	policy, err := services.CreateSLAPolicy(c.Request.Context(), input)
+	if err != nil {
+		utils.RespondError(c, err, "创建 SLA 策略失败")
+		return
+	}
+
+	logSLAChange(c, services.SLAPolicyCreated, services.Target("sla_policies", policy.PolicyID), services.LogDetails{"policy_id": policy.PolicyID, "policy": input})
+	setETag(c, policy.Version)
+	c.JSON(http.StatusCreated, policy)
+}
+
+// UpdateSLAPolicy 处理修改 SLA 策略的请求（整体替换），已经计算过截止时间的工单不受影响
+func UpdateSLAPolicy(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var input services.SLAPolicyInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	policy, err := services.UpdateSLAPolicy(c.Request.Context(), id, input, expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "修改 SLA 策略失败")
+		return
+	}
+
+	logSLAChange(c, services.SLAPolicyUpdated, services.Target("sla_policies", policy.PolicyID), services.LogDetails{"policy_id": policy.PolicyID, "policy": input})
+	setETag(c, policy.Version)
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteSLAPolicy 处理删除 SLA 策略的请求
+func DeleteSLAPolicy(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err := services.DeleteSLAPolicy(c.Request.Context(), id, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "删除 SLA 策略失败")
+		return
+	}
+
+	logSLAChange(c, services.SLAPolicyDeleted, services.Target("sla_policies", id), services.LogDetails{"policy_id": id})
+	c.Status(http.StatusNoContent)
+}
+
+// logSLAChange 记录管理员修改 SLA 配置的审计日志
+func logSLAChange(c *gin.Context, action services.LogAction, target services.AuditTarget, details services.LogDetails) {
+	details["ip_address"] = c.ClientIP()
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(action), target, details)
+}
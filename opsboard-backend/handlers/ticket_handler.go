@@ -2,30 +2,223 @@
  * @file handlers/ticket_handler.go
  * @description 处理与工单相关的 HTTP 请求，支持分页查询。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [新文件]：创建此文件以暴露工单相关的 API 端点。
- *   - [功能]：`GetTicketList` 处理器支持分页，调用 `TicketService` 获取聚合后的工单数据，并将其作为 JSON 响应返回。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
  */
 
 package handlers
 
 import (
 	"net/http"
+	"opsboard-backend/models"
 	"opsboard-backend/services"
-	"strconv"
+	"opsboard-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GetTicketList 处理获取工单列表的请求（支持分页）
 func GetTicketList(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	var filter services.TicketFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
 
-	result, err := services.GetPaginatedTickets(page, pageSize)
+	result, err := services.GetPaginatedTickets(c.Request.Context(), page, pageSize, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "获取工单列表失败"})
+		utils.RespondError(c, err, "获取工单列表失败")
 		return
 	}
 
 	c.JSON(http.StatusOK, result)
 }
+
+// ticketExportColumns 定义了工单导出文件的列
+var ticketExportColumns = []utils.ExportColumn{
+	utils.Col("id", "工单编号", "Ticket ID"),
+	utils.Col("customerName", "客户名称", "Customer"),
+	utils.Col("status", "状态", "Status"),
+	utils.Col("operationType", "操作类别", "Operation Type"),
+	utils.Col("operationContent", "工单内容", "Content"),
+	utils.Col("publicationTime", "发布时间", "Publication Time"),
+	utils.Col("completionTime", "完成时间", "Completion Time"),
+	utils.Col("priority", "优先级", "Priority"),
+	utils.Col("responseSlaStatus", "响应 SLA", "Response SLA"),
+	utils.Col("resolveSlaStatus", "解决 SLA", "Resolve SLA"),
+}
+
+// ExportTickets 处理工单导出的请求，筛选条件与 GetTicketList 相同，但不分页。
+func ExportTickets(c *gin.Context) {
+	var filter services.TicketFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	streamExport(c, "tickets", ticketExportColumns, func(e *utils.Exporter) error {
+		return services.StreamTickets(c.Request.Context(), filter, func(t *models.Ticket) error {
+			return e.Write(t.ID, t.CustomerName, t.Status, t.OperationType, t.OperationContent,
+				t.PublicationTime, t.CompletionTime, t.Priority, t.ResponseSLAStatus, t.ResolveSLAStatus)
+		})
+	})
+}
+
+// GetTicketByID 处理根据 ID 获取单个工单详情的请求，并返回 ETag。
+func GetTicketByID(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	ticket, err := services.GetTicketByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "获取工单详情失败")
+		return
+	}
+
+	setETag(c, ticket.Version)
+	c.JSON(http.StatusOK, ticket)
+}
+
+// DeleteTicket 处理删除工单的请求
+func DeleteTicket(c *gin.Context) {
+	ticketID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err = services.DeleteTicketByID(c.Request.Context(), ticketID, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "删除工单失败")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetTicketStats 处理工单统计的请求，筛选条件与 GetTicketList 相同
+func GetTicketStats(c *gin.Context) {
+	var filter services.TicketFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	stats, err := services.GetTicketStats(c.Request.Context(), filter)
+	if err != nil {
+		utils.RespondError(c, err, "获取工单统计失败")
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// SetTicketPriorityRequest 是修改工单优先级的请求体
+type SetTicketPriorityRequest struct {
+	Priority string `json:"priority" binding:"required,oneof=P1 P2 P3 P4"`
+}
+
+// SetTicketPriority 处理修改工单优先级的请求，SLA 截止时间随后按新的优先级重新计算
+func SetTicketPriority(c *gin.Context) {
+	ticketID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req SetTicketPriorityRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err = services.SetTicketPriority(c.Request.Context(), ticketID, req.Priority, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "修改工单优先级失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.TicketPriorityChanged), services.Target("tickets", ticketID), services.LogDetails{
+		"ticket_id":  ticketID,
+		"priority":   req.Priority,
+		"ip_address": c.ClientIP(),
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// SetTicketStatusRequest 是修改工单状态的请求体
+type SetTicketStatusRequest struct {
+	Status string `json:"status" binding:"required,max=50"`
+}
+
+// AddTicketCommentRequest 是添加工单评论的请求体，isInternal 为 true 时添加客户看不到的内部备注
+type AddTicketCommentRequest struct {
+	Content    string `json:"content" binding:"required,max=5000"`
+	IsInternal bool   `json:"isInternal"`
+}
+
+// SetTicketStatus 处理修改工单状态的请求。处理完成后改为“待确认”，由客户在门户中确认解决。
+func SetTicketStatus(c *gin.Context) {
+	ticketID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req SetTicketStatusRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err = services.SetTicketStatus(c.Request.Context(), ticketID, req.Status, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "修改工单状态失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.TicketStatusChanged), services.Target("tickets", ticketID), services.LogDetails{
+		"ticket_id":  ticketID,
+		"status":     req.Status,
+		"ip_address": c.ClientIP(),
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// GetTicketComments 处理获取工单评论（包括内部备注）的请求
+func GetTicketComments(c *gin.Context) {
+	ticketID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	comments, err := services.GetTicketComments(c.Request.Context(), ticketID, true)
+	if err != nil {
+		utils.RespondError(c, err, "获取工单评论失败")
+		return
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+// AddTicketComment 处理添加工单评论的请求
+func AddTicketComment(c *gin.Context) {
+	ticketID, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req AddTicketCommentRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	comment, err := services.AddTicketComment(c.Request.Context(), ticketID, c.GetString("user_id"), req.Content, req.IsInternal)
+	if err != nil {
+		utils.RespondError(c, err, "添加工单评论失败")
+		return
+	}
+	c.JSON(http.StatusCreated, comment)
+}
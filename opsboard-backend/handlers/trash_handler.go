@@ -0,0 +1,77 @@
+/**
+ * @file handlers/trash_handler.go
+ * @description 处理与回收站相关的 HTTP 请求，支持列出已删除记录、恢复记录和彻底清除记录。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTrashList 处理获取回收站列表的请求（支持分页，可通过 `entity` 查询参数只查看某一类实体）
+func GetTrashList(c *gin.Context) {
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	result, err := services.GetPaginatedTrash(c.Request.Context(), page, pageSize, c.Query("entity"))
+	if err != nil {
+		utils.RespondError(c, err, "获取回收站列表失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RestoreTrashItem 处理从回收站恢复一条记录的请求
+func RestoreTrashItem(c *gin.Context) {
+	entity := c.Param("entity")
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err := services.RestoreTrashItem(c.Request.Context(), entity, id); err != nil {
+		utils.RespondError(c, err, "恢复记录失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.TrashItemRestored), services.Target(entity, id), services.LogDetails{
+		"entity":     entity,
+		"id":         id,
+		"ip_address": c.ClientIP(),
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// PurgeTrashItem 处理彻底删除回收站中一条记录的请求（仅限管理员）
+func PurgeTrashItem(c *gin.Context) {
+	entity := c.Param("entity")
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err := services.PurgeTrashItem(c.Request.Context(), entity, id); err != nil {
+		utils.RespondError(c, err, "彻底删除记录失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.TrashItemPurged), services.Target(entity, id), services.LogDetails{
+		"entity":     entity,
+		"id":         id,
+		"ip_address": c.ClientIP(),
+	})
+	c.Status(http.StatusNoContent)
+}
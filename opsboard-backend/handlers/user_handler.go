@@ -1,43 +1,450 @@
 /**
  * @file user_handler.go
- * @description 处理用户相关的 HTTP 请求，例如获取当前用户信息。
+ * @description 处理用户相关的 HTTP 请求，例如获取当前用户信息，以及管理员对用户的管理。
  * @modification
- *   - [UUID Migration]: 导入了 `github.com/google/uuid` 包。
- *   - [UUID Migration]: 从 Gin 上下文中获取 `user_id` 后，将其解析为 `uuid.UUID` 类型，然后再传递给 `services.GetUserByID` 函数。
- *   - [Error Handling]: 添加了对 UUID 解析失败的错误处理。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
  */
 
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"net/mail"
+	"opsboard-backend/models"
 	"opsboard-backend/services"
+	"opsboard-backend/utils"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// currentUser 返回 AuthMiddleware 认证的当前用户
+func currentUser(c *gin.Context) (*models.User, error) {
+	// 从中间件设置的 context 中获取用户 ID 字符串
+	id, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		return nil, utils.ErrUnauthorized("认证凭证中的用户 ID 格式错误")
+	}
+
+	user, err := services.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrNotFound("用户不存在")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
 // GetMe 获取当前已认证用户的信息
 func GetMe(c *gin.Context) {
-	// 从中间件设置的 context 中获取用户 ID 字符串
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "无效的认证凭证"})
+	user, err := currentUser(c)
+	if err != nil {
+		utils.RespondError(c, err, "获取用户信息失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateMe 处理当前用户修改自己昵称的请求
+func UpdateMe(c *gin.Context) {
+	var req UpdateMeRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	id, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		utils.RespondError(c, utils.ErrUnauthorized("认证凭证中的用户 ID 格式错误"), "")
+		return
+	}
+
+	if err := validateEmail(req.Email); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	user, err := services.UpdateUserProfile(c.Request.Context(), id, req.Nickname, req.Email)
+	if err != nil {
+		utils.RespondError(c, err, "修改用户信息失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), user.UserID.String(), string(services.UserUpdated), services.Target("users", user.UserID), services.LogDetails{
+		"nickname":      user.Nickname,
+		"email_changed": req.Email != nil,
+		"ip_address":    c.ClientIP(),
+	})
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangeMyPassword 处理当前用户修改自己密码的请求。成功后该用户其他会话的令牌全部失效，响应中返回当前会话的新令牌。
+func ChangeMyPassword(c *gin.Context) {
+	var req ChangeMyPasswordRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	user, err := currentUser(c)
+	if err != nil {
+		utils.RespondError(c, err, "获取用户信息失败")
+		return
+	}
+
+	ctx := c.Request.Context()
+	block, err := services.CheckLoginAttempt(ctx, user.Username, c.ClientIP())
+	if err != nil {
+		utils.RespondError(c, err, "检查登录限制失败")
+		return
+	}
+	if block != nil {
+		utils.RespondError(c, loginBlockedError(c, block), "")
+		return
+	}
+
+	updated, err := services.ChangeOwnPassword(ctx, user.UserID, req.CurrentPassword, req.NewPassword)
+	if errors.Is(err, services.ErrInvalidCurrentPassword) {
+		recordLoginFailure(c, user.Username, user.UserID.String(), "invalid_current_password")
+	}
+	if err != nil {
+		utils.RespondError(c, err, "修改密码失败")
+		return
+	}
+
+	resp, err := newTokenPair(updated)
+	if err != nil {
+		utils.RespondError(c, err, "生成令牌失败")
+		return
+	}
+	services.CreateLog(ctx, user.UserID.String(), string(services.UserPasswordChanged), services.Target("users", user.UserID), services.LogDetails{
+		"ip_address": c.ClientIP(),
+	})
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateUserRequest 是管理员创建本地用户的请求体，昵称为空时使用用户名
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required,max=50"`
+	Nickname string `json:"nickname" binding:"max=100"`
+	Email    string `json:"email" binding:"omitempty,email,max=254"`
+	Password string `json:"password" binding:"required,max=128"`
+	Role     string `json:"role" binding:"required"`
+}
+
+// UpdateUserRequest 是管理员修改用户资料的请求体，Email 为空时邮箱保持不变，为空字符串时清除邮箱
+type UpdateUserRequest struct {
+	Nickname string  `json:"nickname" binding:"required,max=100"`
+	Email    *string `json:"email" binding:"omitempty,max=254"`
+}
+
+// ChangeUserRoleRequest 是管理员修改用户角色的请求体
+type ChangeUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ResetUserPasswordRequest 是管理员重置用户密码的请求体
+type ResetUserPasswordRequest struct {
+	Password string `json:"password" binding:"required,max=128"`
+}
+
+// SetUserCustomersRequest 是管理员设置用户可访问客户的请求体，空列表表示不能访问任何客户
+type SetUserCustomersRequest struct {
+	CustomerIDs []uint `json:"customerIds" binding:"required,max=1000"`
+}
+
+// UpdateMeRequest 是用户修改自己资料的请求体，Email 的含义与 UpdateUserRequest 相同
+type UpdateMeRequest struct {
+	Nickname string  `json:"nickname" binding:"required,max=100"`
+	Email    *string `json:"email" binding:"omitempty,max=254"`
+}
+
+// ChangeMyPasswordRequest 是用户修改自己密码的请求体
+type ChangeMyPasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required,max=128"`
+	NewPassword     string `json:"newPassword" binding:"required,max=128"`
+}
+
+// GetUserList 处理获取用户列表的请求（支持分页，按用户名/昵称关键字、角色、状态和认证源筛选）
+func GetUserList(c *gin.Context) {
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var filter services.UserFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	result, err := services.GetPaginatedUsers(c.Request.Context(), page, pageSize, filter)
+	if err != nil {
+		utils.RespondError(c, err, "获取用户列表失败")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetUser 处理根据 ID 获取单个用户的请求
+func GetUser(c *gin.Context) {
+	id, err := parseUUIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	user, err := services.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = utils.ErrNotFound("用户不存在")
+		}
+		utils.RespondError(c, err, "获取用户详情失败")
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// CreateUser 处理管理员创建本地用户的请求
+func CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	if !models.IsValidRole(req.Role) {
+		utils.RespondError(c, invalidRoleError(), "")
+		return
+	}
+
+	user, err := services.CreateLocalUser(c.Request.Context(), req.Username, req.Nickname, req.Email, req.Password, req.Role)
+	if err != nil {
+		utils.RespondError(c, err, "创建用户失败")
+		return
+	}
+
+	logUserChange(c, services.UserCreated, user, services.LogDetails{"role": user.Role})
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUser 处理管理员修改用户昵称的请求
+func UpdateUser(c *gin.Context) {
+	id, err := parseUUIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req UpdateUserRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err := validateEmail(req.Email); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	user, err := services.UpdateUserProfile(c.Request.Context(), id, req.Nickname, req.Email)
+	if err != nil {
+		utils.RespondError(c, err, "修改用户失败")
+		return
+	}
+
+	logUserChange(c, services.UserUpdated, user, services.LogDetails{"nickname": user.Nickname, "email_changed": req.Email != nil})
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangeUserRole 处理管理员修改用户角色的请求
+func ChangeUserRole(c *gin.Context) {
+	id, err := parseUUIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req ChangeUserRoleRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	if !models.IsValidRole(req.Role) {
+		utils.RespondError(c, invalidRoleError(), "")
 		return
 	}
+	if err := rejectSelf(c, id, "不能修改自己的角色"); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	user, err := services.SetUserRole(c.Request.Context(), id, req.Role)
+	if err != nil {
+		utils.RespondError(c, err, "修改用户角色失败")
+		return
+	}
+
+	logUserChange(c, services.UserRoleChanged, user, services.LogDetails{"role": user.Role})
+	c.JSON(http.StatusOK, user)
+}
+
+// DisableUser 处理管理员禁用用户的请求。禁用立即生效：用户已签发的访问令牌、刷新令牌和 API 令牌都会被拒绝。
+func DisableUser(c *gin.Context) {
+	setUserDisabled(c, true)
+}
+
+// EnableUser 处理管理员重新启用用户的请求
+func EnableUser(c *gin.Context) {
+	setUserDisabled(c, false)
+}
 
-	// [核心修改] 将字符串解析为 UUID
-	id, err := uuid.Parse(userIDStr.(string))
+// setUserDisabled 禁用或启用路径中的用户并写入审计日志
+func setUserDisabled(c *gin.Context, disabled bool) {
+	id, err := parseUUIDParam(c, "id")
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "认证凭证中的用户 ID 格式错误"})
+		utils.RespondError(c, err, "")
 		return
 	}
+	if disabled {
+		if err := rejectSelf(c, id, "不能禁用自己的账户"); err != nil {
+			utils.RespondError(c, err, "")
+			return
+		}
+	}
 
-	user, err := services.GetUserByID(id)
+	user, err := services.SetUserDisabled(c.Request.Context(), id, disabled)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "用户不存在"})
+		utils.RespondError(c, err, "修改用户状态失败")
 		return
 	}
 
+	action := services.UserEnabled
+	if disabled {
+		action = services.UserDisabled
+	}
+	logUserChange(c, action, user, nil)
 	c.JSON(http.StatusOK, user)
 }
+
+// ResetUserPassword 处理管理员为本地用户重置密码的请求
+func ResetUserPassword(c *gin.Context) {
+	id, err := parseUUIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req ResetUserPasswordRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	user, err := services.ResetUserPassword(c.Request.Context(), id, req.Password)
+	if err != nil {
+		utils.RespondError(c, err, "重置密码失败")
+		return
+	}
+
+	logUserChange(c, services.UserPasswordReset, user, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// GetUserCustomers 处理管理员查看用户可访问客户的请求
+func GetUserCustomers(c *gin.Context) {
+	id, err := parseUUIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	customers, err := services.GetUserCustomers(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "获取用户的客户失败")
+		return
+	}
+	c.JSON(http.StatusOK, customers)
+}
+
+// SetUserCustomers 处理管理员整体替换用户可访问客户的请求。管理员本身可以访问所有客户，不受此设置影响。
+func SetUserCustomers(c *gin.Context) {
+	id, err := parseUUIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var req SetUserCustomersRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	customers, err := services.SetUserCustomers(c.Request.Context(), id, req.CustomerIDs)
+	if err != nil {
+		utils.RespondError(c, err, "设置用户的客户失败")
+		return
+	}
+
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(services.UserCustomersChanged), services.Target("users", id), services.LogDetails{
+		"target_user_id": id.String(),
+		"customer_ids":   req.CustomerIDs,
+		"ip_address":     c.ClientIP(),
+	})
+	c.JSON(http.StatusOK, customers)
+}
+
+// DeleteUser 处理管理员删除用户的请求
+func DeleteUser(c *gin.Context) {
+	id, err := parseUUIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	if err := rejectSelf(c, id, "不能删除自己的账户"); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	user, err := services.DeleteUser(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "删除用户失败")
+		return
+	}
+
+	logUserChange(c, services.UserDeleted, user, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// rejectSelf 在操作对象是当前用户时返回 400
+func rejectSelf(c *gin.Context, id uuid.UUID, message string) error {
+	if c.GetString("user_id") == id.String() {
+		return utils.ErrBadRequest(message)
+	}
+	return nil
+}
+
+// validateEmail 校验修改资料时提交的邮箱地址，nil 和空字符串（保持不变和清除邮箱）不需要校验
+func validateEmail(email *string) error {
+	if email == nil || *email == "" {
+		return nil
+	}
+	if addr, err := mail.ParseAddress(*email); err != nil || addr.Address != *email {
+		return utils.ErrValidation(utils.FieldError{Field: "email", Message: "不是有效的邮箱地址"})
+	}
+	return nil
+}
+
+// invalidRoleError 返回角色不合法时的校验错误
+func invalidRoleError() error {
+	return utils.ErrValidation(utils.FieldError{Field: "role", Message: "必须是 " + strings.Join(models.Roles, "、") + " 之一"})
+}
+
+// logUserChange 写入一条用户管理的审计日志，details 中会补充被操作用户和客户端 IP
+func logUserChange(c *gin.Context, action services.LogAction, user *models.User, details services.LogDetails) {
+	if details == nil {
+		details = services.LogDetails{}
+	}
+	details["target_user_id"] = user.UserID.String()
+	details["username"] = user.Username
+	details["ip_address"] = c.ClientIP()
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(action), services.Target("users", user.UserID), details)
+}
@@ -0,0 +1,212 @@
+/**
+ * @file handlers/webhook_handler.go
+ * @description 处理 webhook 订阅管理的 HTTP 请求：订阅的增删改查、签名密钥轮换、投递日志查询和手动重新投递。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"opsboard-backend/models"
+	"opsboard-backend/services"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookSecretResponse 是创建 webhook 订阅和轮换密钥的响应，Secret 为只显示一次的签名密钥
+type WebhookSecretResponse struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookEventTypesResponse 是可订阅事件类型列表的响应
+type WebhookEventTypesResponse struct {
+	Events []services.EventType `json:"events"`
+}
+
+// GetWebhookEventTypes 返回所有可以订阅的事件类型
+func GetWebhookEventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, WebhookEventTypesResponse{Events: services.EventTypes})
+}
+
+// GetWebhooks 处理获取 webhook 订阅列表的请求
+func GetWebhooks(c *gin.Context) {
+	webhooks, err := services.GetWebhooks(c.Request.Context())
+	if err != nil {
+		utils.RespondError(c, err, "获取 webhook 列表失败")
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhook 处理获取单个 webhook 订阅的请求，并返回 ETag
+func GetWebhook(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	webhook, err := services.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondError(c, err, "获取 webhook 失败")
+		return
+	}
+	setETag(c, webhook.Version)
+	c.JSON(http.StatusOK, webhook)
+}
+
+// CreateWebhook 处理创建 webhook 订阅的请求
+func CreateWebhook(c *gin.Context) {
+	var input services.WebhookInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	webhook, secret, err := services.CreateWebhook(c.Request.Context(), c.GetString("user_id"), input)
+	if err != nil {
+		utils.RespondError(c, err, "创建 webhook 失败")
+		return
+	}
+
+	logWebhookChange(c, services.WebhookCreated, services.Target("webhooks", webhook.WebhookID), services.LogDetails{
+		"webhook_id": webhook.WebhookID,
+		"name":       webhook.Name,
+		"url":        webhook.URL,
+		"events":     webhook.Events,
+	})
+	setETag(c, webhook.Version)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, WebhookSecretResponse{Webhook: *webhook, Secret: secret})
+}
+
+// UpdateWebhook 处理修改 webhook 订阅的请求
+func UpdateWebhook(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var input services.WebhookInput
+	if err := bindJSON(c, &input); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	webhook, err := services.UpdateWebhook(c.Request.Context(), id, input, expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "修改 webhook 失败")
+		return
+	}
+
+	logWebhookChange(c, services.WebhookUpdated, services.Target("webhooks", webhook.WebhookID), services.LogDetails{
+		"webhook_id": webhook.WebhookID,
+		"name":       webhook.Name,
+		"url":        webhook.URL,
+		"events":     webhook.Events,
+		"enabled":    webhook.Enabled,
+	})
+	setETag(c, webhook.Version)
+	c.JSON(http.StatusOK, webhook)
+}
+
+// RotateWebhookSecret 处理轮换 webhook 签名密钥的请求
+func RotateWebhookSecret(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	webhook, secret, err := services.RotateWebhookSecret(c.Request.Context(), id, expectedVersion(c))
+	if err != nil {
+		utils.RespondError(c, err, "轮换 webhook 密钥失败")
+		return
+	}
+
+	logWebhookChange(c, services.WebhookSecretRotated, services.Target("webhooks", webhook.WebhookID), services.LogDetails{"webhook_id": webhook.WebhookID})
+	setETag(c, webhook.Version)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, WebhookSecretResponse{Webhook: *webhook, Secret: secret})
+}
+
+// DeleteWebhook 处理删除 webhook 订阅的请求
+func DeleteWebhook(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	if err := services.DeleteWebhook(c.Request.Context(), id, expectedVersion(c)); err != nil {
+		utils.RespondError(c, err, "删除 webhook 失败")
+		return
+	}
+
+	logWebhookChange(c, services.WebhookDeleted, services.Target("webhooks", id), services.LogDetails{"webhook_id": id})
+	c.Status(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries 处理分页查询 webhook 投递日志的请求
+func GetWebhookDeliveries(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	page, pageSize, err := parsePagination(c)
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	var filter services.WebhookDeliveryFilter
+	if err := bindQuery(c, &filter); err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	result, err := services.GetWebhookDeliveries(c.Request.Context(), id, page, pageSize, filter)
+	if err != nil {
+		utils.RespondError(c, err, "获取投递日志失败")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RedeliverWebhookDelivery 处理手动重新投递的请求，返回新建的投递记录
+func RedeliverWebhookDelivery(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+	deliveryID, err := parseIDParam(c, "deliveryId")
+	if err != nil {
+		utils.RespondError(c, err, "")
+		return
+	}
+
+	delivery, err := services.RedeliverWebhookDelivery(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		utils.RespondError(c, err, "重新投递失败")
+		return
+	}
+
+	logWebhookChange(c, services.WebhookRedelivered, services.Target("webhook_deliveries", delivery.DeliveryID), services.LogDetails{
+		"webhook_id":    delivery.WebhookID,
+		"delivery_id":   delivery.DeliveryID,
+		"redelivery_of": deliveryID,
+		"event_id":      delivery.EventID,
+	})
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// logWebhookChange 记录管理员修改 webhook 配置的审计日志
+func logWebhookChange(c *gin.Context, action services.LogAction, target services.AuditTarget, details services.LogDetails) {
+	details["ip_address"] = c.ClientIP()
+	services.CreateLog(c.Request.Context(), c.GetString("user_id"), string(action), target, details)
+}
@@ -1,17 +1,27 @@
 // @file main.go
 // @description 应用主入口文件，负责初始化、路由注册和服务器启动。
 // @modification 本次提交中所做的具体修改摘要。
-//   - [路由新增]：在受保护的 `/api/servers` 路由组下，新增了 `GET "/:id"` 路由。
-//   - [功能对接]：将新路由指向了新创建的 `handlers.GetServerByID` 处理器，从而使获取单个服务器详情的 API 端点能够正常工作，解决了前端的 404 错误。
+//   - [JWT]：开启 `JWT_ACCEPT_LEGACY_HS256` 时在启动时设置旧令牌的密钥并输出警告，提醒旧令牌过期后关闭。
 
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"opsboard-backend/config"
 	"opsboard-backend/database"
 	"opsboard-backend/handlers"
+	"opsboard-backend/metrics"
 	"opsboard-backend/middleware"
+	"opsboard-backend/models"
+	"opsboard-backend/services"
+	"opsboard-backend/tracing"
+	"opsboard-backend/utils"
+	"os"
+	"runtime/debug"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -20,75 +30,575 @@ import (
 func main() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("无法加载配置: %v", err)
+		slog.Error("无法加载配置", "error", err)
+		os.Exit(1)
 	}
-	log.Printf("正在尝试使用以下连接字符串进行连接: %s", cfg.DBConnectionString)
+	utils.InitLogger(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+
+	shutdownTracing, err := tracing.Init(tracing.Config{
+		Exporter:    cfg.TracesExporter,
+		FilePath:    cfg.TracesFile,
+		ServiceName: cfg.ServiceName,
+		SampleRatio: cfg.TracesSampleRatio,
+	})
+	if err != nil {
+		slog.Error("无法初始化链路追踪", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("关闭链路追踪时出错", "error", err)
+		}
+	}()
+
+	slog.Info("正在连接数据库", "database", utils.RedactDSN(cfg.DBConnectionString))
 
 	if err := database.InitDB(cfg.DBConnectionString); err != nil {
-		log.Fatalf("无法连接到数据库: %v", err)
+		slog.Error("无法连接到数据库", "error", err)
+		os.Exit(1)
 	}
 	defer database.CloseDB()
+	metrics.RegisterDBStats(database.DB)
+	services.StartAuditWorker()
+
+	utils.RegisterValidatorTagNames()
+	if err := utils.ConfigureSigningKey(cfg.FormSigningSecret); err != nil {
+		slog.Error("必须设置签名密钥 FORM_SIGNING_SECRET", "error", err)
+		os.Exit(1)
+	}
+	services.ConfigureLoginGuard(loginPolicy(cfg))
+	services.ConfigureFormTokens(formTokenPolicy(cfg))
+	services.ConfigureMFA(cfg.MFAIssuer)
+	if err := services.ConfigurePasswordPolicy(passwordPolicy(cfg)); err != nil {
+		slog.Error("密码策略配置无效", "error", err)
+		os.Exit(1)
+	}
+
+	if err := services.ConfigureJWTKeys(jwtKeyPolicy(cfg)); err != nil {
+		slog.Error("JWT 签名密钥配置无效", "error", err)
+		os.Exit(1)
+	}
+	if err := services.RotateJWTKeys(context.Background()); err != nil {
+		slog.Error("无法加载 JWT 签名密钥", "error", err)
+		os.Exit(1)
+	}
+	services.StartJWTKeyRotation()
+	if cfg.JWTAcceptLegacyHS256 {
+		if cfg.JWTSecret == "" {
+			slog.Warn("已开启 JWT_ACCEPT_LEGACY_HS256 但 JWT_SECRET 为空，不会接受 HS256 旧令牌")
+		} else {
+			slog.Warn("正在接受用 JWT_SECRET 签发的 HS256 旧令牌，旧的刷新令牌全部过期后应关闭 JWT_ACCEPT_LEGACY_HS256")
+			utils.ConfigureLegacyHS256(cfg.JWTSecret)
+		}
+	}
+
+	if err := services.LoadSystemSettings(context.Background()); err != nil {
+		slog.Error("无法加载系统设置", "error", err)
+		os.Exit(1)
+	}
+	services.StartSettingsReload()
+	services.StartSLAEvaluator(cfg.SLAEvaluationInterval)
+	services.ConfigureWebhooks(webhookPolicy(cfg))
+	services.StartWebhookDispatcher()
+	services.StartChatNotifier()
+	services.ConfigureEmail(emailPolicy(cfg))
+	services.StartEmailDispatcher()
+	services.StartNotificationCenter()
+
+	providers, err := authenticators(cfg)
+	if err != nil {
+		slog.Error("登录认证源配置无效", "error", err)
+		os.Exit(1)
+	}
+	services.ConfigureAuthenticators(providers...)
+	if err := configureOIDC(cfg); err != nil {
+		slog.Error("OIDC 单点登录配置无效", "error", err)
+		os.Exit(1)
+	}
+
+	r := gin.New()
+	r.HandleMethodNotAllowed = true
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		slog.Error("TRUSTED_PROXIES 配置无效", "error", err)
+		os.Exit(1)
+	}
+	r.Use(middleware.RequestID(), middleware.Tracing(), middleware.RequestLogger(), middleware.Metrics(), gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		slog.ErrorContext(c.Request.Context(), "处理请求时发生 panic", "panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+		utils.RespondError(c, fmt.Errorf("panic: %v", recovered), "服务器内部错误")
+	}))
+	r.NoRoute(func(c *gin.Context) {
+		utils.RespondError(c, utils.ErrNotFound("请求的资源不存在"), "")
+	})
+	r.NoMethod(func(c *gin.Context) {
+		utils.RespondError(c, utils.NewAppError(utils.CodeMethodNotAllowed, "不支持的请求方法"), "")
+	})
 
-	r := gin.Default()
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = []string{"http://localhost:5173"}
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "If-Match", middleware.RequestIDHeader, "traceparent", "tracestate"}
+	corsConfig.ExposeHeaders = []string{"ETag", "Content-Disposition", middleware.RequestIDHeader}
 	r.Use(cors.New(corsConfig))
 
+	// --- Prometheus 指标端点 ---
+	switch {
+	case cfg.MetricsAddr != "":
+		go serveMetrics(cfg.MetricsAddr, cfg.MetricsToken)
+	case cfg.MetricsToken != "":
+		r.GET("/metrics", middleware.MetricsToken(cfg.MetricsToken), gin.WrapH(metrics.Handler()))
+	default:
+		slog.Info("未设置 METRICS_ADDR 或 METRICS_TOKEN，不暴露 /metrics 端点")
+	}
+
+	// 供其他内部服务校验访问令牌的公钥
+	r.GET("/.well-known/jwks.json", handlers.GetJWKS)
+
+	// 修改和删除路由共用的 If-Match 条件请求中间件
+	ifMatch := middleware.IfMatch(cfg.RequireIfMatch)
+	// 内部管理路由组共用的角色限制，客户门户用户只能访问 /api/portal
+	staff := middleware.RequireRole(models.StaffRoles...)
+
 	api := r.Group("/api")
 	{
 		// --- 公开路由组 (Public Routes) ---
 		auth := api.Group("/auth")
 		{
+			auth.GET("/form-token", handlers.GetFormToken)
 			auth.POST("/login", handlers.Login)
 			auth.POST("/refresh", handlers.RefreshToken)
+			auth.POST("/unlock", middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin), handlers.UnlockLogin)
+			// 两步验证：使用 Login 返回的挑战令牌完成验证或强制绑定
+			auth.POST("/mfa/verify", handlers.VerifyMFA)
+			auth.POST("/mfa/enroll", handlers.BeginMFAChallengeEnrollment)
+			auth.POST("/mfa/enroll/confirm", handlers.ConfirmMFAChallengeEnrollment)
+			// OpenID Connect 单点登录，未配置时返回 404
+			auth.GET("/oidc/login", handlers.OIDCLogin)
+			auth.GET("/oidc/callback", handlers.OIDCCallback)
 		}
 
 		// --- 受保护的路由组 (Protected Routes) ---
 
 		users := api.Group("/users")
-		users.Use(middleware.AuthMiddleware())
+		users.Use(middleware.AuthMiddleware(), middleware.RequireScope(models.ScopeUsers))
 		{
 			users.GET("/me", handlers.GetMe)
+			users.PATCH("/me", handlers.UpdateMe)
+			users.GET("/me/preferences", handlers.GetMyPreferences)
+			users.PUT("/me/preferences", ifMatch, handlers.UpdateMyPreferences)
+
+			// 账户安全相关的操作只允许交互式登录的会话访问
+			session := users.Group("", middleware.RequireSession())
+			session.POST("/me/password", handlers.ChangeMyPassword)
+			session.GET("/me/mfa", handlers.GetMyMFA)
+			session.POST("/me/mfa/enroll", handlers.BeginMyMFAEnrollment)
+			session.POST("/me/mfa/confirm", handlers.ConfirmMyMFAEnrollment)
+			session.POST("/me/mfa/recovery-codes", handlers.RegenerateMyRecoveryCodes)
+			session.POST("/me/mfa/disable", handlers.DisableMyMFA)
+			session.GET("/me/tokens", handlers.GetMyAPITokens)
+			session.POST("/me/tokens", handlers.CreateMyAPIToken)
+			session.DELETE("/me/tokens/:tokenId", handlers.RevokeMyAPIToken)
+			session.DELETE("/:id/mfa", middleware.RequireRole(models.RoleAdmin), handlers.ResetUserMFA)
+
+			// 用户管理：查询允许带 users 范围的 API 令牌访问，修改只允许交互式登录的会话
+			users.GET("", middleware.RequireRole(models.RoleAdmin), handlers.GetUserList)
+			users.GET("/:id", middleware.RequireRole(models.RoleAdmin), handlers.GetUser)
+			users.GET("/:id/customers", middleware.RequireRole(models.RoleAdmin), handlers.GetUserCustomers)
+			admin := session.Group("", middleware.RequireRole(models.RoleAdmin))
+			admin.POST("", handlers.CreateUser)
+			admin.PUT("/:id", handlers.UpdateUser)
+			admin.PUT("/:id/role", handlers.ChangeUserRole)
+			admin.POST("/:id/disable", handlers.DisableUser)
+			admin.POST("/:id/enable", handlers.EnableUser)
+			admin.POST("/:id/password", handlers.ResetUserPassword)
+			admin.DELETE("/:id", handlers.DeleteUser)
+			admin.PUT("/:id/customers", handlers.SetUserCustomers)
+		}
+
+		mfa := api.Group("/mfa")
+		mfa.Use(middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin))
+		{
+			mfa.GET("/policy", handlers.GetMFAPolicy)
+			mfa.PUT("/policy", handlers.UpdateMFAPolicy)
+		}
+
+		settings := api.Group("/settings")
+		settings.Use(middleware.AuthMiddleware(), middleware.RequireSession())
+		{
+			settings.GET("", handlers.GetSystemSettings)
+			settings.PUT("", middleware.RequireRole(models.RoleAdmin), ifMatch, handlers.UpdateSystemSettings)
+		}
+
+		notifications := api.Group("/notifications")
+		notifications.Use(middleware.AuthMiddleware(), middleware.RequireSession())
+		{
+			notifications.GET("", handlers.GetNotifications)
+			notifications.GET("/unread-count", handlers.GetUnreadNotificationCount)
+			notifications.GET("/stream", handlers.StreamNotifications)
+			notifications.POST("/read-all", handlers.MarkAllNotificationsRead)
+			notifications.POST("/:id/read", handlers.MarkNotificationRead)
+		}
+
+		sla := api.Group("/sla")
+		sla.Use(middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin))
+		{
+			sla.GET("/policies", handlers.GetSLAPolicies)
+			sla.POST("/policies", handlers.CreateSLAPolicy)
+			sla.PUT("/policies/:id", ifMatch, handlers.UpdateSLAPolicy)
+			sla.DELETE("/policies/:id", ifMatch, handlers.DeleteSLAPolicy)
+			sla.GET("/calendars", handlers.GetBusinessCalendars)
+			sla.POST("/calendars", handlers.CreateBusinessCalendar)
+			sla.PUT("/calendars/:id", ifMatch, handlers.UpdateBusinessCalendar)
+			sla.DELETE("/calendars/:id", ifMatch, handlers.DeleteBusinessCalendar)
+		}
+
+		webhooks := api.Group("/webhooks")
+		webhooks.Use(middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin))
+		{
+			webhooks.GET("/events", handlers.GetWebhookEventTypes)
+			webhooks.GET("", handlers.GetWebhooks)
+			webhooks.POST("", handlers.CreateWebhook)
+			webhooks.GET("/:id", handlers.GetWebhook)
+			webhooks.PUT("/:id", ifMatch, handlers.UpdateWebhook)
+			webhooks.DELETE("/:id", ifMatch, handlers.DeleteWebhook)
+			webhooks.POST("/:id/secret", ifMatch, handlers.RotateWebhookSecret)
+			webhooks.GET("/:id/deliveries", handlers.GetWebhookDeliveries)
+			webhooks.POST("/:id/deliveries/:deliveryId/redeliver", handlers.RedeliverWebhookDelivery)
+		}
+
+		channels := api.Group("/notification-channels")
+		channels.Use(middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin))
+		{
+			channels.GET("", handlers.GetNotificationChannels)
+			channels.POST("", handlers.CreateNotificationChannel)
+			channels.GET("/:id", handlers.GetNotificationChannel)
+			channels.PUT("/:id", ifMatch, handlers.UpdateNotificationChannel)
+			channels.DELETE("/:id", ifMatch, handlers.DeleteNotificationChannel)
+			channels.POST("/:id/test", handlers.TestNotificationChannel)
+		}
+
+		rules := api.Group("/notification-rules")
+		rules.Use(middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin))
+		{
+			rules.GET("", handlers.GetNotificationRules)
+			rules.POST("", handlers.CreateNotificationRule)
+			rules.GET("/:id", handlers.GetNotificationRule)
+			rules.PUT("/:id", ifMatch, handlers.UpdateNotificationRule)
+			rules.DELETE("/:id", ifMatch, handlers.DeleteNotificationRule)
+		}
+
+		templates := api.Group("/notification-templates")
+		templates.Use(middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin))
+		{
+			templates.GET("/:kind", handlers.GetNotificationTemplates)
+			templates.GET("/:kind/:event", handlers.GetNotificationTemplate)
+			templates.PUT("/:kind/:event", ifMatch, handlers.UpdateNotificationTemplate)
+			templates.DELETE("/:kind/:event", ifMatch, handlers.ResetNotificationTemplate)
+		}
+
+		email := api.Group("/email")
+		email.Use(middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequireRole(models.RoleAdmin))
+		{
+			email.GET("/outbox", handlers.GetEmailOutbox)
+			email.POST("/outbox/:id/retry", handlers.RetryEmail)
+			email.POST("/test", handlers.SendTestEmail)
 		}
 
 		servers := api.Group("/servers")
-		servers.Use(middleware.AuthMiddleware())
+		servers.Use(middleware.AuthMiddleware(), middleware.RequireScope(models.ScopeServers), staff)
 		{
 			servers.GET("/list", handlers.GetServerList)
+			servers.GET("/export", handlers.ExportServers)
+			servers.POST("/import", handlers.ImportServers)
 			// [核心新增] 注册获取单个服务器详情的路由
 			servers.GET("/:id", handlers.GetServerByID)
-			servers.DELETE("/:id", handlers.DeleteServer)
+			servers.DELETE("/:id", ifMatch, handlers.DeleteServer)
+			servers.POST("/:id/offline", handlers.ReportServerOffline)
 		}
 
 		changelogs := api.Group("/changelogs")
-		changelogs.Use(middleware.AuthMiddleware())
+		changelogs.Use(middleware.AuthMiddleware(), middleware.RequireScope(models.ScopeChangelogs), staff)
 		{
 			changelogs.GET("/list", handlers.GetChangelogList)
-			changelogs.DELETE("/:id", handlers.DeleteChangelog)
-			changelogs.PUT("/:id/complete", handlers.CompleteChangelog)
-			changelogs.PUT("/:id/uncomplete", handlers.UncompleteChangelog)
+			changelogs.GET("/export", handlers.ExportChangelogs)
+			changelogs.GET("/:id", handlers.GetChangelogByID)
+			changelogs.DELETE("/:id", ifMatch, handlers.DeleteChangelog)
+			changelogs.PUT("/:id/complete", ifMatch, handlers.CompleteChangelog)
+			changelogs.PUT("/:id/uncomplete", ifMatch, handlers.UncompleteChangelog)
 		}
 
 		maintenance := api.Group("/maintenance")
-		maintenance.Use(middleware.AuthMiddleware())
+		maintenance.Use(middleware.AuthMiddleware(), middleware.RequireScope(models.ScopeMaintenance), staff)
 		{
 			maintenance.GET("/list", handlers.GetMaintenanceTaskList)
-			maintenance.DELETE("/:id", handlers.DeleteMaintenanceTask)
-			maintenance.PUT("/:id/complete", handlers.CompleteMaintenanceTask)
-			maintenance.PUT("/:id/uncomplete", handlers.UncompleteMaintenanceTask)
+			maintenance.GET("/export", handlers.ExportMaintenanceTasks)
+			maintenance.GET("/:id", handlers.GetMaintenanceTaskByID)
+			maintenance.DELETE("/:id", ifMatch, handlers.DeleteMaintenanceTask)
+			maintenance.PUT("/:id/complete", ifMatch, handlers.CompleteMaintenanceTask)
+			maintenance.PUT("/:id/uncomplete", ifMatch, handlers.UncompleteMaintenanceTask)
+			maintenance.PUT("/:id/fail", ifMatch, handlers.FailMaintenanceTask)
 		}
 
 		tickets := api.Group("/tickets")
-		tickets.Use(middleware.AuthMiddleware())
+		tickets.Use(middleware.AuthMiddleware(), middleware.RequireScope(models.ScopeTickets), staff)
 		{
 			tickets.GET("/list", handlers.GetTicketList)
+			tickets.GET("/export", handlers.ExportTickets)
+			tickets.GET("/stats", handlers.GetTicketStats)
+			tickets.GET("/:id", handlers.GetTicketByID)
+			tickets.DELETE("/:id", ifMatch, handlers.DeleteTicket)
+			tickets.PUT("/:id/status", ifMatch, handlers.SetTicketStatus)
+			tickets.PUT("/:id/priority", ifMatch, handlers.SetTicketPriority)
+			tickets.GET("/:id/comments", handlers.GetTicketComments)
+			tickets.POST("/:id/comments", handlers.AddTicketComment)
+		}
+
+		// --- 客户门户路由组：只返回对客户公开的工单信息 ---
+		portal := api.Group("/portal")
+		portal.Use(middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequireRole(models.RoleCustomer))
+		{
+			portal.GET("/customers", handlers.GetPortalCustomers)
+			portal.GET("/tickets", handlers.GetPortalTicketList)
+			portal.POST("/tickets", handlers.SubmitPortalTicket)
+			portal.GET("/tickets/:id", handlers.GetPortalTicket)
+			portal.POST("/tickets/:id/confirm", ifMatch, handlers.ConfirmPortalTicket)
+			portal.PUT("/tickets/:id/rating", ifMatch, handlers.RatePortalTicket)
+			portal.GET("/tickets/:id/comments", handlers.GetPortalTicketComments)
+			portal.POST("/tickets/:id/comments", handlers.AddPortalTicketComment)
+		}
+
+		customers := api.Group("/customers")
+		customers.Use(middleware.AuthMiddleware(), middleware.RequireScope(models.ScopeCustomers), staff)
+		{
+			customers.GET("/list", handlers.GetCustomerList)
+			customers.GET("/:id", handlers.GetCustomerByID)
+			customers.DELETE("/:id", ifMatch, handlers.DeleteCustomer)
+		}
+
+		trash := api.Group("/trash")
+		trash.Use(middleware.AuthMiddleware(), middleware.RequireScope(models.ScopeTrash), staff)
+		{
+			trash.GET("", handlers.GetTrashList)
+			trash.POST("/:entity/:id/restore", handlers.RestoreTrashItem)
+			trash.DELETE("/:entity/:id", middleware.RequireRole(models.RoleAdmin), handlers.PurgeTrashItem)
+		}
+
+		auditLogs := api.Group("/audit-logs")
+		// 审计日志包含所有用户和客户的操作记录，只有管理员可以查看和导出
+		auditLogs.Use(middleware.AuthMiddleware(), middleware.RequireScope(models.ScopeAuditLogs), middleware.RequireRole(models.RoleAdmin))
+		{
+			auditLogs.GET("/list", handlers.GetAuditLogList)
+			auditLogs.GET("/export", handlers.ExportAuditLogs)
 		}
 	}
 
-	log.Printf("服务器正在端口 %s 上运行", cfg.ServerPort)
+	slog.Info("服务器正在运行", "port", cfg.ServerPort)
 	if err := r.Run(":" + cfg.ServerPort); err != nil {
-		log.Fatalf("无法启动服务器: %v", err)
+		slog.Error("无法启动服务器", "error", err)
+		os.Exit(1)
+	}
+}
+
+// loginPolicy 根据配置生成登录防护策略和存储，未配置的项沿用 services.DefaultLoginPolicy
+func loginPolicy(cfg *config.Config) (services.LoginPolicy, services.LoginAttemptStore) {
+	policy := services.DefaultLoginPolicy
+	if cfg.LoginMaxFailures > 0 {
+		policy.MaxUserFailures = cfg.LoginMaxFailures
+	}
+	if cfg.LoginIPMaxFailures > 0 {
+		policy.MaxIPFailures = cfg.LoginIPMaxFailures
+	}
+	if cfg.LoginFailureWindow > 0 {
+		policy.FailureWindow = cfg.LoginFailureWindow
+	}
+	if cfg.LoginLockoutDuration > 0 {
+		policy.LockoutDuration = cfg.LoginLockoutDuration
+	}
+	if cfg.LoginBackoffBase > 0 {
+		policy.BackoffBase = cfg.LoginBackoffBase
+	}
+	if cfg.LoginBackoffMax > 0 {
+		policy.BackoffMax = cfg.LoginBackoffMax
+	}
+
+	switch cfg.LoginAttemptStore {
+	case "", "memory":
+		return policy, services.NewMemoryLoginAttemptStore(policy.FailureWindow)
+	case "database":
+		return policy, services.NewDatabaseLoginAttemptStore(policy.FailureWindow)
+	default:
+		slog.Warn("不支持的 LOGIN_ATTEMPT_STORE，将使用内存存储", "value", cfg.LoginAttemptStore)
+		return policy, services.NewMemoryLoginAttemptStore(policy.FailureWindow)
+	}
+}
+
+// webhookPolicy 根据配置生成 webhook 投递策略，未配置的项沿用 services.DefaultWebhookPolicy
+func webhookPolicy(cfg *config.Config) services.WebhookPolicy {
+	policy := services.DefaultWebhookPolicy
+	if cfg.WebhookTimeout > 0 {
+		policy.Timeout = cfg.WebhookTimeout
+	}
+	if cfg.WebhookMaxAttempts > 0 {
+		policy.MaxAttempts = cfg.WebhookMaxAttempts
+	}
+	if cfg.WebhookRetryBase > 0 {
+		policy.RetryBase = cfg.WebhookRetryBase
+	}
+	if cfg.WebhookRetryMax > 0 {
+		policy.RetryMax = cfg.WebhookRetryMax
+	}
+	return policy
+}
+
+// emailPolicy 根据配置生成邮件策略，未配置的项使用默认值
+func emailPolicy(cfg *config.Config) services.EmailPolicy {
+	policy := services.DefaultEmailPolicy
+	policy.SMTP.Host = cfg.SMTPHost
+	policy.SMTP.Username = cfg.SMTPUsername
+	policy.SMTP.Password = cfg.SMTPPassword
+	policy.SMTP.From = cfg.SMTPFrom
+	policy.SMTP.InsecureSkipVerify = cfg.SMTPInsecureSkipVerify
+	if cfg.SMTPPort > 0 {
+		policy.SMTP.Port = cfg.SMTPPort
+	}
+	if cfg.SMTPSecurity != "" {
+		policy.SMTP.Security = cfg.SMTPSecurity
+	}
+	if cfg.SMTPTimeout > 0 {
+		policy.SMTP.Timeout = cfg.SMTPTimeout
+	}
+	if cfg.EmailMaxAttempts > 0 {
+		policy.MaxAttempts = cfg.EmailMaxAttempts
+	}
+	if cfg.EmailRetryBase > 0 {
+		policy.RetryBase = cfg.EmailRetryBase
+	}
+	if cfg.EmailRetryMax > 0 {
+		policy.RetryMax = cfg.EmailRetryMax
+	}
+	if cfg.EmailDigestTime >= 0 {
+		policy.DigestTime = cfg.EmailDigestTime
+	}
+	return policy
+}
+
+// authenticators 按 AUTH_PROVIDERS 的顺序创建登录认证源
+func authenticators(cfg *config.Config) ([]services.Authenticator, error) {
+	var providers []services.Authenticator
+	for _, name := range cfg.AuthProviders {
+		switch name {
+		case models.AuthSourceLocal:
+			providers = append(providers, services.LocalAuthenticator{})
+		case models.AuthSourceLDAP:
+			groupRoles, err := services.ParseGroupRoles(cfg.LDAPGroupRoles)
+			if err != nil {
+				return nil, err
+			}
+			ldapAuth, err := services.NewLDAPAuthenticator(services.LDAPConfig{
+				URL:                cfg.LDAPURL,
+				StartTLS:           cfg.LDAPStartTLS,
+				InsecureSkipVerify: cfg.LDAPInsecureSkipVerify,
+				BindDN:             cfg.LDAPBindDN,
+				BindPassword:       cfg.LDAPBindPassword,
+				BaseDN:             cfg.LDAPBaseDN,
+				UserFilter:         cfg.LDAPUserFilter,
+				NicknameAttribute:  cfg.LDAPNicknameAttribute,
+				GroupAttribute:     cfg.LDAPGroupAttribute,
+				GroupRoles:         groupRoles,
+				DefaultRole:        cfg.LDAPDefaultRole,
+				Timeout:            cfg.LDAPTimeout,
+			})
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, ldapAuth)
+		default:
+			return nil, fmt.Errorf("不支持的认证源: %s", name)
+		}
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("至少需要配置一个认证源")
+	}
+	return providers, nil
+}
+
+// configureOIDC 按配置启用 OpenID Connect 单点登录，未配置 OIDC_ISSUER_URL 时不启用
+func configureOIDC(cfg *config.Config) error {
+	groupRoles, err := services.ParseGroupRoles(cfg.OIDCGroupRoles)
+	if err != nil {
+		return err
+	}
+	return services.ConfigureOIDC(services.OIDCConfig{
+		IssuerURL:     cfg.OIDCIssuerURL,
+		ClientID:      cfg.OIDCClientID,
+		ClientSecret:  cfg.OIDCClientSecret,
+		RedirectURL:   cfg.OIDCRedirectURL,
+		Scopes:        cfg.OIDCScopes,
+		UsernameClaim: cfg.OIDCUsernameClaim,
+		NicknameClaim: cfg.OIDCNicknameClaim,
+		GroupsClaim:   cfg.OIDCGroupsClaim,
+		GroupRoles:    groupRoles,
+		DefaultRole:   cfg.OIDCDefaultRole,
+		PostLoginURL:  cfg.OIDCPostLoginURL,
+	})
+}
+
+// formTokenPolicy 根据配置生成登录表单令牌策略，未配置的项沿用 services.DefaultFormTokenPolicy
+func formTokenPolicy(cfg *config.Config) services.FormTokenPolicy {
+	policy := services.DefaultFormTokenPolicy
+	if cfg.LoginFormMinAge > 0 {
+		policy.MinAge = cfg.LoginFormMinAge
+	}
+	if cfg.LoginFormMaxAge > 0 {
+		policy.MaxAge = cfg.LoginFormMaxAge
+	}
+	return policy
+}
+
+// jwtKeyPolicy 根据配置生成 JWT 签名密钥策略，未配置的项沿用 services.DefaultJWTKeyPolicy
+func jwtKeyPolicy(cfg *config.Config) services.JWTKeyPolicy {
+	policy := services.DefaultJWTKeyPolicy
+	if cfg.JWTSigningAlg != "" {
+		policy.Algorithm = cfg.JWTSigningAlg
+	}
+	if cfg.JWTKeyRotationInterval > 0 {
+		policy.RotationInterval = cfg.JWTKeyRotationInterval
+	}
+	if cfg.JWTKeyPrePublish > 0 {
+		policy.PrePublish = cfg.JWTKeyPrePublish
+	}
+	return policy
+}
+
+// passwordPolicy 根据配置生成本地账户密码策略，未配置的项沿用 services.DefaultPasswordPolicy
+func passwordPolicy(cfg *config.Config) services.PasswordPolicy {
+	policy := services.DefaultPasswordPolicy
+	if cfg.PasswordMinLength > 0 {
+		policy.MinLength = cfg.PasswordMinLength
+	}
+	if cfg.PasswordMinClasses > 0 {
+		policy.MinClasses = min(cfg.PasswordMinClasses, 4)
+	}
+	if cfg.PasswordHistory > 0 {
+		policy.HistorySize = cfg.PasswordHistory
+	}
+	policy.BreachListFile = cfg.PasswordBreachList
+	return policy
+}
+
+// serveMetrics 在独立的地址上提供 `/metrics` 端点（例如只绑定内网或回环地址），token 不为空时同样要求携带令牌
+func serveMetrics(addr, token string) {
+	mr := gin.New()
+	mr.Use(gin.Recovery())
+	chain := []gin.HandlerFunc{gin.WrapH(metrics.Handler())}
+	if token != "" {
+		chain = append([]gin.HandlerFunc{middleware.MetricsToken(token)}, chain...)
+	}
+	mr.GET("/metrics", chain...)
+
+	slog.Info("指标端点正在运行", "addr", addr)
+	if err := mr.Run(addr); err != nil {
+		slog.Error("无法启动指标端点", "addr", addr, "error", err)
 	}
 }
@@ -0,0 +1,120 @@
+/**
+ * @file metrics/metrics.go
+ * @description 定义应用暴露给 Prometheus 的全部指标，以及 `/metrics` 端点的 HTTP 处理器。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [机器人验证]：新增登录结果 `rejected`，统计被蜜罐或表单令牌检查拒绝的登录提交。
+ */
+
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace 是所有自定义指标的前缀
+const namespace = "opsboard"
+
+// 任务/登录结果标签的取值
+const (
+	ResultSuccess  = "success"
+	ResultFailure  = "failure"
+	ResultBlocked  = "blocked"  // 仅用于登录：因失败次数过多被拒绝，未校验密码
+	ResultRejected = "rejected" // 仅用于登录：未通过蜜罐或表单令牌检查
+)
+
+var (
+	// HTTPRequestsTotal 按方法、路由模板和状态码统计请求数
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP 请求总数，按方法、路由模板和状态码划分。",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration 按方法、路由模板和状态码统计请求耗时
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP 请求耗时（秒），按方法、路由模板和状态码划分。",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestsInFlight 是当前正在处理的请求数
+	HTTPRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "当前正在处理的 HTTP 请求数。",
+	})
+
+	// LoginAttemptsTotal 按结果（success|failure|blocked|rejected）统计登录尝试次数
+	LoginAttemptsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_attempts_total",
+		Help:      "登录尝试总数，按结果划分。",
+	}, []string{"result"})
+
+	// SchedulerRunsTotal 按任务名和结果统计后台调度任务的运行次数
+	SchedulerRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scheduler_runs_total",
+		Help:      "后台调度任务运行总数，按任务名和结果划分。",
+	}, []string{"job", "result"})
+
+	// SchedulerRunDuration 按任务名统计后台调度任务的耗时
+	SchedulerRunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scheduler_run_duration_seconds",
+		Help:      "后台调度任务单次运行耗时（秒）。",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"job"})
+
+	// TaskExecutionsTotal 按执行器和结果统计任务执行次数（例如审计日志写入、webhook 投递、通知发送）
+	TaskExecutionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "task_executions_total",
+		Help:      "任务执行器执行任务总数，按执行器和结果划分。",
+	}, []string{"executor", "result"})
+)
+
+// Result 将错误转换为结果标签：nil 为 success，否则为 failure
+func Result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
+
+// ObserveSchedulerRun 执行一次调度任务 fn，并记录其运行次数、结果和耗时
+func ObserveSchedulerRun(job string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	SchedulerRunDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+	SchedulerRunsTotal.WithLabelValues(job, Result(err)).Inc()
+	return err
+}
+
+// RegisterDBStats 注册数据库连接池统计（打开/使用中/空闲连接数、等待次数和等待时长等）
+func RegisterDBStats(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "opsboard"))
+}
+
+// RegisterQueueDepth 注册一个队列深度指标，depth 在每次抓取时被调用
+func RegisterQueueDepth(queue string, depth func() int) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "queue_depth",
+		Help:        "后台队列中等待处理的条目数。",
+		ConstLabels: prometheus.Labels{"queue": queue},
+	}, func() float64 { return float64(depth()) }))
+}
+
+// Handler 返回输出 Prometheus 文本格式的 HTTP 处理器
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
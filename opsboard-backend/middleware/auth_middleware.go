@@ -1,16 +1,16 @@
 /**
  * @file auth_middleware.go
- * @description 提供 JWT 认证中间件。
+ * @description 提供认证中间件，接受登录获得的 JWT 访问令牌和个人 API 令牌。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [最终修复]：确保了在任何认证失败的情况下（无论是缺少头、格式错误还是令牌无效），中间件都一致地返回 `401 Unauthorized`。
- *   - [原因]：此修改解决了当请求未携带认证头时，路由可能返回 `404` 而不是 `401` 的问题，为前端提供了更可靠、更一致的错误信号。
+ *   - [长连接]：在 gin context 中保存认证凭证的过期时间和访问令牌的会话版本，供 SSE 推送连接定期重新校验。
  */
 
 package middleware
 
 import (
 	"errors"
-	"net/http"
+	"opsboard-backend/models"
+	"opsboard-backend/services"
 	"opsboard-backend/utils"
 	"strings"
 
@@ -18,39 +18,125 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// apiTokenKey 是 AuthMiddleware 通过 API 令牌认证时在 gin context 中保存 *models.APIToken 的键
+const apiTokenKey = "api_token"
+
+// authUserKey 是 AuthMiddleware 在 gin context 中保存通过认证的 *models.User 的键，该用户是本次请求时从数据库读取的
+const authUserKey = "auth_user"
+
+// AuthExpiresAtKey 是 AuthMiddleware 在 gin context 中保存认证凭证（访问令牌或 API 令牌）过期时间 time.Time 的键，
+// SSE 等长连接在凭证过期后需要主动断开
+const AuthExpiresAtKey = "auth_expires_at"
+
+// SessionVersionKey 是 AuthMiddleware 通过 JWT 认证时在 gin context 中保存令牌会话版本 uint 的键，
+// 长连接用它重新调用 `services.CheckUserSession` 确认会话没有被吊销
+const SessionVersionKey = "session_version"
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			// [核心修复] 即使没有头，也应该返回 401
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "请求未包含认证信息"})
+			utils.RespondError(c, utils.ErrUnauthorized("请求未包含认证信息"), "")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "认证信息格式错误"})
+			utils.RespondError(c, utils.ErrUnauthorized("认证信息格式错误"), "")
 			return
 		}
 
 		tokenString := parts[1]
-		claims, err := utils.ValidateToken(tokenString)
+		if utils.IsAPIToken(tokenString) {
+			token, err := services.AuthenticateAPIToken(c.Request.Context(), tokenString, c.ClientIP())
+			if err != nil {
+				utils.RespondError(c, err, "校验 API 令牌失败")
+				return
+			}
+			c.Set(apiTokenKey, token)
+			c.Set(AuthExpiresAtKey, token.ExpiresAt)
+			user, err := services.CheckUserActive(c.Request.Context(), token.UserID)
+			setAuthenticatedUser(c, user, err)
+			return
+		}
+
+		claims, err := utils.ValidateTokenType(tokenString, utils.TokenTypeAccess)
 		if err != nil {
 			if errors.Is(err, jwt.ErrTokenExpired) {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "访问令牌已过期"})
+				utils.RespondError(c, utils.NewAppError(utils.CodeTokenExpired, "访问令牌已过期"), "")
 			} else {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "无效的访问令牌"})
+				utils.RespondError(c, utils.ErrUnauthorized("无效的访问令牌"), "")
 			}
 			return
 		}
 
 		userIDStr, ok := claims["user_id"].(string)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "令牌中缺少或格式错误的用户信息"})
+			utils.RespondError(c, utils.ErrUnauthorized("令牌中缺少或格式错误的用户信息"), "")
+			return
+		}
+
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			c.Set(AuthExpiresAtKey, exp.Time)
+		}
+		c.Set(SessionVersionKey, utils.SessionVersion(claims))
+		user, err := services.CheckUserSession(c.Request.Context(), userIDStr, utils.SessionVersion(claims))
+		setAuthenticatedUser(c, user, err)
+	}
+}
+
+// setAuthenticatedUser 根据用户状态的检查结果 checkErr 拒绝请求，或者记录通过认证的用户及其访问范围并继续处理请求
+func setAuthenticatedUser(c *gin.Context, user *models.User, checkErr error) {
+	if err := checkErr; err != nil {
+		if errors.Is(err, services.ErrUserDisabled) {
+			err = utils.ErrUnauthorized("账户已被禁用")
+		}
+		utils.RespondError(c, err, "校验用户状态失败")
+		return
+	}
+	userID := user.UserID.String()
+	c.Set("user_id", userID)
+	c.Set(authUserKey, user)
+	ctx := utils.WithUserID(c.Request.Context(), userID)
+	c.Request = c.Request.WithContext(services.WithAccessScope(ctx, services.UserAccessScope(user)))
+	c.Next()
+}
+
+// apiTokenFromContext 返回本次请求使用的 API 令牌；通过 JWT 认证时返回 nil
+func apiTokenFromContext(c *gin.Context) *models.APIToken {
+	token, _ := c.Get(apiTokenKey)
+	apiToken, _ := token.(*models.APIToken)
+	return apiToken
+}
+
+// authenticatedUser 返回 AuthMiddleware 认证通过的用户，未经过 AuthMiddleware 时返回 nil
+func authenticatedUser(c *gin.Context) *models.User {
+	value, _ := c.Get(authUserKey)
+	user, _ := value.(*models.User)
+	return user
+}
+
+// RequireScope 返回一个中间件，要求通过 API 令牌认证的请求具有 scope 权限范围，否则返回 403。
+// 通过 JWT 认证的交互式会话不受权限范围限制。必须挂载在 AuthMiddleware 之后。
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := apiTokenFromContext(c); token != nil && !token.HasScope(scope) {
+			utils.RespondError(c, utils.ErrForbidden("API 令牌没有访问该资源的权限（需要 "+scope+"）"), "")
 			return
 		}
+		c.Next()
+	}
+}
 
-		c.Set("user_id", userIDStr)
+// RequireSession 返回一个中间件，拒绝通过 API 令牌认证的请求，只允许交互式登录的会话访问。
+// 用于创建令牌、修改两步验证等账户安全操作，避免泄露的令牌被用来扩大或延续自身的权限。
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiTokenFromContext(c) != nil {
+			utils.RespondError(c, utils.ErrForbidden("该操作不允许使用 API 令牌，请登录后操作"), "")
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,51 @@
+/**
+ * @file logging_middleware.go
+ * @description 提供结构化的 HTTP 访问日志中间件，取代 gin 默认的纯文本日志。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建 `RequestLogger` 中间件。每个请求结束后输出一条 JSON 日志，包含请求 ID、路由模板、状态码、耗时以及 `AuthMiddleware` 解析出的用户 ID。
+ */
+
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger 返回访问日志中间件，必须挂载在 `RequestID` 之后。
+// 状态码 >= 500 记为 ERROR，>= 400 记为 WARN，其余记为 INFO。
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+
+		// 请求 ID 和用户 ID 分别由 RequestID 和 AuthMiddleware 写入请求的 context，会被日志处理器自动附加。
+		// AuthMiddleware 会替换 c.Request，因此这里必须在 c.Next() 之后再读取 c.Request.Context()。
+		slog.LogAttrs(c.Request.Context(), level, "http request", attrs...)
+	}
+}
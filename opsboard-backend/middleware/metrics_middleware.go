@@ -0,0 +1,55 @@
+/**
+ * @file metrics_middleware.go
+ * @description 提供 Prometheus 指标相关的中间件：HTTP 请求指标采集和 `/metrics` 端点的令牌校验。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建 `Metrics` 中间件（按路由模板和状态码记录请求数与耗时）和 `MetricsToken` 中间件。
+ */
+
+package middleware
+
+import (
+	"crypto/subtle"
+	"opsboard-backend/metrics"
+	"opsboard-backend/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute 是未匹配任何路由的请求使用的路由标签，避免把任意路径作为标签值导致指标基数爆炸
+const unmatchedRoute = "unmatched"
+
+// Metrics 返回采集 HTTP 请求指标的中间件。
+// 路由标签使用路由模板（例如 `/api/servers/:id`），而不是实际路径。
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequestsTotal.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsToken 返回校验 `/metrics` 访问令牌的中间件。
+// 抓取方必须携带 `Authorization: Bearer <token>` 请求头；该令牌与用户的 JWT 无关。
+func MetricsToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			utils.RespondError(c, utils.ErrUnauthorized("无效的指标访问令牌"), "")
+			return
+		}
+		c.Next()
+	}
+}
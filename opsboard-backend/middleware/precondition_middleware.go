@@ -0,0 +1,50 @@
+/**
+ * @file precondition_middleware.go
+ * @description 提供处理 `If-Match` 条件请求头的中间件，用于实现乐观并发控制。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [错误处理]：428 和 412 响应改用统一的错误结构，错误码分别为 `PRECONDITION_REQUIRED` 和 `PRECONDITION_FAILED`。
+ */
+
+package middleware
+
+import (
+	"opsboard-backend/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IfMatchVersionKey 是期望版本号在 gin.Context 中的键名
+const IfMatchVersionKey = "if_match_version"
+
+// IfMatch 返回一个解析 `If-Match` 请求头的中间件。
+//   - 请求头缺失时：若 required 为 true 返回 428 Precondition Required，否则不做版本校验。
+//   - 请求头为 `*` 时：只要记录存在即可，不做版本校验。
+//   - 请求头无法解析为本服务签发的 ETag 时：返回 412 Precondition Failed（它不可能与任何版本匹配）。
+func IfMatch(required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := strings.TrimSpace(c.GetHeader("If-Match"))
+		if header == "" {
+			if required {
+				utils.RespondError(c, utils.NewAppError(utils.CodePreconditionRequired, "请求必须携带 If-Match 请求头"), "")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if header == "*" {
+			c.Next()
+			return
+		}
+
+		version, ok := utils.ParseETag(header)
+		if !ok {
+			utils.RespondError(c, utils.NewAppError(utils.CodePreconditionFailed, "If-Match 请求头格式无效"), "")
+			return
+		}
+
+		c.Set(IfMatchVersionKey, version)
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+/**
+ * @file request_id_middleware.go
+ * @description 提供请求 ID 中间件，为每个请求分配一个唯一标识，用于错误响应和日志关联。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [日志关联]：请求 ID 同时写入请求的 `context.Context`，使结构化日志和审计日志都能带上同一个请求 ID。
+ */
+
+package middleware
+
+import (
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 是请求 ID 使用的 HTTP 头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 限制客户端传入的请求 ID 长度，防止日志被超长内容污染
+const maxRequestIDLength = 128
+
+// RequestID 返回请求 ID 中间件，应作为第一个全局中间件挂载。
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(utils.RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(utils.WithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// isValidRequestID 只接受由可打印 ASCII 字符（不含空格）组成、长度适中的请求 ID
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+/**
+ * @file role_middleware.go
+ * @description 提供基于用户角色的访问控制中间件。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [性能]：角色取自 `AuthMiddleware` 在本次请求中读取并保存的用户，不再为每个请求重复查询一次用户。
+ */
+
+package middleware
+
+import (
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole 返回一个中间件，仅允许角色在 roles 中的用户继续访问，否则返回 403。必须挂载在 AuthMiddleware 之后。
+// 用户角色以 AuthMiddleware 在本次请求中从数据库读取的当前值为准（而不是令牌签发时的值），因此角色变更会立即生效。
+func RequireRole(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		allowed[r] = true
+	}
+
+	return func(c *gin.Context) {
+		user := authenticatedUser(c)
+		if user == nil {
+			utils.RespondError(c, utils.ErrUnauthorized("无效的认证凭证"), "")
+			return
+		}
+
+		if !allowed[user.Role] {
+			utils.RespondError(c, utils.ErrForbidden("权限不足"), "")
+			return
+		}
+
+		c.Set("user_role", user.Role)
+		c.Next()
+	}
+}
@@ -0,0 +1,67 @@
+/**
+ * @file tracing_middleware.go
+ * @description 提供 OpenTelemetry 链路追踪中间件，为每个 HTTP 请求创建一个服务端 span。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建 `Tracing` 中间件。它从请求头中提取 W3C `traceparent`/`tracestate`，以路由模板命名 span，
+ *     并把携带 span 的 context 写回请求，使服务层和 GORM 的 span 成为其子 span。
+ */
+
+package middleware
+
+import (
+	"fmt"
+	"opsboard-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing 返回链路追踪中间件，应挂载在 `RequestID` 之后、`RequestLogger` 之前，使访问日志也能带上 trace ID。
+func Tracing() gin.HandlerFunc {
+	tracer := otel.Tracer("opsboard-backend/http")
+
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		spanName := c.Request.Method
+		if route != "" {
+			spanName += " " + route
+		}
+
+		ctx, span := tracer.Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodOriginal(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				semconv.UserAgentOriginal(c.Request.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		if requestID := c.GetString(utils.RequestIDKey); requestID != "" {
+			span.SetAttributes(utils.RequestIDAttribute(requestID))
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if userID := c.GetString("user_id"); userID != "" {
+			span.SetAttributes(semconv.EnduserID(userID))
+		}
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+			if len(c.Errors) > 0 {
+				span.RecordError(c.Errors.Last())
+			}
+		}
+	}
+}
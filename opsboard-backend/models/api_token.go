@@ -0,0 +1,71 @@
+/**
+ * @file models/api_token.go
+ * @description 定义了个人 API 令牌的数据模型，与 `api_tokens` 表对应，以及令牌可授予的权限范围。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件以支持供 CI 和运维脚本使用的个人 API 令牌。
+ */
+
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// API 令牌的权限范围，每个范围对应一个受保护的路由分组
+const (
+	ScopeUsers       = "users"
+	ScopeServers     = "servers"
+	ScopeChangelogs  = "changelogs"
+	ScopeMaintenance = "maintenance"
+	ScopeTickets     = "tickets"
+	ScopeCustomers   = "customers"
+	ScopeTrash       = "trash"
+	ScopeAuditLogs   = "audit-logs"
+)
+
+// Scopes 列出了所有合法的权限范围
+var Scopes = []string{
+	ScopeUsers, ScopeServers, ScopeChangelogs, ScopeMaintenance,
+	ScopeTickets, ScopeCustomers, ScopeTrash, ScopeAuditLogs,
+}
+
+// IsValidScope 判断 scope 是否为合法的权限范围
+func IsValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken 是用户创建的个人 API 令牌。只保存令牌明文的 SHA-256 哈希，明文只在创建时返回一次。
+type APIToken struct {
+	TokenID    string         `gorm:"primaryKey;column:token_id" json:"id"`
+	UserID     string         `gorm:"column:user_id" json:"-"`
+	Name       string         `gorm:"column:name" json:"name"`
+	Prefix     string         `gorm:"column:token_prefix" json:"prefix"`
+	TokenHash  string         `gorm:"column:token_hash" json:"-"`
+	Scopes     []string       `gorm:"column:scopes;serializer:json" json:"scopes"`
+	ExpiresAt  time.Time      `gorm:"column:expires_at" json:"expiresAt"`
+	LastUsedAt sql.NullTime   `gorm:"column:last_used_at" json:"lastUsedAt"`
+	LastUsedIP sql.NullString `gorm:"column:last_used_ip" json:"lastUsedIp"`
+	RevokedAt  sql.NullTime   `gorm:"column:revoked_at" json:"-"`
+	CreatedAt  time.Time      `gorm:"column:created_at" json:"createdAt"`
+}
+
+// TableName 明确指定 APIToken 模型对应的数据库表名。
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// HasScope 判断令牌是否被授予了 scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
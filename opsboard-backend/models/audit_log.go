@@ -0,0 +1,30 @@
+/**
+ * @file models/audit_log.go
+ * @description 定义了 AuditLog 数据模型，与数据库的 `audit_logs` 表对应。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件以支持审计日志的分页查询和导出。
+ */
+
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// AuditLog 结构体定义了一条系统操作日志。
+type AuditLog struct {
+	LogID        uint64         `gorm:"primaryKey;column:log_id" json:"id"`
+	UserID       string         `gorm:"column:user_id" json:"userId"`
+	Action       string         `gorm:"column:action" json:"action"`
+	TargetEntity sql.NullString `gorm:"column:target_entity" json:"targetEntity"`
+	TargetID     sql.NullString `gorm:"column:target_id" json:"targetId"`
+	Details      sql.NullString `gorm:"column:details" json:"details"` // JSON 字符串
+	CreatedAt    time.Time      `gorm:"column:created_at" json:"createdAt"`
+	Username     string         `gorm:"->;column:username;-:migration" json:"username"` // 只读字段，由 JOIN 查询填充
+}
+
+// TableName 明确指定 AuditLog 模型对应的数据库表名。
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
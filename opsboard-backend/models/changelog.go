@@ -2,7 +2,7 @@
  * @file models/changelog.go
  * @description 定义了 Changelog 数据模型，用于与数据库和 JSON 响应进行交互。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [功能增强]：为 `Changelog` 结构体增加了 `Status` 和 `CompletionTime` 字段，以匹配新的数据库表结构，支持状态管理功能。
+ *   - [乐观锁]：新增 `Version` 字段，每次修改递增，用于生成 ETag 并校验 `If-Match` 请求头。
  */
 
 package models
@@ -10,18 +10,22 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Changelog 结构体定义了更新日志的核心属性。
 type Changelog struct {
-	LogID          uint         `db:"log_id" json:"id"`
-	CustomerID     uint         `db:"customer_id" json:"customerId"`
-	UserID         string       `db:"user_id" json:"userId"`
-	UpdateTime     time.Time    `db:"update_time" json:"updateTime"`
-	UpdateType     string       `db:"update_type" json:"updateType"`
-	UpdateContent  string       `db:"update_content" json:"updateContent"`
-	Status         string       `db:"status" json:"status"`
-	CompletionTime sql.NullTime `db:"completion_time" json:"completionTime"`
-	CreatedAt      time.Time    `db:"created_at" json:"createdAt"`
-	CustomerName   string       `db:"customer_name" json:"customerName"`
+	LogID          uint           `gorm:"primaryKey;column:log_id" db:"log_id" json:"id"`
+	CustomerID     uint           `db:"customer_id" json:"customerId"`
+	UserID         string         `db:"user_id" json:"userId"`
+	UpdateTime     time.Time      `db:"update_time" json:"updateTime"`
+	UpdateType     string         `db:"update_type" json:"updateType"`
+	UpdateContent  string         `db:"update_content" json:"updateContent"`
+	Status         string         `db:"status" json:"status"`
+	CompletionTime sql.NullTime   `db:"completion_time" json:"completionTime"`
+	CreatedAt      time.Time      `db:"created_at" json:"createdAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"column:deleted_at;index" db:"deleted_at" json:"-"`
+	Version        uint           `gorm:"column:version;default:1" db:"version" json:"version"`
+	CustomerName   string         `gorm:"->;column:customer_name;-:migration" db:"customer_name" json:"customerName"`
 }
@@ -0,0 +1,32 @@
+/**
+ * @file models/customer.go
+ * @description 定义了 Customer 数据模型，与数据库的 `customers` 表对应。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [乐观锁]：新增 `Version` 字段，每次修改递增，用于生成 ETag 并校验 `If-Match` 请求头。
+ */
+
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Customer 结构体定义了客户的核心属性。
+type Customer struct {
+	CustomerID    uint           `gorm:"primaryKey;column:customer_id" json:"id"`
+	RegionID      sql.NullInt64  `gorm:"column:region_id" json:"regionId"`
+	CustomerName  string         `gorm:"column:customer_name" json:"customerName"`
+	ContactPerson sql.NullString `gorm:"column:contact_person" json:"contactPerson"`
+	ContactPhone  sql.NullString `gorm:"column:contact_phone" json:"contactPhone"`
+	CreatedAt     time.Time      `gorm:"column:created_at" json:"createdAt"`
+	DeletedAt     gorm.DeletedAt `gorm:"column:deleted_at;index" json:"-"`
+	Version       uint           `gorm:"column:version;default:1" json:"version"`
+}
+
+// TableName 明确指定 Customer 模型对应的数据库表名。
+func (Customer) TableName() string {
+	return "customers"
+}
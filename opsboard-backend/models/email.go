@@ -0,0 +1,45 @@
+/**
+ * @file models/email.go
+ * @description 定义了邮件发件箱（`email_outbox` 表）的数据模型。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。每封通知邮件在渲染后写入发件箱，由后台任务发送并按指数退避重试，
+ *     发件箱既是待发送队列，也是发送日志。
+ */
+
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 邮件的发送状态
+const (
+	EmailPending = "pending" // 等待发送或等待重试
+	EmailSent    = "sent"    // SMTP 服务器已接收
+	EmailFailed  = "failed"  // 达到最大尝试次数仍未成功
+)
+
+// EmailMessage 是发件箱中的一封邮件。DedupeKey 保证同一事件或同一天的摘要对同一收件人只生成一封邮件。
+type EmailMessage struct {
+	EmailID       uint64         `gorm:"primaryKey;column:email_id" json:"id"`
+	DedupeKey     string         `gorm:"column:dedupe_key" json:"-"`
+	UserID        sql.NullString `gorm:"column:user_id" json:"userId"`
+	ToAddress     string         `gorm:"column:to_address" json:"to"`
+	Template      string         `gorm:"column:template" json:"template"`
+	Subject       string         `gorm:"column:subject" json:"subject"`
+	TextBody      string         `gorm:"column:text_body" json:"-"`
+	HTMLBody      string         `gorm:"column:html_body" json:"-"`
+	Status        string         `gorm:"column:status" json:"status"`
+	Attempts      uint           `gorm:"column:attempts" json:"attempts"`
+	NextAttemptAt time.Time      `gorm:"column:next_attempt_at" json:"nextAttemptAt"`
+	LastAttemptAt sql.NullTime   `gorm:"column:last_attempt_at" json:"lastAttemptAt"`
+	LastError     sql.NullString `gorm:"column:last_error" json:"lastError"`
+	SentAt        sql.NullTime   `gorm:"column:sent_at" json:"sentAt"`
+	CreatedAt     time.Time      `gorm:"column:created_at" json:"createdAt"`
+}
+
+// TableName 明确指定 EmailMessage 模型对应的数据库表名。
+func (EmailMessage) TableName() string {
+	return "email_outbox"
+}
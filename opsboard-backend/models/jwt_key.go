@@ -0,0 +1,26 @@
+/**
+ * @file models/jwt_key.go
+ * @description 定义了 JWT 签名密钥的数据模型，与 `jwt_signing_keys` 表对应。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件以支持非对称签名密钥的持久化和轮换，多实例部署时各实例共享同一组密钥。
+ */
+
+package models
+
+import "time"
+
+// JWTSigningKey 是一把持久化的 JWT 签名密钥。
+// 同一密钥组中 ActivatesAt 最晚且已到达的密钥用于签名；ActivatesAt 尚未到达的密钥已发布、可用于校验，但还不用于签名。
+type JWTSigningKey struct {
+	KeyID       string    `gorm:"primaryKey;column:key_id" json:"kid"`
+	KeySet      string    `gorm:"column:key_set" json:"keySet"`
+	Algorithm   string    `gorm:"column:algorithm" json:"algorithm"`
+	PrivateKey  string    `gorm:"column:private_key" json:"-"`
+	ActivatesAt time.Time `gorm:"column:activates_at" json:"activatesAt"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"createdAt"`
+}
+
+// TableName 明确指定 JWTSigningKey 模型对应的数据库表名。
+func (JWTSigningKey) TableName() string {
+	return "jwt_signing_keys"
+}
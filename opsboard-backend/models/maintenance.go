@@ -2,7 +2,7 @@
  * @file models/maintenance.go
  * @description 定义了 MaintenanceTask 数据模型，用于与数据库和 JSON 响应进行交互。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [功能增强]：将 `ExecutionTime` 重命名为 `CompletionTime`，并新增 `PublicationTime` 字段，以匹配新的数据库表结构。
+ *   - [乐观锁]：新增 `Version` 字段，每次修改递增，用于生成 ETag 并校验 `If-Match` 请求头。
  */
 
 package models
@@ -10,11 +10,13 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // MaintenanceTask 结构体定义了维护任务的核心属性。
 type MaintenanceTask struct {
-	TaskID           uint           `db:"task_id" json:"id"`
+	TaskID           uint           `gorm:"primaryKey;column:task_id" db:"task_id" json:"id"`
 	TaskName         string         `db:"task_name" json:"taskName"`
 	TaskType         string         `db:"task_type" json:"type"`
 	TargetServerID   sql.NullInt64  `db:"target_server_id" json:"targetServerId"`
@@ -23,7 +25,9 @@ type MaintenanceTask struct {
 	CompletionTime   sql.NullTime   `db:"completion_time" json:"completionTime"`
 	LogOutput        sql.NullString `db:"log_output" json:"logOutput"`
 	CreatedAt        time.Time      `db:"created_at" json:"createdAt"`
-	TargetServerName sql.NullString `db:"target_server_name" json:"target"`
+	DeletedAt        gorm.DeletedAt `gorm:"column:deleted_at;index" db:"deleted_at" json:"-"`
+	Version          uint           `gorm:"column:version;default:1" db:"version" json:"version"`
+	TargetServerName sql.NullString `gorm:"->;column:target_server_name;-:migration" db:"target_server_name" json:"target"`
 }
 
 // TableName 方法覆盖 GORM 的默认表名猜测。
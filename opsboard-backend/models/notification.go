@@ -0,0 +1,46 @@
+/**
+ * @file models/notification.go
+ * @description 定义了站内通知（`notifications` 表）的数据模型。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。站内通知由领域事件生成，每条通知属于一个用户，ReadAt 为空表示未读。
+ */
+
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 站内通知的类型
+const (
+	NotificationTicketAssigned  = "ticket.assigned"     // 工单分配给自己
+	NotificationTicketMentioned = "ticket.mentioned"    // 在工单评论中被 @ 提到
+	NotificationSLABreached     = "ticket.sla_breached" // 自己处理的工单 SLA 超时（管理员接收所有工单的超时通知）
+	NotificationTaskFailed      = "task.failed"         // 可访问客户的维护任务失败
+)
+
+// 站内通知关联的资源类型
+const (
+	NotificationResourceTicket = "ticket"
+	NotificationResourceTask   = "task"
+)
+
+// Notification 是一条站内通知。ResourceType 和 ResourceID 指向通知相关的工单或维护任务，前端据此生成跳转链接。
+type Notification struct {
+	NotificationID uint64       `gorm:"primaryKey;column:notification_id" json:"id"`
+	UserID         string       `gorm:"column:user_id" json:"-"`
+	Type           string       `gorm:"column:type" json:"type"`
+	Title          string       `gorm:"column:title" json:"title"`
+	Body           string       `gorm:"column:body" json:"body"`
+	ResourceType   string       `gorm:"column:resource_type" json:"resourceType"`
+	ResourceID     string       `gorm:"column:resource_id" json:"resourceId"`
+	EventID        string       `gorm:"column:event_id" json:"eventId"`
+	ReadAt         sql.NullTime `gorm:"column:read_at" json:"readAt"`
+	CreatedAt      time.Time    `gorm:"column:created_at" json:"createdAt"`
+}
+
+// TableName 明确指定 Notification 模型对应的数据库表名。
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,90 @@
+/**
+ * @file models/notification_channel.go
+ * @description 定义了群机器人通知相关的数据模型：通知渠道（`notification_channels` 表）、路由规则（`notification_rules` 表）
+ *   和消息模板（`notification_templates` 表）。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。渠道对应钉钉、企业微信或飞书群中的一个机器人，路由规则决定哪些事件发送到哪个渠道，
+ *     模板决定每种事件的消息内容，没有保存模板的事件使用内置的默认模板。
+ *   - [邮件通知]：新增邮件模板种类，模板新增纯文本正文 `Text`。
+ */
+
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 通知渠道的类型
+const (
+	ChannelTypeDingTalk = "dingtalk" // 钉钉群机器人
+	ChannelTypeWeCom    = "wecom"    // 企业微信群机器人
+	ChannelTypeFeishu   = "feishu"   // 飞书群机器人
+)
+
+// ChannelTypes 列出了所有支持的通知渠道类型
+var ChannelTypes = []string{ChannelTypeDingTalk, ChannelTypeWeCom, ChannelTypeFeishu}
+
+// 消息模板的种类
+const (
+	TemplateKindChat  = "chat"  // 群机器人的 Markdown 消息
+	TemplateKindEmail = "email" // 邮件，Title 为主题，Body 为 HTML 正文，Text 为纯文本正文
+)
+
+// NotificationChannel 是一个群机器人。Secret 为机器人的签名密钥（企业微信机器人没有签名密钥），不会出现在响应中。
+type NotificationChannel struct {
+	ChannelID  uint         `gorm:"primaryKey;column:channel_id" json:"id"`
+	Name       string       `gorm:"column:name" json:"name"`
+	Type       string       `gorm:"column:type" json:"type"`
+	WebhookURL string       `gorm:"column:webhook_url" json:"webhookUrl"`
+	Secret     string       `gorm:"column:secret" json:"-"`
+	HasSecret  bool         `gorm:"->;column:has_secret;-:migration" json:"hasSecret"` // 查询时计算，只读
+	Enabled    bool         `gorm:"column:enabled" json:"enabled"`
+	CreatedAt  time.Time    `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt  sql.NullTime `gorm:"column:updated_at" json:"updatedAt"`
+	Version    uint         `gorm:"column:version;default:1" json:"version"`
+}
+
+// TableName 明确指定 NotificationChannel 模型对应的数据库表名。
+func (NotificationChannel) TableName() string {
+	return "notification_channels"
+}
+
+// NotificationRule 是一条路由规则：Events 中的事件发送到 ChannelID 对应的渠道。
+// CustomerID 不为空时只发送与该客户相关的事件（例如该客户的工单）。
+type NotificationRule struct {
+	RuleID       uint           `gorm:"primaryKey;column:rule_id" json:"id"`
+	Name         string         `gorm:"column:name" json:"name"`
+	ChannelID    uint           `gorm:"column:channel_id" json:"channelId"`
+	ChannelName  string         `gorm:"->;column:channel_name;-:migration" json:"channelName"` // 查询时关联 notification_channels 表得到，只读
+	Events       []string       `gorm:"column:events;serializer:json" json:"events"`
+	CustomerID   sql.NullInt64  `gorm:"column:customer_id" json:"customerId"`
+	CustomerName sql.NullString `gorm:"->;column:customer_name;-:migration" json:"customerName"` // 查询时关联 customers 表得到，只读
+	Enabled      bool           `gorm:"column:enabled" json:"enabled"`
+	CreatedAt    time.Time      `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt    sql.NullTime   `gorm:"column:updated_at" json:"updatedAt"`
+	Version      uint           `gorm:"column:version;default:1" json:"version"`
+}
+
+// TableName 明确指定 NotificationRule 模型对应的数据库表名。
+func (NotificationRule) TableName() string {
+	return "notification_rules"
+}
+
+// NotificationTemplate 是管理员修改过的一种事件的消息模板，Title 和 Body 为 Go text/template 模板。
+// 与设置文档一样，Version 为 0 表示管理员没有保存过，使用内置的默认模板。
+type NotificationTemplate struct {
+	Kind      string         `gorm:"primaryKey;column:kind" json:"kind"`
+	EventType string         `gorm:"primaryKey;column:event_type" json:"eventType"`
+	Title     string         `gorm:"column:title" json:"title"`
+	Body      string         `gorm:"column:body" json:"body"`
+	Text      string         `gorm:"column:text_body" json:"text"`
+	UpdatedBy sql.NullString `gorm:"column:updated_by" json:"updatedBy"`
+	UpdatedAt time.Time      `gorm:"column:updated_at" json:"updatedAt"`
+	Version   uint           `gorm:"column:version" json:"version"`
+}
+
+// TableName 明确指定 NotificationTemplate 模型对应的数据库表名。
+func (NotificationTemplate) TableName() string {
+	return "notification_templates"
+}
@@ -0,0 +1,23 @@
+/**
+ * @file models/password_history.go
+ * @description 定义了用户密码历史的数据模型，与 `user_password_history` 表对应。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。每次修改或重置密码时记录被替换的密码哈希，用于禁止重复使用最近的密码。
+ */
+
+package models
+
+import "time"
+
+// PasswordHistory 是用户曾经使用过的一个密码的哈希
+type PasswordHistory struct {
+	HistoryID    uint64    `gorm:"primaryKey;column:history_id"`
+	UserID       string    `gorm:"column:user_id"`
+	PasswordHash string    `gorm:"column:password_hash"`
+	CreatedAt    time.Time `gorm:"column:created_at"`
+}
+
+// TableName 明确指定 PasswordHistory 模型对应的数据库表名。
+func (PasswordHistory) TableName() string {
+	return "user_password_history"
+}
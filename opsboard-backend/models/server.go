@@ -1,13 +1,15 @@
 // @file models/server.go
 // @description 定义了 Server 数据模型以及用于特定 API 响应的数据传输对象 (DTO)。
 // @modification 本次提交中所做的具体修改摘要。
-//   - [模型新增]：新增了 `ServerDetailResponse` 结构体。此结构体的 JSON 标签与前端详情页组件期望的字段名（如 `ipAddress`, `deploymentType`）完全匹配，确保了 API 合约的准确性，同时避免了修改现有 `Server` 模型可能带来的副作用。
+//   - [乐观锁]：为 `Server` 和 `ServerDetailResponse` 新增 `Version` 字段，每次修改递增，用于生成 ETag 并校验 `If-Match` 请求头。
 
 package models
 
 import (
 	"database/sql"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Server 结构体定义了服务器的核心属性，与数据库的 `servers` 表一一对应。
@@ -23,7 +25,9 @@ type Server struct {
 	UsageNote      sql.NullString `gorm:"column:usage_note" json:"note"`
 	CreatedAt      time.Time      `gorm:"column:created_at" json:"createdAt"`
 	UpdatedAt      sql.NullTime   `gorm:"column:updated_at" json:"updatedAt"`
-	CustomerName   string         `gorm:"-" json:"customerName"` // GORM 忽略此字段，由 JOIN 手动填充
+	DeletedAt      gorm.DeletedAt `gorm:"column:deleted_at;index" json:"-"`
+	Version        uint           `gorm:"column:version;default:1" json:"version"`
+	CustomerName   string         `gorm:"->;column:customer_name;-:migration" json:"customerName"` // 只读字段，由 JOIN 查询填充
 }
 
 // TableName 明确指定 Server 模型对应的数据库表名。
@@ -45,4 +49,5 @@ type ServerDetailResponse struct {
 	UsageNote      sql.NullString `json:"usageNote"`
 	CreatedAt      time.Time      `json:"createdAt"`
 	UpdatedAt      sql.NullTime   `json:"updatedAt"`
+	Version        uint           `json:"version"`
 }
@@ -0,0 +1,41 @@
+/**
+ * @file models/setting.go
+ * @description 定义了设置相关的数据模型，分别与 `user_preferences` 和 `system_settings` 表对应。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。设置内容以 JSON 文档整体保存在 `data` 列中，文档的结构和校验规则由 `services` 定义；
+ *     `Version` 从 0 开始，每次保存递增，用于生成 ETag 并校验 `If-Match` 请求头。
+ */
+
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UserPreference 保存一个用户的个人偏好设置
+type UserPreference struct {
+	UserID    string    `gorm:"primaryKey;column:user_id"`
+	Data      string    `gorm:"column:data"`
+	Version   uint      `gorm:"column:version"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+// TableName 明确指定 UserPreference 模型对应的数据库表名。
+func (UserPreference) TableName() string {
+	return "user_preferences"
+}
+
+// SystemSetting 保存一组由管理员维护的全局设置
+type SystemSetting struct {
+	SettingKey string         `gorm:"primaryKey;column:setting_key"`
+	Data       string         `gorm:"column:data"`
+	Version    uint           `gorm:"column:version"`
+	UpdatedBy  sql.NullString `gorm:"column:updated_by"` // 最后修改人的用户 ID
+	UpdatedAt  time.Time      `gorm:"column:updated_at"`
+}
+
+// TableName 明确指定 SystemSetting 模型对应的数据库表名。
+func (SystemSetting) TableName() string {
+	return "system_settings"
+}
@@ -0,0 +1,59 @@
+/**
+ * @file models/sla.go
+ * @description 定义了 SLA 相关的数据模型：工作日历（`business_calendars` 表）和 SLA 策略（`sla_policies` 表）。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。SLA 策略按客户和工单优先级定义首次响应和解决时限，可选地只按工作日历中的工作时间计算。
+ */
+
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// WorkPeriod 是工作日历中某个星期几的一个工作时段，时间为日历时区的 `HH:MM`，End 可以为 `24:00`
+type WorkPeriod struct {
+	Weekday int    `json:"weekday"` // 0 为周日，1 为周一，依此类推
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+// BusinessCalendar 结构体定义了计算 SLA 截止时间使用的工作日历。
+type BusinessCalendar struct {
+	CalendarID uint         `gorm:"primaryKey;column:calendar_id" json:"id"`
+	Name       string       `gorm:"column:name" json:"name"`
+	Timezone   string       `gorm:"column:timezone" json:"timezone"`
+	WorkHours  []WorkPeriod `gorm:"column:work_hours;serializer:json" json:"workHours"`
+	Holidays   []string     `gorm:"column:holidays;serializer:json" json:"holidays"` // 节假日（YYYY-MM-DD），当天不计入工作时间
+	CreatedAt  time.Time    `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt  sql.NullTime `gorm:"column:updated_at" json:"updatedAt"`
+	Version    uint         `gorm:"column:version;default:1" json:"version"`
+}
+
+// TableName 明确指定 BusinessCalendar 模型对应的数据库表名。
+func (BusinessCalendar) TableName() string {
+	return "business_calendars"
+}
+
+// SLAPolicy 结构体定义了一条 SLA 策略。
+// CustomerID 为空的策略适用于所有客户，同一优先级下客户专属的策略优先。
+type SLAPolicy struct {
+	PolicyID         uint           `gorm:"primaryKey;column:policy_id" json:"id"`
+	Name             string         `gorm:"column:name" json:"name"`
+	CustomerID       sql.NullInt64  `gorm:"column:customer_id" json:"customerId"`
+	CustomerName     sql.NullString `gorm:"->;column:customer_name" json:"customerName"` // 查询时关联 customers 表得到，只读
+	Priority         string         `gorm:"column:priority" json:"priority"`
+	ResponseMinutes  uint           `gorm:"column:response_minutes" json:"responseMinutes"`
+	ResolveMinutes   uint           `gorm:"column:resolve_minutes" json:"resolveMinutes"`
+	CalendarID       sql.NullInt64  `gorm:"column:calendar_id" json:"calendarId"` // 为空表示按自然时间（7x24）计算
+	EscalateToUserID sql.NullString `gorm:"column:escalate_to_user_id" json:"escalateToUserId"`
+	CreatedAt        time.Time      `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt        sql.NullTime   `gorm:"column:updated_at" json:"updatedAt"`
+	Version          uint           `gorm:"column:version;default:1" json:"version"`
+}
+
+// TableName 明确指定 SLAPolicy 模型对应的数据库表名。
+func (SLAPolicy) TableName() string {
+	return "sla_policies"
+}
@@ -2,7 +2,8 @@
  * @file models/ticket.go
  * @description 定义了 Ticket 数据模型，该模型对应于数据库中的 `v_tickets` 视图。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [功能增强]：为 `Ticket` 结构体增加了 `PublicationTime` 和 `CompletionTime` 字段，以匹配更新后的 `v_tickets` 视图，支持更完整的时间线展示。
+ *   - [SLA]：新增工单优先级常量；`Ticket` 和 `TicketRecord` 新增优先级、SLA 截止时间、首次响应和解决时间，
+ *     `Ticket` 新增由视图计算的响应和解决 SLA 状态。
  */
 
 package models
@@ -10,11 +11,40 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// 客户门户流程使用的工单状态，与系统设置中的默认工单状态一致
+const (
+	TicketStatusPending   = "待处理" // 客户新提交的工单
+	TicketStatusResolving = "待确认" // 处理完成，等待客户确认
+	TicketStatusCompleted = "完成"  // 客户已确认解决
+)
+
+// 工单优先级常量，与 `tickets.priority` 列的取值对应
+const (
+	TicketPriorityP1 = "P1" // 紧急
+	TicketPriorityP2 = "P2" // 高
+	TicketPriorityP3 = "P3" // 中（默认）
+	TicketPriorityP4 = "P4" // 低
+)
+
+// TicketPriorities 列出了所有合法的工单优先级
+var TicketPriorities = []string{TicketPriorityP1, TicketPriorityP2, TicketPriorityP3, TicketPriorityP4}
+
+// SLA 状态常量，与 v_tickets 视图中 `response_sla_status` 和 `resolve_sla_status` 列的取值对应
+const (
+	SLAStatusNone     = "none"     // 没有适用的 SLA 策略（或尚未计算截止时间）
+	SLAStatusOnTrack  = "on_track" // 尚未到截止时间
+	SLAStatusMet      = "met"      // 在截止时间之前完成
+	SLAStatusBreached = "breached" // 已超过截止时间
 )
 
 // Ticket 结构体定义了从 v_tickets 视图中查询出的统一工单格式。
 type Ticket struct {
 	ID               string `db:"id" json:"id"`
+	CustomerID       uint   `db:"customer_id" json:"customerId"`
 	CustomerName     string `db:"customer_name" json:"customerName"`
 	Status           string `db:"status" json:"status"`
 	OperationType    string `db:"operation_type" json:"operationType"`
@@ -22,4 +52,47 @@ type Ticket struct {
 	// [核心修改] 字段重命名和新增
 	PublicationTime time.Time    `db:"publication_time" json:"publicationTime"`
 	CompletionTime  sql.NullTime `db:"completion_time" json:"completionTime"`
+	Priority        string       `db:"priority" json:"priority"`
+	ResponseDueAt   sql.NullTime `db:"response_due_at" json:"responseDueAt"`
+	ResolveDueAt    sql.NullTime `db:"resolve_due_at" json:"resolveDueAt"`
+	FirstResponseAt sql.NullTime `db:"first_response_at" json:"firstResponseAt"`
+	ResolvedAt      sql.NullTime `db:"resolved_at" json:"resolvedAt"`
+	// SLA 状态由视图按当前时间计算，取值见 SLAStatus 常量
+	ResponseSLAStatus string `db:"response_sla_status" json:"responseSlaStatus"`
+	ResolveSLAStatus  string `db:"resolve_sla_status" json:"resolveSlaStatus"`
+	Version           uint   `db:"version" json:"version"`
+}
+
+// TicketRecord 结构体与数据库的 `tickets` 表一一对应，用于对工单进行写操作。
+// 列表查询仍然使用 `Ticket`（v_tickets 视图），视图本身已排除软删除的工单。
+type TicketRecord struct {
+	TicketID           uint           `gorm:"primaryKey;column:ticket_id" json:"id"`
+	CustomerID         uint           `gorm:"column:customer_id" json:"customerId"`
+	SubmitterID        string         `gorm:"column:submitter_id" json:"submitterId"`
+	AssigneeID         sql.NullString `gorm:"column:assignee_id" json:"assigneeId"`
+	Status             string         `gorm:"column:status" json:"status"`
+	OperationType      sql.NullString `gorm:"column:operation_type" json:"operationType"`
+	OperationContent   string         `gorm:"column:operation_content" json:"operationContent"`
+	CreatedAt          time.Time      `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt          sql.NullTime   `gorm:"column:updated_at" json:"updatedAt"`
+	CompletionTime     sql.NullTime   `gorm:"column:completion_time" json:"completionTime"`
+	Priority           string         `gorm:"column:priority;default:P3" json:"priority"`
+	SLAPolicyID        sql.NullInt64  `gorm:"column:sla_policy_id" json:"slaPolicyId"`
+	SLAEvaluatedAt     sql.NullTime   `gorm:"column:sla_evaluated_at" json:"slaEvaluatedAt"`
+	ResponseDueAt      sql.NullTime   `gorm:"column:response_due_at" json:"responseDueAt"`
+	ResolveDueAt       sql.NullTime   `gorm:"column:resolve_due_at" json:"resolveDueAt"`
+	FirstResponseAt    sql.NullTime   `gorm:"column:first_response_at" json:"firstResponseAt"`
+	ResolvedAt         sql.NullTime   `gorm:"column:resolved_at" json:"resolvedAt"`
+	ResponseBreachedAt sql.NullTime   `gorm:"column:response_breached_at" json:"responseBreachedAt"`
+	ResolveBreachedAt  sql.NullTime   `gorm:"column:resolve_breached_at" json:"resolveBreachedAt"`
+	Rating             sql.NullInt16  `gorm:"column:rating" json:"rating"`
+	RatingComment      sql.NullString `gorm:"column:rating_comment" json:"ratingComment"`
+	RatedAt            sql.NullTime   `gorm:"column:rated_at" json:"ratedAt"`
+	DeletedAt          gorm.DeletedAt `gorm:"column:deleted_at;index" json:"-"`
+	Version            uint           `gorm:"column:version;default:1" json:"version"`
+}
+
+// TableName 明确指定 TicketRecord 模型对应的数据库表名。
+func (TicketRecord) TableName() string {
+	return "tickets"
 }
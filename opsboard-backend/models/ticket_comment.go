@@ -0,0 +1,26 @@
+/**
+ * @file models/ticket_comment.go
+ * @description 定义了 TicketComment 数据模型，与数据库的 `ticket_comments` 表对应。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。工单评论分为对客户公开的回复和内部备注，内部备注对客户门户用户不可见。
+ */
+
+package models
+
+import "time"
+
+// TicketComment 结构体定义了工单下的一条评论。
+type TicketComment struct {
+	CommentID  uint64    `gorm:"primaryKey;column:comment_id" json:"id"`
+	TicketID   uint      `gorm:"column:ticket_id" json:"ticketId"`
+	AuthorID   string    `gorm:"column:author_id" json:"authorId"`
+	AuthorName string    `gorm:"->;column:author_name" json:"authorName"` // 查询时关联 users 表得到的评论人昵称，只读
+	IsInternal bool      `gorm:"column:is_internal" json:"isInternal"`
+	Content    string    `gorm:"column:content" json:"content"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"createdAt"`
+}
+
+// TableName 明确指定 TicketComment 模型对应的数据库表名。
+func (TicketComment) TableName() string {
+	return "ticket_comments"
+}
@@ -2,24 +2,70 @@
  * @file user.go
  * @description 定义 User 模型，与数据库中的 USERS 表对应。
  * @modification
- *   - [UUID Migration]: 将 `UserID` 字段的类型从 `int64` 修改为 `uuid.UUID`，以匹配数据库 schema 的变更。
- *   - [UUID Migration]: 在 `json:"id"` 标签后添加了 `example:"..."`，以便在 API 文档中提供一个有效的 UUID 示例。
+ *   - [Email]: 新增 `Email` 字段，为空的用户不会收到邮件通知。
  */
 
 package models
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 用户角色常量，与 `users.role` 列的取值对应
+const (
+	RoleAdmin    = "ADMIN"
+	RoleUser     = "USER"
+	RoleCustomer = "CUSTOMER" // 客户门户用户，只能访问分配给自己的客户（所属单位）的工单
+)
+
+// Roles 列出了所有合法的角色
+var Roles = []string{RoleAdmin, RoleUser, RoleCustomer}
+
+// StaffRoles 列出了可以访问内部管理接口的角色
+var StaffRoles = []string{RoleAdmin, RoleUser}
+
+// IsValidRole 判断 role 是否为合法的角色
+func IsValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// 用户的认证源常量，与 `users.auth_source` 列的取值对应
+const (
+	AuthSourceLocal = "local" // 使用 users 表中的密码登录
+	AuthSourceLDAP  = "ldap"  // 由 LDAP / Active Directory 认证，首次登录时自动创建
+	AuthSourceOIDC  = "oidc"  // 通过 OpenID Connect 单点登录，首次登录时自动创建
 )
 
 type User struct {
-	UserID    uuid.UUID `json:"id" example:"a8b4b3e6-e3d2-4d1b-b8e1-7a2a3f4c5d6e"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"`
-	Nickname  string    `json:"nickname"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	UserID         uuid.UUID      `gorm:"primaryKey;column:user_id" json:"id" example:"a8b4b3e6-e3d2-4d1b-b8e1-7a2a3f4c5d6e"`
+	Username       string         `gorm:"column:username" json:"username"`
+	Password       string         `gorm:"column:password" json:"-"`
+	Nickname       string         `gorm:"column:nickname" json:"nickname"`
+	Email          sql.NullString `gorm:"column:email" json:"email"`
+	Role           string         `gorm:"column:role" json:"role"`
+	AuthSource     string         `gorm:"column:auth_source" json:"authSource"`
+	DisabledAt     sql.NullTime   `gorm:"column:disabled_at" json:"disabledAt"`
+	SessionVersion uint           `gorm:"column:session_version" json:"-"`
+	CreatedAt      time.Time      `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt      time.Time      `gorm:"column:updated_at" json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"column:deleted_at;index" json:"-"`
+}
+
+// TableName 明确指定 User 模型对应的数据库表名。
+func (User) TableName() string {
+	return "users"
+}
+
+// IsDisabled 判断用户是否已被管理员禁用
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt.Valid
 }
@@ -0,0 +1,22 @@
+/**
+ * @file models/user_customer.go
+ * @description 定义了 UserCustomer 数据模型，与数据库的 `user_customers` 表对应。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。记录非管理员用户可以访问哪些客户的服务器、更新日志、维护任务和工单。
+ */
+
+package models
+
+import "time"
+
+// UserCustomer 表示把一个客户分配给一个用户
+type UserCustomer struct {
+	UserID     string    `gorm:"primaryKey;column:user_id"`
+	CustomerID uint      `gorm:"primaryKey;column:customer_id"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+}
+
+// TableName 明确指定 UserCustomer 模型对应的数据库表名。
+func (UserCustomer) TableName() string {
+	return "user_customers"
+}
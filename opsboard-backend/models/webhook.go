@@ -0,0 +1,73 @@
+/**
+ * @file models/webhook.go
+ * @description 定义了 webhook 订阅（`webhooks` 表）和投递记录（`webhook_deliveries` 表）的数据模型。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。每个订阅的每个匹配事件生成一条投递记录，投递记录既是待投递队列，也是投递日志。
+ */
+
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 投递记录的状态
+const (
+	WebhookDeliveryPending   = "pending"   // 等待投递或等待重试
+	WebhookDeliverySucceeded = "succeeded" // 订阅方返回了 2xx 响应
+	WebhookDeliveryFailed    = "failed"    // 达到最大尝试次数仍未成功
+)
+
+// Webhook 是一个 webhook 订阅。Secret 用于对请求体签名，只在创建和轮换时返回一次。
+type Webhook struct {
+	WebhookID uint           `gorm:"primaryKey;column:webhook_id" json:"id"`
+	Name      string         `gorm:"column:name" json:"name"`
+	URL       string         `gorm:"column:url" json:"url"`
+	Secret    string         `gorm:"column:secret" json:"-"`
+	Events    []string       `gorm:"column:events;serializer:json" json:"events"`
+	Enabled   bool           `gorm:"column:enabled" json:"enabled"`
+	CreatedBy sql.NullString `gorm:"column:created_by" json:"createdBy"`
+	CreatedAt time.Time      `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt sql.NullTime   `gorm:"column:updated_at" json:"updatedAt"`
+	Version   uint           `gorm:"column:version;default:1" json:"version"`
+}
+
+// TableName 明确指定 Webhook 模型对应的数据库表名。
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Subscribes 判断订阅是否关注 eventType 类型的事件
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery 是一次事件投递。Payload 为发送的请求体，重新投递时原样复制到新的投递记录。
+type WebhookDelivery struct {
+	DeliveryID     uint64         `gorm:"primaryKey;column:delivery_id" json:"id"`
+	WebhookID      uint           `gorm:"column:webhook_id" json:"webhookId"`
+	EventID        string         `gorm:"column:event_id" json:"eventId"`
+	EventType      string         `gorm:"column:event_type" json:"eventType"`
+	Payload        string         `gorm:"column:payload" json:"payload"`
+	Status         string         `gorm:"column:status" json:"status"`
+	Attempts       uint           `gorm:"column:attempts" json:"attempts"`
+	NextAttemptAt  time.Time      `gorm:"column:next_attempt_at" json:"nextAttemptAt"`
+	LastAttemptAt  sql.NullTime   `gorm:"column:last_attempt_at" json:"lastAttemptAt"`
+	ResponseStatus sql.NullInt32  `gorm:"column:response_status" json:"responseStatus"`
+	ResponseBody   sql.NullString `gorm:"column:response_body" json:"responseBody"` // 截断后的响应体
+	LastError      sql.NullString `gorm:"column:last_error" json:"lastError"`
+	DeliveredAt    sql.NullTime   `gorm:"column:delivered_at" json:"deliveredAt"`
+	RedeliveryOf   sql.NullInt64  `gorm:"column:redelivery_of" json:"redeliveryOf"` // 手动重新投递时为原投递记录的 ID
+	CreatedAt      time.Time      `gorm:"column:created_at" json:"createdAt"`
+}
+
+// TableName 明确指定 WebhookDelivery 模型对应的数据库表名。
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
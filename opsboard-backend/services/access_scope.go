@@ -0,0 +1,176 @@
+/**
+ * @file services/access_scope.go
+ * @description 按用户被分配的客户限制其可以访问的数据，并提供管理用户与客户分配关系的业务逻辑。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。`AuthMiddleware` 把当前用户的访问范围存入请求的 context，查询客户、服务器、更新日志、
+ *     维护任务和工单时通过 GORM scope 自动追加条件：管理员不受限制，其他用户只能访问分配给自己的客户的记录。
+ *     context 中没有访问范围时不返回任何记录，后台任务需要显式使用 `GlobalAccess`。
+ */
+
+package services
+
+import (
+	"context"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AccessScope 描述一次调用可以访问的客户范围
+type AccessScope struct {
+	UserID string // 非全局访问时，只能访问分配给该用户的客户
+	Global bool   // 可以访问所有客户
+}
+
+// GlobalAccess 是不受客户范围限制的访问范围，用于管理员和不代表任何用户的后台任务
+var GlobalAccess = AccessScope{Global: true}
+
+// accessScopeKey 是访问范围在 context 中的键
+type accessScopeKey struct{}
+
+// UserAccessScope 返回用户的访问范围：管理员可以访问所有客户，其他用户只能访问分配给自己的客户
+func UserAccessScope(user *models.User) AccessScope {
+	if user.Role == models.RoleAdmin {
+		return GlobalAccess
+	}
+	return AccessScope{UserID: user.UserID.String()}
+}
+
+// WithAccessScope 返回带有访问范围的 context
+func WithAccessScope(ctx context.Context, scope AccessScope) context.Context {
+	return context.WithValue(ctx, accessScopeKey{}, scope)
+}
+
+// customerCondition 返回把 column（客户 ID 列）限制在 ctx 的访问范围内的 SQL 条件和参数；不需要限制时返回空字符串
+func customerCondition(ctx context.Context, column string) (string, []interface{}) {
+	scope, ok := ctx.Value(accessScopeKey{}).(AccessScope)
+	switch {
+	case !ok:
+		return "1 = 0", nil
+	case scope.Global:
+		return "", nil
+	default:
+		return column + " IN (SELECT customer_id FROM user_customers WHERE user_id = ?)", []interface{}{scope.UserID}
+	}
+}
+
+// serverCondition 返回把 column（服务器 ID 列）限制在 ctx 的访问范围内的 SQL 条件和参数；不需要限制时返回空字符串
+func serverCondition(ctx context.Context, column string) (string, []interface{}) {
+	cond, args := customerCondition(ctx, "customer_id")
+	if args == nil {
+		// 不受限制或没有访问范围时，条件与具体用户无关
+		return cond, args
+	}
+	return column + " IN (SELECT server_id FROM servers WHERE " + cond + ")", args
+}
+
+// customerScope 返回按 ctx 的访问范围限制 column（客户 ID 列）的 GORM scope
+func customerScope(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return conditionScope(customerCondition(ctx, column))
+}
+
+// serverScope 返回按 ctx 的访问范围限制 column（服务器 ID 列）的 GORM scope
+func serverScope(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return conditionScope(serverCondition(ctx, column))
+}
+
+// conditionScope 把 SQL 条件包装为 GORM scope，条件为空时不修改查询
+func conditionScope(cond string, args []interface{}) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if cond == "" {
+			return db
+		}
+		return db.Where(cond, args...)
+	}
+}
+
+// recordScope 返回 model 所在表的访问范围 scope，用于按主键读写单条记录
+func recordScope(ctx context.Context, model interface{}) func(*gorm.DB) *gorm.DB {
+	return conditionScope(recordCondition(ctx, model))
+}
+
+// recordCondition 返回把 model 所在表的记录限制在访问范围内的 SQL 条件和参数；不属于任何客户的模型不受限制
+func recordCondition(ctx context.Context, model interface{}) (string, []interface{}) {
+	switch model.(type) {
+	case *models.Customer:
+		return customerCondition(ctx, "customers.customer_id")
+	case *models.Server:
+		return customerCondition(ctx, "servers.customer_id")
+	case *models.Changelog:
+		return customerCondition(ctx, "changelogs.customer_id")
+	case *models.TicketRecord:
+		return customerCondition(ctx, "tickets.customer_id")
+	case *models.MaintenanceTask:
+		return serverCondition(ctx, "maintenance.target_server_id")
+	default:
+		return "", nil
+	}
+}
+
+// GetUserCustomers 返回分配给用户的客户，按名称排序
+func GetUserCustomers(ctx context.Context, userID uuid.UUID) (_ []models.Customer, err error) {
+	ctx, span := startSpan(ctx, "GetUserCustomers")
+	defer endSpan(span, &err)
+
+	if _, err := GetUserByID(ctx, userID); err != nil {
+		return nil, asNotFound(err, "用户")
+	}
+	return userCustomers(gormDB(ctx), userID.String())
+}
+
+// SetUserCustomers 将分配给用户的客户整体替换为 customerIDs，返回替换后的客户列表
+func SetUserCustomers(ctx context.Context, userID uuid.UUID, customerIDs []uint) (_ []models.Customer, err error) {
+	ctx, span := startSpan(ctx, "SetUserCustomers")
+	defer endSpan(span, &err)
+
+	var customers []models.Customer
+	err = gormDB(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUser(tx, userID); err != nil {
+			return err
+		}
+
+		unique := make(map[uint]struct{}, len(customerIDs))
+		for _, id := range customerIDs {
+			unique[id] = struct{}{}
+		}
+		var count int64
+		if err := tx.Model(&models.Customer{}).Where("customer_id IN ?", customerIDs).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(unique) {
+			return utils.ErrValidation(utils.FieldError{Field: "customerIds", Message: "包含不存在的客户"})
+		}
+
+		if err := tx.Where("user_id = ?", userID.String()).Delete(&models.UserCustomer{}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		for id := range unique {
+			if err := tx.Create(&models.UserCustomer{UserID: userID.String(), CustomerID: id, CreatedAt: now}).Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		customers, err = userCustomers(tx, userID.String())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+// userCustomers 查询分配给用户且未被删除的客户
+func userCustomers(db *gorm.DB, userID string) ([]models.Customer, error) {
+	customers := make([]models.Customer, 0)
+	err := db.Model(&models.Customer{}).
+		Joins("JOIN user_customers uc ON uc.customer_id = customers.customer_id").
+		Where("uc.user_id = ?", userID).
+		Order("customers.customer_name ASC").
+		Find(&customers).Error
+	return customers, err
+}
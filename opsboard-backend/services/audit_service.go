@@ -2,53 +2,260 @@
  * @file opsboard-backend/services/audit_service.go
  * @description 封装与系统操作日志相关的数据库操作。
  * @modification
- *   - [New File]: 创建此文件以提供一个中心化的日志记录服务。
- *   - [Functionality]: 提供了 `CreateLog` 函数，用于向 `audit_logs` 表中插入一条新的日志记录。
- *   - [Performance]: 日志的数据库插入操作在一个新的 goroutine 中异步执行，这可以防止日志记录阻塞主业务流程（如登录响应），从而提升 API 性能。
+ *   - [审计日志]：`CreateLog` 新增被操作对象 `AuditTarget`，写入 `target_entity` 和 `target_id` 列，
+ *     列表按目标实体筛选不再总是为空；新增按目标 ID 筛选。
  */
 
 package services
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"opsboard-backend/database"
+	"opsboard-backend/metrics"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // LogAction 定义了可被记录的操作类型常量
 type LogAction string
 
 const (
-	UserLoginSuccess LogAction = "USER_LOGIN_SUCCESS"
-	UserLoginFailure LogAction = "USER_LOGIN_FAILURE"
+	UserLoginSuccess  LogAction = "USER_LOGIN_SUCCESS"
+	UserLoginFailure  LogAction = "USER_LOGIN_FAILURE"
+	UserLoginLocked   LogAction = "USER_LOGIN_LOCKED"
+	UserLoginUnlocked LogAction = "USER_LOGIN_UNLOCKED"
+	UserLoginRejected LogAction = "USER_LOGIN_REJECTED"
+	ServersImported   LogAction = "SERVERS_IMPORTED"
+	TrashItemRestored LogAction = "TRASH_ITEM_RESTORED"
+	TrashItemPurged   LogAction = "TRASH_ITEM_PURGED"
+
+	MFAEnabled                  LogAction = "MFA_ENABLED"
+	MFADisabled                 LogAction = "MFA_DISABLED"
+	MFAReset                    LogAction = "MFA_RESET"
+	MFARecoveryCodesRegenerated LogAction = "MFA_RECOVERY_CODES_REGENERATED"
+	MFARecoveryCodeUsed         LogAction = "MFA_RECOVERY_CODE_USED"
+	MFAPolicyUpdated            LogAction = "MFA_POLICY_UPDATED"
+
+	APITokenCreated LogAction = "API_TOKEN_CREATED"
+	APITokenRevoked LogAction = "API_TOKEN_REVOKED"
+
+	UserCreated          LogAction = "USER_CREATED"
+	UserUpdated          LogAction = "USER_UPDATED"
+	UserRoleChanged      LogAction = "USER_ROLE_CHANGED"
+	UserDisabled         LogAction = "USER_DISABLED"
+	UserEnabled          LogAction = "USER_ENABLED"
+	UserDeleted          LogAction = "USER_DELETED"
+	UserPasswordReset    LogAction = "USER_PASSWORD_RESET"
+	UserPasswordChanged  LogAction = "USER_PASSWORD_CHANGED"
+	UserCustomersChanged LogAction = "USER_CUSTOMERS_CHANGED"
+
+	SettingsUpdated LogAction = "SETTINGS_UPDATED"
+
+	TicketSubmitted           LogAction = "TICKET_SUBMITTED"
+	TicketStatusChanged       LogAction = "TICKET_STATUS_CHANGED"
+	TicketResolutionConfirmed LogAction = "TICKET_RESOLUTION_CONFIRMED"
+	TicketRated               LogAction = "TICKET_RATED"
+	TicketPriorityChanged     LogAction = "TICKET_PRIORITY_CHANGED"
+	TicketSLABreached         LogAction = "TICKET_SLA_BREACHED"
+
+	SLAPolicyCreated        LogAction = "SLA_POLICY_CREATED"
+	SLAPolicyUpdated        LogAction = "SLA_POLICY_UPDATED"
+	SLAPolicyDeleted        LogAction = "SLA_POLICY_DELETED"
+	BusinessCalendarCreated LogAction = "BUSINESS_CALENDAR_CREATED"
+	BusinessCalendarUpdated LogAction = "BUSINESS_CALENDAR_UPDATED"
+	BusinessCalendarDeleted LogAction = "BUSINESS_CALENDAR_DELETED"
+
+	WebhookCreated       LogAction = "WEBHOOK_CREATED"
+	WebhookUpdated       LogAction = "WEBHOOK_UPDATED"
+	WebhookDeleted       LogAction = "WEBHOOK_DELETED"
+	WebhookSecretRotated LogAction = "WEBHOOK_SECRET_ROTATED"
+	WebhookRedelivered   LogAction = "WEBHOOK_REDELIVERED"
+
+	MaintenanceTaskFailed LogAction = "MAINTENANCE_TASK_FAILED"
+	ServerReportedOffline LogAction = "SERVER_REPORTED_OFFLINE"
+
+	NotificationChannelCreated  LogAction = "NOTIFICATION_CHANNEL_CREATED"
+	NotificationChannelUpdated  LogAction = "NOTIFICATION_CHANNEL_UPDATED"
+	NotificationChannelDeleted  LogAction = "NOTIFICATION_CHANNEL_DELETED"
+	NotificationChannelTested   LogAction = "NOTIFICATION_CHANNEL_TESTED"
+	NotificationRuleCreated     LogAction = "NOTIFICATION_RULE_CREATED"
+	NotificationRuleUpdated     LogAction = "NOTIFICATION_RULE_UPDATED"
+	NotificationRuleDeleted     LogAction = "NOTIFICATION_RULE_DELETED"
+	NotificationTemplateUpdated LogAction = "NOTIFICATION_TEMPLATE_UPDATED"
+	NotificationTemplateReset   LogAction = "NOTIFICATION_TEMPLATE_RESET"
+
+	EmailTestSent LogAction = "EMAIL_TEST_SENT"
+	EmailRetried  LogAction = "EMAIL_RETRIED"
 	// 未来可以添加更多操作类型...
-	// TicketCreated    LogAction = "TICKET_CREATED"
 	// ServerUpdated    LogAction = "SERVER_UPDATED"
 )
 
 // LogDetails 定义了可以被序列化为 JSON 的日志详情结构
 type LogDetails map[string]interface{}
 
-// CreateLog 创建一条新的审计日志记录。
-// 它在一个新的 goroutine 中运行，以避免阻塞调用者。
-func CreateLog(userID, action string, details LogDetails) {
+// AuditTarget 是审计日志中被操作的对象。Entity 使用表名（例如 users、tickets），
+// 没有单一对象的操作（批量导入、修改系统设置等）只填 Entity，与具体对象无关的操作（发送测试邮件等）使用零值。
+type AuditTarget struct {
+	Entity string
+	ID     string
+}
+
+// Target 返回 entity 表中主键为 id 的审计对象，id 可以是数字、字符串或 UUID
+func Target(entity string, id interface{}) AuditTarget {
+	return AuditTarget{Entity: entity, ID: fmt.Sprint(id)}
+}
+
+// auditQueueSize 是审计日志队列的容量
+const auditQueueSize = 1024
+
+// auditEntry 是一条等待写入数据库的审计日志
+type auditEntry struct {
+	ctx       context.Context // 仅用于输出关联了请求 ID 的错误日志
+	userID    string
+	action    string
+	target    AuditTarget
+	details   LogDetails
+	createdAt time.Time
+}
+
+// auditQueue 是审计日志的写入队列，由 StartAuditWorker 启动的 worker 串行消费
+var auditQueue = make(chan auditEntry, auditQueueSize)
+
+// StartAuditWorker 启动写入审计日志的后台 worker，并注册队列深度指标。应在数据库初始化后调用一次。
+func StartAuditWorker() {
+	metrics.RegisterQueueDepth("audit_log", func() int { return len(auditQueue) })
 	go func() {
-		// 将详情 map 转换为 JSON 字符串
-		detailsJSON, err := json.Marshal(details)
-		if err != nil {
-			log.Printf("错误: 无法将日志详情序列化为 JSON: %v", err)
-			return
+		for entry := range auditQueue {
+			err := writeAuditLog(entry)
+			metrics.TaskExecutionsTotal.WithLabelValues("audit_log", metrics.Result(err)).Inc()
+			if err != nil {
+				slog.ErrorContext(entry.ctx, "无法写入审计日志", "action", entry.action, "error", err)
+			}
 		}
+	}()
+}
 
-		query := `
-            INSERT INTO audit_logs (user_id, action, details, created_at)
-            VALUES (?, ?, ?, ?)
-        `
-		_, err = database.DB.Exec(query, userID, action, string(detailsJSON), time.Now())
-		if err != nil {
-			// 在生产环境中，这里应该使用一个更健壮的日志库
-			log.Printf("错误: 无法插入审计日志: %v", err)
+// CreateLog 创建一条新的审计日志记录，target 为被操作的对象。
+// ctx 中的请求 ID 会被写入 details 的 `request_id` 字段。
+// 日志会被放入队列异步写入，以避免阻塞调用者；仅当队列已满时才会等待。
+func CreateLog(ctx context.Context, userID, action string, target AuditTarget, details LogDetails) {
+	if requestID := utils.RequestIDFromContext(ctx); requestID != "" {
+		if details == nil {
+			details = LogDetails{}
 		}
-	}()
+		details["request_id"] = requestID
+	}
+
+	entry := auditEntry{
+		ctx:       context.WithoutCancel(ctx),
+		userID:    userID,
+		action:    action,
+		target:    target,
+		details:   details,
+		createdAt: time.Now(),
+	}
+	select {
+	case auditQueue <- entry:
+	default:
+		slog.WarnContext(ctx, "审计日志队列已满，等待写入", "action", action)
+		auditQueue <- entry
+	}
+}
+
+// writeAuditLog 将一条审计日志写入数据库
+func writeAuditLog(entry auditEntry) error {
+	// 将详情 map 转换为 JSON 字符串
+	detailsJSON, err := json.Marshal(entry.details)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO audit_logs (user_id, action, target_entity, target_id, details, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `
+	_, err = database.DB.Exec(query, nullString(entry.userID), entry.action, nullString(entry.target.Entity), nullString(entry.target.ID),
+		string(detailsJSON), entry.createdAt)
+	return err
+}
+
+// PaginatedAuditLogsResult 定义了审计日志分页查询的返回结构
+type PaginatedAuditLogsResult struct {
+	Total int64             `json:"total"`
+	Data  []models.AuditLog `json:"data"`
+}
+
+// AuditLogFilter 定义了审计日志列表的筛选条件，所有字段均为可选。
+type AuditLogFilter struct {
+	UserID       string `form:"userId"`
+	Action       string `form:"action"`
+	TargetEntity string `form:"targetEntity"`
+	TargetID     string `form:"targetId"`
+	TimeRangeFilter
+}
+
+// auditLogListQuery 构造审计日志列表的基础查询（包含用户表 JOIN 和筛选条件）
+func auditLogListQuery(ctx context.Context, filter AuditLogFilter) *gorm.DB {
+	query := gormDB(ctx).Model(&models.AuditLog{}).
+		Joins("LEFT JOIN users u ON audit_logs.user_id = u.user_id")
+
+	if filter.UserID != "" {
+		query = query.Where("audit_logs.user_id = ?", filter.UserID)
+	}
+	if filter.Action != "" {
+		query = query.Where("audit_logs.action = ?", filter.Action)
+	}
+	if filter.TargetEntity != "" {
+		query = query.Where("audit_logs.target_entity = ?", filter.TargetEntity)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("audit_logs.target_id = ?", filter.TargetID)
+	}
+	return filter.TimeRangeFilter.apply(query, "audit_logs.created_at")
+}
+
+// GetPaginatedAuditLogs 使用 GORM 从数据库中分页查询审计日志列表。
+func GetPaginatedAuditLogs(ctx context.Context, page, pageSize int, filter AuditLogFilter) (_ *PaginatedAuditLogsResult, err error) {
+	ctx, span := startSpan(ctx, "GetPaginatedAuditLogs")
+	defer endSpan(span, &err)
+
+	var logs []models.AuditLog
+	var total int64
+
+	if err := auditLogListQuery(ctx, filter).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	err = auditLogListQuery(ctx, filter).
+		Select("audit_logs.*, u.username").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Order("audit_logs.created_at DESC").
+		Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if logs == nil {
+		logs = make([]models.AuditLog, 0)
+	}
+
+	return &PaginatedAuditLogsResult{Total: total, Data: logs}, nil
+}
+
+// StreamAuditLogs 按列表的筛选和排序逐行遍历全部审计日志，并对每一行调用 fn。
+func StreamAuditLogs(ctx context.Context, filter AuditLogFilter, fn func(log *models.AuditLog) error) (err error) {
+	ctx, span := startSpan(ctx, "StreamAuditLogs")
+	defer endSpan(span, &err)
+
+	query := auditLogListQuery(ctx, filter).
+		Select("audit_logs.*, u.username").
+		Order("audit_logs.created_at DESC")
+	return streamQuery(query, fn)
 }
@@ -2,16 +2,19 @@
  * @file services/changelog_service.go
  * @description 提供与更新日志相关的业务逻辑，使用 GORM 实现分页查询、删除和状态变更操作。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [新增功能]：添加了 `MarkChangelogAsCompleted` 和 `MarkChangelogAsPending` 两个函数，用于更新日志的状态和完成时间。
- *   - [SQL 更新]：`GetPaginatedChangelogs` 的查询语句现在也包含了新的 `status` 和 `completion_time` 字段。
+ *   - [重复完成]：`MarkChangelogAsCompleted` 只在状态确实变为“完成”时更新完成时间和版本号并发布 `changelog.completed` 事件，
+ *     对已完成的日志重复调用不做任何修改。
  */
 
 package services
 
 import (
-	"opsboard-backend/database"
+	"context"
+	"log/slog"
 	"opsboard-backend/models"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // PaginatedChangelogsResult 定义了更新日志分页查询的返回结构
@@ -20,20 +23,46 @@ type PaginatedChangelogsResult struct {
 	Data  []models.Changelog `json:"data"`
 }
 
+// ChangelogFilter 定义了更新日志列表的筛选条件，所有字段均为可选。
+type ChangelogFilter struct {
+	CustomerName string `form:"customerName"`
+	UpdateType   string `form:"updateType"`
+	Status       string `form:"status"`
+	TimeRangeFilter
+}
+
+// changelogListQuery 构造更新日志列表的基础查询（包含客户表 JOIN 和筛选条件）
+func changelogListQuery(ctx context.Context, filter ChangelogFilter) *gorm.DB {
+	query := gormDB(ctx).Model(&models.Changelog{}).
+		Joins("LEFT JOIN customers c ON changelogs.customer_id = c.customer_id").
+		Scopes(customerScope(ctx, "changelogs.customer_id"))
+
+	if filter.CustomerName != "" {
+		query = query.Where("c.customer_name LIKE ?", "%"+filter.CustomerName+"%")
+	}
+	if filter.UpdateType != "" {
+		query = query.Where("changelogs.update_type = ?", filter.UpdateType)
+	}
+	if filter.Status != "" {
+		query = query.Where("changelogs.status = ?", filter.Status)
+	}
+	return filter.TimeRangeFilter.apply(query, "changelogs.update_time")
+}
+
 // GetPaginatedChangelogs 使用 GORM 从数据库中分页查询更新日志列表。
-func GetPaginatedChangelogs(page, pageSize int) (*PaginatedChangelogsResult, error) {
-	db := database.GormDB
+func GetPaginatedChangelogs(ctx context.Context, page, pageSize int, filter ChangelogFilter) (_ *PaginatedChangelogsResult, err error) {
+	ctx, span := startSpan(ctx, "GetPaginatedChangelogs")
+	defer endSpan(span, &err)
+
 	var changelogs []models.Changelog
 	var total int64
 
-	query := db.Model(&models.Changelog{})
-	if err := query.Count(&total).Error; err != nil {
+	if err := changelogListQuery(ctx, filter).Count(&total).Error; err != nil {
 		return nil, err
 	}
 
 	offset := (page - 1) * pageSize
-	err := query.
-		Joins("LEFT JOIN customers c ON changelogs.customer_id = c.customer_id").
+	err = changelogListQuery(ctx, filter).
 		// [核心修改] 确保查询包含了新字段
 		Select("changelogs.*, c.customer_name").
 		Offset(offset).
@@ -57,29 +86,72 @@ func GetPaginatedChangelogs(page, pageSize int) (*PaginatedChangelogsResult, err
 	return result, nil
 }
 
-// DeleteChangelogByID 使用 GORM 根据 ID 从数据库中删除一个更新日志。
-func DeleteChangelogByID(id string) error {
-	db := database.GormDB
-	result := db.Delete(&models.Changelog{}, id)
-	return result.Error
+// StreamChangelogs 按列表的筛选和排序逐行遍历全部更新日志，并对每一行调用 fn。
+func StreamChangelogs(ctx context.Context, filter ChangelogFilter, fn func(changelog *models.Changelog) error) (err error) {
+	ctx, span := startSpan(ctx, "StreamChangelogs")
+	defer endSpan(span, &err)
+
+	query := changelogListQuery(ctx, filter).
+		Select("changelogs.*, c.customer_name").
+		Order("changelogs.update_time DESC")
+	return streamQuery(query, fn)
+}
+
+// GetChangelogByID 使用 GORM 根据 ID 查找一条更新日志（包含客户名称）。
+// 如果没有找到记录，返回 404 `NOT_FOUND` 错误。
+func GetChangelogByID(ctx context.Context, id string) (_ *models.Changelog, err error) {
+	ctx, span := startSpan(ctx, "GetChangelogByID")
+	defer endSpan(span, &err)
+
+	var changelog models.Changelog
+	err = gormDB(ctx).Model(&models.Changelog{}).
+		Joins("LEFT JOIN customers c ON changelogs.customer_id = c.customer_id").
+		Scopes(customerScope(ctx, "changelogs.customer_id")).
+		Select("changelogs.*, c.customer_name").
+		First(&changelog, "changelogs.log_id = ?", id).Error
+	if err != nil {
+		return nil, asNotFound(err, "更新日志")
+	}
+	return &changelog, nil
 }
 
-// MarkChangelogAsCompleted 将指定ID的更新日志标记为“完成”。
-func MarkChangelogAsCompleted(id string) error {
-	db := database.GormDB
-	result := db.Model(&models.Changelog{}).Where("log_id = ?", id).Updates(map[string]interface{}{
-		"status":          "完成",
+// DeleteChangelogByID 使用 GORM 根据 ID 软删除一个更新日志。
+// 日志不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func DeleteChangelogByID(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteChangelogByID")
+	defer endSpan(span, &err)
+
+	return deleteVersioned(ctx, &models.Changelog{}, "更新日志", "log_id", id, expectedVersion)
+}
+
+// MarkChangelogAsCompleted 将指定ID的更新日志标记为“完成”。日志已经是“完成”状态时不做修改，也不再发布事件。
+func MarkChangelogAsCompleted(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "MarkChangelogAsCompleted")
+	defer endSpan(span, &err)
+
+	changed, err := updateVersionedStatus(ctx, &models.Changelog{}, "更新日志", "log_id", id, expectedVersion, "完成", map[string]interface{}{
 		"completion_time": time.Now(),
 	})
-	return result.Error
+	if err != nil || !changed {
+		return err
+	}
+
+	changelog, err := GetChangelogByID(ctx, id)
+	if err != nil {
+		slog.WarnContext(ctx, "无法读取更新日志，未发布事件", "log_id", id, "error", err)
+		return nil
+	}
+	PublishEvent(ctx, EventChangelogCompleted, changelog)
+	return nil
 }
 
 // MarkChangelogAsPending 将指定ID的更新日志标记为“挂起”。
-func MarkChangelogAsPending(id string) error {
-	db := database.GormDB
-	result := db.Model(&models.Changelog{}).Where("log_id = ?", id).Updates(map[string]interface{}{
+func MarkChangelogAsPending(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "MarkChangelogAsPending")
+	defer endSpan(span, &err)
+
+	return updateVersioned(ctx, &models.Changelog{}, "更新日志", "log_id", id, expectedVersion, map[string]interface{}{
 		"status":          "挂起",
 		"completion_time": nil,
 	})
-	return result.Error
 }
@@ -0,0 +1,328 @@
+/**
+ * @file services/chat_notifier.go
+ * @description 把领域事件按路由规则发送到钉钉、企业微信和飞书群机器人，并提供发送测试消息的功能。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。事件订阅者只匹配规则和渲染模板，消息放入内存队列由后台 worker 发送，
+ *     发送失败时短暂等待后重试几次；群消息是给人看的提醒，进程退出时队列中未发送的消息直接丢弃。
+ *     三个平台的请求格式：
+ *       - 钉钉：`markdown` 消息，加签时在 URL 上附加 `timestamp` 和 `sign` 参数；
+ *       - 企业微信：`markdown` 消息，内容最多 4096 字节，不支持加签；
+ *       - 飞书：`interactive` 卡片消息，加签时在请求体中附加 `timestamp` 和 `sign` 字段。
+ */
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"opsboard-backend/metrics"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// chatQueueSize 是待发送群消息队列的容量
+const chatQueueSize = 256
+
+// chatMaxAttempts 是每条群消息最多尝试发送的次数
+const chatMaxAttempts = 3
+
+// chatRetryDelay 是第一次发送失败后的重试间隔，之后每次失败翻倍
+const chatRetryDelay = 2 * time.Second
+
+// chatSendTimeout 是每次发送的 HTTP 超时时间
+const chatSendTimeout = 10 * time.Second
+
+// weComMaxContent 是企业微信 markdown 消息内容的最大字节数
+const weComMaxContent = 4096
+
+// chatMessage 是一条等待发送的群消息
+type chatMessage struct {
+	ctx     context.Context // 仅用于输出关联了请求 ID 的日志
+	channel models.NotificationChannel
+	eventID string
+	title   string
+	body    string
+}
+
+// chatQueue 是群消息的发送队列，由 StartChatNotifier 启动的 worker 串行消费
+var chatQueue = make(chan chatMessage, chatQueueSize)
+
+// chatClient 是发送群消息使用的 HTTP 客户端，机器人地址不会重定向
+var chatClient = &http.Client{
+	Timeout:       chatSendTimeout,
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// StartChatNotifier 订阅事件总线并启动发送群消息的后台 worker。应在数据库初始化后调用一次。
+func StartChatNotifier() {
+	metrics.RegisterQueueDepth("chat_notification", func() int { return len(chatQueue) })
+	SubscribeEvents("chat_notifications", enqueueChatNotifications)
+	go func() {
+		for msg := range chatQueue {
+			err := deliverChatMessage(msg)
+			metrics.TaskExecutionsTotal.WithLabelValues("chat_notification", metrics.Result(err)).Inc()
+			if err != nil {
+				slog.ErrorContext(msg.ctx, "群消息发送失败", "channel_id", msg.channel.ChannelID, "event_id", msg.eventID, "error", err)
+			}
+		}
+	}()
+}
+
+// deliverChatMessage 发送一条群消息，失败时重试，最多尝试 chatMaxAttempts 次
+func deliverChatMessage(msg chatMessage) error {
+	delay := chatRetryDelay
+	var err error
+	for attempt := 1; attempt <= chatMaxAttempts; attempt++ {
+		if err = SendChatMessage(msg.ctx, &msg.channel, msg.title, msg.body); err == nil {
+			return nil
+		}
+		if attempt < chatMaxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return err
+}
+
+// enqueueChatNotifications 是群机器人通知的事件订阅者：找出匹配事件的已启用规则，每个渠道渲染并放入一条消息
+func enqueueChatNotifications(ctx context.Context, event Event) error {
+	var rules []models.NotificationRule
+	if err := gormDB(ctx).Where("enabled = ?", true).Find(&rules).Error; err != nil {
+		return err
+	}
+
+	customerID, hasCustomer, err := eventCustomerID(ctx, event)
+	if err != nil {
+		return err
+	}
+	channelIDs := make([]uint, 0)
+	seen := make(map[uint]bool)
+	for _, rule := range rules {
+		if !ruleMatches(&rule, event, customerID, hasCustomer) || seen[rule.ChannelID] {
+			continue
+		}
+		seen[rule.ChannelID] = true
+		channelIDs = append(channelIDs, rule.ChannelID)
+	}
+	if len(channelIDs) == 0 {
+		return nil
+	}
+
+	var channels []models.NotificationChannel
+	if err := gormDB(ctx).Where("channel_id IN ? AND enabled = ?", channelIDs, true).Find(&channels).Error; err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+	title, body, err := RenderEventTemplate(ctx, models.TemplateKindChat, event)
+	if err != nil {
+		return err
+	}
+
+	for _, channel := range channels {
+		msg := chatMessage{ctx: ctx, channel: channel, eventID: event.ID, title: title, body: body}
+		select {
+		case chatQueue <- msg:
+		default:
+			// 群消息不值得阻塞发布事件的业务请求，队列已满时丢弃
+			slog.WarnContext(ctx, "群消息队列已满，丢弃消息", "channel_id", channel.ChannelID, "event_id", event.ID)
+		}
+	}
+	return nil
+}
+
+// ruleMatches 判断规则是否匹配事件：事件类型在规则的事件列表中，且规则限定的客户与事件相关的客户一致
+func ruleMatches(rule *models.NotificationRule, event Event, customerID uint, hasCustomer bool) bool {
+	matched := false
+	for _, e := range rule.Events {
+		if e == string(event.Type) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	return !rule.CustomerID.Valid || (hasCustomer && uint(rule.CustomerID.Int64) == customerID)
+}
+
+// eventCustomerID 返回事件相关的客户 ID；维护任务事件通过目标服务器确定客户，没有目标服务器的任务不属于任何客户
+func eventCustomerID(ctx context.Context, event Event) (uint, bool, error) {
+	switch data := event.Data.(type) {
+	case TicketEventData:
+		if data.Ticket != nil {
+			return data.Ticket.CustomerID, true, nil
+		}
+	case ServerOfflineEventData:
+		if data.Server != nil {
+			return data.Server.CustomerID, true, nil
+		}
+	case *models.Changelog:
+		return data.CustomerID, true, nil
+	case *models.Server:
+		return data.CustomerID, true, nil
+	case *models.MaintenanceTask:
+		if !data.TargetServerID.Valid {
+			return 0, false, nil
+		}
+		var customerIDs []uint
+		err := gormDB(ctx).Unscoped().Model(&models.Server{}).
+			Where("server_id = ?", data.TargetServerID.Int64).
+			Pluck("customer_id", &customerIDs).Error
+		if err != nil || len(customerIDs) == 0 {
+			return 0, false, err
+		}
+		return customerIDs[0], true, nil
+	}
+	return 0, false, nil
+}
+
+// SendTestChatMessage 向通知渠道同步发送一条测试消息，渠道停用时也会发送。
+// 渠道不存在时返回 404；机器人返回错误时返回 502，错误信息中包含机器人返回的原因。
+func SendTestChatMessage(ctx context.Context, id string) (err error) {
+	ctx, span := startSpan(ctx, "SendTestChatMessage")
+	defer endSpan(span, &err)
+
+	var channel models.NotificationChannel
+	if err := gormDB(ctx).Take(&channel, "channel_id = ?", id).Error; err != nil {
+		return asNotFound(err, "通知渠道")
+	}
+	body := fmt.Sprintf("这是一条来自 OpsBoard 的测试消息，收到说明渠道“%s”配置正确。\n\n发送时间：%s", channel.Name, time.Now().Format(templateDisplayTime))
+	if err := SendChatMessage(ctx, &channel, "OpsBoard 测试消息", body); err != nil {
+		return &utils.AppError{Code: utils.CodeBadGateway, Message: "发送测试消息失败：" + err.Error(), Err: err}
+	}
+	return nil
+}
+
+// SendChatMessage 按渠道类型构造请求并发送一条群消息，HTTP 状态码不是 2xx 或机器人返回错误码时返回错误
+func SendChatMessage(ctx context.Context, channel *models.NotificationChannel, title, body string) (err error) {
+	ctx, span := startSpan(ctx, "SendChatMessage")
+	defer endSpan(span, &err)
+
+	target, payload, err := chatRequest(channel, title, body, time.Now())
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := chatClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("机器人返回 HTTP %d: %s", resp.StatusCode, truncateRunes(string(respBody), 200))
+	}
+	return chatResponseError(respBody)
+}
+
+// chatRequest 按渠道类型返回请求地址和请求体，now 为签名使用的时间
+func chatRequest(channel *models.NotificationChannel, title, body string, now time.Time) (string, interface{}, error) {
+	switch channel.Type {
+	case models.ChannelTypeDingTalk:
+		target := channel.WebhookURL
+		if channel.Secret != "" {
+			u, err := url.Parse(target)
+			if err != nil {
+				return "", nil, err
+			}
+			timestamp := now.UnixMilli()
+			q := u.Query()
+			q.Set("timestamp", strconv.FormatInt(timestamp, 10))
+			q.Set("sign", utils.DingTalkSign(channel.Secret, timestamp))
+			u.RawQuery = q.Encode()
+			target = u.String()
+		}
+		return target, map[string]interface{}{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"title": title,
+				"text":  "### " + title + "\n\n" + body,
+			},
+		}, nil
+
+	case models.ChannelTypeWeCom:
+		return channel.WebhookURL, map[string]interface{}{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"content": truncateBytes("### "+title+"\n"+body, weComMaxContent),
+			},
+		}, nil
+
+	case models.ChannelTypeFeishu:
+		payload := map[string]interface{}{
+			"msg_type": "interactive",
+			"card": map[string]interface{}{
+				"header": map[string]interface{}{
+					"title":    map[string]string{"tag": "plain_text", "content": title},
+					"template": "blue",
+				},
+				"elements": []map[string]string{
+					{"tag": "markdown", "content": body},
+				},
+			},
+		}
+		if channel.Secret != "" {
+			timestamp := now.Unix()
+			payload["timestamp"] = strconv.FormatInt(timestamp, 10)
+			payload["sign"] = utils.FeishuSign(channel.Secret, timestamp)
+		}
+		return channel.WebhookURL, payload, nil
+
+	default:
+		return "", nil, fmt.Errorf("不支持的渠道类型 %q", channel.Type)
+	}
+}
+
+// chatResponse 是三个平台响应体中表示结果的字段：钉钉和企业微信为 errcode/errmsg，飞书为 code/msg
+type chatResponse struct {
+	ErrCode *int   `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	Code    *int   `json:"code"`
+	Msg     string `json:"msg"`
+}
+
+// chatResponseError 检查机器人响应体中的错误码，响应体不是 JSON 时视为成功（例如本地的测试替身）
+func chatResponseError(body []byte) error {
+	var resp chatResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+	switch {
+	case resp.ErrCode != nil && *resp.ErrCode != 0:
+		return fmt.Errorf("机器人返回错误 %d: %s", *resp.ErrCode, resp.ErrMsg)
+	case resp.Code != nil && *resp.Code != 0:
+		return fmt.Errorf("机器人返回错误 %d: %s", *resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// truncateBytes 把 s 截断为最多 n 个字节，不会截断在多字节字符的中间
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,545 @@
+/**
+ * @file services/chat_notifier_test.go
+ * @description 测试群机器人消息的发送：加签参数的位置和取值、三个平台的请求体、错误码的处理、默认模板的渲染和路由规则的匹配。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [测试]：新增通过本地 httptest 替身发送三种渠道消息的测试，按替身收到的时间戳重新计算签名并检查请求体；
+ *     机器人返回非 0 的 errcode/code 或非 2xx 状态码时发送失败。默认模板逐个事件类型渲染，
+ *     渲染结果发送到飞书后应原样出现在卡片中；路由规则按事件类型和客户匹配。
+ */
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestChatRequestDingTalkSignsURL(t *testing.T) {
+	channel := &models.NotificationChannel{
+		Type:       models.ChannelTypeDingTalk,
+		WebhookURL: "https://oapi.dingtalk.com/robot/send?access_token=abc",
+		Secret:     "SEC5b1c4d2e3f",
+	}
+	now := time.UnixMilli(1700000000000)
+
+	target, _, err := chatRequest(channel, "标题", "正文", now)
+	if err != nil {
+		t.Fatalf("chatRequest 返回错误: %v", err)
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		t.Fatalf("请求地址无效: %v", err)
+	}
+	q := u.Query()
+	if got := q.Get("access_token"); got != "abc" {
+		t.Errorf("access_token = %q，原有参数应保留", got)
+	}
+	if got := q.Get("timestamp"); got != "1700000000000" {
+		t.Errorf("timestamp = %q，期望毫秒时间戳 1700000000000", got)
+	}
+	// sign 中的 + / = 必须经过 URL 编码，解码后与签名算法的结果一致
+	if got, want := q.Get("sign"), "lBf+tbpooNE7faa/777k7FelbZkoWSvMkqYmpHlX4/I="; got != want {
+		t.Errorf("sign = %q，期望 %q", got, want)
+	}
+}
+
+func TestChatRequestDingTalkWithoutSecret(t *testing.T) {
+	channel := &models.NotificationChannel{
+		Type:       models.ChannelTypeDingTalk,
+		WebhookURL: "https://oapi.dingtalk.com/robot/send?access_token=abc",
+	}
+	target, _, err := chatRequest(channel, "标题", "正文", time.Now())
+	if err != nil {
+		t.Fatalf("chatRequest 返回错误: %v", err)
+	}
+	if target != channel.WebhookURL {
+		t.Errorf("未设置密钥时请求地址 = %q，期望保持原样", target)
+	}
+}
+
+func TestChatRequestFeishuSignsBody(t *testing.T) {
+	channel := &models.NotificationChannel{
+		Type:       models.ChannelTypeFeishu,
+		WebhookURL: "https://open.feishu.cn/open-apis/bot/v2/hook/xyz",
+		Secret:     "demo",
+	}
+	now := time.Unix(1700000000, 0)
+
+	target, body, err := chatRequest(channel, "标题", "正文", now)
+	if err != nil {
+		t.Fatalf("chatRequest 返回错误: %v", err)
+	}
+	if target != channel.WebhookURL {
+		t.Errorf("请求地址 = %q，飞书的签名不应放在 URL 中", target)
+	}
+	payload, ok := body.(map[string]interface{})
+	if !ok {
+		t.Fatalf("请求体类型为 %T", body)
+	}
+	if got := payload["timestamp"]; got != "1700000000" {
+		t.Errorf("timestamp = %v，期望秒级时间戳字符串 1700000000", got)
+	}
+	if got, want := payload["sign"], utils.FeishuSign("demo", 1700000000); got != want {
+		t.Errorf("sign = %v，期望 %v", got, want)
+	}
+}
+
+// chatStandIn 是群机器人的本地替身，记录收到的请求并返回设定的响应
+type chatStandIn struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	response string
+	requests []recordedChatRequest
+}
+
+// recordedChatRequest 是替身收到的一次请求
+type recordedChatRequest struct {
+	method      string
+	path        string
+	query       url.Values
+	contentType string
+	body        map[string]interface{}
+}
+
+// newChatStandIn 启动一个替身，默认按钉钉和企业微信的格式返回成功
+func newChatStandIn(t *testing.T) *chatStandIn {
+	t.Helper()
+	s := &chatStandIn{status: http.StatusOK, response: `{"errcode":0,"errmsg":"ok"}`}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("请求体不是 JSON 对象: %v", err)
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, recordedChatRequest{
+			method:      r.Method,
+			path:        r.URL.Path,
+			query:       r.URL.Query(),
+			contentType: r.Header.Get("Content-Type"),
+			body:        body,
+		})
+		status, response := s.status, s.response
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// respond 设定之后请求的响应
+func (s *chatStandIn) respond(status int, response string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.response = status, response
+}
+
+// lastRequest 返回替身收到的最后一次请求，并检查共同的请求格式
+func (s *chatStandIn) lastRequest(t *testing.T) recordedChatRequest {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		t.Fatal("替身没有收到请求")
+	}
+	req := s.requests[len(s.requests)-1]
+	if req.method != http.MethodPost {
+		t.Errorf("请求方法 = %s，期望 POST", req.method)
+	}
+	if !strings.HasPrefix(req.contentType, "application/json") {
+		t.Errorf("Content-Type = %q，期望 application/json", req.contentType)
+	}
+	return req
+}
+
+// jsonObject 取出 JSON 对象中名为 key 的子对象
+func jsonObject(t *testing.T, v map[string]interface{}, key string) map[string]interface{} {
+	t.Helper()
+	obj, ok := v[key].(map[string]interface{})
+	if !ok {
+		t.Fatalf("%s = %#v，期望 JSON 对象", key, v[key])
+	}
+	return obj
+}
+
+// assertRecentTimestamp 解析签名使用的时间戳并检查它落在发送前后的时间范围内
+func assertRecentTimestamp(t *testing.T, raw string, from, to int64) int64 {
+	t.Helper()
+	ts, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp = %q，不是整数", raw)
+	}
+	if ts < from || ts > to {
+		t.Errorf("timestamp = %d，不在发送时间范围 [%d, %d] 内", ts, from, to)
+	}
+	return ts
+}
+
+func TestSendChatMessageDingTalk(t *testing.T) {
+	srv := newChatStandIn(t)
+	channel := &models.NotificationChannel{
+		Type:       models.ChannelTypeDingTalk,
+		WebhookURL: srv.URL + "/robot/send?access_token=abc",
+		Secret:     "SEC5b1c4d2e3f",
+	}
+
+	from := time.Now().UnixMilli()
+	if err := SendChatMessage(context.Background(), channel, "新工单 #42", "- **客户**：示例客户"); err != nil {
+		t.Fatalf("SendChatMessage 返回错误: %v", err)
+	}
+	to := time.Now().UnixMilli()
+
+	req := srv.lastRequest(t)
+	if req.path != "/robot/send" {
+		t.Errorf("请求路径 = %q，期望 /robot/send", req.path)
+	}
+	if got := req.query.Get("access_token"); got != "abc" {
+		t.Errorf("access_token = %q，原有参数应保留", got)
+	}
+	ts := assertRecentTimestamp(t, req.query.Get("timestamp"), from, to)
+	if got, want := req.query.Get("sign"), utils.DingTalkSign(channel.Secret, ts); got != want {
+		t.Errorf("sign = %q，期望 %q", got, want)
+	}
+
+	if got := req.body["msgtype"]; got != "markdown" {
+		t.Errorf("msgtype = %v，期望 markdown", got)
+	}
+	markdown := jsonObject(t, req.body, "markdown")
+	if got := markdown["title"]; got != "新工单 #42" {
+		t.Errorf("markdown.title = %v", got)
+	}
+	if got, want := markdown["text"], "### 新工单 #42\n\n- **客户**：示例客户"; got != want {
+		t.Errorf("markdown.text = %q，期望 %q", got, want)
+	}
+}
+
+func TestSendChatMessageWeCom(t *testing.T) {
+	srv := newChatStandIn(t)
+	channel := &models.NotificationChannel{
+		Type:       models.ChannelTypeWeCom,
+		WebhookURL: srv.URL + "/cgi-bin/webhook/send?key=k1",
+	}
+
+	if err := SendChatMessage(context.Background(), channel, "标题", "正文"); err != nil {
+		t.Fatalf("SendChatMessage 返回错误: %v", err)
+	}
+	req := srv.lastRequest(t)
+	if got := req.query.Get("key"); got != "k1" {
+		t.Errorf("key = %q，原有参数应保留", got)
+	}
+	if req.query.Has("sign") || req.query.Has("timestamp") {
+		t.Errorf("企业微信不支持加签，请求参数为 %v", req.query)
+	}
+	if got := req.body["msgtype"]; got != "markdown" {
+		t.Errorf("msgtype = %v，期望 markdown", got)
+	}
+	if got, want := jsonObject(t, req.body, "markdown")["content"], "### 标题\n正文"; got != want {
+		t.Errorf("markdown.content = %q，期望 %q", got, want)
+	}
+
+	// 超过 4096 字节的内容按字节截断，不能截断在多字节字符的中间
+	long := strings.Repeat("内容", 1000)
+	if err := SendChatMessage(context.Background(), channel, "标题", long); err != nil {
+		t.Fatalf("SendChatMessage 返回错误: %v", err)
+	}
+	content, _ := jsonObject(t, srv.lastRequest(t).body, "markdown")["content"].(string)
+	if len(content) > weComMaxContent || len(content) < weComMaxContent-utf8.UTFMax {
+		t.Errorf("截断后的内容为 %d 字节，期望不超过 %d 字节且尽量用满", len(content), weComMaxContent)
+	}
+	if !utf8.ValidString(content) || !strings.HasPrefix(content, "### 标题\n内容") {
+		t.Errorf("截断后的内容无效: %q", truncateRunes(content, 20))
+	}
+}
+
+func TestSendChatMessageFeishu(t *testing.T) {
+	srv := newChatStandIn(t)
+	srv.respond(http.StatusOK, `{"StatusCode":0,"StatusMessage":"success","code":0,"data":{},"msg":"success"}`)
+	channel := &models.NotificationChannel{
+		Type:       models.ChannelTypeFeishu,
+		WebhookURL: srv.URL + "/open-apis/bot/v2/hook/xyz",
+		Secret:     "demo",
+	}
+
+	from := time.Now().Unix()
+	if err := SendChatMessage(context.Background(), channel, "服务器离线：web-01", "- **IP 地址**：10.0.0.1"); err != nil {
+		t.Fatalf("SendChatMessage 返回错误: %v", err)
+	}
+	to := time.Now().Unix()
+
+	req := srv.lastRequest(t)
+	if req.path != "/open-apis/bot/v2/hook/xyz" || len(req.query) != 0 {
+		t.Errorf("请求地址 = %s?%s，飞书的签名不应放在 URL 中", req.path, req.query.Encode())
+	}
+	ts := assertRecentTimestamp(t, jsonString(req.body["timestamp"]), from, to)
+	if got, want := req.body["sign"], utils.FeishuSign(channel.Secret, ts); got != want {
+		t.Errorf("sign = %v，期望 %v", got, want)
+	}
+
+	if got := req.body["msg_type"]; got != "interactive" {
+		t.Errorf("msg_type = %v，期望 interactive", got)
+	}
+	card := jsonObject(t, req.body, "card")
+	header := jsonObject(t, card, "header")
+	if got := header["template"]; got != "blue" {
+		t.Errorf("card.header.template = %v，期望 blue", got)
+	}
+	title := jsonObject(t, header, "title")
+	if title["tag"] != "plain_text" || title["content"] != "服务器离线：web-01" {
+		t.Errorf("card.header.title = %v", title)
+	}
+	elements, _ := card["elements"].([]interface{})
+	if len(elements) != 1 {
+		t.Fatalf("card.elements = %v，期望一个 markdown 元素", card["elements"])
+	}
+	element, _ := elements[0].(map[string]interface{})
+	if element["tag"] != "markdown" || element["content"] != "- **IP 地址**：10.0.0.1" {
+		t.Errorf("card.elements[0] = %v", element)
+	}
+}
+
+// jsonString 把 JSON 中的字符串值转换为 string，其他类型返回空字符串
+func jsonString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func TestSendChatMessageResponseErrors(t *testing.T) {
+	cases := []struct {
+		name        string
+		channelType string
+		status      int
+		response    string
+		wantErr     string // 为空表示发送成功
+	}{
+		{"钉钉成功", models.ChannelTypeDingTalk, http.StatusOK, `{"errcode":0,"errmsg":"ok"}`, ""},
+		{"钉钉签名错误", models.ChannelTypeDingTalk, http.StatusOK, `{"errcode":310000,"errmsg":"sign not match"}`, "310000"},
+		{"企业微信成功", models.ChannelTypeWeCom, http.StatusOK, `{"errcode":0,"errmsg":"ok"}`, ""},
+		{"企业微信 key 无效", models.ChannelTypeWeCom, http.StatusOK, `{"errcode":93000,"errmsg":"invalid webhook url"}`, "invalid webhook url"},
+		{"飞书成功", models.ChannelTypeFeishu, http.StatusOK, `{"code":0,"msg":"success"}`, ""},
+		{"飞书签名错误", models.ChannelTypeFeishu, http.StatusOK, `{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`, "19021"},
+		{"HTTP 错误", models.ChannelTypeFeishu, http.StatusInternalServerError, `internal error`, "HTTP 500"},
+		{"响应体不是 JSON", models.ChannelTypeWeCom, http.StatusOK, `ok`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newChatStandIn(t)
+			srv.respond(tc.status, tc.response)
+			channel := &models.NotificationChannel{Type: tc.channelType, WebhookURL: srv.URL, Secret: "secret"}
+
+			err := SendChatMessage(context.Background(), channel, "标题", "正文")
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("SendChatMessage 返回错误: %v", err)
+			case tc.wantErr != "" && err == nil:
+				t.Errorf("机器人返回 %s 时应发送失败", tc.response)
+			case tc.wantErr != "" && !strings.Contains(err.Error(), tc.wantErr):
+				t.Errorf("错误 = %q，应包含 %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// sampleEvents 为每种事件类型构造一个与业务服务发布的数据类型一致的事件
+func sampleEvents() map[EventType]Event {
+	occurredAt := time.Date(2024, 5, 6, 7, 8, 0, 0, time.UTC)
+	ticket := &models.Ticket{
+		ID: "42", CustomerID: 7, CustomerName: "示例客户", Status: "处理中", Priority: "P1",
+		OperationType: "故障", OperationContent: "数据库连接超时",
+	}
+	server := &models.Server{ServerID: 3, CustomerID: 7, CustomerName: "示例客户", ServerName: "web-01", IPAddress: "10.0.0.1"}
+	task := &models.MaintenanceTask{
+		TaskID: 5, TaskName: "备份数据库", TaskType: "备份",
+		TargetServerName: sql.NullString{String: "db-01", Valid: true},
+		CompletionTime:   sql.NullTime{Time: occurredAt, Valid: true},
+		LogOutput:        sql.NullString{String: "磁盘空间不足", Valid: true},
+	}
+	data := map[EventType]interface{}{
+		EventTicketCreated:     TicketEventData{Ticket: ticket},
+		EventTicketUpdated:     TicketEventData{Ticket: ticket, Changes: []string{"状态", "优先级"}},
+		EventTicketSLABreached: TicketEventData{Ticket: ticket, SLATarget: "response"},
+		EventTicketCommented: TicketEventData{Ticket: ticket, Comment: &models.TicketComment{
+			AuthorName: "张三", IsInternal: true, Content: "已联系客户", CreatedAt: occurredAt,
+		}},
+		EventChangelogCompleted: &models.Changelog{
+			CustomerID: 7, CustomerName: "示例客户", UpdateType: "版本升级", UpdateContent: "升级到 2.0",
+			CompletionTime: sql.NullTime{Time: occurredAt, Valid: true},
+		},
+		EventTaskFinished:  task,
+		EventTaskFailed:    task,
+		EventServerAdded:   server,
+		EventServerDeleted: server,
+		EventServerOffline: ServerOfflineEventData{Server: server, Reason: "心跳超时"},
+	}
+	events := make(map[EventType]Event, len(data))
+	for eventType, d := range data {
+		events[eventType] = Event{ID: "evt-1", Type: eventType, OccurredAt: occurredAt, Data: d}
+	}
+	return events
+}
+
+func TestDefaultChatTemplatesRender(t *testing.T) {
+	wantTitles := map[EventType]string{
+		EventTicketCreated:      "新工单 #42",
+		EventTicketUpdated:      "工单 #42 已更新",
+		EventTicketSLABreached:  "工单 #42 首次响应已超时",
+		EventTicketCommented:    "工单 #42 有新内部备注",
+		EventChangelogCompleted: "更新日志已完成：示例客户",
+		EventTaskFinished:       "维护任务已完成：备份数据库",
+		EventTaskFailed:         "维护任务失败：备份数据库",
+		EventServerAdded:        "新增服务器：web-01",
+		EventServerDeleted:      "服务器已删除：web-01",
+		EventServerOffline:      "服务器离线：web-01",
+	}
+	events := sampleEvents()
+	for _, eventType := range EventTypes {
+		t.Run(string(eventType), func(t *testing.T) {
+			tmpl, ok := defaultTemplate(models.TemplateKindChat, string(eventType))
+			if !ok {
+				t.Fatal("缺少默认群消息模板")
+			}
+			event, ok := events[eventType]
+			if !ok {
+				t.Fatal("缺少示例事件，新增事件类型时请补充 sampleEvents")
+			}
+			data, err := templateData(event)
+			if err != nil {
+				t.Fatalf("templateData 返回错误: %v", err)
+			}
+			msg, err := renderTemplate(&tmpl, data)
+			if err != nil {
+				t.Fatalf("renderTemplate 返回错误: %v", err)
+			}
+			if msg.Title != wantTitles[eventType] {
+				t.Errorf("标题 = %q，期望 %q", msg.Title, wantTitles[eventType])
+			}
+			// 默认模板中的每个字段都应取到值：列表项的冒号后不能为空，也不能输出 Go 的默认格式
+			for _, line := range strings.Split(msg.Body, "\n") {
+				if strings.HasSuffix(line, "：") || strings.Contains(line, "<no value>") || strings.Contains(line, "map[") {
+					t.Errorf("正文中有未取到值的字段: %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultChatTemplateFormatsValues(t *testing.T) {
+	event := sampleEvents()[EventTaskFailed]
+	tmpl, _ := defaultTemplate(models.TemplateKindChat, string(EventTaskFailed))
+	data, err := templateData(event)
+	if err != nil {
+		t.Fatalf("templateData 返回错误: %v", err)
+	}
+	msg, err := renderTemplate(&tmpl, data)
+	if err != nil {
+		t.Fatalf("renderTemplate 返回错误: %v", err)
+	}
+	// sql.Null* 字段取其中的值，时间按本地时区格式化
+	for _, want := range []string{
+		"**目标服务器**：db-01",
+		"**失败时间**：" + event.OccurredAt.Local().Format(templateDisplayTime),
+		"**日志**：磁盘空间不足",
+	} {
+		if !strings.Contains(msg.Body, want) {
+			t.Errorf("正文缺少 %q:\n%s", want, msg.Body)
+		}
+	}
+}
+
+func TestRenderedTicketCardSentToFeishu(t *testing.T) {
+	srv := newChatStandIn(t)
+	srv.respond(http.StatusOK, `{"code":0,"msg":"success"}`)
+	channel := &models.NotificationChannel{Type: models.ChannelTypeFeishu, WebhookURL: srv.URL}
+
+	tmpl, _ := defaultTemplate(models.TemplateKindChat, string(EventTicketCreated))
+	data, err := templateData(sampleEvents()[EventTicketCreated])
+	if err != nil {
+		t.Fatalf("templateData 返回错误: %v", err)
+	}
+	msg, err := renderTemplate(&tmpl, data)
+	if err != nil {
+		t.Fatalf("renderTemplate 返回错误: %v", err)
+	}
+	if err := SendChatMessage(context.Background(), channel, msg.Title, msg.Body); err != nil {
+		t.Fatalf("SendChatMessage 返回错误: %v", err)
+	}
+
+	card := jsonObject(t, srv.lastRequest(t).body, "card")
+	if got := jsonObject(t, jsonObject(t, card, "header"), "title")["content"]; got != "新工单 #42" {
+		t.Errorf("卡片标题 = %v", got)
+	}
+	elements, _ := card["elements"].([]interface{})
+	if len(elements) != 1 {
+		t.Fatalf("card.elements = %v", card["elements"])
+	}
+	content := jsonString(elements[0].(map[string]interface{})["content"])
+	if content != msg.Body {
+		t.Errorf("卡片正文 = %q，期望与渲染结果一致 %q", content, msg.Body)
+	}
+	for _, want := range []string{"- **客户**：示例客户", "- **优先级**：P1", "- **内容**：数据库连接超时"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("卡片正文缺少 %q", want)
+		}
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	event := Event{Type: EventTicketCreated}
+	customer := func(id int64) sql.NullInt64 { return sql.NullInt64{Int64: id, Valid: true} }
+	cases := []struct {
+		name        string
+		rule        models.NotificationRule
+		customerID  uint
+		hasCustomer bool
+		want        bool
+	}{
+		{"事件类型匹配，不限客户", models.NotificationRule{Events: []string{"ticket.updated", "ticket.created"}}, 7, true, true},
+		{"不限客户时匹配没有客户的事件", models.NotificationRule{Events: []string{"ticket.created"}}, 0, false, true},
+		{"事件类型不匹配", models.NotificationRule{Events: []string{"ticket.updated"}}, 7, true, false},
+		{"事件列表为空", models.NotificationRule{}, 7, true, false},
+		{"限定的客户一致", models.NotificationRule{Events: []string{"ticket.created"}, CustomerID: customer(7)}, 7, true, true},
+		{"限定的客户不一致", models.NotificationRule{Events: []string{"ticket.created"}, CustomerID: customer(8)}, 7, true, false},
+		{"限定客户时不匹配没有客户的事件", models.NotificationRule{Events: []string{"ticket.created"}, CustomerID: customer(7)}, 0, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ruleMatches(&tc.rule, event, tc.customerID, tc.hasCustomer); got != tc.want {
+				t.Errorf("ruleMatches = %v，期望 %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEventCustomerID(t *testing.T) {
+	events := sampleEvents()
+	for _, eventType := range []EventType{
+		EventTicketCreated, EventTicketCommented, EventChangelogCompleted, EventServerAdded, EventServerOffline,
+	} {
+		id, ok, err := eventCustomerID(context.Background(), events[eventType])
+		if err != nil || !ok || id != 7 {
+			t.Errorf("%s: eventCustomerID = (%d, %v, %v)，期望客户 7", eventType, id, ok, err)
+		}
+	}
+
+	// 没有目标服务器的维护任务不属于任何客户，不需要查询数据库
+	task := &models.MaintenanceTask{TaskName: "巡检"}
+	if id, ok, err := eventCustomerID(context.Background(), Event{Type: EventTaskFinished, Data: task}); err != nil || ok {
+		t.Errorf("没有目标服务器的任务: eventCustomerID = (%d, %v, %v)，期望不属于任何客户", id, ok, err)
+	}
+	if _, ok, _ := eventCustomerID(context.Background(), Event{Type: EventTicketCreated, Data: TicketEventData{}}); ok {
+		t.Error("没有工单的事件不应属于任何客户")
+	}
+}
@@ -0,0 +1,136 @@
+/**
+ * @file services/concurrency.go
+ * @description 提供乐观并发控制（基于 `version` 列）的公共更新和删除函数。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [软删除版本号]：`deleteVersioned` 软删除记录时同时将 `version` 加 1，与 `updateVersioned` 和回收站的恢复操作一致，
+ *     删除前读取的版本号在恢复后不再有效。
+ */
+
+package services
+
+import (
+	"context"
+	"errors"
+	"opsboard-backend/utils"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict 表示记录的当前版本与调用方期望的版本不一致
+var ErrVersionConflict = utils.NewAppError(utils.CodePreconditionFailed, "记录已被他人修改，请刷新后重试")
+
+// notFound 返回指定实体不存在的 404 错误，entity 为面向用户的实体名称（如“服务器”）
+func notFound(entity string) *utils.AppError {
+	return utils.ErrNotFound(entity + "不存在")
+}
+
+// updateVersioned 按主键更新一条记录，并将其 `version` 加 1。
+// expectedVersion 为 nil 时不做版本校验；否则只有当前版本等于期望版本时才会更新。
+func updateVersioned(ctx context.Context, model interface{}, entity, primaryKey, id string, expectedVersion *uint, updates map[string]interface{}) error {
+	query := gormDB(ctx).Model(model).Scopes(recordScope(ctx, model)).Where(primaryKey+" = ?", id)
+	if expectedVersion != nil {
+		query = query.Where("version = ?", *expectedVersion)
+	}
+
+	updates["version"] = gorm.Expr("version + 1")
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return missingOrConflict(ctx, model, entity, primaryKey, id)
+	}
+	return nil
+}
+
+// updateVersionedStatus 与 updateVersioned 相同，但只在记录的 `status` 不是 status 时才更新（同时写入 updates 中的其他列）。
+// 记录已经处于该状态且版本符合期望时不做任何修改，返回的 changed 为 false。
+func updateVersionedStatus(ctx context.Context, model interface{}, entity, primaryKey, id string, expectedVersion *uint, status string, updates map[string]interface{}) (changed bool, err error) {
+	query := gormDB(ctx).Model(model).Scopes(recordScope(ctx, model)).Where(primaryKey+" = ? AND status <> ?", id, status)
+	if expectedVersion != nil {
+		query = query.Where("version = ?", *expectedVersion)
+	}
+
+	updates["status"] = status
+	updates["version"] = gorm.Expr("version + 1")
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	unchanged := gormDB(ctx).Model(model).Scopes(recordScope(ctx, model)).Where(primaryKey+" = ? AND status = ?", id, status)
+	if expectedVersion != nil {
+		unchanged = unchanged.Where("version = ?", *expectedVersion)
+	}
+	var count int64
+	if err := unchanged.Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	return false, missingOrConflict(ctx, model, entity, primaryKey, id)
+}
+
+// deleteVersioned 按主键（软）删除一条记录；支持软删除的记录在设置 `deleted_at` 的同时将其 `version` 加 1。
+// expectedVersion 为 nil 时不做版本校验；否则只有当前版本等于期望版本时才会删除。
+func deleteVersioned(ctx context.Context, model interface{}, entity, primaryKey, id string, expectedVersion *uint) error {
+	soft, err := softDeletable(gormDB(ctx), model)
+	if err != nil {
+		return err
+	}
+	query := gormDB(ctx).Model(model).Scopes(recordScope(ctx, model)).Where(primaryKey+" = ?", id)
+	if expectedVersion != nil {
+		query = query.Where("version = ?", *expectedVersion)
+	}
+
+	var result *gorm.DB
+	if soft {
+		// Updates 与 Delete 一样只匹配未删除的记录
+		result = query.Updates(map[string]interface{}{"deleted_at": time.Now(), "version": gorm.Expr("version + 1")})
+	} else {
+		result = query.Delete(model)
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return missingOrConflict(ctx, model, entity, primaryKey, id)
+	}
+	return nil
+}
+
+// softDeletable 判断 model 是否通过 gorm.DeletedAt 类型的 `deleted_at` 列支持软删除
+func softDeletable(db *gorm.DB, model interface{}) (bool, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return false, err
+	}
+	field := stmt.Schema.LookUpField("deleted_at")
+	return field != nil && field.FieldType == reflect.TypeOf(gorm.DeletedAt{}), nil
+}
+
+// missingOrConflict 在写操作未影响任何行时判断原因：记录不存在（或已在回收站中）返回 404，否则视为版本冲突
+func missingOrConflict(ctx context.Context, model interface{}, entity, primaryKey, id string) error {
+	var count int64
+	if err := gormDB(ctx).Model(model).Scopes(recordScope(ctx, model)).Where(primaryKey+" = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return notFound(entity)
+	}
+	return ErrVersionConflict
+}
+
+// asNotFound 将查询返回的 gorm.ErrRecordNotFound 转换为指定实体的 404 错误，其余错误原样返回
+func asNotFound(err error, entity string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound(entity)
+	}
+	return err
+}
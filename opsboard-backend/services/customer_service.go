@@ -0,0 +1,75 @@
+/**
+ * @file services/customer_service.go
+ * @description 提供与客户相关的业务逻辑，使用 GORM 实现分页查询和删除操作。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [客户范围]：列表和详情查询按请求的访问范围过滤，非管理员只能看到分配给自己的客户。
+ */
+
+package services
+
+import (
+	"context"
+	"opsboard-backend/models"
+)
+
+// PaginatedCustomersResult 定义了客户分页查询的返回结构
+type PaginatedCustomersResult struct {
+	Total int64             `json:"total"`
+	Data  []models.Customer `json:"data"`
+}
+
+// GetPaginatedCustomers 使用 GORM 从数据库中分页查询客户列表，可按名称模糊筛选。
+func GetPaginatedCustomers(ctx context.Context, page, pageSize int, customerName string) (_ *PaginatedCustomersResult, err error) {
+	ctx, span := startSpan(ctx, "GetPaginatedCustomers")
+	defer endSpan(span, &err)
+
+	var customers []models.Customer
+	var total int64
+
+	query := gormDB(ctx).Model(&models.Customer{}).Scopes(customerScope(ctx, "customers.customer_id"))
+	if customerName != "" {
+		query = query.Where("customer_name LIKE ?", "%"+customerName+"%")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	err = query.
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Order("customer_name ASC").
+		Find(&customers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if customers == nil {
+		customers = make([]models.Customer, 0)
+	}
+
+	return &PaginatedCustomersResult{Total: total, Data: customers}, nil
+}
+
+// GetCustomerByID 使用 GORM 根据 ID 查找一个客户。
+// 如果没有找到记录，返回 404 `NOT_FOUND` 错误。
+func GetCustomerByID(ctx context.Context, id string) (_ *models.Customer, err error) {
+	ctx, span := startSpan(ctx, "GetCustomerByID")
+	defer endSpan(span, &err)
+
+	var customer models.Customer
+	err = gormDB(ctx).Scopes(customerScope(ctx, "customers.customer_id")).First(&customer, "customer_id = ?", id).Error
+	if err != nil {
+		return nil, asNotFound(err, "客户")
+	}
+	return &customer, nil
+}
+
+// DeleteCustomerByID 使用 GORM 根据 ID 软删除一个客户。
+// 客户不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func DeleteCustomerByID(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteCustomerByID")
+	defer endSpan(span, &err)
+
+	return deleteVersioned(ctx, &models.Customer{}, "客户", "customer_id", id, expectedVersion)
+}
@@ -0,0 +1,250 @@
+/**
+ * @file services/email_notifier.go
+ * @description 根据领域事件和用户的订阅设置生成通知邮件，并生成每日待处理事项摘要。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [通知中心]：把按事件确定收件人的逻辑提取为 `eventRecipients`，站内通知使用相同的收件人规则。
+ */
+
+package services
+
+import (
+	"context"
+	"opsboard-backend/models"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// digestMaxItems 是每日摘要中每类待处理事项最多列出的条数
+const digestMaxItems = 50
+
+// digestPendingStatus 是每日摘要统计的更新日志和维护任务状态
+const digestPendingStatus = "挂起"
+
+// templateRecipient 是邮件模板中的收件人 `recipient`
+type templateRecipient struct {
+	Username string `json:"username"`
+	Nickname string `json:"nickname"` // 没有昵称时为用户名
+}
+
+// digestData 是每日摘要邮件模板的数据
+type digestData struct {
+	Recipient           templateRecipient        `json:"recipient"`
+	Date                string                   `json:"date"`
+	Changelogs          []models.Changelog       `json:"changelogs"`
+	ChangelogTotal      int64                    `json:"changelogTotal"`
+	ChangelogsTruncated bool                     `json:"changelogsTruncated"`
+	Tasks               []models.MaintenanceTask `json:"tasks"`
+	TaskTotal           int64                    `json:"taskTotal"`
+	TasksTruncated      bool                     `json:"tasksTruncated"`
+}
+
+// enqueueEventEmails 是邮件通知的事件订阅者：找出事件的收件人，为每个设置了邮箱且订阅了该通知的收件人渲染一封邮件写入发件箱
+func enqueueEventEmails(ctx context.Context, event Event) error {
+	template, recipients, err := eventRecipients(ctx, event)
+	if err != nil || len(recipients) == 0 {
+		return err
+	}
+
+	data, err := templateData(event)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	emails := make([]models.EmailMessage, 0, len(recipients))
+	for i := range recipients {
+		user := &recipients[i]
+		if user.Email.String == "" {
+			continue
+		}
+		subscribed, err := emailSubscribed(ctx, user, template)
+		if err != nil {
+			return err
+		}
+		if !subscribed {
+			continue
+		}
+		data["recipient"] = map[string]interface{}{"username": user.Username, "nickname": recipientNickname(user)}
+		msg, err := renderNotification(ctx, models.TemplateKindEmail, template, data)
+		if err != nil {
+			return err
+		}
+		emails = append(emails, newEmail("event:"+event.ID+":"+user.UserID.String(), user, template, msg, now))
+	}
+	return enqueueEmails(ctx, emails)
+}
+
+// eventRecipients 返回事件对应的通知类型（与邮件模板名称相同）和接收通知的内部用户，事件不需要通知时 kind 为空：
+//   - 工单分配（`ticket.updated` 中修改了 assignee）：新的处理人；
+//   - 工单 SLA 超时：处理人和所有管理员；
+//   - 维护任务失败：所有管理员和分配了该任务所属客户的用户。
+func eventRecipients(ctx context.Context, event Event) (kind string, _ []models.User, err error) {
+	var scope func(*gorm.DB) *gorm.DB
+	switch data := event.Data.(type) {
+	case TicketEventData:
+		if data.Ticket == nil {
+			return "", nil, nil
+		}
+		switch {
+		case event.Type == EventTicketUpdated && slices.Contains(data.Changes, "assignee"):
+			kind = EmailTemplateTicketAssigned
+			scope = func(q *gorm.DB) *gorm.DB {
+				return q.Where("user_id = (SELECT assignee_id FROM tickets WHERE ticket_id = ?)", data.Ticket.ID)
+			}
+		case event.Type == EventTicketSLABreached:
+			kind = EmailTemplateSLABreached
+			scope = func(q *gorm.DB) *gorm.DB {
+				return q.Where("role = ? OR user_id = (SELECT assignee_id FROM tickets WHERE ticket_id = ?)", models.RoleAdmin, data.Ticket.ID)
+			}
+		default:
+			return "", nil, nil
+		}
+	case *models.MaintenanceTask:
+		if event.Type != EventTaskFailed {
+			return "", nil, nil
+		}
+		customerID, hasCustomer, err := eventCustomerID(ctx, event)
+		if err != nil {
+			return "", nil, err
+		}
+		kind = EmailTemplateTaskFailed
+		scope = func(q *gorm.DB) *gorm.DB {
+			if !hasCustomer {
+				return q.Where("role = ?", models.RoleAdmin)
+			}
+			return q.Where("role = ? OR user_id IN (SELECT user_id FROM user_customers WHERE customer_id = ?)", models.RoleAdmin, customerID)
+		}
+	default:
+		return "", nil, nil
+	}
+
+	recipients, err := staffRecipients(ctx, scope)
+	return kind, recipients, err
+}
+
+// EnqueueDailyDigests 为订阅了每日摘要的用户生成 day 当天的摘要邮件；同一天重复调用不会重复生成
+func EnqueueDailyDigests(ctx context.Context, day time.Time) (err error) {
+	ctx, span := startSpan(ctx, "EnqueueDailyDigests")
+	defer endSpan(span, &err)
+
+	recipients, err := staffRecipients(ctx, func(q *gorm.DB) *gorm.DB {
+		return q.Where("email IS NOT NULL AND email <> ''")
+	})
+	if err != nil {
+		return err
+	}
+	date := day.Format("2006-01-02")
+	now := time.Now()
+	emails := make([]models.EmailMessage, 0)
+	for i := range recipients {
+		user := &recipients[i]
+		subscribed, err := emailSubscribed(ctx, user, EmailTemplateDailyDigest)
+		if err != nil {
+			return err
+		}
+		if !subscribed {
+			continue
+		}
+
+		digest, err := userDigest(WithAccessScope(ctx, UserAccessScope(user)), user, date)
+		if err != nil {
+			return err
+		}
+		if digest.ChangelogTotal == 0 && digest.TaskTotal == 0 {
+			continue
+		}
+		data, err := toTemplateData(digest)
+		if err != nil {
+			return err
+		}
+		msg, err := renderNotification(ctx, models.TemplateKindEmail, EmailTemplateDailyDigest, data)
+		if err != nil {
+			return err
+		}
+		emails = append(emails, newEmail("digest:"+date+":"+user.UserID.String(), user, EmailTemplateDailyDigest, msg, now))
+	}
+	return enqueueEmails(ctx, emails)
+}
+
+// userDigest 在 ctx 的访问范围内查询挂起的更新日志和维护任务，各自最多列出最早的 digestMaxItems 条
+func userDigest(ctx context.Context, user *models.User, date string) (*digestData, error) {
+	digest := &digestData{
+		Recipient:  templateRecipient{Username: user.Username, Nickname: recipientNickname(user)},
+		Date:       date,
+		Changelogs: make([]models.Changelog, 0),
+		Tasks:      make([]models.MaintenanceTask, 0),
+	}
+
+	changelogFilter := ChangelogFilter{Status: digestPendingStatus}
+	if err := changelogListQuery(ctx, changelogFilter).Count(&digest.ChangelogTotal).Error; err != nil {
+		return nil, err
+	}
+	if digest.ChangelogTotal > 0 {
+		err := changelogListQuery(ctx, changelogFilter).
+			Select("changelogs.*, c.customer_name").
+			Order("changelogs.update_time ASC").
+			Limit(digestMaxItems).
+			Find(&digest.Changelogs).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	taskFilter := MaintenanceTaskFilter{Status: digestPendingStatus}
+	if err := maintenanceListQuery(ctx, taskFilter).Count(&digest.TaskTotal).Error; err != nil {
+		return nil, err
+	}
+	if digest.TaskTotal > 0 {
+		err := maintenanceListQuery(ctx, taskFilter).
+			Select("maintenance.*, s.server_name as target_server_name").
+			Order("maintenance.publication_time ASC").
+			Limit(digestMaxItems).
+			Find(&digest.Tasks).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	digest.ChangelogsTruncated = digest.ChangelogTotal > int64(len(digest.Changelogs))
+	digest.TasksTruncated = digest.TaskTotal > int64(len(digest.Tasks))
+	return digest, nil
+}
+
+// staffRecipients 返回满足 scope 条件且未被禁用的内部用户
+func staffRecipients(ctx context.Context, scope func(*gorm.DB) *gorm.DB) ([]models.User, error) {
+	var users []models.User
+	err := gormDB(ctx).Model(&models.User{}).
+		Where("role IN ? AND disabled_at IS NULL", models.StaffRoles).
+		Scopes(scope).
+		Find(&users).Error
+	return users, err
+}
+
+// emailSubscribed 判断用户是否订阅了 template 对应的邮件通知
+func emailSubscribed(ctx context.Context, user *models.User, template string) (bool, error) {
+	doc, err := GetUserPreferences(ctx, user.UserID.String())
+	if err != nil {
+		return false, err
+	}
+	prefs := doc.Preferences.EmailNotifications
+	switch template {
+	case EmailTemplateTicketAssigned:
+		return prefs.TicketAssigned, nil
+	case EmailTemplateSLABreached:
+		return prefs.SLABreached, nil
+	case EmailTemplateTaskFailed:
+		return prefs.TaskFailed, nil
+	case EmailTemplateDailyDigest:
+		return prefs.DailyDigest, nil
+	}
+	return false, nil
+}
+
+// recipientNickname 返回邮件中称呼收件人使用的名字，没有昵称时使用用户名
+func recipientNickname(user *models.User) string {
+	if user.Nickname != "" {
+		return user.Nickname
+	}
+	return user.Username
+}
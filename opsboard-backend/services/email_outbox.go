@@ -0,0 +1,366 @@
+/**
+ * @file services/email_outbox.go
+ * @description 发送通知邮件的后台任务：从发件箱中取出到期的邮件通过 SMTP 发送，失败时按指数退避重试；
+ *   另外提供发件箱的查询、手动重试和发送测试邮件的功能。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [测试]：把一次发送后需要更新的字段提取为 `emailAttemptUpdates`，重试和失败的判断可以不依赖数据库测试。
+ */
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"opsboard-backend/metrics"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EmailPolicy 定义了邮件的 SMTP 服务器和重试策略
+type EmailPolicy struct {
+	SMTP        utils.SMTPConfig // Host 为空表示不发送邮件
+	MaxAttempts int              // 最多尝试的总次数，达到后邮件标记为 failed
+	RetryBase   time.Duration    // 第一次失败后的重试间隔，之后每次失败翻倍
+	RetryMax    time.Duration    // 重试间隔的上限
+	DigestTime  time.Duration    // 每天发送摘要的时间，为距当天零点的时长
+}
+
+// DefaultEmailPolicy 是未配置时使用的默认策略：最多尝试 6 次，重试间隔从 1 分钟翻倍到最多 2 小时，每天 8 点发送摘要
+var DefaultEmailPolicy = EmailPolicy{
+	SMTP:        utils.SMTPConfig{Port: 587, Security: utils.SMTPSecuritySTARTTLS, Timeout: 30 * time.Second},
+	MaxAttempts: 6,
+	RetryBase:   time.Minute,
+	RetryMax:    2 * time.Hour,
+	DigestTime:  8 * time.Hour,
+}
+
+// ErrEmailDisabled 表示没有配置 SMTP 服务器
+var ErrEmailDisabled = utils.NewAppError(utils.CodeConflict, "未配置 SMTP 服务器，邮件通知未启用")
+
+// ErrEmailPending 表示邮件仍在等待发送，不需要重试
+var ErrEmailPending = utils.NewAppError(utils.CodeConflict, "邮件仍在等待发送，无需重试")
+
+// emailPollInterval 是发送任务在没有新邮件时检查到期重试的间隔
+const emailPollInterval = 30 * time.Second
+
+// emailBatchSize 是发送任务每次查询处理的邮件数
+const emailBatchSize = 50
+
+// maxEmailError 是发件箱中保存的错误信息的最大字符数，与表结构中的列长度一致
+const maxEmailError = 1000
+
+var (
+	emailMu     sync.RWMutex
+	emailPolicy = DefaultEmailPolicy
+
+	// emailWake 用于在写入新的邮件后立即唤醒发送任务
+	emailWake = make(chan struct{}, 1)
+)
+
+// EmailOutboxFilter 定义了发件箱列表的筛选条件
+type EmailOutboxFilter struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending sent failed"`
+	Template string `form:"template"`
+}
+
+// PaginatedEmailOutboxResult 定义了发件箱分页查询的返回结构
+type PaginatedEmailOutboxResult struct {
+	Total int64                 `json:"total"`
+	Data  []models.EmailMessage `json:"data"`
+}
+
+// ConfigureEmail 设置邮件的 SMTP 服务器和重试策略，应在启动时调用一次
+func ConfigureEmail(policy EmailPolicy) {
+	emailMu.Lock()
+	defer emailMu.Unlock()
+	emailPolicy = policy
+}
+
+// currentEmailPolicy 返回当前的邮件策略
+func currentEmailPolicy() EmailPolicy {
+	emailMu.RLock()
+	defer emailMu.RUnlock()
+	return emailPolicy
+}
+
+// emailEnabled 判断是否配置了 SMTP 服务器
+func emailEnabled() bool {
+	return currentEmailPolicy().SMTP.Host != ""
+}
+
+// StartEmailDispatcher 订阅事件总线并启动发送邮件和每日摘要的后台任务。应在数据库初始化后调用一次；
+// 未配置 SMTP 服务器时什么也不做。
+func StartEmailDispatcher() {
+	if !emailEnabled() {
+		slog.Info("未配置 SMTP 服务器，邮件通知未启用")
+		return
+	}
+	SubscribeEvents("email_notifications", enqueueEventEmails)
+	go func() {
+		ticker := time.NewTicker(emailPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-emailWake:
+			}
+			err := metrics.ObserveSchedulerRun("email_dispatcher", func() error {
+				return DeliverDueEmails(context.Background())
+			})
+			if err != nil {
+				slog.Error("发送邮件失败", "error", err)
+			}
+		}
+	}()
+	go runDigestScheduler()
+	wakeEmailDispatcher() // 发送上次退出时未发送的邮件
+}
+
+// runDigestScheduler 每天在摘要时间生成摘要邮件。启动时已经过了当天的摘要时间则立即生成一次，
+// 去重键保证重启不会重复发送。
+func runDigestScheduler() {
+	for {
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		next := today.Add(currentEmailPolicy().DigestTime)
+		if !now.Before(next) {
+			err := metrics.ObserveSchedulerRun("email_digest", func() error {
+				return EnqueueDailyDigests(context.Background(), today)
+			})
+			if err != nil {
+				slog.Error("生成每日摘要邮件失败", "error", err)
+			}
+			next = today.AddDate(0, 0, 1).Add(currentEmailPolicy().DigestTime)
+		}
+		time.Sleep(time.Until(next))
+	}
+}
+
+// wakeEmailDispatcher 唤醒发送任务；发送任务已经被唤醒时不重复唤醒
+func wakeEmailDispatcher() {
+	select {
+	case emailWake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueueEmails 把邮件写入发件箱，去重键已存在的邮件被忽略
+func enqueueEmails(ctx context.Context, emails []models.EmailMessage) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	if err := gormDB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&emails).Error; err != nil {
+		return err
+	}
+	wakeEmailDispatcher()
+	return nil
+}
+
+// DeliverDueEmails 发送所有到期的邮件，直到没有到期的邮件为止
+func DeliverDueEmails(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "DeliverDueEmails")
+	defer endSpan(span, &err)
+
+	policy := currentEmailPolicy()
+	for {
+		var emails []models.EmailMessage
+		err := gormDB(ctx).
+			Where("status = ? AND next_attempt_at <= ?", models.EmailPending, time.Now()).
+			Order("next_attempt_at ASC").
+			Limit(emailBatchSize).
+			Find(&emails).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range emails {
+			claimed, err := claimEmail(ctx, &emails[i], policy)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+			if err := attemptEmail(ctx, &emails[i], policy); err != nil {
+				return err
+			}
+		}
+
+		if len(emails) < emailBatchSize {
+			return nil
+		}
+	}
+}
+
+// claimEmail 把邮件的下一次尝试时间推迟到租约到期时间，返回是否由本实例取得了这封邮件
+func claimEmail(ctx context.Context, email *models.EmailMessage, policy EmailPolicy) (bool, error) {
+	now := time.Now()
+	result := gormDB(ctx).Model(&models.EmailMessage{}).
+		Where("email_id = ? AND status = ? AND next_attempt_at <= ?", email.EmailID, models.EmailPending, now).
+		Update("next_attempt_at", now.Add(2*policy.SMTP.Timeout))
+	return result.RowsAffected == 1, result.Error
+}
+
+// attemptEmail 发送一次邮件并记录结果，失败时在未达到最大尝试次数时安排重试
+func attemptEmail(ctx context.Context, email *models.EmailMessage, policy EmailPolicy) error {
+	attempts := email.Attempts + 1
+	sendErr := sendEmail(ctx, policy.SMTP, email)
+	metrics.TaskExecutionsTotal.WithLabelValues("email", metrics.Result(sendErr)).Inc()
+	if sendErr != nil {
+		slog.WarnContext(ctx, "邮件发送失败", "email_id", email.EmailID, "template", email.Template,
+			"attempts", attempts, "error", sendErr)
+	}
+
+	updates := emailAttemptUpdates(attempts, sendErr, policy, time.Now())
+	return gormDB(ctx).Model(&models.EmailMessage{}).Where("email_id = ?", email.EmailID).Updates(updates).Error
+}
+
+// emailAttemptUpdates 返回第 attempts 次发送后需要写入发件箱的字段：成功时标记为已发送，
+// 失败且达到最大尝试次数时标记为失败，否则按指数退避安排下一次重试
+func emailAttemptUpdates(attempts uint, sendErr error, policy EmailPolicy, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"last_attempt_at": now,
+	}
+	switch {
+	case sendErr == nil:
+		updates["status"] = models.EmailSent
+		updates["sent_at"] = now
+		updates["last_error"] = nil
+	case int(attempts) >= policy.MaxAttempts:
+		updates["status"] = models.EmailFailed
+		updates["last_error"] = truncateRunes(sendErr.Error(), maxEmailError)
+	default:
+		updates["next_attempt_at"] = now.Add(retryDelay(policy.RetryBase, policy.RetryMax, attempts))
+		updates["last_error"] = truncateRunes(sendErr.Error(), maxEmailError)
+	}
+	return updates
+}
+
+// sendEmail 通过 SMTP 发送发件箱中的一封邮件
+func sendEmail(ctx context.Context, cfg utils.SMTPConfig, email *models.EmailMessage) (err error) {
+	_, span := startSpan(ctx, "sendEmail")
+	defer endSpan(span, &err)
+
+	return utils.SendMail(cfg, &utils.MailMessage{
+		To:       email.ToAddress,
+		Subject:  email.Subject,
+		TextBody: email.TextBody,
+		HTMLBody: email.HTMLBody,
+	})
+}
+
+// GetEmailOutbox 分页查询发件箱，按创建时间倒序排列
+func GetEmailOutbox(ctx context.Context, page, pageSize int, filter EmailOutboxFilter) (_ *PaginatedEmailOutboxResult, err error) {
+	ctx, span := startSpan(ctx, "GetEmailOutbox")
+	defer endSpan(span, &err)
+
+	query := func() *gorm.DB {
+		q := gormDB(ctx).Model(&models.EmailMessage{})
+		if filter.Status != "" {
+			q = q.Where("status = ?", filter.Status)
+		}
+		if filter.Template != "" {
+			q = q.Where("template = ?", filter.Template)
+		}
+		return q
+	}
+
+	var total int64
+	if err := query().Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	emails := make([]models.EmailMessage, 0)
+	err = query().
+		Order("email_id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&emails).Error
+	if err != nil {
+		return nil, err
+	}
+	return &PaginatedEmailOutboxResult{Total: total, Data: emails}, nil
+}
+
+// RetryEmail 把一封发送失败的邮件重新放回发送队列，尝试次数从零开始计算。
+// 邮件不存在时返回 404；邮件仍在等待发送时返回 ErrEmailPending；已经发送成功的邮件会再发送一次。
+func RetryEmail(ctx context.Context, id string) (_ *models.EmailMessage, err error) {
+	ctx, span := startSpan(ctx, "RetryEmail")
+	defer endSpan(span, &err)
+
+	if !emailEnabled() {
+		return nil, ErrEmailDisabled
+	}
+	var email models.EmailMessage
+	if err := gormDB(ctx).Take(&email, "email_id = ?", id).Error; err != nil {
+		return nil, asNotFound(err, "邮件")
+	}
+	if email.Status == models.EmailPending {
+		return nil, ErrEmailPending
+	}
+
+	result := gormDB(ctx).Model(&models.EmailMessage{}).
+		Where("email_id = ? AND status = ?", email.EmailID, email.Status).
+		Updates(map[string]interface{}{
+			"status":          models.EmailPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrEmailPending // 另一个请求刚刚重试了这封邮件
+	}
+	wakeEmailDispatcher()
+	if err := gormDB(ctx).Take(&email, "email_id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// SendTestEmail 向 to 同步发送一封测试邮件，不经过发件箱。
+// 未配置 SMTP 服务器时返回 ErrEmailDisabled；发送失败时返回 502，错误信息中包含 SMTP 服务器返回的原因。
+func SendTestEmail(ctx context.Context, to string) (err error) {
+	ctx, span := startSpan(ctx, "SendTestEmail")
+	defer endSpan(span, &err)
+
+	policy := currentEmailPolicy()
+	if policy.SMTP.Host == "" {
+		return ErrEmailDisabled
+	}
+	sentAt := time.Now().Format(templateDisplayTime)
+	err = sendEmail(ctx, policy.SMTP, &models.EmailMessage{
+		ToAddress: to,
+		Subject:   "[OpsBoard] 测试邮件",
+		TextBody:  "这是一封来自 OpsBoard 的测试邮件，收到说明邮件通知配置正确。\n\n发送时间：" + sentAt,
+		HTMLBody:  "<p>这是一封来自 OpsBoard 的测试邮件，收到说明邮件通知配置正确。</p><p>发送时间：" + sentAt + "</p>",
+	})
+	if err != nil {
+		return &utils.AppError{Code: utils.CodeBadGateway, Message: "发送测试邮件失败：" + err.Error(), Err: err}
+	}
+	return nil
+}
+
+// newEmail 用渲染后的模板为收件人生成一封待发送的邮件
+func newEmail(dedupeKey string, user *models.User, template string, msg *renderedMessage, now time.Time) models.EmailMessage {
+	return models.EmailMessage{
+		DedupeKey:     dedupeKey,
+		UserID:        sql.NullString{String: user.UserID.String(), Valid: true},
+		ToAddress:     user.Email.String,
+		Template:      template,
+		Subject:       truncateRunes(msg.Title, 255),
+		TextBody:      msg.Text,
+		HTMLBody:      msg.Body,
+		Status:        models.EmailPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
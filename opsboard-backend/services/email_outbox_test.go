@@ -0,0 +1,244 @@
+/**
+ * @file services/email_outbox_test.go
+ * @description 用本地的假 SMTP 服务器测试邮件发送和发件箱的重试策略。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。假服务器按预设的顺序拒绝或接受收件人，测试临时失败后按指数退避重试、
+ *     成功后标记为已发送，以及达到最大尝试次数后标记为失败。写入数据库的部分不在此测试。
+ */
+
+package services
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer 是一个只支持明文连接、不要求认证的最小 SMTP 服务器。
+// 每个连接按顺序取出 rcptReplies 中的一个回复作为 RCPT TO 的响应，用完后一律接受。
+type fakeSMTPServer struct {
+	listener net.Listener
+
+	mu          sync.Mutex
+	rcptReplies []string
+	messages    [][]byte
+}
+
+func newFakeSMTPServer(t *testing.T, rcptReplies ...string) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("无法监听本地端口: %v", err)
+	}
+	s := &fakeSMTPServer{listener: ln, rcptReplies: rcptReplies}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// config 返回连接到该服务器的 SMTP 配置
+func (s *fakeSMTPServer) config() utils.SMTPConfig {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return utils.SMTPConfig{
+		Host:     "127.0.0.1",
+		Port:     addr.Port,
+		From:     "OpsBoard <noreply@example.com>",
+		Security: utils.SMTPSecurityNone,
+		Timeout:  5 * time.Second,
+	}
+}
+
+// received 返回服务器已接受的邮件
+func (s *fakeSMTPServer) received() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.messages...)
+}
+
+func (s *fakeSMTPServer) nextRcptReply() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.rcptReplies) == 0 {
+		return "250 OK"
+	}
+	reply := s.rcptReplies[0]
+	s.rcptReplies = s.rcptReplies[1:]
+	return reply
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	tp := textproto.NewConn(conn)
+	rcptReply := s.nextRcptReply()
+	_ = tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250 localhost")
+		case "MAIL":
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			_ = tp.PrintfLine("%s", rcptReply)
+		case "DATA":
+			_ = tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data)
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 OK")
+		case "RSET", "NOOP":
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// testEmail 返回一封等待发送的测试邮件
+func testEmail() *models.EmailMessage {
+	return &models.EmailMessage{
+		EmailID:   1,
+		ToAddress: "zhangsan@example.com",
+		Template:  EmailTemplateTicketAssigned,
+		Subject:   "工单 #42 已分配给您",
+		TextBody:  "工单 #42 已分配给您",
+		HTMLBody:  "<p>工单 #42 已分配给您</p>",
+		Status:    models.EmailPending,
+	}
+}
+
+// attemptOnce 模拟发送任务处理一次到期的邮件：发送并把更新的字段写回 email
+func attemptOnce(t *testing.T, email *models.EmailMessage, policy EmailPolicy, now time.Time) (map[string]interface{}, error) {
+	t.Helper()
+	attempts := email.Attempts + 1
+	sendErr := sendEmail(t.Context(), policy.SMTP, email)
+	updates := emailAttemptUpdates(attempts, sendErr, policy, now)
+	email.Attempts = updates["attempts"].(uint)
+	if status, ok := updates["status"].(string); ok {
+		email.Status = status
+	}
+	if next, ok := updates["next_attempt_at"].(time.Time); ok {
+		email.NextAttemptAt = next
+	}
+	return updates, sendErr
+}
+
+func TestEmailOutboxRetriesThenSends(t *testing.T) {
+	server := newFakeSMTPServer(t, "451 4.3.0 Mailbox temporarily unavailable", "451 4.3.0 Mailbox temporarily unavailable")
+	policy := EmailPolicy{SMTP: server.config(), MaxAttempts: 5, RetryBase: time.Minute, RetryMax: 90 * time.Second}
+	email := testEmail()
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	// 前两次临时失败：保持 pending，重试间隔从 RetryBase 翻倍，但不超过 RetryMax
+	for i, wantDelay := range []time.Duration{time.Minute, 90 * time.Second} {
+		updates, err := attemptOnce(t, email, policy, now)
+		if err == nil {
+			t.Fatalf("第 %d 次发送应当失败", i+1)
+		}
+		if email.Status != models.EmailPending {
+			t.Errorf("第 %d 次失败后状态为 %q，期望仍为 pending", i+1, email.Status)
+		}
+		if got := email.NextAttemptAt.Sub(now); got != wantDelay {
+			t.Errorf("第 %d 次失败后的重试间隔为 %s，期望 %s", i+1, got, wantDelay)
+		}
+		if lastError, _ := updates["last_error"].(string); !strings.Contains(lastError, "451") {
+			t.Errorf("第 %d 次失败后 last_error = %q，期望包含服务器的错误码", i+1, lastError)
+		}
+	}
+	if n := len(server.received()); n != 0 {
+		t.Fatalf("收件人被拒绝时服务器不应收到邮件，实际收到 %d 封", n)
+	}
+
+	// 第三次成功：标记为已发送并清除错误
+	updates, err := attemptOnce(t, email, policy, now)
+	if err != nil {
+		t.Fatalf("第 3 次发送失败: %v", err)
+	}
+	if email.Status != models.EmailSent || email.Attempts != 3 {
+		t.Errorf("发送成功后状态为 %q、尝试次数为 %d，期望 sent 和 3", email.Status, email.Attempts)
+	}
+	if v, ok := updates["last_error"]; !ok || v != nil {
+		t.Errorf("发送成功后应清除 last_error，实际为 %v", v)
+	}
+	if _, ok := updates["next_attempt_at"]; ok {
+		t.Error("发送成功后不应再安排重试")
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("服务器收到 %d 封邮件，期望 1 封", len(messages))
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(messages[0]))
+	if err != nil {
+		t.Fatalf("服务器收到的邮件无法解析: %v", err)
+	}
+	if to, _ := msg.Header.AddressList("To"); len(to) != 1 || to[0].Address != email.ToAddress {
+		t.Errorf("To = %v，期望 %s", to, email.ToAddress)
+	}
+}
+
+func TestEmailOutboxGivesUpAfterMaxAttempts(t *testing.T) {
+	policy := EmailPolicy{MaxAttempts: 3, RetryBase: time.Minute, RetryMax: time.Hour}
+	replies := make([]string, policy.MaxAttempts)
+	for i := range replies {
+		replies[i] = "550 5.1.1 No such user"
+	}
+	policy.SMTP = newFakeSMTPServer(t, replies...).config()
+	email := testEmail()
+	now := time.Now()
+
+	for i := 1; i <= policy.MaxAttempts; i++ {
+		updates, err := attemptOnce(t, email, policy, now)
+		if err == nil {
+			t.Fatalf("第 %d 次发送应当失败", i)
+		}
+		_, retry := updates["next_attempt_at"]
+		if i < policy.MaxAttempts && (email.Status != models.EmailPending || !retry) {
+			t.Errorf("第 %d 次失败后状态为 %q（是否重试 %v），期望 pending 并安排重试", i, email.Status, retry)
+		}
+		if i == policy.MaxAttempts && (email.Status != models.EmailFailed || retry) {
+			t.Errorf("达到最大尝试次数后状态为 %q（是否重试 %v），期望 failed 且不再重试", email.Status, retry)
+		}
+	}
+}
+
+func TestEmailAttemptUpdatesTruncatesError(t *testing.T) {
+	policy := EmailPolicy{MaxAttempts: 2, RetryBase: time.Minute, RetryMax: time.Hour}
+	longErr := errors.New(strings.Repeat("错", maxEmailError*2))
+	updates := emailAttemptUpdates(1, longErr, policy, time.Now())
+	lastError, _ := updates["last_error"].(string)
+	if n := len([]rune(lastError)); n != maxEmailError {
+		t.Errorf("last_error 长度为 %d 个字符，期望截断为 %d", n, maxEmailError)
+	}
+	if got := updates["attempts"]; got != uint(1) {
+		t.Errorf("attempts = %v，期望 1", got)
+	}
+}
@@ -0,0 +1,105 @@
+/**
+ * @file services/event_bus.go
+ * @description 进程内的领域事件总线：业务服务在写操作成功后发布事件，webhook 等订阅者接收事件并各自处理。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。事件在发布者的 goroutine 中同步分发给所有订阅者，订阅者应只做轻量的工作（例如写入投递队列），
+ *     订阅者的错误只记录日志，不影响发布事件的业务操作。
+ *   - [通知中心]：新增 `ticket.commented` 事件，工单新增评论（包括内部备注）时发布，站内通知据此提醒评论中提到的用户。
+ */
+
+package services
+
+import (
+	"context"
+	"log/slog"
+	"opsboard-backend/utils"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType 是领域事件的类型
+type EventType string
+
+// 领域事件类型常量
+const (
+	EventTicketCreated      EventType = "ticket.created"
+	EventTicketUpdated      EventType = "ticket.updated"
+	EventTicketSLABreached  EventType = "ticket.sla_breached"
+	EventTicketCommented    EventType = "ticket.commented"
+	EventChangelogCompleted EventType = "changelog.completed"
+	EventTaskFinished       EventType = "task.finished"
+	EventTaskFailed         EventType = "task.failed"
+	EventServerAdded        EventType = "server.added"
+	EventServerDeleted      EventType = "server.deleted"
+	EventServerOffline      EventType = "server.offline"
+)
+
+// EventTypes 列出了所有领域事件类型
+var EventTypes = []EventType{
+	EventTicketCreated, EventTicketUpdated, EventTicketSLABreached, EventTicketCommented, EventChangelogCompleted,
+	EventTaskFinished, EventTaskFailed, EventServerAdded, EventServerDeleted, EventServerOffline,
+}
+
+// IsValidEventType 判断 t 是否为已定义的事件类型
+func IsValidEventType(t string) bool {
+	for _, e := range EventTypes {
+		if string(e) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Event 是一个领域事件，Data 为事件涉及的实体（序列化为 JSON 后发送给订阅者）
+type Event struct {
+	ID         string      `json:"id"`
+	Type       EventType   `json:"type"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	RequestID  string      `json:"requestId,omitempty"` // 触发事件的请求 ID，后台任务触发的事件为空
+	Data       interface{} `json:"data"`
+}
+
+// EventHandler 处理一个领域事件
+type EventHandler func(ctx context.Context, event Event) error
+
+var (
+	eventMu       sync.RWMutex
+	eventHandlers []namedEventHandler
+)
+
+// namedEventHandler 是带有名称的订阅者，名称只用于日志
+type namedEventHandler struct {
+	name    string
+	handler EventHandler
+}
+
+// SubscribeEvents 注册一个订阅者，之后发布的所有事件都会分发给它。应在启动时调用。
+func SubscribeEvents(name string, handler EventHandler) {
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	eventHandlers = append(eventHandlers, namedEventHandler{name: name, handler: handler})
+}
+
+// PublishEvent 发布一个领域事件。ctx 中的请求 ID 会被写入事件；
+// 订阅者使用不会被取消的 ctx，请求结束后仍可以完成处理。
+func PublishEvent(ctx context.Context, eventType EventType, data interface{}) {
+	event := Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		RequestID:  utils.RequestIDFromContext(ctx),
+		Data:       data,
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	eventMu.RLock()
+	handlers := eventHandlers
+	eventMu.RUnlock()
+	for _, h := range handlers {
+		if err := h.handler(ctx, event); err != nil {
+			slog.ErrorContext(ctx, "事件订阅者处理失败", "subscriber", h.name, "event", eventType, "event_id", event.ID, "error", err)
+		}
+	}
+}
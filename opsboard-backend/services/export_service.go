@@ -0,0 +1,54 @@
+/**
+ * @file services/export_service.go
+ * @description 提供列表导出所需的公共查询工具：逐行流式遍历查询结果，以及各列表共用的时间范围筛选。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件，抽象出 `streamQuery` 泛型函数，使服务器、更新日志、维护任务、工单和审计日志的导出都能复用“与列表相同的筛选条件 + 不分页 + 逐行读取”的逻辑。
+ */
+
+package services
+
+import (
+	"opsboard-backend/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TimeRangeFilter 定义了按日期筛选的起止条件（按天，包含结束当天）
+type TimeRangeFilter struct {
+	StartTime time.Time `form:"startTime" time_format:"2006-01-02"`
+	EndTime   time.Time `form:"endTime" time_format:"2006-01-02"`
+}
+
+// apply 将时间范围条件应用到指定列上
+func (r TimeRangeFilter) apply(query *gorm.DB, column string) *gorm.DB {
+	if !r.StartTime.IsZero() {
+		query = query.Where(column+" >= ?", r.StartTime)
+	}
+	if !r.EndTime.IsZero() {
+		query = query.Where(column+" < ?", r.EndTime.AddDate(0, 0, 1))
+	}
+	return query
+}
+
+// streamQuery 使用 `Rows()` 逐行遍历查询结果，将每一行扫描到 T 中并调用 fn。
+// 与 Find 不同，它不会一次性把全部结果加载到内存中，适合导出大量数据。
+// 如果 fn 返回错误，遍历会立即停止并返回该错误。
+func streamQuery[T any](query *gorm.DB, fn func(row *T) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		if err := database.GormDB.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,127 @@
+/**
+ * @file form_token_service.go
+ * @description 实现登录表单的“蜜罐 + 时间戳”机器人验证：签发带时间戳的表单令牌，并在登录时检查蜜罐字段、提交耗时和令牌是否已被使用。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。每个表单令牌只能使用一次（无论本次登录是否成功），已使用的 nonce 保存在内存中直到令牌过期。
+ *     多实例部署时各实例分别记录，同一令牌最多可在每个实例上各使用一次。
+ */
+
+package services
+
+import (
+	"context"
+	"opsboard-backend/utils"
+	"sync"
+	"time"
+)
+
+// HoneypotField 是登录请求中的蜜罐字段名，前端应渲染一个对用户隐藏的同名输入框
+const HoneypotField = "website"
+
+// 机器人验证失败的原因，会写入审计日志
+const (
+	BotCheckHoneypot = "honeypot"      // 蜜罐字段被填写
+	BotCheckMissing  = "missing_token" // 未携带表单令牌
+	BotCheckInvalid  = "invalid_token" // 令牌格式错误或签名不匹配
+	BotCheckTooFast  = "too_fast"      // 从渲染表单到提交的时间短于人类操作所需的最短时间
+	BotCheckExpired  = "expired_token" // 令牌已过期
+	BotCheckReplayed = "replayed"      // 令牌已被使用过
+)
+
+// FormTokenPolicy 定义了表单令牌的时间限制
+type FormTokenPolicy struct {
+	MinAge time.Duration // 签发后至少经过多久才允许提交
+	MaxAge time.Duration // 令牌的有效期
+}
+
+// DefaultFormTokenPolicy 是未配置时使用的默认策略
+var DefaultFormTokenPolicy = FormTokenPolicy{
+	MinAge: 2 * time.Second,
+	MaxAge: time.Hour,
+}
+
+var (
+	formTokenMu     sync.Mutex
+	formTokenPolicy = DefaultFormTokenPolicy
+	usedFormTokens  = make(map[string]time.Time) // nonce -> 令牌过期时间
+	lastFormSweep   time.Time
+)
+
+// ConfigureFormTokens 设置表单令牌策略，应在启动时调用一次
+func ConfigureFormTokens(policy FormTokenPolicy) {
+	formTokenMu.Lock()
+	defer formTokenMu.Unlock()
+	formTokenPolicy = policy
+}
+
+// FormToken 是签发给前端的表单令牌
+type FormToken struct {
+	Token         string    `json:"formToken"`
+	HoneypotField string    `json:"honeypotField"`
+	IssuedAt      time.Time `json:"issuedAt"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+}
+
+// IssueFormToken 签发一个新的表单令牌，前端应在渲染登录表单时获取
+func IssueFormToken() (*FormToken, error) {
+	formTokenMu.Lock()
+	policy := formTokenPolicy
+	formTokenMu.Unlock()
+
+	now := time.Now()
+	token, _, err := utils.GenerateFormToken(now)
+	if err != nil {
+		return nil, err
+	}
+	return &FormToken{
+		Token:         token,
+		HoneypotField: HoneypotField,
+		IssuedAt:      now,
+		ExpiresAt:     now.Add(policy.MaxAge),
+	}, nil
+}
+
+// CheckLoginForm 检查一次登录提交是否像是机器人所为，通过时返回空字符串，否则返回失败原因（BotCheck* 常量）。
+// 只要令牌签名有效，就会将其标记为已使用。
+func CheckLoginForm(ctx context.Context, honeypot, token string) (reason string) {
+	_, span := startSpan(ctx, "CheckLoginForm")
+	defer span.End()
+
+	if honeypot != "" {
+		return BotCheckHoneypot
+	}
+	if token == "" {
+		return BotCheckMissing
+	}
+	nonce, issuedAt, err := utils.ParseFormToken(token)
+	if err != nil {
+		return BotCheckInvalid
+	}
+
+	formTokenMu.Lock()
+	defer formTokenMu.Unlock()
+
+	now := time.Now()
+	if now.Sub(lastFormSweep) >= sweepInterval {
+		for n, expires := range usedFormTokens {
+			if now.After(expires) {
+				delete(usedFormTokens, n)
+			}
+		}
+		lastFormSweep = now
+	}
+
+	expiresAt := issuedAt.Add(formTokenPolicy.MaxAge)
+	if !now.Before(expiresAt) {
+		return BotCheckExpired
+	}
+	if _, used := usedFormTokens[nonce]; used {
+		return BotCheckReplayed
+	}
+	usedFormTokens[nonce] = expiresAt
+
+	if now.Sub(issuedAt) < formTokenPolicy.MinAge {
+		return BotCheckTooFast
+	}
+	return ""
+}
@@ -0,0 +1,243 @@
+/**
+ * @file jwt_key_service.go
+ * @description 负责 JWT 签名密钥的生成、持久化和定期轮换，并把当前的签名密钥和校验密钥加载到 `utils`。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [校验]：新密钥的提前发布时长必须大于 JWKS 缓存时长与密钥重新加载间隔之和，而不只是大于重新加载间隔。
+ */
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"opsboard-backend/metrics"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JWTKeyPolicy 定义了签名密钥的算法和轮换节奏
+type JWTKeyPolicy struct {
+	Algorithm        string        // 新密钥的签名算法，已有密钥保持原算法直到被轮换掉
+	RotationInterval time.Duration // 每把密钥用于签名的时长
+	PrePublish       time.Duration // 新密钥在开始签名前提前多久发布
+}
+
+// DefaultJWTKeyPolicy 是未配置时使用的默认策略
+var DefaultJWTKeyPolicy = JWTKeyPolicy{
+	Algorithm:        utils.JWTAlgRS256,
+	RotationInterval: 30 * 24 * time.Hour,
+	PrePublish:       time.Hour,
+}
+
+// jwtKeyReloadInterval 是各实例检查轮换并重新加载密钥的间隔。PrePublish 必须超过它与 JWKS 缓存时长之和，
+// 否则新密钥开始签名时其他实例可能还没有加载它，或者其他服务缓存的 JWKS 中还没有它
+const jwtKeyReloadInterval = time.Minute
+
+// jwtKeyClockSkew 是删除旧密钥时额外保留的时长，容忍各实例之间的时钟偏差
+const jwtKeyClockSkew = time.Minute
+
+// jwtKeyLockName 是轮换密钥时使用的数据库命名锁
+const jwtKeyLockName = "opsboard_jwt_key_rotation"
+
+var (
+	jwtKeyMu     sync.Mutex
+	jwtKeyPolicy = DefaultJWTKeyPolicy
+	jwtKeyCache  = make(map[string]*utils.JWTKey) // kid -> 已解析的密钥，避免每次加载都重新解析 PEM
+)
+
+// ConfigureJWTKeys 校验并设置签名密钥策略，应在启动时调用一次
+func ConfigureJWTKeys(policy JWTKeyPolicy) error {
+	if !utils.IsValidJWTAlgorithm(policy.Algorithm) {
+		return fmt.Errorf("不支持的 JWT 签名算法: %s", policy.Algorithm)
+	}
+	if minimum := utils.JWKSMaxAge + jwtKeyReloadInterval; policy.PrePublish <= minimum {
+		return fmt.Errorf("JWT 新密钥的提前发布时长必须大于 %s（JWKS 缓存时长 %s 加上密钥重新加载间隔 %s）",
+			minimum, utils.JWKSMaxAge, jwtKeyReloadInterval)
+	}
+	if policy.RotationInterval <= policy.PrePublish {
+		return errors.New("JWT 密钥的轮换周期必须大于提前发布时长")
+	}
+
+	jwtKeyMu.Lock()
+	defer jwtKeyMu.Unlock()
+	jwtKeyPolicy = policy
+	return nil
+}
+
+// StartJWTKeyRotation 启动定期检查轮换并重新加载密钥的后台任务。应在 RotateJWTKeys 首次成功后调用一次。
+func StartJWTKeyRotation() {
+	go func() {
+		ticker := time.NewTicker(jwtKeyReloadInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			err := RotateJWTKeys(context.Background())
+			metrics.TaskExecutionsTotal.WithLabelValues("jwt_key_rotation", metrics.Result(err)).Inc()
+			if err != nil {
+				// 轮换失败时继续使用已加载的密钥，下次再试
+				slog.Error("轮换 JWT 签名密钥失败", "error", err)
+			}
+		}
+	}()
+}
+
+// RotateJWTKeys 为每个密钥组按需生成新密钥、删除不再需要的旧密钥，然后重新加载所有密钥
+func RotateJWTKeys(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "RotateJWTKeys")
+	defer endSpan(span, &err)
+
+	jwtKeyMu.Lock()
+	policy := jwtKeyPolicy
+	jwtKeyMu.Unlock()
+
+	err = gormDB(ctx).Connection(func(conn *gorm.DB) error {
+		// GET_LOCK 绑定在数据库连接上，加锁、轮换和解锁必须使用同一个连接
+		var locked sql.NullInt64
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", jwtKeyLockName, 10).Row().Scan(&locked); err != nil {
+			return err
+		}
+		if locked.Int64 != 1 {
+			return errors.New("等待 JWT 密钥轮换锁超时")
+		}
+		defer conn.Exec("SELECT RELEASE_LOCK(?)", jwtKeyLockName)
+
+		now := time.Now()
+		for _, set := range utils.JWTKeySets {
+			if err := rotateJWTKeySet(conn, set, policy, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return loadJWTKeys(ctx)
+}
+
+// rotateJWTKeySet 在需要时为一个密钥组生成下一把密钥，并删除已过保留期的旧密钥
+func rotateJWTKeySet(db *gorm.DB, set string, policy JWTKeyPolicy, now time.Time) error {
+	var keys []models.JWTSigningKey
+	if err := db.Where("key_set = ?", set).Order("activates_at").Find(&keys).Error; err != nil {
+		return err
+	}
+
+	var expired []string
+	for i := range keys {
+		if jwtKeyExpired(keys, i, now) {
+			expired = append(expired, keys[i].KeyID)
+		}
+	}
+	if len(expired) > 0 {
+		if err := db.Where("key_id IN ?", expired).Delete(&models.JWTSigningKey{}).Error; err != nil {
+			return err
+		}
+		slog.Info("已删除过期的 JWT 签名密钥", "key_set", set, "kids", expired)
+	}
+
+	var activatesAt time.Time
+	switch {
+	case len(keys) == 0:
+		// 第一次启动时没有可发布的旧密钥，新密钥立即生效
+		activatesAt = now
+	default:
+		latest := keys[len(keys)-1]
+		if latest.ActivatesAt.After(now) || now.Before(latest.ActivatesAt.Add(policy.RotationInterval-policy.PrePublish)) {
+			return nil // 下一把密钥已发布，或者还没到发布时间
+		}
+		activatesAt = latest.ActivatesAt.Add(policy.RotationInterval)
+		if earliest := now.Add(policy.PrePublish); activatesAt.Before(earliest) {
+			// 轮换任务停止过一段时间，新密钥仍然需要完整的提前发布时长
+			activatesAt = earliest
+		}
+	}
+
+	key, err := utils.GenerateJWTKey(policy.Algorithm)
+	if err != nil {
+		return err
+	}
+	pemData, err := utils.EncodeJWTPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	record := models.JWTSigningKey{
+		KeyID:       key.ID,
+		KeySet:      set,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  pemData,
+		ActivatesAt: activatesAt,
+		CreatedAt:   now,
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return err
+	}
+	slog.Info("已生成新的 JWT 签名密钥", "key_set", set, "kid", key.ID, "algorithm", key.Algorithm, "activates_at", activatesAt)
+	return nil
+}
+
+// loadJWTKeys 从数据库加载所有密钥，计算每个密钥组当前的签名密钥和校验密钥并写入 utils
+func loadJWTKeys(ctx context.Context) error {
+	var records []models.JWTSigningKey
+	if err := gormDB(ctx).Order("activates_at").Find(&records).Error; err != nil {
+		return err
+	}
+
+	bySet := make(map[string][]models.JWTSigningKey)
+	for _, r := range records {
+		bySet[r.KeySet] = append(bySet[r.KeySet], r)
+	}
+
+	jwtKeyMu.Lock()
+	defer jwtKeyMu.Unlock()
+
+	now := time.Now()
+	cache := make(map[string]*utils.JWTKey, len(records))
+	for _, set := range utils.JWTKeySets {
+		keys := bySet[set]
+		var signing *utils.JWTKey
+		var verification []*utils.JWTKey
+		for i, r := range keys {
+			if jwtKeyExpired(keys, i, now) {
+				continue
+			}
+			key, ok := jwtKeyCache[r.KeyID]
+			if !ok {
+				parsed, err := utils.ParseJWTKey(r.KeyID, r.Algorithm, r.PrivateKey)
+				if err != nil {
+					return err
+				}
+				key = parsed
+			}
+			cache[r.KeyID] = key
+			verification = append(verification, key)
+			if !r.ActivatesAt.After(now) {
+				signing = key
+			}
+		}
+		if signing == nil {
+			return fmt.Errorf("JWT 密钥组 %s 没有可用的签名密钥", set)
+		}
+		utils.SetJWTKeys(set, signing, verification)
+	}
+	jwtKeyCache = cache
+	return nil
+}
+
+// jwtKeyExpired 判断按生效时间排序的 keys 中第 i 把密钥是否可以删除：
+// 它的下一把密钥已生效，且从那时起已经超过了该密钥组令牌的最长有效期
+func jwtKeyExpired(keys []models.JWTSigningKey, i int, now time.Time) bool {
+	if i+1 >= len(keys) {
+		return false
+	}
+	replacedAt := keys[i+1].ActivatesAt
+	if replacedAt.After(now) {
+		return false
+	}
+	return now.After(replacedAt.Add(utils.JWTKeySetMaxTTL(keys[i].KeySet) + jwtKeyClockSkew))
+}
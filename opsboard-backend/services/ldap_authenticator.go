@@ -0,0 +1,210 @@
+/**
+ * @file ldap_authenticator.go
+ * @description 实现基于 LDAP / Active Directory 的认证源：使用服务账号搜索用户条目，再以用户自己的 DN 和密码绑定校验密码，
+ * 按组成员关系映射角色，并即时开通本地账户。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [重构]：组角色映射改用与 OIDC 共用的 `GroupRole` 和 `ParseGroupRoles`。
+ */
+
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"opsboard-backend/models"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConfig 是 LDAP 认证源的配置
+type LDAPConfig struct {
+	URL                string        // 例如 ldap://ldap.example.com:389 或 ldaps://ad.example.com:636
+	StartTLS           bool          // 对 ldap:// 连接执行 StartTLS
+	InsecureSkipVerify bool          // 跳过服务端证书校验，仅用于测试环境
+	BindDN             string        // 用于搜索用户的服务账号，为空时匿名搜索
+	BindPassword       string        // 服务账号的密码
+	BaseDN             string        // 搜索用户的起点
+	UserFilter         string        // 搜索用户的过滤器，%s 会被替换为转义后的用户名，例如 (uid=%s) 或 (sAMAccountName=%s)
+	NicknameAttribute  string        // 作为昵称的属性，例如 displayName
+	GroupAttribute     string        // 用户条目上记录所属组 DN 的属性，例如 memberOf
+	GroupRoles         []GroupRole   // 组 DN 到角色的映射，按顺序匹配
+	DefaultRole        string        // 不属于任何映射组时使用的角色，为空表示拒绝登录
+	Timeout            time.Duration // 连接和每个操作的超时时间
+}
+
+// ldapConn 是 LDAPAuthenticator 用到的 LDAP 连接操作，*ldap.Conn 实现了该接口
+type ldapConn interface {
+	Bind(username, password string) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// LDAPAuthenticator 是基于 LDAP 的 Authenticator
+type LDAPAuthenticator struct {
+	cfg        LDAPConfig
+	groupRoles []parsedGroupRole
+	dial       func(ctx context.Context) (ldapConn, error)
+}
+
+// parsedGroupRole 是解析过 DN 的 GroupRole，用于忽略大小写和空白差异比较 DN
+type parsedGroupRole struct {
+	dn   *ldap.DN
+	role string
+}
+
+// NewLDAPAuthenticator 校验配置并创建 LDAP 认证源
+func NewLDAPAuthenticator(cfg LDAPConfig) (*LDAPAuthenticator, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, errors.New("LDAP 认证需要配置服务器地址和 Base DN")
+	}
+	if !strings.Contains(cfg.UserFilter, "%s") {
+		return nil, errors.New("LDAP 用户过滤器必须包含 %s")
+	}
+	if cfg.DefaultRole != "" && !models.IsValidRole(cfg.DefaultRole) {
+		return nil, fmt.Errorf("未知的 LDAP 默认角色: %s", cfg.DefaultRole)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	a := &LDAPAuthenticator{cfg: cfg}
+	for _, gr := range cfg.GroupRoles {
+		if !models.IsValidRole(gr.Role) {
+			return nil, fmt.Errorf("未知的 LDAP 组映射角色: %s", gr.Role)
+		}
+		dn, err := ldap.ParseDN(gr.Group)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 LDAP 组 DN %q: %w", gr.Group, err)
+		}
+		a.groupRoles = append(a.groupRoles, parsedGroupRole{dn: dn, role: gr.Role})
+	}
+	a.dial = a.dialServer
+	return a, nil
+}
+
+// Name 实现 Authenticator
+func (a *LDAPAuthenticator) Name() string {
+	return models.AuthSourceLDAP
+}
+
+// Authenticate 实现 Authenticator
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "LDAPAuthenticate")
+	defer endSpan(span, &err)
+
+	// 密码为空的简单绑定在许多服务器上会被当作匿名绑定而“成功”，必须提前拒绝
+	if password == "" {
+		return nil, &AuthFailure{Reason: AuthFailureInvalidPassword}
+	}
+
+	conn, err := a.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("连接 LDAP 服务器失败: %w", err)
+	}
+	defer conn.Close()
+
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP 服务账号绑定失败: %w", err)
+		}
+	}
+
+	entry, err := a.searchUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, &AuthFailure{Reason: AuthFailureInvalidPassword}
+		}
+		return nil, fmt.Errorf("LDAP 用户绑定失败: %w", err)
+	}
+
+	role := a.mapRole(entry.GetEqualFoldAttributeValues(a.cfg.GroupAttribute))
+	if role == "" {
+		return nil, &AuthFailure{Reason: AuthFailureNoRole}
+	}
+	nickname := entry.GetEqualFoldAttributeValue(a.cfg.NicknameAttribute)
+	if nickname == "" {
+		nickname = username
+	}
+
+	return ProvisionExternalUser(ctx, models.AuthSourceLDAP, username, nickname, role)
+}
+
+// searchUser 搜索用户名对应的唯一条目，找不到或不唯一时返回 AuthFailureUnknownUser
+func (a *LDAPAuthenticator) searchUser(conn ldapConn, username string) (*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, // 只需要知道结果是否唯一
+		int(a.cfg.Timeout.Seconds()),
+		false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{a.cfg.NicknameAttribute, a.cfg.GroupAttribute},
+		nil,
+	)
+	result, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, &AuthFailure{Reason: AuthFailureUnknownUser}
+		}
+		return nil, fmt.Errorf("搜索 LDAP 用户失败: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, &AuthFailure{Reason: AuthFailureUnknownUser}
+	}
+	return result.Entries[0], nil
+}
+
+// mapRole 按配置顺序返回第一个匹配的组对应的角色，都不匹配时返回默认角色
+func (a *LDAPAuthenticator) mapRole(groups []string) string {
+	parsed := make([]*ldap.DN, 0, len(groups))
+	for _, g := range groups {
+		if dn, err := ldap.ParseDN(g); err == nil {
+			parsed = append(parsed, dn)
+		}
+	}
+	for _, gr := range a.groupRoles {
+		for _, dn := range parsed {
+			if gr.dn.EqualFold(dn) {
+				return gr.role
+			}
+		}
+	}
+	return a.cfg.DefaultRole
+}
+
+// dialServer 连接配置中的 LDAP 服务器，按需执行 StartTLS
+func (a *LDAPAuthenticator) dialServer(ctx context.Context) (ldapConn, error) {
+	u, err := url.Parse(a.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: a.cfg.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(a.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: a.cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+
+	if a.cfg.StartTLS && u.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,436 @@
+/**
+ * @file services/ldap_authenticator_test.go
+ * @description 用本地的假 LDAP 服务器测试 LDAP 认证源。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。假服务器实现简单绑定和搜索，测试用户名在过滤器中被转义、密码错误和用户不存在或不唯一时的
+ *     失败原因，以及组 DN 到角色的映射。认证通过后开通本地账户需要数据库，不在此测试。
+ */
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"opsboard-backend/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	testLDAPBaseDN       = "dc=example,dc=com"
+	testLDAPBindDN       = "cn=svc,dc=example,dc=com"
+	testLDAPBindPassword = "svc-secret"
+	testLDAPAdminsDN     = "cn=ops-admins,ou=groups,dc=example,dc=com"
+	testLDAPOpsDN        = "cn=ops,ou=groups,dc=example,dc=com"
+)
+
+// fakeLDAPEntry 是假服务器目录中的一个条目
+type fakeLDAPEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+func (e fakeLDAPEntry) values(name string) []string {
+	for k, v := range e.attributes {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// fakeLDAPServer 是一个只支持明文连接上的简单绑定和搜索的最小 LDAP 服务器。
+// 过滤器只支持与、或、相等和存在判断，足以覆盖认证源使用的用户过滤器。
+type fakeLDAPServer struct {
+	listener net.Listener
+	entries  []fakeLDAPEntry
+
+	mu      sync.Mutex
+	conns   int
+	binds   []string
+	filters []string
+}
+
+func newFakeLDAPServer(t *testing.T, entries ...fakeLDAPEntry) *fakeLDAPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("无法监听本地端口: %v", err)
+	}
+	s := &fakeLDAPServer{listener: ln, entries: entries}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// config 返回连接到该服务器的 LDAP 配置
+func (s *fakeLDAPServer) config() LDAPConfig {
+	return LDAPConfig{
+		URL:               "ldap://" + s.listener.Addr().String(),
+		BindDN:            testLDAPBindDN,
+		BindPassword:      testLDAPBindPassword,
+		BaseDN:            testLDAPBaseDN,
+		UserFilter:        "(&(objectClass=person)(uid=%s))",
+		NicknameAttribute: "displayName",
+		GroupAttribute:    "memberOf",
+		GroupRoles: []GroupRole{
+			{Group: testLDAPAdminsDN, Role: models.RoleAdmin},
+			{Group: testLDAPOpsDN, Role: models.RoleUser},
+		},
+		Timeout: 5 * time.Second,
+	}
+}
+
+// recorded 返回服务器收到的连接数、绑定的 DN 和搜索过滤器
+func (s *fakeLDAPServer) recorded() (int, []string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns, append([]string(nil), s.binds...), append([]string(nil), s.filters...)
+}
+
+func (s *fakeLDAPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	s.mu.Lock()
+	s.conns++
+	s.mu.Unlock()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			name := ber.DecodeString(op.Children[1].Data.Bytes())
+			password := ber.DecodeString(op.Children[2].Data.Bytes())
+			s.mu.Lock()
+			s.binds = append(s.binds, name)
+			s.mu.Unlock()
+
+			code := ldap.LDAPResultInvalidCredentials
+			if s.checkPassword(name, password) {
+				code = ldap.LDAPResultSuccess
+			}
+			conn.Write(ldapResponse(messageID, ldapResult(ldap.ApplicationBindResponse, code)).Bytes())
+
+		case ldap.ApplicationSearchRequest:
+			filter := op.Children[6]
+			decompiled, err := ldap.DecompileFilter(filter)
+			if err != nil {
+				conn.Write(ldapResponse(messageID, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultFilterError)).Bytes())
+				continue
+			}
+			s.mu.Lock()
+			s.filters = append(s.filters, decompiled)
+			s.mu.Unlock()
+
+			var attributes []string
+			for _, a := range op.Children[7].Children {
+				attributes = append(attributes, ber.DecodeString(a.Data.Bytes()))
+			}
+			for _, e := range s.entries {
+				if matchLDAPFilter(filter, e) {
+					conn.Write(ldapResponse(messageID, ldapSearchEntry(e, attributes)).Bytes())
+				}
+			}
+			conn.Write(ldapResponse(messageID, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess)).Bytes())
+
+		default:
+			return
+		}
+	}
+}
+
+// checkPassword 校验服务账号或目录中条目的密码，空密码一律拒绝
+func (s *fakeLDAPServer) checkPassword(dn, password string) bool {
+	if password == "" {
+		return false
+	}
+	if dn == testLDAPBindDN {
+		return password == testLDAPBindPassword
+	}
+	for _, e := range s.entries {
+		if e.dn == dn {
+			return e.password == password
+		}
+	}
+	return false
+}
+
+func matchLDAPFilter(f *ber.Packet, e fakeLDAPEntry) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, c := range f.Children {
+			if !matchLDAPFilter(c, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range f.Children {
+			if matchLDAPFilter(c, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterEqualityMatch:
+		want := ber.DecodeString(f.Children[1].Data.Bytes())
+		for _, v := range e.values(ber.DecodeString(f.Children[0].Data.Bytes())) {
+			if strings.EqualFold(v, want) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(e.values(ber.DecodeString(f.Data.Bytes()))) > 0
+	default:
+		return false
+	}
+}
+
+func ldapResponse(messageID interface{}, op *ber.Packet) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	p.AppendChild(op)
+	return p
+}
+
+func ldapResult(tag ber.Tag, code int) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return p
+}
+
+func ldapSearchEntry(e fakeLDAPEntry, attributes []string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "DN"))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, name := range attributes {
+		values := e.values(name)
+		if len(values) == 0 {
+			continue
+		}
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+	p.AppendChild(attrs)
+	return p
+}
+
+func testLDAPPerson(uid, ou, password string, groups ...string) fakeLDAPEntry {
+	return fakeLDAPEntry{
+		dn:       fmt.Sprintf("uid=%s,ou=%s,%s", uid, ou, testLDAPBaseDN),
+		password: password,
+		attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {uid},
+			"displayName": {strings.ToUpper(uid)},
+			"memberOf":    groups,
+		},
+	}
+}
+
+func testLDAPDirectory() []fakeLDAPEntry {
+	return []fakeLDAPEntry{
+		testLDAPPerson("alice", "people", "alice-pw", testLDAPAdminsDN),
+		testLDAPPerson("bob", "people", "bob-pw", testLDAPOpsDN),
+		testLDAPPerson("bob", "contractors", "bob-pw", testLDAPOpsDN),
+		testLDAPPerson("carol", "people", "carol-pw", "cn=finance,ou=groups,dc=example,dc=com"),
+		testLDAPPerson("dave", "people", "dave-pw", "CN=Ops-Admins, OU=Groups, DC=Example, DC=com"),
+		testLDAPPerson("erin", "people", "erin-pw", testLDAPOpsDN, testLDAPAdminsDN),
+		testLDAPPerson("frank", "people", "frank-pw", "not a dn", testLDAPOpsDN),
+	}
+}
+
+func newTestLDAPAuthenticator(t *testing.T, cfg LDAPConfig) *LDAPAuthenticator {
+	t.Helper()
+	a, err := NewLDAPAuthenticator(cfg)
+	if err != nil {
+		t.Fatalf("NewLDAPAuthenticator 返回错误: %v", err)
+	}
+	return a
+}
+
+func authFailureReason(err error) string {
+	var failure *AuthFailure
+	if errors.As(err, &failure) {
+		return failure.Reason
+	}
+	return ""
+}
+
+func TestLDAPAuthenticateEscapesUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		filter   string
+	}{
+		{"*", `(&(objectClass=person)(uid=\2a))`},
+		{"alice)(uid=*", `(&(objectClass=person)(uid=alice\29\28uid=\2a))`},
+		{"alice)(|(objectClass=*", `(&(objectClass=person)(uid=alice\29\28|\28objectClass=\2a))`},
+		{`alice\`, `(&(objectClass=person)(uid=alice\5c))`},
+		{"alice\x00", `(&(objectClass=person)(uid=alice\00))`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			s := newFakeLDAPServer(t, testLDAPDirectory()...)
+			a := newTestLDAPAuthenticator(t, s.config())
+
+			_, err := a.Authenticate(context.Background(), tt.username, "alice-pw")
+			if reason := authFailureReason(err); reason != AuthFailureUnknownUser {
+				t.Fatalf("Authenticate(%q) 的错误 = %v，期望失败原因 %s", tt.username, err, AuthFailureUnknownUser)
+			}
+			_, binds, filters := s.recorded()
+			if len(filters) != 1 || filters[0] != tt.filter {
+				t.Errorf("搜索过滤器 = %q，期望 [%q]", filters, tt.filter)
+			}
+			// 用户名被当作过滤器注入时会匹配到 alice 并以她的 DN 绑定
+			if len(binds) != 1 || binds[0] != testLDAPBindDN {
+				t.Errorf("绑定的 DN = %q，期望只有服务账号", binds)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticateFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		reason   string
+	}{
+		{"密码错误", "alice", "wrong", AuthFailureInvalidPassword},
+		{"空密码", "alice", "", AuthFailureInvalidPassword},
+		{"用户不存在", "mallory", "mallory-pw", AuthFailureUnknownUser},
+		{"用户名不唯一", "bob", "bob-pw", AuthFailureUnknownUser},
+		{"不属于映射的组", "carol", "carol-pw", AuthFailureNoRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeLDAPServer(t, testLDAPDirectory()...)
+			a := newTestLDAPAuthenticator(t, s.config())
+
+			_, err := a.Authenticate(context.Background(), tt.username, tt.password)
+			if reason := authFailureReason(err); reason != tt.reason {
+				t.Errorf("Authenticate(%q) 的错误 = %v，期望失败原因 %s", tt.username, err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticateEmptyPasswordSkipsServer(t *testing.T) {
+	s := newFakeLDAPServer(t, testLDAPDirectory()...)
+	a := newTestLDAPAuthenticator(t, s.config())
+
+	if _, err := a.Authenticate(context.Background(), "alice", ""); err == nil {
+		t.Fatal("空密码应被拒绝")
+	}
+	if conns, _, _ := s.recorded(); conns != 0 {
+		t.Errorf("空密码时连接了 LDAP 服务器 %d 次，期望不连接", conns)
+	}
+}
+
+func TestLDAPAuthenticateServiceBindFailure(t *testing.T) {
+	s := newFakeLDAPServer(t, testLDAPDirectory()...)
+	cfg := s.config()
+	cfg.BindPassword = "wrong"
+	a := newTestLDAPAuthenticator(t, cfg)
+
+	_, err := a.Authenticate(context.Background(), "alice", "alice-pw")
+	if err == nil {
+		t.Fatal("服务账号绑定失败时应返回错误")
+	}
+	// 服务账号配置错误是认证源故障，不能当作用户密码错误计入登录失败
+	if reason := authFailureReason(err); reason != "" {
+		t.Errorf("服务账号绑定失败被当作认证失败 %s", reason)
+	}
+}
+
+func TestLDAPGroupRoleMapping(t *testing.T) {
+	s := newFakeLDAPServer(t, testLDAPDirectory()...)
+
+	tests := []struct {
+		username    string
+		defaultRole string
+		role        string
+	}{
+		{"alice", "", models.RoleAdmin},
+		{"dave", "", models.RoleAdmin}, // 组 DN 忽略大小写和空白差异
+		{"erin", "", models.RoleAdmin}, // 属于多个组时按配置顺序取第一个
+		{"frank", "", models.RoleUser}, // 无法解析的组 DN 被忽略
+		{"carol", "", ""},              // 不属于映射的组且没有默认角色
+		{"carol", models.RoleCustomer, models.RoleCustomer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username+"/"+tt.defaultRole, func(t *testing.T) {
+			cfg := s.config()
+			cfg.DefaultRole = tt.defaultRole
+			a := newTestLDAPAuthenticator(t, cfg)
+
+			conn, err := a.dial(context.Background())
+			if err != nil {
+				t.Fatalf("连接假 LDAP 服务器失败: %v", err)
+			}
+			defer conn.Close()
+			if err := conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+				t.Fatalf("服务账号绑定失败: %v", err)
+			}
+			entry, err := a.searchUser(conn, tt.username)
+			if err != nil {
+				t.Fatalf("searchUser(%q) 返回错误: %v", tt.username, err)
+			}
+			if role := a.mapRole(entry.GetEqualFoldAttributeValues(cfg.GroupAttribute)); role != tt.role {
+				t.Errorf("%s 映射到角色 %q，期望 %q", tt.username, role, tt.role)
+			}
+		})
+	}
+}
+
+func TestNewLDAPAuthenticatorRejectsInvalidConfig(t *testing.T) {
+	valid := LDAPConfig{URL: "ldap://127.0.0.1:389", BaseDN: testLDAPBaseDN, UserFilter: "(uid=%s)"}
+	tests := []struct {
+		name   string
+		modify func(*LDAPConfig)
+	}{
+		{"缺少 Base DN", func(c *LDAPConfig) { c.BaseDN = "" }},
+		{"过滤器没有占位符", func(c *LDAPConfig) { c.UserFilter = "(uid=admin)" }},
+		{"未知的默认角色", func(c *LDAPConfig) { c.DefaultRole = "ROOT" }},
+		{"未知的组映射角色", func(c *LDAPConfig) { c.GroupRoles = []GroupRole{{Group: testLDAPOpsDN, Role: "ROOT"}} }},
+		{"无效的组 DN", func(c *LDAPConfig) { c.GroupRoles = []GroupRole{{Group: "ops", Role: models.RoleUser}} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			if _, err := NewLDAPAuthenticator(cfg); err == nil {
+				t.Error("NewLDAPAuthenticator 应拒绝该配置")
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+/**
+ * @file login_attempt_store.go
+ * @description 定义登录失败计数的存储接口 `LoginAttemptStore`，并提供内存和数据库两种实现。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。单实例部署默认使用内存存储；多实例部署可以改用数据库存储（`login_attempts` 表），
+ *     使各实例共享同一份失败计数和锁定状态，也可以通过 `ConfigureLoginGuard` 接入其他实现（例如 Redis）。
+ */
+
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttempt 是某个限制维度（用户名或客户端 IP）的登录失败状态
+type LoginAttempt struct {
+	Failures    int       // 统计窗口内连续失败的次数
+	LastFailure time.Time // 最近一次失败的时间
+	LockedUntil time.Time // 锁定截止时间，零值表示未锁定
+}
+
+// expiresAt 返回该状态不再有意义、可以被清理的时间点
+func (a LoginAttempt) expiresAt(retention time.Duration) time.Time {
+	expires := a.LastFailure.Add(retention)
+	if a.LockedUntil.After(expires) {
+		return a.LockedUntil
+	}
+	return expires
+}
+
+// LoginAttemptStore 保存登录失败状态。Update 必须是原子的：并发的失败尝试不能互相覆盖计数。
+type LoginAttemptStore interface {
+	// Get 返回 key 的当前状态，不存在时返回零值
+	Get(ctx context.Context, key string) (LoginAttempt, error)
+	// Update 在同一把锁（或事务）内读取 key 的状态、交给 fn 修改并写回，返回修改后的状态
+	Update(ctx context.Context, key string, fn func(*LoginAttempt)) (LoginAttempt, error)
+	// Delete 清除 key 的状态
+	Delete(ctx context.Context, key string) error
+}
+
+// sweepInterval 是存储清理过期状态的最小间隔
+const sweepInterval = time.Minute
+
+// memoryLoginAttemptStore 是进程内的 LoginAttemptStore 实现
+type memoryLoginAttemptStore struct {
+	mu        sync.Mutex
+	attempts  map[string]LoginAttempt
+	retention time.Duration
+	lastSweep time.Time
+}
+
+// NewMemoryLoginAttemptStore 创建一个内存存储，超过 retention 未再失败且未锁定的状态会被清理
+func NewMemoryLoginAttemptStore(retention time.Duration) LoginAttemptStore {
+	return &memoryLoginAttemptStore{attempts: make(map[string]LoginAttempt), retention: retention}
+}
+
+func (s *memoryLoginAttemptStore) Get(_ context.Context, key string) (LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[key], nil
+}
+
+func (s *memoryLoginAttemptStore) Update(_ context.Context, key string, fn func(*LoginAttempt)) (LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, a := range s.attempts {
+			if now.After(a.expiresAt(s.retention)) {
+				delete(s.attempts, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	attempt := s.attempts[key]
+	fn(&attempt)
+	s.attempts[key] = attempt
+	return attempt, nil
+}
+
+func (s *memoryLoginAttemptStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+// loginAttemptRow 对应 `login_attempts` 表的一行
+type loginAttemptRow struct {
+	AttemptKey    string     `gorm:"primaryKey;column:attempt_key"`
+	Failures      int        `gorm:"column:failures"`
+	LastFailureAt time.Time  `gorm:"column:last_failure_at"`
+	LockedUntil   *time.Time `gorm:"column:locked_until"`
+}
+
+func (loginAttemptRow) TableName() string {
+	return "login_attempts"
+}
+
+func (r loginAttemptRow) attempt() LoginAttempt {
+	a := LoginAttempt{Failures: r.Failures, LastFailure: r.LastFailureAt}
+	if r.LockedUntil != nil {
+		a.LockedUntil = *r.LockedUntil
+	}
+	return a
+}
+
+// databaseLoginAttemptStore 是基于 `login_attempts` 表的 LoginAttemptStore 实现，供多实例部署共享状态
+type databaseLoginAttemptStore struct {
+	retention time.Duration
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewDatabaseLoginAttemptStore 创建一个数据库存储，超过 retention 未再失败且未锁定的行会被定期删除
+func NewDatabaseLoginAttemptStore(retention time.Duration) LoginAttemptStore {
+	return &databaseLoginAttemptStore{retention: retention}
+}
+
+func (s *databaseLoginAttemptStore) Get(ctx context.Context, key string) (LoginAttempt, error) {
+	var row loginAttemptRow
+	err := gormDB(ctx).Where("attempt_key = ?", key).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return LoginAttempt{}, nil
+	}
+	if err != nil {
+		return LoginAttempt{}, err
+	}
+	return row.attempt(), nil
+}
+
+func (s *databaseLoginAttemptStore) Update(ctx context.Context, key string, fn func(*LoginAttempt)) (LoginAttempt, error) {
+	s.sweep(ctx)
+
+	var attempt LoginAttempt
+	err := gormDB(ctx).Transaction(func(tx *gorm.DB) error {
+		var row loginAttemptRow
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("attempt_key = ?", key).Take(&row).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		attempt = row.attempt()
+		fn(&attempt)
+
+		row = loginAttemptRow{AttemptKey: key, Failures: attempt.Failures, LastFailureAt: attempt.LastFailure}
+		if !attempt.LockedUntil.IsZero() {
+			row.LockedUntil = &attempt.LockedUntil
+		}
+		// 首次失败时行还不存在，SELECT ... FOR UPDATE 锁不住；用 upsert 避免并发插入时的主键冲突
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error
+	})
+	return attempt, err
+}
+
+func (s *databaseLoginAttemptStore) Delete(ctx context.Context, key string) error {
+	return gormDB(ctx).Where("attempt_key = ?", key).Delete(&loginAttemptRow{}).Error
+}
+
+// sweep 定期删除过期的行，避免大量不同 IP 的失败尝试使表无限增长
+func (s *databaseLoginAttemptStore) sweep(ctx context.Context) {
+	s.mu.Lock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	err := gormDB(ctx).
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-s.retention), now).
+		Delete(&loginAttemptRow{}).Error
+	if err != nil {
+		slog.WarnContext(ctx, "清理过期的登录失败记录失败", "error", err)
+	}
+}
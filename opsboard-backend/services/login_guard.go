@@ -0,0 +1,219 @@
+/**
+ * @file login_guard.go
+ * @description 实现登录暴力破解防护：按用户名和客户端 IP 分别统计失败次数，失败后指数退避，连续失败达到上限后临时锁定。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
+ */
+
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 登录限制的维度
+const (
+	LoginScopeUser = "user"
+	LoginScopeIP   = "ip"
+)
+
+// LoginPolicy 定义了登录暴力破解防护的参数
+type LoginPolicy struct {
+	MaxUserFailures int           // 同一用户名连续失败多少次后锁定
+	MaxIPFailures   int           // 同一客户端 IP 连续失败多少次后锁定
+	FailureWindow   time.Duration // 超过该时长没有新的失败，计数清零
+	LockoutDuration time.Duration // 锁定时长
+	BackoffBase     time.Duration // 第一次失败后需要等待的时长，之后每次失败翻倍
+	BackoffMax      time.Duration // 退避等待时长的上限
+}
+
+// DefaultLoginPolicy 是未配置时使用的默认策略
+var DefaultLoginPolicy = LoginPolicy{
+	MaxUserFailures: 5,
+	MaxIPFailures:   20,
+	FailureWindow:   15 * time.Minute,
+	LockoutDuration: 15 * time.Minute,
+	BackoffBase:     time.Second,
+	BackoffMax:      30 * time.Second,
+}
+
+var (
+	loginGuardMu      sync.RWMutex
+	loginPolicy       = DefaultLoginPolicy
+	loginAttemptStore = NewMemoryLoginAttemptStore(DefaultLoginPolicy.FailureWindow)
+)
+
+// ConfigureLoginGuard 设置登录防护策略和存储，应在启动时调用一次；store 为 nil 时使用内存存储
+func ConfigureLoginGuard(policy LoginPolicy, store LoginAttemptStore) {
+	if store == nil {
+		store = NewMemoryLoginAttemptStore(policy.FailureWindow)
+	}
+	loginGuardMu.Lock()
+	defer loginGuardMu.Unlock()
+	loginPolicy = policy
+	loginAttemptStore = store
+}
+
+// currentLoginGuard 返回当前的策略和存储
+func currentLoginGuard() (LoginPolicy, LoginAttemptStore) {
+	loginGuardMu.RLock()
+	defer loginGuardMu.RUnlock()
+	return loginPolicy, loginAttemptStore
+}
+
+// loginAttemptKey 返回某个维度在存储中的键；用户名不区分大小写，与数据库的排序规则保持一致
+func loginAttemptKey(scope, value string) string {
+	if scope == LoginScopeUser {
+		value = strings.ToLower(strings.TrimSpace(value))
+	}
+	return scope + ":" + value
+}
+
+// backoff 返回第 failures 次失败之后需要等待的时长
+func (p LoginPolicy) backoff(failures int) time.Duration {
+	if failures <= 0 || p.BackoffBase <= 0 {
+		return 0
+	}
+	wait := p.BackoffBase
+	for i := 1; i < failures && wait < p.BackoffMax; i++ {
+		wait *= 2
+	}
+	if p.BackoffMax > 0 && wait > p.BackoffMax {
+		wait = p.BackoffMax
+	}
+	return wait
+}
+
+// LoginBlock 描述了一次因失败过多而被拒绝的登录尝试
+type LoginBlock struct {
+	Scope      string        // 触发限制的维度：user 或 ip
+	Locked     bool          // true 表示已被锁定，false 表示仍处于退避等待期
+	RetryAfter time.Duration // 距离可以再次尝试的时长
+}
+
+// CheckLoginAttempt 在校验密码之前调用，判断该用户名或客户端 IP 当前是否允许尝试登录。
+// 需要拒绝时返回非 nil 的 LoginBlock。
+func CheckLoginAttempt(ctx context.Context, username, ip string) (_ *LoginBlock, err error) {
+	ctx, span := startSpan(ctx, "CheckLoginAttempt")
+	defer endSpan(span, &err)
+
+	policy, store := currentLoginGuard()
+	now := time.Now()
+
+	var block *LoginBlock
+	for _, scope := range []struct{ name, value string }{{LoginScopeUser, username}, {LoginScopeIP, ip}} {
+		attempt, err := store.Get(ctx, loginAttemptKey(scope.name, scope.value))
+		if err != nil {
+			return nil, err
+		}
+
+		var b *LoginBlock
+		switch {
+		case now.Before(attempt.LockedUntil):
+			b = &LoginBlock{Scope: scope.name, Locked: true, RetryAfter: attempt.LockedUntil.Sub(now)}
+		case !attempt.LockedUntil.IsZero():
+			// 锁定已经到期，下一次失败会重新开始计数，无需等待
+		case attempt.Failures > 0 && now.Sub(attempt.LastFailure) < policy.FailureWindow:
+			if next := attempt.LastFailure.Add(policy.backoff(attempt.Failures)); now.Before(next) {
+				b = &LoginBlock{Scope: scope.name, RetryAfter: next.Sub(now)}
+			}
+		}
+		if b != nil && (block == nil || b.RetryAfter > block.RetryAfter) {
+			block = b
+		}
+	}
+	return block, nil
+}
+
+// LoginFailure 描述了一次失败的登录尝试
+type LoginFailure struct {
+	Username  string
+	UserID    string // 用户名存在时为该用户的 ID，否则为空
+	IP        string
+	UserAgent string
+	Reason    string // 失败原因，例如 unknown_user、invalid_password
+}
+
+// RecordLoginFailure 记录一次失败的登录：累加用户名和 IP 两个维度的失败次数，达到上限时锁定，并写入审计日志
+func RecordLoginFailure(ctx context.Context, failure LoginFailure) (err error) {
+	ctx, span := startSpan(ctx, "RecordLoginFailure")
+	defer endSpan(span, &err)
+
+	policy, store := currentLoginGuard()
+	now := time.Now()
+
+	details := LogDetails{
+		"username":   failure.Username,
+		"ip_address": failure.IP,
+		"user_agent": failure.UserAgent,
+		"reason":     failure.Reason,
+	}
+
+	for _, scope := range []struct {
+		name, value string
+		max         int
+	}{{LoginScopeUser, failure.Username, policy.MaxUserFailures}, {LoginScopeIP, failure.IP, policy.MaxIPFailures}} {
+		lockedNow := false
+		attempt, err := store.Update(ctx, loginAttemptKey(scope.name, scope.value), func(a *LoginAttempt) {
+			// 锁定到期或超过统计窗口没有新的失败时，从头开始计数
+			if (!a.LockedUntil.IsZero() && !now.Before(a.LockedUntil)) || now.Sub(a.LastFailure) >= policy.FailureWindow {
+				*a = LoginAttempt{}
+			}
+			a.Failures++
+			a.LastFailure = now
+			if scope.max > 0 && a.Failures >= scope.max && a.LockedUntil.IsZero() {
+				a.LockedUntil = now.Add(policy.LockoutDuration)
+				lockedNow = true
+			}
+		})
+		if err != nil {
+			return err
+		}
+		details[scope.name+"_failures"] = attempt.Failures
+
+		if lockedNow {
+			CreateLog(ctx, failure.UserID, string(UserLoginLocked), Target("users", failure.UserID), LogDetails{
+				"scope":        scope.name,
+				"username":     failure.Username,
+				"ip_address":   failure.IP,
+				"failures":     attempt.Failures,
+				"locked_until": attempt.LockedUntil,
+			})
+		}
+	}
+
+	CreateLog(ctx, failure.UserID, string(UserLoginFailure), Target("users", failure.UserID), details)
+	return nil
+}
+
+// RecordLoginSuccess 在登录成功后清除该用户名的失败计数。
+// IP 维度的计数不会被清除，以免攻击者用一个自己的账号重置针对其他账号的撞库计数。
+func RecordLoginSuccess(ctx context.Context, username string) (err error) {
+	ctx, span := startSpan(ctx, "RecordLoginSuccess")
+	defer endSpan(span, &err)
+
+	_, store := currentLoginGuard()
+	return store.Delete(ctx, loginAttemptKey(LoginScopeUser, username))
+}
+
+// UnlockLogin 由管理员调用，清除指定用户名和/或客户端 IP 的失败计数和锁定状态
+func UnlockLogin(ctx context.Context, username, ip string) (err error) {
+	ctx, span := startSpan(ctx, "UnlockLogin")
+	defer endSpan(span, &err)
+
+	_, store := currentLoginGuard()
+	if username != "" {
+		if err := store.Delete(ctx, loginAttemptKey(LoginScopeUser, username)); err != nil {
+			return err
+		}
+	}
+	if ip != "" {
+		if err := store.Delete(ctx, loginAttemptKey(LoginScopeIP, ip)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
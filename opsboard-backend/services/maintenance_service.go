@@ -2,16 +2,20 @@
  * @file services/maintenance_service.go
  * @description 提供与维护任务相关的业务逻辑，使用 GORM 实现分页查询、删除和状态变更操作。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [新增功能]：添加了 `MarkTaskAsCompleted` 和 `MarkTaskAsPending` 两个函数，用于更新任务的状态和完成时间。
- *   - [SQL 更新]：`GetPaginatedMaintenanceTasks` 的查询语句现在也包含了新的 `publication_time` 和 `completion_time` 字段。
+ *   - [重复完成]：`MarkTaskAsCompleted` 只在状态确实变为“完成”时更新完成时间和版本号并发布 `task.finished` 事件，
+ *     对已完成的任务重复调用不做任何修改。
  */
 
 package services
 
 import (
-	"opsboard-backend/database"
+	"context"
+	"database/sql"
+	"log/slog"
 	"opsboard-backend/models"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // PaginatedMaintenanceTasksResult 定义了维护任务分页查询的返回结构
@@ -20,22 +24,51 @@ type PaginatedMaintenanceTasksResult struct {
 	Data  []models.MaintenanceTask `json:"data"`
 }
 
+// MaintenanceTaskFilter 定义了维护任务列表的筛选条件，所有字段均为可选。
+type MaintenanceTaskFilter struct {
+	TaskName   string `form:"taskName"`
+	TaskType   string `form:"type"`
+	Status     string `form:"status"`
+	ServerName string `form:"target"`
+	TimeRangeFilter
+}
+
+// maintenanceListQuery 构造维护任务列表的基础查询（包含服务器表 JOIN 和筛选条件）
+func maintenanceListQuery(ctx context.Context, filter MaintenanceTaskFilter) *gorm.DB {
+	query := gormDB(ctx).Model(&models.MaintenanceTask{}).
+		Joins("LEFT JOIN servers s ON maintenance.target_server_id = s.server_id").
+		Scopes(serverScope(ctx, "maintenance.target_server_id"))
+
+	if filter.TaskName != "" {
+		query = query.Where("maintenance.task_name LIKE ?", "%"+filter.TaskName+"%")
+	}
+	if filter.TaskType != "" {
+		query = query.Where("maintenance.task_type = ?", filter.TaskType)
+	}
+	if filter.Status != "" {
+		query = query.Where("maintenance.status = ?", filter.Status)
+	}
+	if filter.ServerName != "" {
+		query = query.Where("s.server_name LIKE ?", "%"+filter.ServerName+"%")
+	}
+	return filter.TimeRangeFilter.apply(query, "maintenance.created_at")
+}
+
 // GetPaginatedMaintenanceTasks 使用 GORM 从数据库中分页查询维护任务列表。
-func GetPaginatedMaintenanceTasks(page, pageSize int) (*PaginatedMaintenanceTasksResult, error) {
-	db := database.GormDB
+func GetPaginatedMaintenanceTasks(ctx context.Context, page, pageSize int, filter MaintenanceTaskFilter) (_ *PaginatedMaintenanceTasksResult, err error) {
+	ctx, span := startSpan(ctx, "GetPaginatedMaintenanceTasks")
+	defer endSpan(span, &err)
+
 	var tasks []models.MaintenanceTask
 	var total int64
 
-	query := db.Model(&models.MaintenanceTask{})
-
-	if err := query.Count(&total).Error; err != nil {
+	if err := maintenanceListQuery(ctx, filter).Count(&total).Error; err != nil {
 		return nil, err
 	}
 
 	offset := (page - 1) * pageSize
 
-	err := query.
-		Joins("LEFT JOIN servers s ON maintenance.target_server_id = s.server_id").
+	err = maintenanceListQuery(ctx, filter).
 		Select("maintenance.*, s.server_name as target_server_name").
 		Offset(offset).
 		Limit(pageSize).
@@ -58,29 +91,95 @@ func GetPaginatedMaintenanceTasks(page, pageSize int) (*PaginatedMaintenanceTask
 	return result, nil
 }
 
-// DeleteMaintenanceTaskByID 使用 GORM 根据 ID 从数据库中删除一个维护任务。
-func DeleteMaintenanceTaskByID(id string) error {
-	db := database.GormDB
-	result := db.Delete(&models.MaintenanceTask{}, id)
-	return result.Error
+// StreamMaintenanceTasks 按列表的筛选和排序逐行遍历全部维护任务，并对每一行调用 fn。
+func StreamMaintenanceTasks(ctx context.Context, filter MaintenanceTaskFilter, fn func(task *models.MaintenanceTask) error) (err error) {
+	ctx, span := startSpan(ctx, "StreamMaintenanceTasks")
+	defer endSpan(span, &err)
+
+	query := maintenanceListQuery(ctx, filter).
+		Select("maintenance.*, s.server_name as target_server_name").
+		Order("maintenance.created_at DESC")
+	return streamQuery(query, fn)
+}
+
+// GetMaintenanceTaskByID 使用 GORM 根据 ID 查找一个维护任务（包含目标服务器名称）。
+// 如果没有找到记录，返回 404 `NOT_FOUND` 错误。
+func GetMaintenanceTaskByID(ctx context.Context, id string) (_ *models.MaintenanceTask, err error) {
+	ctx, span := startSpan(ctx, "GetMaintenanceTaskByID")
+	defer endSpan(span, &err)
+
+	var task models.MaintenanceTask
+	err = gormDB(ctx).Model(&models.MaintenanceTask{}).
+		Joins("LEFT JOIN servers s ON maintenance.target_server_id = s.server_id").
+		Scopes(serverScope(ctx, "maintenance.target_server_id")).
+		Select("maintenance.*, s.server_name as target_server_name").
+		First(&task, "maintenance.task_id = ?", id).Error
+	if err != nil {
+		return nil, asNotFound(err, "维护任务")
+	}
+	return &task, nil
+}
+
+// DeleteMaintenanceTaskByID 使用 GORM 根据 ID 软删除一个维护任务。
+// 任务不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func DeleteMaintenanceTaskByID(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteMaintenanceTaskByID")
+	defer endSpan(span, &err)
+
+	return deleteVersioned(ctx, &models.MaintenanceTask{}, "维护任务", "task_id", id, expectedVersion)
 }
 
-// MarkTaskAsCompleted 将指定ID的任务标记为“完成”。
-func MarkTaskAsCompleted(id string) error {
-	db := database.GormDB
-	result := db.Model(&models.MaintenanceTask{}).Where("task_id = ?", id).Updates(map[string]interface{}{
-		"status":          "完成",
+// MarkTaskAsCompleted 将指定ID的任务标记为“完成”。任务已经是“完成”状态时不做修改，也不再发布事件。
+func MarkTaskAsCompleted(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "MarkTaskAsCompleted")
+	defer endSpan(span, &err)
+
+	changed, err := updateVersionedStatus(ctx, &models.MaintenanceTask{}, "维护任务", "task_id", id, expectedVersion, "完成", map[string]interface{}{
 		"completion_time": time.Now(),
 	})
-	return result.Error
+	if err != nil || !changed {
+		return err
+	}
+	publishTaskEvent(ctx, EventTaskFinished, id)
+	return nil
+}
+
+// MarkTaskAsFailed 将指定ID的任务标记为“失败”，logOutput 不为空时覆盖任务的日志输出。
+func MarkTaskAsFailed(ctx context.Context, id, logOutput string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "MarkTaskAsFailed")
+	defer endSpan(span, &err)
+
+	updates := map[string]interface{}{
+		"status":          "失败",
+		"completion_time": time.Now(),
+	}
+	if logOutput != "" {
+		updates["log_output"] = sql.NullString{String: logOutput, Valid: true}
+	}
+	if err := updateVersioned(ctx, &models.MaintenanceTask{}, "维护任务", "task_id", id, expectedVersion, updates); err != nil {
+		return err
+	}
+	publishTaskEvent(ctx, EventTaskFailed, id)
+	return nil
 }
 
 // MarkTaskAsPending 将指定ID的任务标记为“挂起”。
-func MarkTaskAsPending(id string) error {
-	db := database.GormDB
-	result := db.Model(&models.MaintenanceTask{}).Where("task_id = ?", id).Updates(map[string]interface{}{
+func MarkTaskAsPending(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "MarkTaskAsPending")
+	defer endSpan(span, &err)
+
+	return updateVersioned(ctx, &models.MaintenanceTask{}, "维护任务", "task_id", id, expectedVersion, map[string]interface{}{
 		"status":          "挂起",
 		"completion_time": nil,
 	})
-	return result.Error
+}
+
+// publishTaskEvent 读取维护任务修改后的内容并发布任务事件，读取失败时只记录日志
+func publishTaskEvent(ctx context.Context, eventType EventType, id string) {
+	task, err := GetMaintenanceTaskByID(ctx, id)
+	if err != nil {
+		slog.WarnContext(ctx, "无法读取维护任务，未发布任务事件", "task_id", id, "event", eventType, "error", err)
+		return
+	}
+	PublishEvent(ctx, eventType, task)
 }
@@ -0,0 +1,408 @@
+/**
+ * @file services/notification_channel_service.go
+ * @description 提供群机器人通知的配置管理：通知渠道和路由规则的增删改查，以及消息模板的查询、保存和恢复默认。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。渠道的加签密钥只能写入不能读出；模板以内置默认模板为基础，
+ *     管理员保存后覆盖默认模板，删除保存的模板即恢复默认。
+ *   - [邮件通知]：模板按种类区分可用的模板名称，新增邮件模板（主题、HTML 正文和纯文本正文）。
+ */
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationChannelInput 是创建或修改通知渠道时提交的内容。
+// Secret 为 nil 时创建的渠道不签名、修改时保持不变，为空字符串时清除密钥；Enabled 为空时创建的渠道默认启用、修改时保持不变。
+type NotificationChannelInput struct {
+	Name       string  `json:"name" binding:"required,max=100"`
+	Type       string  `json:"type" binding:"required,oneof=dingtalk wecom feishu"`
+	WebhookURL string  `json:"webhookUrl" binding:"required,url,max=500"`
+	Secret     *string `json:"secret" binding:"omitempty,max=200"`
+	Enabled    *bool   `json:"enabled"`
+}
+
+// NotificationRuleInput 是创建或修改路由规则时提交的内容，CustomerID 为空时规则匹配所有客户的事件
+type NotificationRuleInput struct {
+	Name       string   `json:"name" binding:"required,max=100"`
+	ChannelID  uint     `json:"channelId" binding:"required"`
+	Events     []string `json:"events" binding:"required,min=1"`
+	CustomerID *uint    `json:"customerId"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// NotificationTemplateInput 是保存消息模板时提交的内容，Text 为邮件的纯文本正文，只有邮件模板需要
+type NotificationTemplateInput struct {
+	Title string `json:"title" binding:"required,max=200"`
+	Body  string `json:"body" binding:"required,max=10000"`
+	Text  string `json:"text" binding:"max=10000"`
+}
+
+// channelQuery 返回查询通知渠道的基础查询，计算只读的 has_secret 列
+func channelQuery(ctx context.Context) *gorm.DB {
+	return gormDB(ctx).Model(&models.NotificationChannel{}).
+		Select("notification_channels.*, notification_channels.secret <> '' AS has_secret")
+}
+
+// GetNotificationChannels 返回所有通知渠道，按名称排序
+func GetNotificationChannels(ctx context.Context) (_ []models.NotificationChannel, err error) {
+	ctx, span := startSpan(ctx, "GetNotificationChannels")
+	defer endSpan(span, &err)
+
+	channels := make([]models.NotificationChannel, 0)
+	err = channelQuery(ctx).Order("name ASC").Find(&channels).Error
+	return channels, err
+}
+
+// GetNotificationChannel 根据 ID 查找一个通知渠道，不存在时返回 404
+func GetNotificationChannel(ctx context.Context, id string) (_ *models.NotificationChannel, err error) {
+	ctx, span := startSpan(ctx, "GetNotificationChannel")
+	defer endSpan(span, &err)
+
+	var channel models.NotificationChannel
+	if err := channelQuery(ctx).Where("channel_id = ?", id).Take(&channel).Error; err != nil {
+		return nil, asNotFound(err, "通知渠道")
+	}
+	return &channel, nil
+}
+
+// CreateNotificationChannel 创建一个通知渠道
+func CreateNotificationChannel(ctx context.Context, input NotificationChannelInput) (_ *models.NotificationChannel, err error) {
+	ctx, span := startSpan(ctx, "CreateNotificationChannel")
+	defer endSpan(span, &err)
+
+	if err := validateChannelInput(input); err != nil {
+		return nil, err
+	}
+	channel := models.NotificationChannel{
+		Name:       input.Name,
+		Type:       input.Type,
+		WebhookURL: input.WebhookURL,
+		Enabled:    input.Enabled == nil || *input.Enabled,
+		CreatedAt:  time.Now(),
+	}
+	if input.Secret != nil {
+		channel.Secret = *input.Secret
+	}
+	if err := gormDB(ctx).Create(&channel).Error; err != nil {
+		return nil, err
+	}
+	return GetNotificationChannel(ctx, strconv.FormatUint(uint64(channel.ChannelID), 10))
+}
+
+// UpdateNotificationChannel 修改一个通知渠道。
+// 渠道不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func UpdateNotificationChannel(ctx context.Context, id string, input NotificationChannelInput, expectedVersion *uint) (_ *models.NotificationChannel, err error) {
+	ctx, span := startSpan(ctx, "UpdateNotificationChannel")
+	defer endSpan(span, &err)
+
+	if err := validateChannelInput(input); err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{
+		"name":        input.Name,
+		"type":        input.Type,
+		"webhook_url": input.WebhookURL,
+	}
+	switch {
+	case input.Secret != nil:
+		updates["secret"] = *input.Secret
+	case input.Type == models.ChannelTypeWeCom:
+		updates["secret"] = "" // 改为企业微信渠道时清除原来的密钥
+	}
+	if input.Enabled != nil {
+		updates["enabled"] = *input.Enabled
+	}
+	if err := updateVersioned(ctx, &models.NotificationChannel{}, "通知渠道", "channel_id", id, expectedVersion, updates); err != nil {
+		return nil, err
+	}
+	return GetNotificationChannel(ctx, id)
+}
+
+// DeleteNotificationChannel 删除一个通知渠道及其全部路由规则。
+// 渠道不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func DeleteNotificationChannel(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteNotificationChannel")
+	defer endSpan(span, &err)
+
+	return deleteVersioned(ctx, &models.NotificationChannel{}, "通知渠道", "channel_id", id, expectedVersion)
+}
+
+// validateChannelInput 校验 webhook 地址的协议，以及企业微信机器人不能设置加签密钥
+func validateChannelInput(input NotificationChannelInput) error {
+	var details []utils.FieldError
+	if u, err := url.Parse(input.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		details = append(details, utils.FieldError{Field: "webhookUrl", Message: "必须是 http 或 https 地址"})
+	}
+	if input.Type == models.ChannelTypeWeCom && input.Secret != nil && *input.Secret != "" {
+		details = append(details, utils.FieldError{Field: "secret", Message: "企业微信机器人不支持加签"})
+	}
+	if len(details) > 0 {
+		return utils.ErrValidation(details...)
+	}
+	return nil
+}
+
+// ruleQuery 返回查询路由规则的基础查询，关联渠道名称和客户名称
+func ruleQuery(ctx context.Context) *gorm.DB {
+	return gormDB(ctx).Model(&models.NotificationRule{}).
+		Select("notification_rules.*, nc.name AS channel_name, c.customer_name").
+		Joins("JOIN notification_channels nc ON nc.channel_id = notification_rules.channel_id").
+		Joins("LEFT JOIN customers c ON c.customer_id = notification_rules.customer_id")
+}
+
+// GetNotificationRules 返回所有路由规则，按名称排序
+func GetNotificationRules(ctx context.Context) (_ []models.NotificationRule, err error) {
+	ctx, span := startSpan(ctx, "GetNotificationRules")
+	defer endSpan(span, &err)
+
+	rules := make([]models.NotificationRule, 0)
+	err = ruleQuery(ctx).Order("notification_rules.name ASC").Find(&rules).Error
+	return rules, err
+}
+
+// GetNotificationRule 根据 ID 查找一条路由规则，不存在时返回 404
+func GetNotificationRule(ctx context.Context, id string) (_ *models.NotificationRule, err error) {
+	ctx, span := startSpan(ctx, "GetNotificationRule")
+	defer endSpan(span, &err)
+
+	var rule models.NotificationRule
+	if err := ruleQuery(ctx).Where("notification_rules.rule_id = ?", id).Take(&rule).Error; err != nil {
+		return nil, asNotFound(err, "路由规则")
+	}
+	return &rule, nil
+}
+
+// CreateNotificationRule 创建一条路由规则
+func CreateNotificationRule(ctx context.Context, input NotificationRuleInput) (_ *models.NotificationRule, err error) {
+	ctx, span := startSpan(ctx, "CreateNotificationRule")
+	defer endSpan(span, &err)
+
+	if err := validateRuleInput(ctx, input); err != nil {
+		return nil, err
+	}
+	rule := models.NotificationRule{
+		Name:      input.Name,
+		ChannelID: input.ChannelID,
+		Events:    input.Events,
+		Enabled:   input.Enabled == nil || *input.Enabled,
+		CreatedAt: time.Now(),
+	}
+	if input.CustomerID != nil {
+		rule.CustomerID = sql.NullInt64{Int64: int64(*input.CustomerID), Valid: true}
+	}
+	if err := gormDB(ctx).Create(&rule).Error; err != nil {
+		return nil, err
+	}
+	return GetNotificationRule(ctx, strconv.FormatUint(uint64(rule.RuleID), 10))
+}
+
+// UpdateNotificationRule 修改一条路由规则，CustomerID 为空时规则改为匹配所有客户。
+// 规则不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func UpdateNotificationRule(ctx context.Context, id string, input NotificationRuleInput, expectedVersion *uint) (_ *models.NotificationRule, err error) {
+	ctx, span := startSpan(ctx, "UpdateNotificationRule")
+	defer endSpan(span, &err)
+
+	if err := validateRuleInput(ctx, input); err != nil {
+		return nil, err
+	}
+	events, err := json.Marshal(input.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"name":        input.Name,
+		"channel_id":  input.ChannelID,
+		"events":      string(events),
+		"customer_id": nil,
+	}
+	if input.CustomerID != nil {
+		updates["customer_id"] = *input.CustomerID
+	}
+	if input.Enabled != nil {
+		updates["enabled"] = *input.Enabled
+	}
+	if err := updateVersioned(ctx, &models.NotificationRule{}, "路由规则", "rule_id", id, expectedVersion, updates); err != nil {
+		return nil, err
+	}
+	return GetNotificationRule(ctx, id)
+}
+
+// DeleteNotificationRule 删除一条路由规则。
+// 规则不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func DeleteNotificationRule(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteNotificationRule")
+	defer endSpan(span, &err)
+
+	return deleteVersioned(ctx, &models.NotificationRule{}, "路由规则", "rule_id", id, expectedVersion)
+}
+
+// validateRuleInput 校验规则的事件类型，以及渠道和客户是否存在
+func validateRuleInput(ctx context.Context, input NotificationRuleInput) error {
+	var details []utils.FieldError
+	for i, e := range input.Events {
+		if !IsValidEventType(e) {
+			details = append(details, utils.FieldError{Field: fmt.Sprintf("events[%d]", i), Message: "不是支持的事件类型"})
+		}
+	}
+
+	var count int64
+	if err := gormDB(ctx).Model(&models.NotificationChannel{}).Where("channel_id = ?", input.ChannelID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		details = append(details, utils.FieldError{Field: "channelId", Message: "通知渠道不存在"})
+	}
+	if input.CustomerID != nil {
+		if err := gormDB(ctx).Model(&models.Customer{}).Where("customer_id = ?", *input.CustomerID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			details = append(details, utils.FieldError{Field: "customerId", Message: "客户不存在"})
+		}
+	}
+
+	if len(details) > 0 {
+		return utils.ErrValidation(details...)
+	}
+	return nil
+}
+
+// GetNotificationTemplates 返回 kind 种类下每个模板当前的内容，没有保存过的模板返回版本为 0 的默认模板。
+// 模板种类不存在时返回 404。
+func GetNotificationTemplates(ctx context.Context, kind string) (_ []models.NotificationTemplate, err error) {
+	ctx, span := startSpan(ctx, "GetNotificationTemplates")
+	defer endSpan(span, &err)
+
+	names := templateNames(kind)
+	if names == nil {
+		return nil, notFound("消息模板")
+	}
+	var saved []models.NotificationTemplate
+	if err := gormDB(ctx).Where("kind = ?", kind).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	savedByEvent := make(map[string]models.NotificationTemplate, len(saved))
+	for _, t := range saved {
+		savedByEvent[t.EventType] = t
+	}
+
+	templates := make([]models.NotificationTemplate, 0, len(names))
+	for _, name := range names {
+		if t, ok := savedByEvent[name]; ok {
+			templates = append(templates, t)
+		} else if t, ok := defaultTemplate(kind, name); ok {
+			templates = append(templates, t)
+		}
+	}
+	return templates, nil
+}
+
+// GetNotificationTemplate 返回 kind 种类下名为 eventType 的消息模板，没有保存过时返回版本为 0 的默认模板。
+// 群消息模板以事件类型命名，邮件模板的名称见 emailTemplateNames。模板不存在时返回 404。
+func GetNotificationTemplate(ctx context.Context, kind, eventType string) (_ *models.NotificationTemplate, err error) {
+	ctx, span := startSpan(ctx, "GetNotificationTemplate")
+	defer endSpan(span, &err)
+
+	def, ok := defaultTemplate(kind, eventType)
+	if !ok {
+		return nil, notFound("消息模板")
+	}
+	var saved models.NotificationTemplate
+	err = gormDB(ctx).Where("kind = ? AND event_type = ?", kind, eventType).Take(&saved).Error
+	switch {
+	case err == nil:
+		return &saved, nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &def, nil
+	default:
+		return nil, err
+	}
+}
+
+// UpdateNotificationTemplate 以 updatedBy 的身份保存 kind 种类下名为 eventType 的消息模板，保存前检查模板语法。
+// 模板不存在时返回 404；expectedVersion 不为 nil 且与当前版本（未保存过时为 0）不一致时返回 ErrVersionConflict。
+func UpdateNotificationTemplate(ctx context.Context, kind, eventType string, input NotificationTemplateInput, updatedBy string, expectedVersion *uint) (_ *models.NotificationTemplate, err error) {
+	ctx, span := startSpan(ctx, "UpdateNotificationTemplate")
+	defer endSpan(span, &err)
+
+	if _, ok := defaultTemplate(kind, eventType); !ok {
+		return nil, notFound("消息模板")
+	}
+	if err := validateTemplate(kind, input); err != nil {
+		return nil, err
+	}
+
+	row := models.NotificationTemplate{Kind: kind, EventType: eventType}
+	err = gormDB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockTemplateRow(tx, &row, expectedVersion); err != nil {
+			return err
+		}
+		return tx.Model(&row).Where("kind = ? AND event_type = ?", kind, eventType).Updates(map[string]interface{}{
+			"title":      input.Title,
+			"body":       input.Body,
+			"text_body":  input.Text,
+			"updated_by": sql.NullString{String: updatedBy, Valid: updatedBy != ""},
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetNotificationTemplate(ctx, kind, eventType)
+}
+
+// ResetNotificationTemplate 删除保存的消息模板，恢复为内置的默认模板并返回。
+// 模板不存在时返回 404；expectedVersion 不为 nil 且与当前版本不一致时返回 ErrVersionConflict。
+func ResetNotificationTemplate(ctx context.Context, kind, eventType string, expectedVersion *uint) (_ *models.NotificationTemplate, err error) {
+	ctx, span := startSpan(ctx, "ResetNotificationTemplate")
+	defer endSpan(span, &err)
+
+	def, ok := defaultTemplate(kind, eventType)
+	if !ok {
+		return nil, notFound("消息模板")
+	}
+	query := gormDB(ctx).Where("kind = ? AND event_type = ?", kind, eventType)
+	if expectedVersion != nil {
+		query = query.Where("version = ?", *expectedVersion)
+	}
+	result := query.Delete(&models.NotificationTemplate{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 && expectedVersion != nil && *expectedVersion != 0 {
+		return nil, ErrVersionConflict
+	}
+	return &def, nil
+}
+
+// lockTemplateRow 在事务中锁定模板行（不存在时先插入一行版本为 0 的空模板），并确认当前版本与 expectedVersion 一致
+func lockTemplateRow(tx *gorm.DB, row *models.NotificationTemplate, expectedVersion *uint) error {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(row).Error; err != nil {
+		return err
+	}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("kind = ? AND event_type = ?", row.Kind, row.EventType).
+		Take(row).Error
+	if err != nil {
+		return err
+	}
+	if expectedVersion != nil && *expectedVersion != row.Version {
+		return ErrVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+/**
+ * @file services/notification_service.go
+ * @description 站内通知中心：根据领域事件生成通知，提供通知列表、未读数和标记已读，并在有新通知时唤醒用户的推送连接。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。工单分配、SLA 超时和维护任务失败的接收人与邮件通知相同（见 `eventRecipients`）；
+ *     评论中以 `@用户名` 提到的、可以访问该工单客户的内部用户会收到提及通知（评论人自己除外）。
+ *     同一事件对同一用户只生成一条通知。推送连接的唤醒只在本实例内生效，
+ *     其他实例生成的通知由推送连接定期查询数据库发现。
+ */
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"opsboard-backend/models"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxMentions 是一条评论中最多处理的 @ 提及数
+const maxMentions = 20
+
+// notificationBodyLength 是通知正文中引用的内容的最大字符数
+const notificationBodyLength = 200
+
+// mentionPattern 匹配评论中的 `@用户名`，@ 前面是字母或数字时（例如邮箱地址）不视为提及
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_.\-]+)`)
+
+// NotificationFilter 定义了通知列表的筛选条件
+type NotificationFilter struct {
+	Unread bool   `form:"unread"`
+	Type   string `form:"type"`
+}
+
+// PaginatedNotificationsResult 定义了通知分页查询的返回结构，Unread 为用户全部未读通知的数量
+type PaginatedNotificationsResult struct {
+	Total  int64                 `json:"total"`
+	Unread int64                 `json:"unread"`
+	Data   []models.Notification `json:"data"`
+}
+
+var (
+	notificationMu sync.Mutex
+	// notificationListeners 是每个用户当前打开的推送连接的唤醒通道
+	notificationListeners = make(map[string]map[chan struct{}]struct{})
+)
+
+// StartNotificationCenter 订阅事件总线，把需要提醒用户的事件写入站内通知。应在数据库初始化后调用一次。
+func StartNotificationCenter() {
+	SubscribeEvents("notifications", createEventNotifications)
+}
+
+// ListenNotifications 为用户的一个推送连接注册唤醒通道：用户有新通知或通知被标记为已读时通道收到信号。
+// 连接关闭时必须调用返回的取消函数。
+func ListenNotifications(userID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	notificationMu.Lock()
+	if notificationListeners[userID] == nil {
+		notificationListeners[userID] = make(map[chan struct{}]struct{})
+	}
+	notificationListeners[userID][ch] = struct{}{}
+	notificationMu.Unlock()
+
+	return ch, func() {
+		notificationMu.Lock()
+		defer notificationMu.Unlock()
+		delete(notificationListeners[userID], ch)
+		if len(notificationListeners[userID]) == 0 {
+			delete(notificationListeners, userID)
+		}
+	}
+}
+
+// wakeNotificationListeners 唤醒用户在本实例上的所有推送连接；连接已经被唤醒时不重复唤醒
+func wakeNotificationListeners(userIDs ...string) {
+	notificationMu.Lock()
+	defer notificationMu.Unlock()
+	for _, userID := range userIDs {
+		for ch := range notificationListeners[userID] {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// createEventNotifications 是站内通知的事件订阅者：为事件的每个接收人写入一条通知，并唤醒接收人的推送连接
+func createEventNotifications(ctx context.Context, event Event) error {
+	var notifications []models.Notification
+	var err error
+	if event.Type == EventTicketCommented {
+		notifications, err = mentionNotifications(ctx, event)
+	} else {
+		notifications, err = recipientNotifications(ctx, event)
+	}
+	if err != nil || len(notifications) == 0 {
+		return err
+	}
+
+	if err := gormDB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications).Error; err != nil {
+		return err
+	}
+	userIDs := make([]string, len(notifications))
+	for i := range notifications {
+		userIDs[i] = notifications[i].UserID
+	}
+	wakeNotificationListeners(userIDs...)
+	return nil
+}
+
+// recipientNotifications 为工单分配、SLA 超时和维护任务失败事件的接收人生成通知
+func recipientNotifications(ctx context.Context, event Event) ([]models.Notification, error) {
+	kind, recipients, err := eventRecipients(ctx, event)
+	if err != nil || len(recipients) == 0 {
+		return nil, err
+	}
+
+	template := models.Notification{Type: kind, EventID: event.ID, CreatedAt: event.OccurredAt}
+	switch data := event.Data.(type) {
+	case TicketEventData:
+		ticket := data.Ticket
+		template.ResourceType, template.ResourceID = models.NotificationResourceTicket, ticket.ID
+		switch kind {
+		case models.NotificationTicketAssigned:
+			template.Title = fmt.Sprintf("工单 #%s 已分配给你", ticket.ID)
+			template.Body = ticket.CustomerName + "：" + truncateText(ticket.OperationContent, notificationBodyLength)
+		case models.NotificationSLABreached:
+			target := "解决"
+			if data.SLATarget == "response" {
+				target = "首次响应"
+			}
+			template.Title = fmt.Sprintf("工单 #%s %s已超时", ticket.ID, target)
+			template.Body = fmt.Sprintf("%s · %s · %s", ticket.CustomerName, ticket.Priority, ticket.Status)
+		}
+	case *models.MaintenanceTask:
+		template.ResourceType, template.ResourceID = models.NotificationResourceTask, fmt.Sprint(data.TaskID)
+		template.Title = "维护任务失败：" + data.TaskName
+		template.Body = "任务类型：" + data.TaskType
+		if data.TargetServerName.Valid {
+			template.Body += "，目标服务器：" + data.TargetServerName.String
+		}
+	}
+
+	notifications := make([]models.Notification, len(recipients))
+	for i := range recipients {
+		notifications[i] = template
+		notifications[i].UserID = recipients[i].UserID.String()
+	}
+	return notifications, nil
+}
+
+// mentionNotifications 为评论中提到的用户生成提及通知。只通知可以访问工单客户的内部用户，不通知评论人自己。
+func mentionNotifications(ctx context.Context, event Event) ([]models.Notification, error) {
+	data, ok := event.Data.(TicketEventData)
+	if !ok || data.Ticket == nil || data.Comment == nil {
+		return nil, nil
+	}
+	usernames := mentionedUsernames(data.Comment.Content)
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	comment, ticket := data.Comment, data.Ticket
+	recipients, err := staffRecipients(ctx, func(q *gorm.DB) *gorm.DB {
+		return q.Where("username IN ? AND user_id <> ?", usernames, comment.AuthorID).
+			Where("role = ? OR user_id IN (SELECT user_id FROM user_customers WHERE customer_id = ?)", models.RoleAdmin, ticket.CustomerID)
+	})
+	if err != nil || len(recipients) == 0 {
+		return nil, err
+	}
+
+	kind := "回复"
+	if comment.IsInternal {
+		kind = "内部备注"
+	}
+	notifications := make([]models.Notification, len(recipients))
+	for i := range recipients {
+		notifications[i] = models.Notification{
+			UserID:       recipients[i].UserID.String(),
+			Type:         models.NotificationTicketMentioned,
+			Title:        fmt.Sprintf("%s 在工单 #%s 的%s中提到了你", comment.AuthorName, ticket.ID, kind),
+			Body:         truncateText(comment.Content, notificationBodyLength),
+			ResourceType: models.NotificationResourceTicket,
+			ResourceID:   ticket.ID,
+			EventID:      event.ID,
+			CreatedAt:    event.OccurredAt,
+		}
+	}
+	return notifications, nil
+}
+
+// mentionedUsernames 返回评论中以 `@用户名` 提到的用户名（去重，最多 maxMentions 个），用户名末尾的 "." 视为标点
+func mentionedUsernames(content string) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimRight(m[1], ".")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		usernames = append(usernames, name)
+		if len(usernames) == maxMentions {
+			break
+		}
+	}
+	return usernames
+}
+
+// truncateText 把 s 截断为最多 n 个字符，被截断时以 "…" 结尾
+func truncateText(s string, n int) string {
+	if t := truncateRunes(s, n); t != s {
+		return t + "…"
+	}
+	return s
+}
+
+// GetNotifications 分页查询用户的通知，按时间倒序排列
+func GetNotifications(ctx context.Context, userID string, page, pageSize int, filter NotificationFilter) (_ *PaginatedNotificationsResult, err error) {
+	ctx, span := startSpan(ctx, "GetNotifications")
+	defer endSpan(span, &err)
+
+	query := func() *gorm.DB {
+		q := gormDB(ctx).Model(&models.Notification{}).Where("user_id = ?", userID)
+		if filter.Unread {
+			q = q.Where("read_at IS NULL")
+		}
+		if filter.Type != "" {
+			q = q.Where("type = ?", filter.Type)
+		}
+		return q
+	}
+
+	result := &PaginatedNotificationsResult{Data: make([]models.Notification, 0)}
+	if err := query().Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+	if result.Unread, err = GetUnreadNotificationCount(ctx, userID); err != nil {
+		return nil, err
+	}
+	err = query().
+		Order("notification_id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&result.Data).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetUnreadNotificationCount 返回用户未读通知的数量
+func GetUnreadNotificationCount(ctx context.Context, userID string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "GetUnreadNotificationCount")
+	defer endSpan(span, &err)
+
+	var count int64
+	err = gormDB(ctx).Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkNotificationRead 把用户的一条通知标记为已读，已读的通知保持原来的已读时间。通知不存在或不属于该用户时返回 404。
+func MarkNotificationRead(ctx context.Context, userID, id string) (_ *models.Notification, err error) {
+	ctx, span := startSpan(ctx, "MarkNotificationRead")
+	defer endSpan(span, &err)
+
+	result := gormDB(ctx).Model(&models.Notification{}).
+		Where("notification_id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var notification models.Notification
+	if err := gormDB(ctx).Take(&notification, "notification_id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return nil, asNotFound(err, "通知")
+	}
+	if result.RowsAffected > 0 {
+		wakeNotificationListeners(userID)
+	}
+	return &notification, nil
+}
+
+// MarkAllNotificationsRead 把用户的所有未读通知标记为已读，返回标记的数量
+func MarkAllNotificationsRead(ctx context.Context, userID string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "MarkAllNotificationsRead")
+	defer endSpan(span, &err)
+
+	result := gormDB(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		wakeNotificationListeners(userID)
+	}
+	return result.RowsAffected, nil
+}
+
+// GetNotificationsAfter 按 ID 顺序返回用户 ID 大于 afterID 的通知，最多 limit 条，用于推送连接补发新通知
+func GetNotificationsAfter(ctx context.Context, userID string, afterID uint64, limit int) ([]models.Notification, error) {
+	notifications := make([]models.Notification, 0)
+	err := gormDB(ctx).
+		Where("user_id = ? AND notification_id > ?", userID, afterID).
+		Order("notification_id ASC").
+		Limit(limit).
+		Find(&notifications).Error
+	return notifications, err
+}
+
+// LatestNotificationID 返回用户最新一条通知的 ID，没有通知时返回 0
+func LatestNotificationID(ctx context.Context, userID string) (uint64, error) {
+	var id uint64
+	err := gormDB(ctx).Model(&models.Notification{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(MAX(notification_id), 0)").
+		Scan(&id).Error
+	return id, err
+}
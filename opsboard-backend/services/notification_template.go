@@ -0,0 +1,424 @@
+/**
+ * @file services/notification_template.go
+ * @description 通知消息模板的渲染：内置默认模板、模板函数和按事件渲染标题与正文。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [通知中心]：新增 `ticket.commented` 事件的群消息默认模板，评论内容可以通过 `{{.data.comment.content}}` 引用。
+ */
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateDisplayTime 是模板中 `time` 函数输出的时间格式
+const templateDisplayTime = "2006-01-02 15:04"
+
+// defaultChatTemplates 是群机器人消息的内置默认模板，正文为各平台都支持的 Markdown 子集
+var defaultChatTemplates = map[EventType]NotificationTemplateInput{
+	EventTicketCreated: {
+		Title: `新工单 #{{.data.ticket.id}}`,
+		Body: `- **客户**：{{.data.ticket.customerName}}
+- **优先级**：{{.data.ticket.priority}}
+- **类型**：{{.data.ticket.operationType}}
+- **内容**：{{truncate 200 .data.ticket.operationContent}}
+- **提交时间**：{{time .occurredAt}}`,
+	},
+	EventTicketUpdated: {
+		Title: `工单 #{{.data.ticket.id}} 已更新`,
+		Body: `- **客户**：{{.data.ticket.customerName}}
+- **修改内容**：{{join .data.changes "、"}}
+- **状态**：{{.data.ticket.status}}
+- **优先级**：{{.data.ticket.priority}}
+- **更新时间**：{{time .occurredAt}}`,
+	},
+	EventChangelogCompleted: {
+		Title: `更新日志已完成：{{.data.customerName}}`,
+		Body: `- **更新类型**：{{.data.updateType}}
+- **更新内容**：{{truncate 200 .data.updateContent}}
+- **完成时间**：{{time .data.completionTime}}`,
+	},
+	EventTaskFinished: {
+		Title: `维护任务已完成：{{.data.taskName}}`,
+		Body: `- **任务类型**：{{.data.type}}
+- **目标服务器**：{{value .data.target}}
+- **完成时间**：{{time .data.completionTime}}`,
+	},
+	EventTaskFailed: {
+		Title: `维护任务失败：{{.data.taskName}}`,
+		Body: `- **任务类型**：{{.data.type}}
+- **目标服务器**：{{value .data.target}}
+- **失败时间**：{{time .data.completionTime}}
+- **日志**：{{truncate 500 .data.logOutput}}`,
+	},
+	EventServerAdded: {
+		Title: `新增服务器：{{.data.serverName}}`,
+		Body: `- **客户**：{{.data.customerName}}
+- **IP 地址**：{{.data.ip}}
+- **添加时间**：{{time .occurredAt}}`,
+	},
+	EventServerDeleted: {
+		Title: `服务器已删除：{{.data.serverName}}`,
+		Body: `- **客户**：{{.data.customerName}}
+- **IP 地址**：{{.data.ip}}
+- **删除时间**：{{time .occurredAt}}`,
+	},
+	EventTicketSLABreached: {
+		Title: `工单 #{{.data.ticket.id}} {{if eq .data.slaTarget "response"}}首次响应{{else}}解决{{end}}已超时`,
+		Body: `- **客户**：{{.data.ticket.customerName}}
+- **优先级**：{{.data.ticket.priority}}
+- **状态**：{{.data.ticket.status}}
+- **内容**：{{truncate 200 .data.ticket.operationContent}}
+- **超时时间**：{{time .occurredAt}}`,
+	},
+	EventTicketCommented: {
+		Title: `工单 #{{.data.ticket.id}} 有新{{if .data.comment.isInternal}}内部备注{{else}}回复{{end}}`,
+		Body: `- **客户**：{{.data.ticket.customerName}}
+- **评论人**：{{.data.comment.authorName}}
+- **内容**：{{truncate 200 .data.comment.content}}
+- **评论时间**：{{time .data.comment.createdAt}}`,
+	},
+	EventServerOffline: {
+		Title: `服务器离线：{{.data.server.serverName}}`,
+		Body: `- **客户**：{{.data.server.customerName}}
+- **IP 地址**：{{.data.server.ip}}
+- **原因**：{{with .data.reason}}{{.}}{{else}}未提供{{end}}
+- **报告时间**：{{time .occurredAt}}`,
+	},
+}
+
+// 邮件模板的名称：除每日摘要外，数据为事件的 JSON 对象加上收件人 `recipient`
+const (
+	EmailTemplateTicketAssigned = "ticket.assigned"     // 工单分配给收件人
+	EmailTemplateSLABreached    = "ticket.sla_breached" // 收件人负责的工单 SLA 超时
+	EmailTemplateTaskFailed     = "task.failed"         // 维护任务失败
+	EmailTemplateDailyDigest    = "digest.daily"        // 每日待处理事项摘要，数据见 digestData
+)
+
+// emailTemplateNames 是邮件模板的全部名称
+var emailTemplateNames = []string{EmailTemplateTicketAssigned, EmailTemplateSLABreached, EmailTemplateTaskFailed, EmailTemplateDailyDigest}
+
+// defaultEmailTemplates 是邮件的内置默认模板
+var defaultEmailTemplates = map[string]NotificationTemplateInput{
+	EmailTemplateTicketAssigned: {
+		Title: `[OpsBoard] 工单 #{{.data.ticket.id}} 已分配给你`,
+		Body: `<p>{{.recipient.nickname}}，你好：</p>
+<p>工单 #{{.data.ticket.id}} 已分配给你，请及时处理。</p>
+<ul>
+<li>客户：{{.data.ticket.customerName}}</li>
+<li>优先级：{{.data.ticket.priority}}</li>
+<li>类型：{{.data.ticket.operationType}}</li>
+<li>内容：{{truncate 500 .data.ticket.operationContent}}</li>
+<li>分配时间：{{time .occurredAt}}</li>
+</ul>`,
+		Text: `{{.recipient.nickname}}，你好：
+
+工单 #{{.data.ticket.id}} 已分配给你，请及时处理。
+
+客户：{{.data.ticket.customerName}}
+优先级：{{.data.ticket.priority}}
+类型：{{.data.ticket.operationType}}
+内容：{{truncate 500 .data.ticket.operationContent}}
+分配时间：{{time .occurredAt}}`,
+	},
+	EmailTemplateSLABreached: {
+		Title: `[OpsBoard] 工单 #{{.data.ticket.id}} {{if eq .data.slaTarget "response"}}首次响应{{else}}解决{{end}}已超时`,
+		Body: `<p>{{.recipient.nickname}}，你好：</p>
+<p>工单 #{{.data.ticket.id}} 的{{if eq .data.slaTarget "response"}}首次响应{{else}}解决{{end}}时间已超过 SLA 目标。</p>
+<ul>
+<li>客户：{{.data.ticket.customerName}}</li>
+<li>优先级：{{.data.ticket.priority}}</li>
+<li>状态：{{.data.ticket.status}}</li>
+<li>内容：{{truncate 500 .data.ticket.operationContent}}</li>
+<li>超时时间：{{time .occurredAt}}</li>
+</ul>`,
+		Text: `{{.recipient.nickname}}，你好：
+
+工单 #{{.data.ticket.id}} 的{{if eq .data.slaTarget "response"}}首次响应{{else}}解决{{end}}时间已超过 SLA 目标。
+
+客户：{{.data.ticket.customerName}}
+优先级：{{.data.ticket.priority}}
+状态：{{.data.ticket.status}}
+内容：{{truncate 500 .data.ticket.operationContent}}
+超时时间：{{time .occurredAt}}`,
+	},
+	EmailTemplateTaskFailed: {
+		Title: `[OpsBoard] 维护任务失败：{{.data.taskName}}`,
+		Body: `<p>{{.recipient.nickname}}，你好：</p>
+<p>维护任务“{{.data.taskName}}”执行失败。</p>
+<ul>
+<li>任务类型：{{.data.type}}</li>
+<li>目标服务器：{{value .data.target}}</li>
+<li>失败时间：{{time .data.completionTime}}</li>
+</ul>
+<pre>{{truncate 2000 .data.logOutput}}</pre>`,
+		Text: `{{.recipient.nickname}}，你好：
+
+维护任务“{{.data.taskName}}”执行失败。
+
+任务类型：{{.data.type}}
+目标服务器：{{value .data.target}}
+失败时间：{{time .data.completionTime}}
+
+日志：
+{{truncate 2000 .data.logOutput}}`,
+	},
+	EmailTemplateDailyDigest: {
+		Title: `[OpsBoard] {{.date}} 待处理事项摘要`,
+		Body: `<p>{{.recipient.nickname}}，你好：</p>
+<p>截至 {{.date}}，共有 {{.changelogTotal}} 条挂起的更新日志和 {{.taskTotal}} 个挂起的维护任务。</p>
+{{if .changelogs}}<h3>挂起的更新日志</h3>
+<ul>
+{{range .changelogs}}<li>{{.customerName}} · {{.updateType}} · {{truncate 100 .updateContent}}（{{time .updateTime}}）</li>
+{{end}}</ul>
+{{if .changelogsTruncated}}<p>仅列出最早的 {{len .changelogs}} 条。</p>{{end}}{{end}}
+{{if .tasks}}<h3>挂起的维护任务</h3>
+<ul>
+{{range .tasks}}<li>{{.taskName}} · {{.type}} · {{value .target}}（{{time .publicationTime}}）</li>
+{{end}}</ul>
+{{if .tasksTruncated}}<p>仅列出最早的 {{len .tasks}} 个。</p>{{end}}{{end}}`,
+		Text: `{{.recipient.nickname}}，你好：
+
+截至 {{.date}}，共有 {{.changelogTotal}} 条挂起的更新日志和 {{.taskTotal}} 个挂起的维护任务。
+{{if .changelogs}}
+挂起的更新日志：
+{{range .changelogs}}- {{.customerName}} · {{.updateType}} · {{truncate 100 .updateContent}}（{{time .updateTime}}）
+{{end}}{{if .changelogsTruncated}}仅列出最早的 {{len .changelogs}} 条。
+{{end}}{{end}}{{if .tasks}}
+挂起的维护任务：
+{{range .tasks}}- {{.taskName}} · {{.type}} · {{value .target}}（{{time .publicationTime}}）
+{{end}}{{if .tasksTruncated}}仅列出最早的 {{len .tasks}} 个。
+{{end}}{{end}}`,
+	},
+}
+
+// templateNames 返回 kind 种类下全部模板的名称，模板种类不存在时返回 nil
+func templateNames(kind string) []string {
+	switch kind {
+	case models.TemplateKindChat:
+		names := make([]string, len(EventTypes))
+		for i, e := range EventTypes {
+			names[i] = string(e)
+		}
+		return names
+	case models.TemplateKindEmail:
+		return emailTemplateNames
+	}
+	return nil
+}
+
+// defaultTemplate 返回 kind 种类下名为 name 的内置默认模板（版本为 0），没有对应的默认模板时 ok 为 false
+func defaultTemplate(kind, name string) (_ models.NotificationTemplate, ok bool) {
+	var input NotificationTemplateInput
+	switch kind {
+	case models.TemplateKindChat:
+		input, ok = defaultChatTemplates[EventType(name)]
+	case models.TemplateKindEmail:
+		input, ok = defaultEmailTemplates[name]
+	}
+	if !ok {
+		return models.NotificationTemplate{}, false
+	}
+	return models.NotificationTemplate{Kind: kind, EventType: name, Title: input.Title, Body: input.Body, Text: input.Text}, true
+}
+
+// templateFuncs 是消息模板中可以使用的函数
+var templateFuncs = template.FuncMap{
+	// value 把值转换为字符串：null 和 sql.Null* 序列化得到的 {"Valid": false} 为空字符串
+	"value": templateValue,
+	// time 把 RFC 3339 时间字符串转换为本地时间的 "2006-01-02 15:04" 格式
+	"time": func(v interface{}) string {
+		s := templateValue(v)
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return s
+		}
+		return t.Local().Format(templateDisplayTime)
+	},
+	// truncate 把值转换为字符串后截断为最多 n 个字符，被截断时以 "…" 结尾
+	"truncate": func(n int, v interface{}) string {
+		s := templateValue(v)
+		if t := truncateRunes(s, n); t != s {
+			return t + "…"
+		}
+		return s
+	},
+	// join 用 sep 连接列表中的各项
+	"join": func(v interface{}, sep string) string {
+		items, _ := v.([]interface{})
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = templateValue(item)
+		}
+		return strings.Join(parts, sep)
+	},
+}
+
+// templateValue 把模板数据中的值转换为字符串，sql.Null* 类型序列化得到的对象取其中的有效值
+func templateValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}:
+		if valid, ok := v["Valid"].(bool); ok {
+			if !valid {
+				return ""
+			}
+			for key, inner := range v {
+				if key != "Valid" {
+					return templateValue(inner)
+				}
+			}
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// parseTemplate 解析一个消息模板，模板中不存在的字段输出为空
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// parseHTMLTemplate 解析一个 HTML 消息模板，输出的数据按所在位置转义
+func parseHTMLTemplate(name, text string) (*htmltemplate.Template, error) {
+	return htmltemplate.New(name).Funcs(htmltemplate.FuncMap(templateFuncs)).Option("missingkey=zero").Parse(text)
+}
+
+// validateTemplate 检查 kind 种类模板的标题和正文语法，邮件模板还要求填写纯文本正文
+func validateTemplate(kind string, input NotificationTemplateInput) error {
+	var details []utils.FieldError
+	if _, err := parseTemplate("title", input.Title); err != nil {
+		details = append(details, utils.FieldError{Field: "title", Message: "模板语法错误：" + err.Error()})
+	}
+	if kind == models.TemplateKindEmail {
+		if _, err := parseHTMLTemplate("body", input.Body); err != nil {
+			details = append(details, utils.FieldError{Field: "body", Message: "模板语法错误：" + err.Error()})
+		}
+		if strings.TrimSpace(input.Text) == "" {
+			details = append(details, utils.FieldError{Field: "text", Message: "邮件模板必须填写纯文本正文"})
+		} else if _, err := parseTemplate("text", input.Text); err != nil {
+			details = append(details, utils.FieldError{Field: "text", Message: "模板语法错误：" + err.Error()})
+		}
+	} else if _, err := parseTemplate("body", input.Body); err != nil {
+		details = append(details, utils.FieldError{Field: "body", Message: "模板语法错误：" + err.Error()})
+	}
+	if len(details) > 0 {
+		return utils.ErrValidation(details...)
+	}
+	return nil
+}
+
+// renderedMessage 是渲染后的消息，Text 只有邮件才有
+type renderedMessage struct {
+	Title string
+	Body  string
+	Text  string
+}
+
+// renderTemplate 用数据渲染模板的标题和正文，邮件模板的正文按 HTML 渲染
+func renderTemplate(tmpl *models.NotificationTemplate, data map[string]interface{}) (*renderedMessage, error) {
+	var msg renderedMessage
+	var err error
+	if msg.Title, err = executeTemplate("title", tmpl.Title, data); err != nil {
+		return nil, err
+	}
+	if tmpl.Kind != models.TemplateKindEmail {
+		if msg.Body, err = executeTemplate("body", tmpl.Body, data); err != nil {
+			return nil, err
+		}
+		return &renderedMessage{Title: strings.TrimSpace(msg.Title), Body: strings.TrimSpace(msg.Body)}, nil
+	}
+
+	t, err := parseHTMLTemplate("body", tmpl.Body)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	if msg.Text, err = executeTemplate("text", tmpl.Text, data); err != nil {
+		return nil, err
+	}
+	// 邮件主题不能换行
+	msg.Title = strings.Join(strings.Fields(msg.Title), " ")
+	msg.Body = strings.TrimSpace(buf.String())
+	msg.Text = strings.TrimSpace(msg.Text)
+	return &msg, nil
+}
+
+// executeTemplate 解析并执行一个模板
+func executeTemplate(name, text string, data map[string]interface{}) (string, error) {
+	t, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	// 数据中不存在的字段在 text/template 中输出为 "<no value>"
+	return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+}
+
+// renderNotification 用 kind 种类下名为 name 的模板渲染数据，保存的模板渲染失败时使用默认模板
+func renderNotification(ctx context.Context, kind, name string, data map[string]interface{}) (*renderedMessage, error) {
+	tmpl, err := GetNotificationTemplate(ctx, kind, name)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := renderTemplate(tmpl, data)
+	if err != nil && tmpl.Version != 0 {
+		slog.WarnContext(ctx, "消息模板渲染失败，使用默认模板", "kind", kind, "name", name, "error", err)
+		def, _ := defaultTemplate(kind, name)
+		msg, err = renderTemplate(&def, data)
+	}
+	return msg, err
+}
+
+// RenderEventTemplate 用 kind 种类下事件对应的模板渲染事件的标题和正文，保存的模板渲染失败时使用默认模板
+func RenderEventTemplate(ctx context.Context, kind string, event Event) (title, body string, err error) {
+	data, err := templateData(event)
+	if err != nil {
+		return "", "", err
+	}
+	msg, err := renderNotification(ctx, kind, string(event.Type), data)
+	if err != nil {
+		return "", "", err
+	}
+	return msg.Title, msg.Body, nil
+}
+
+// templateData 把事件转换为模板数据，字段名与事件的 JSON 序列化结果一致
+func templateData(event Event) (map[string]interface{}, error) {
+	return toTemplateData(event)
+}
+
+// toTemplateData 把任意值转换为模板数据，字段名与 JSON 序列化结果一致
+func toTemplateData(v interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// 数字保留为 json.Number，避免 ID 等整数被输出为浮点数格式
+	var data map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,274 @@
+/**
+ * @file oidc_service.go
+ * @description 实现 OpenID Connect 单点登录的授权码流程：生成带 PKCE、state 和 nonce 的授权地址，回调时用授权码换取令牌，
+ * 通过 IdP 的 JWKS 校验 ID 令牌，按声明映射角色并即时开通本地账户。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [安全]：签名登录状态 Cookie 失败（未设置 `FORM_SIGNING_SECRET`）时返回错误。
+ */
+
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCStateTTL 是从跳转到 IdP 到回调之间允许的最长时间
+const OIDCStateTTL = 10 * time.Minute
+
+// oidcStatePurpose 是登录状态 Cookie 签名的用途
+const oidcStatePurpose = "oidc-state"
+
+// OIDC 登录失败的原因，会写入登录失败的审计日志
+const (
+	OIDCFailureIdPError       = "idp_error"        // IdP 在回调中返回了错误（例如用户拒绝授权）
+	OIDCFailureInvalidState   = "invalid_state"    // 登录状态 Cookie 缺失、过期或与回调参数不匹配
+	OIDCFailureExchangeFailed = "exchange_failed"  // 用授权码换取令牌失败
+	OIDCFailureInvalidIDToken = "invalid_id_token" // ID 令牌缺失、签名或声明校验失败、nonce 不匹配
+	OIDCFailureMissingClaim   = "missing_claim"    // ID 令牌中缺少用户名声明
+)
+
+// ErrOIDCDisabled 表示未配置 OIDC 单点登录
+var ErrOIDCDisabled = utils.ErrNotFound("未启用 OIDC 单点登录")
+
+// OIDCConfig 是 OIDC 单点登录的配置
+type OIDCConfig struct {
+	IssuerURL     string      // IdP 的 Issuer，发现文档位于 <IssuerURL>/.well-known/openid-configuration
+	ClientID      string      // 在 IdP 注册的客户端 ID
+	ClientSecret  string      // 客户端密钥，公共客户端可为空（依赖 PKCE）
+	RedirectURL   string      // 回调地址，即 /api/auth/oidc/callback 的完整外部 URL
+	Scopes        []string    // 请求的 scope，必须包含 openid
+	UsernameClaim string      // 作为用户名的声明，例如 preferred_username
+	NicknameClaim string      // 作为昵称的声明，例如 name
+	GroupsClaim   string      // 记录所属组的声明，例如 groups
+	GroupRoles    []GroupRole // 组到角色的映射，按顺序匹配
+	DefaultRole   string      // 不属于任何映射组时使用的角色，为空表示拒绝登录
+	PostLoginURL  string      // 回调完成后跳转的前端地址，令牌放在 URL 片段中；为空时回调直接返回 JSON
+}
+
+// oidcLoginState 是签名后保存在 Cookie 中的登录状态
+type oidcLoginState struct {
+	State     string `json:"s"`
+	Nonce     string `json:"n"`
+	Verifier  string `json:"v"`
+	ExpiresAt int64  `json:"e"`
+}
+
+var (
+	oidcMu       sync.Mutex
+	oidcConfig   *OIDCConfig
+	oidcProvider *oidc.Provider
+)
+
+// ConfigureOIDC 设置 OIDC 单点登录，应在启动时调用一次；IssuerURL 为空表示不启用
+func ConfigureOIDC(cfg OIDCConfig) error {
+	if cfg.IssuerURL == "" {
+		return nil
+	}
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return errors.New("OIDC 单点登录需要配置客户端 ID 和回调地址")
+	}
+	if cfg.UsernameClaim == "" {
+		return errors.New("OIDC 单点登录需要配置用户名声明")
+	}
+	if cfg.DefaultRole != "" && !models.IsValidRole(cfg.DefaultRole) {
+		return fmt.Errorf("未知的 OIDC 默认角色: %s", cfg.DefaultRole)
+	}
+	hasOpenID := false
+	for _, s := range cfg.Scopes {
+		hasOpenID = hasOpenID || s == oidc.ScopeOpenID
+	}
+	if !hasOpenID {
+		cfg.Scopes = append([]string{oidc.ScopeOpenID}, cfg.Scopes...)
+	}
+
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	oidcConfig = &cfg
+	oidcProvider = nil
+	return nil
+}
+
+// BeginOIDCLogin 生成跳转到 IdP 的授权地址，以及需要保存在浏览器 Cookie 中的签名登录状态
+func BeginOIDCLogin(ctx context.Context) (authURL, stateCookie string, err error) {
+	ctx, span := startSpan(ctx, "BeginOIDCLogin")
+	defer endSpan(span, &err)
+
+	cfg, provider, err := oidcClient(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state := oidcLoginState{
+		State:     randomURLToken(),
+		Nonce:     randomURLToken(),
+		Verifier:  oauth2.GenerateVerifier(),
+		ExpiresAt: time.Now().Add(OIDCStateTTL).Unix(),
+	}
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return "", "", err
+	}
+	stateCookie, err = utils.SignValue(oidcStatePurpose, base64.RawURLEncoding.EncodeToString(payload))
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL = oauth2Config(cfg, provider).AuthCodeURL(state.State,
+		oidc.Nonce(state.Nonce),
+		oauth2.S256ChallengeOption(state.Verifier),
+	)
+	return authURL, stateCookie, nil
+}
+
+// CompleteOIDCLogin 处理 IdP 的回调：校验登录状态，用授权码换取令牌并校验 ID 令牌，返回即时开通的本地用户。
+// IdP 明确拒绝或校验失败时返回 *AuthFailure，原因为 OIDCFailure*、AuthFailureNoRole 或 AuthFailureDisabled。
+func CompleteOIDCLogin(ctx context.Context, stateCookie, state, code string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "CompleteOIDCLogin")
+	defer endSpan(span, &err)
+
+	cfg, provider, err := oidcClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, ok := parseOIDCState(stateCookie)
+	if !ok || state == "" || subtle.ConstantTimeCompare([]byte(saved.State), []byte(state)) != 1 {
+		return nil, &AuthFailure{Reason: OIDCFailureInvalidState}
+	}
+
+	token, err := oauth2Config(cfg, provider).Exchange(ctx, code, oauth2.VerifierOption(saved.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", &AuthFailure{Reason: OIDCFailureExchangeFailed}, err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, &AuthFailure{Reason: OIDCFailureInvalidIDToken}
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", &AuthFailure{Reason: OIDCFailureInvalidIDToken}, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(saved.Nonce)) != 1 {
+		return nil, &AuthFailure{Reason: OIDCFailureInvalidIDToken}
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", &AuthFailure{Reason: OIDCFailureInvalidIDToken}, err)
+	}
+	username, _ := claims[cfg.UsernameClaim].(string)
+	if username == "" || len(username) > 50 {
+		return nil, &AuthFailure{Reason: OIDCFailureMissingClaim}
+	}
+	nickname, _ := claims[cfg.NicknameClaim].(string)
+	if nickname == "" {
+		nickname = username
+	}
+	role := mapGroupRole(cfg.GroupRoles, claimStrings(claims[cfg.GroupsClaim]), cfg.DefaultRole)
+	if role == "" {
+		return nil, &AuthFailure{Reason: AuthFailureNoRole}
+	}
+
+	user, err := ProvisionExternalUser(ctx, models.AuthSourceOIDC, username, nickname, role)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return nil, &AuthFailure{Reason: AuthFailureDisabled, UserID: user.UserID.String()}
+	}
+	return user, nil
+}
+
+// OIDCPostLoginURL 返回回调完成后跳转的前端地址，为空表示回调直接返回 JSON
+func OIDCPostLoginURL() string {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcConfig == nil {
+		return ""
+	}
+	return oidcConfig.PostLoginURL
+}
+
+// oidcClient 返回当前配置和 IdP 的发现信息；发现文档在第一次使用时加载，失败后下次请求会重试
+func oidcClient(ctx context.Context) (*OIDCConfig, *oidc.Provider, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcConfig == nil {
+		return nil, nil, ErrOIDCDisabled
+	}
+	if oidcProvider == nil {
+		// 发现文档中的 JWKS 地址会被长期使用，不能随本次请求的 context 一起取消
+		provider, err := oidc.NewProvider(context.WithoutCancel(ctx), oidcConfig.IssuerURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("加载 OIDC 发现文档失败: %w", err)
+		}
+		oidcProvider = provider
+	}
+	return oidcConfig, oidcProvider, nil
+}
+
+// oauth2Config 返回授权码流程使用的 OAuth2 客户端配置
+func oauth2Config(cfg *OIDCConfig, provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       cfg.Scopes,
+	}
+}
+
+// parseOIDCState 校验并解析登录状态 Cookie
+func parseOIDCState(cookie string) (*oidcLoginState, bool) {
+	payload, err := utils.VerifySignedValue(oidcStatePurpose, cookie)
+	if err != nil {
+		return nil, false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, false
+	}
+	var state oidcLoginState
+	if err := json.Unmarshal(raw, &state); err != nil || time.Now().Unix() > state.ExpiresAt {
+		return nil, false
+	}
+	return &state, true
+}
+
+// claimStrings 将字符串或字符串数组类型的声明统一转换为字符串切片
+func claimStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// randomURLToken 返回 32 字节随机数的 base64url 编码，用作 state 和 nonce
+func randomURLToken() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
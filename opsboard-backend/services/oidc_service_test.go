@@ -0,0 +1,371 @@
+/**
+ * @file services/oidc_service_test.go
+ * @description 用 httptest 模拟的 IdP 测试 OIDC 单点登录的回调处理。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。假 IdP 提供发现文档、JWKS 和校验 PKCE 的令牌端点，测试登录状态 Cookie 被篡改、过期或与
+ *     回调参数不匹配，ID 令牌的 nonce 或签名不正确，以及授权码被换到其他登录会话中使用时回调都会拒绝登录。
+ *     校验全部通过后开通本地账户需要数据库，不在此测试。
+ */
+
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testOIDCClientID = "opsboard"
+	testOIDCKeyID    = "test-key"
+)
+
+// fakeOIDCProvider 是一个用 httptest 实现的最小 IdP。
+// 授权端点由 authorize 直接模拟，令牌端点按 RFC 7636 校验 code_verifier，授权码成功换取令牌后失效。
+type fakeOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu            sync.Mutex
+	grants        map[string]fakeOIDCGrant
+	tokenRequests int
+	pkceFailures  int
+}
+
+// fakeOIDCGrant 是一次授权记录的 PKCE challenge 和要签入 ID 令牌的声明
+type fakeOIDCGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+	key       *rsa.PrivateKey
+}
+
+var (
+	testOIDCKeysOnce sync.Once
+	testOIDCKeys     [2]*rsa.PrivateKey
+)
+
+// testOIDCKey 返回测试用的 RSA 密钥，第一个由假 IdP 发布在 JWKS 中，第二个不发布
+func testOIDCKey(t *testing.T, i int) *rsa.PrivateKey {
+	t.Helper()
+	testOIDCKeysOnce.Do(func() {
+		for j := range testOIDCKeys {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				panic(err)
+			}
+			testOIDCKeys[j] = key
+		}
+	})
+	return testOIDCKeys[i]
+}
+
+// setupOIDC 启动假 IdP 并让 OIDC 单点登录指向它，测试结束后恢复为未启用
+func setupOIDC(t *testing.T) *fakeOIDCProvider {
+	t.Helper()
+	if err := utils.ConfigureSigningKey("test-form-signing-secret"); err != nil {
+		t.Fatalf("ConfigureSigningKey 返回错误: %v", err)
+	}
+
+	p := &fakeOIDCProvider{key: testOIDCKey(t, 0), grants: map[string]fakeOIDCGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	err := ConfigureOIDC(OIDCConfig{
+		IssuerURL:     p.server.URL,
+		ClientID:      testOIDCClientID,
+		RedirectURL:   "https://opsboard.example.com/api/auth/oidc/callback",
+		Scopes:        []string{"openid", "profile"},
+		UsernameClaim: "preferred_username",
+		NicknameClaim: "name",
+		GroupsClaim:   "groups",
+		GroupRoles:    []GroupRole{{Group: "ops-admins", Role: models.RoleAdmin}},
+	})
+	if err != nil {
+		t.Fatalf("ConfigureOIDC 返回错误: %v", err)
+	}
+	t.Cleanup(func() {
+		oidcMu.Lock()
+		defer oidcMu.Unlock()
+		oidcConfig = nil
+		oidcProvider = nil
+	})
+	return p
+}
+
+func (p *fakeOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *fakeOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": testOIDCKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	p.tokenRequests++
+	grant, ok := p.grants[code]
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" {
+		p.mu.Unlock()
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		p.pkceFailures++
+		p.mu.Unlock()
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+	delete(p.grants, code)
+	p.mu.Unlock()
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": p.server.URL,
+		"aud": testOIDCClientID,
+		"sub": "subject-1",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = testOIDCKeyID
+	signed, err := idToken.SignedString(grant.key)
+	if err != nil {
+		writeTestJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+// authorize 模拟用户在 IdP 登录并同意授权：记录授权地址中的 PKCE challenge 和 nonce，返回回调参数中的 state 和授权码。
+// claims 中的声明会签入 ID 令牌，其中的 nonce 会覆盖授权地址中的 nonce。
+func (p *fakeOIDCProvider) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (state, code string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("无法解析授权地址 %q: %v", authURL, err)
+	}
+	q := u.Query()
+	if q.Get("client_id") != testOIDCClientID || q.Get("response_type") != "code" {
+		t.Fatalf("授权地址的 client_id 或 response_type 不正确: %s", authURL)
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("授权地址缺少 S256 PKCE challenge: %s", authURL)
+	}
+	if q.Get("state") == "" || q.Get("nonce") == "" {
+		t.Fatalf("授权地址缺少 state 或 nonce: %s", authURL)
+	}
+
+	grantClaims := jwt.MapClaims{"nonce": q.Get("nonce")}
+	for k, v := range claims {
+		grantClaims[k] = v
+	}
+	code = randomURLToken()
+	p.mu.Lock()
+	p.grants[code] = fakeOIDCGrant{challenge: q.Get("code_challenge"), claims: grantClaims, key: p.key}
+	p.mu.Unlock()
+	return q.Get("state"), code
+}
+
+// signWith 让授权码换出的 ID 令牌改用指定的密钥签名
+func (p *fakeOIDCProvider) signWith(code string, key *rsa.PrivateKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	grant := p.grants[code]
+	grant.key = key
+	p.grants[code] = grant
+}
+
+// counts 返回令牌端点被请求的次数和其中 code_verifier 校验失败的次数
+func (p *fakeOIDCProvider) counts() (tokenRequests, pkceFailures int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tokenRequests, p.pkceFailures
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// beginTestOIDCLogin 开始一次登录，返回授权地址和登录状态 Cookie
+func beginTestOIDCLogin(t *testing.T) (string, string) {
+	t.Helper()
+	authURL, cookie, err := BeginOIDCLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin 返回错误: %v", err)
+	}
+	return authURL, cookie
+}
+
+// 不属于映射的组的用户：所有校验通过后才会因没有角色被拒绝，不会访问数据库
+var testOIDCUnmappedUser = jwt.MapClaims{"preferred_username": "alice", "groups": []string{"finance"}}
+
+func TestCompleteOIDCLoginAcceptsValidCallback(t *testing.T) {
+	p := setupOIDC(t)
+	authURL, cookie := beginTestOIDCLogin(t)
+	state, code := p.authorize(t, authURL, testOIDCUnmappedUser)
+
+	_, err := CompleteOIDCLogin(context.Background(), cookie, state, code)
+	if reason := authFailureReason(err); reason != AuthFailureNoRole {
+		t.Fatalf("CompleteOIDCLogin 的错误 = %v，期望在角色映射时失败 %s", err, AuthFailureNoRole)
+	}
+}
+
+func TestCompleteOIDCLoginRejectsBadState(t *testing.T) {
+	p := setupOIDC(t)
+
+	expired, _ := json.Marshal(oidcLoginState{State: "s", Nonce: "n", Verifier: "v", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	expiredCookie, err := utils.SignValue(oidcStatePurpose, base64.RawURLEncoding.EncodeToString(expired))
+	if err != nil {
+		t.Fatalf("SignValue 返回错误: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		cookie func(cookie string) string
+		state  func(state string) string
+	}{
+		{"state 与 Cookie 不匹配", keepString, func(string) string { return "forged-state" }},
+		{"回调缺少 state", keepString, func(string) string { return "" }},
+		{"缺少 Cookie", func(string) string { return "" }, keepString},
+		{"Cookie 签名被篡改", func(c string) string { return c[:len(c)-2] + "AA" }, keepString},
+		{"Cookie 已过期", func(string) string { return expiredCookie }, func(string) string { return "s" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authURL, cookie := beginTestOIDCLogin(t)
+			state, code := p.authorize(t, authURL, testOIDCUnmappedUser)
+
+			_, err := CompleteOIDCLogin(context.Background(), tt.cookie(cookie), tt.state(state), code)
+			if reason := authFailureReason(err); reason != OIDCFailureInvalidState {
+				t.Errorf("CompleteOIDCLogin 的错误 = %v，期望失败原因 %s", err, OIDCFailureInvalidState)
+			}
+		})
+	}
+	// 登录状态无效时不能使用授权码
+	if n, _ := p.counts(); n != 0 {
+		t.Errorf("登录状态无效时请求了令牌端点 %d 次", n)
+	}
+}
+
+func TestCompleteOIDCLoginRejectsBadNonce(t *testing.T) {
+	p := setupOIDC(t)
+	authURL, cookie := beginTestOIDCLogin(t)
+	// IdP 返回为另一次登录签发的 ID 令牌（例如被重放的令牌）
+	claims := jwt.MapClaims{"nonce": "nonce-of-another-login"}
+	for k, v := range testOIDCUnmappedUser {
+		claims[k] = v
+	}
+	state, code := p.authorize(t, authURL, claims)
+
+	_, err := CompleteOIDCLogin(context.Background(), cookie, state, code)
+	if reason := authFailureReason(err); reason != OIDCFailureInvalidIDToken {
+		t.Errorf("CompleteOIDCLogin 的错误 = %v，期望失败原因 %s", err, OIDCFailureInvalidIDToken)
+	}
+}
+
+func TestCompleteOIDCLoginRequiresPKCEVerifier(t *testing.T) {
+	p := setupOIDC(t)
+	// 攻击者把自己的授权码注入到受害者的登录会话中：state 和 Cookie 匹配，但 code_verifier 属于另一次登录
+	victimURL, victimCookie := beginTestOIDCLogin(t)
+	attackerURL, _ := beginTestOIDCLogin(t)
+	victimState, _ := p.authorize(t, victimURL, testOIDCUnmappedUser)
+	_, attackerCode := p.authorize(t, attackerURL, testOIDCUnmappedUser)
+
+	_, err := CompleteOIDCLogin(context.Background(), victimCookie, victimState, attackerCode)
+	if reason := authFailureReason(err); reason != OIDCFailureExchangeFailed {
+		t.Errorf("CompleteOIDCLogin 的错误 = %v，期望失败原因 %s", err, OIDCFailureExchangeFailed)
+	}
+	if _, failures := p.counts(); failures == 0 {
+		t.Error("令牌端点没有收到不匹配的 code_verifier")
+	}
+}
+
+func TestCompleteOIDCLoginRejectsInvalidIDToken(t *testing.T) {
+	p := setupOIDC(t)
+
+	t.Run("签名密钥不在 JWKS 中", func(t *testing.T) {
+		authURL, cookie := beginTestOIDCLogin(t)
+		state, code := p.authorize(t, authURL, testOIDCUnmappedUser)
+		p.signWith(code, testOIDCKey(t, 1))
+
+		_, err := CompleteOIDCLogin(context.Background(), cookie, state, code)
+		if reason := authFailureReason(err); reason != OIDCFailureInvalidIDToken {
+			t.Errorf("CompleteOIDCLogin 的错误 = %v，期望失败原因 %s", err, OIDCFailureInvalidIDToken)
+		}
+	})
+
+	t.Run("受众不是本客户端", func(t *testing.T) {
+		authURL, cookie := beginTestOIDCLogin(t)
+		state, code := p.authorize(t, authURL, jwt.MapClaims{"aud": "another-client", "preferred_username": "alice"})
+
+		_, err := CompleteOIDCLogin(context.Background(), cookie, state, code)
+		if reason := authFailureReason(err); reason != OIDCFailureInvalidIDToken {
+			t.Errorf("CompleteOIDCLogin 的错误 = %v，期望失败原因 %s", err, OIDCFailureInvalidIDToken)
+		}
+	})
+
+	t.Run("缺少用户名声明", func(t *testing.T) {
+		authURL, cookie := beginTestOIDCLogin(t)
+		state, code := p.authorize(t, authURL, jwt.MapClaims{"groups": []string{"ops-admins"}})
+
+		_, err := CompleteOIDCLogin(context.Background(), cookie, state, code)
+		if reason := authFailureReason(err); reason != OIDCFailureMissingClaim {
+			t.Errorf("CompleteOIDCLogin 的错误 = %v，期望失败原因 %s", err, OIDCFailureMissingClaim)
+		}
+	})
+}
+
+func keepString(s string) string { return s }
@@ -0,0 +1,250 @@
+/**
+ * @file services/portal_service.go
+ * @description 提供客户门户的业务逻辑：客户门户用户提交和跟踪本单位的工单、确认解决并评价服务。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [Webhook]：客户提交工单后发布 `ticket.created` 事件，确认解决和评价后发布 `ticket.updated` 事件。
+ */
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrTicketNotResolving 表示工单不处于“待确认”状态，不能确认解决
+var ErrTicketNotResolving = utils.NewAppError(utils.CodeConflict, "工单不处于待确认状态，无法确认解决")
+
+// ErrTicketNotCompleted 表示工单尚未完成，不能评价
+var ErrTicketNotCompleted = utils.NewAppError(utils.CodeConflict, "工单尚未完成，无法评价")
+
+// PortalCustomer 是客户门户中展示的单位信息
+type PortalCustomer struct {
+	ID            uint           `json:"id"`
+	CustomerName  string         `json:"customerName"`
+	ContactPerson sql.NullString `json:"contactPerson"`
+	ContactPhone  sql.NullString `json:"contactPhone"`
+}
+
+// PortalTicket 是客户门户中展示的工单，不包含处理人等内部信息
+type PortalTicket struct {
+	ID               uint           `json:"id"`
+	CustomerID       uint           `json:"customerId"`
+	CustomerName     string         `json:"customerName"`
+	Status           string         `json:"status"`
+	Priority         string         `json:"priority"`
+	OperationType    sql.NullString `json:"operationType"`
+	OperationContent string         `json:"operationContent"`
+	SubmittedAt      time.Time      `json:"submittedAt"`
+	CompletionTime   sql.NullTime   `json:"completionTime"`
+	Rating           sql.NullInt16  `json:"rating"`
+	RatingComment    sql.NullString `json:"ratingComment"`
+	RatedAt          sql.NullTime   `json:"ratedAt"`
+	Version          uint           `json:"version"`
+}
+
+// PaginatedPortalTicketsResult 定义了客户门户工单分页查询的返回结构
+type PaginatedPortalTicketsResult struct {
+	Total int64          `json:"total"`
+	Data  []PortalTicket `json:"data"`
+}
+
+// PortalTicketFilter 定义了客户门户工单列表的筛选条件，所有字段均为可选。
+type PortalTicketFilter struct {
+	Status string `form:"status"`
+	TimeRangeFilter
+}
+
+// portalTicketColumns 是客户门户工单查询的列
+const portalTicketColumns = "tickets.ticket_id AS id, tickets.customer_id, c.customer_name, tickets.status, " +
+	"tickets.priority, tickets.operation_type, tickets.operation_content, tickets.created_at AS submitted_at, tickets.completion_time, " +
+	"tickets.rating, tickets.rating_comment, tickets.rated_at, tickets.version"
+
+// portalTicketQuery 构造客户门户工单的基础查询（未被删除且在访问范围内的工单）
+func portalTicketQuery(ctx context.Context) *gorm.DB {
+	return gormDB(ctx).Table("tickets").
+		Joins("LEFT JOIN customers c ON tickets.customer_id = c.customer_id").
+		Where("tickets.deleted_at IS NULL").
+		Scopes(customerScope(ctx, "tickets.customer_id"))
+}
+
+// GetPortalCustomers 返回客户门户用户所属的单位，按名称排序
+func GetPortalCustomers(ctx context.Context) (_ []PortalCustomer, err error) {
+	ctx, span := startSpan(ctx, "GetPortalCustomers")
+	defer endSpan(span, &err)
+
+	customers := make([]PortalCustomer, 0)
+	err = gormDB(ctx).Model(&models.Customer{}).Scopes(customerScope(ctx, "customer_id")).
+		Select("customer_id AS id, customer_name, contact_person, contact_phone").
+		Order("customer_name ASC").
+		Find(&customers).Error
+	return customers, err
+}
+
+// GetPortalTickets 分页查询客户门户用户所属单位的工单，按提交时间倒序排列
+func GetPortalTickets(ctx context.Context, page, pageSize int, filter PortalTicketFilter) (_ *PaginatedPortalTicketsResult, err error) {
+	ctx, span := startSpan(ctx, "GetPortalTickets")
+	defer endSpan(span, &err)
+
+	query := func() *gorm.DB {
+		q := portalTicketQuery(ctx)
+		if filter.Status != "" {
+			q = q.Where("tickets.status = ?", filter.Status)
+		}
+		return filter.TimeRangeFilter.apply(q, "tickets.created_at")
+	}
+
+	var total int64
+	if err := query().Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	tickets := make([]PortalTicket, 0)
+	err = query().Select(portalTicketColumns).
+		Order("tickets.created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&tickets).Error
+	if err != nil {
+		return nil, err
+	}
+	return &PaginatedPortalTicketsResult{Total: total, Data: tickets}, nil
+}
+
+// GetPortalTicket 查找客户门户用户所属单位的一个工单，不存在时返回 404
+func GetPortalTicket(ctx context.Context, id string) (_ *PortalTicket, err error) {
+	ctx, span := startSpan(ctx, "GetPortalTicket")
+	defer endSpan(span, &err)
+
+	var ticket PortalTicket
+	err = portalTicketQuery(ctx).Select(portalTicketColumns).Where("tickets.ticket_id = ?", id).Take(&ticket).Error
+	if err != nil {
+		return nil, asNotFound(err, "工单")
+	}
+	return &ticket, nil
+}
+
+// SubmitPortalTicket 以客户门户用户 submitterID 的身份提交一个工单，工单状态为“待处理”。
+// customerID 为 0 时使用用户所属的唯一单位；用户属于多个单位时必须指定 customerID。
+func SubmitPortalTicket(ctx context.Context, submitterID string, customerID uint, operationType, content string) (_ *PortalTicket, err error) {
+	ctx, span := startSpan(ctx, "SubmitPortalTicket")
+	defer endSpan(span, &err)
+
+	customers, err := GetPortalCustomers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	customerID, err = portalTicketCustomer(customers, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket := models.TicketRecord{
+		CustomerID:       customerID,
+		SubmitterID:      submitterID,
+		Status:           models.TicketStatusPending,
+		OperationType:    sql.NullString{String: operationType, Valid: operationType != ""},
+		OperationContent: content,
+		CreatedAt:        time.Now(),
+	}
+	if err := gormDB(ctx).Create(&ticket).Error; err != nil {
+		return nil, err
+	}
+	id := strconv.FormatUint(uint64(ticket.TicketID), 10)
+	publishTicketEvent(ctx, EventTicketCreated, id)
+	return GetPortalTicket(ctx, id)
+}
+
+// portalTicketCustomer 确定提交工单的单位，customerID 必须是 customers 之一
+func portalTicketCustomer(customers []PortalCustomer, customerID uint) (uint, error) {
+	if customerID == 0 {
+		switch len(customers) {
+		case 0:
+			return 0, utils.ErrForbidden("账户未关联任何单位，无法提交工单")
+		case 1:
+			return customers[0].ID, nil
+		default:
+			return 0, utils.ErrValidation(utils.FieldError{Field: "customerId", Message: "账户关联了多个单位，必须指定提交工单的单位"})
+		}
+	}
+	for _, c := range customers {
+		if c.ID == customerID {
+			return customerID, nil
+		}
+	}
+	return 0, utils.ErrValidation(utils.FieldError{Field: "customerId", Message: "不能为该单位提交工单"})
+}
+
+// ConfirmTicketResolution 由客户确认工单已解决，工单状态从“待确认”改为“完成”。
+// 工单不处于“待确认”状态时返回 ErrTicketNotResolving；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func ConfirmTicketResolution(ctx context.Context, id string, expectedVersion *uint) (_ *PortalTicket, err error) {
+	ctx, span := startSpan(ctx, "ConfirmTicketResolution")
+	defer endSpan(span, &err)
+
+	ticket, err := findTicketRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.Status != models.TicketStatusResolving {
+		return nil, ErrTicketNotResolving
+	}
+	if expectedVersion == nil {
+		// 以读取到的版本为准，避免确认时工单已被处理人改回其他状态
+		expectedVersion = &ticket.Version
+	}
+
+	now := time.Now()
+	err = updateVersioned(ctx, &models.TicketRecord{}, "工单", "ticket_id", id, expectedVersion, map[string]interface{}{
+		"status":          models.TicketStatusCompleted,
+		"completion_time": now,
+		"resolved_at":     gorm.Expr("COALESCE(resolved_at, ?)", now),
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishTicketEvent(ctx, EventTicketUpdated, id, "status")
+	return GetPortalTicket(ctx, id)
+}
+
+// RateTicket 由客户评价已完成工单的服务，rating 为 1 到 5 分，再次评价时覆盖之前的评价。
+// 工单尚未完成时返回 ErrTicketNotCompleted；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func RateTicket(ctx context.Context, id string, rating int, comment string, expectedVersion *uint) (_ *PortalTicket, err error) {
+	ctx, span := startSpan(ctx, "RateTicket")
+	defer endSpan(span, &err)
+
+	ticket, err := findTicketRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.Status != models.TicketStatusCompleted {
+		return nil, ErrTicketNotCompleted
+	}
+	if expectedVersion == nil {
+		expectedVersion = &ticket.Version
+	}
+
+	err = updateVersioned(ctx, &models.TicketRecord{}, "工单", "ticket_id", id, expectedVersion, map[string]interface{}{
+		"rating":         rating,
+		"rating_comment": sql.NullString{String: comment, Valid: comment != ""},
+		"rated_at":       time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishTicketEvent(ctx, EventTicketUpdated, id, "rating")
+	return GetPortalTicket(ctx, id)
+}
+
+// AddPortalTicketComment 以客户门户用户的身份回复工单，客户的回复总是公开的
+func AddPortalTicketComment(ctx context.Context, ticketID, authorID, content string) (_ *models.TicketComment, err error) {
+	ctx, span := startSpan(ctx, "AddPortalTicketComment")
+	defer endSpan(span, &err)
+
+	return addTicketComment(ctx, ticketID, authorID, content, false)
+}
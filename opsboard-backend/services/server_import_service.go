@@ -0,0 +1,290 @@
+/**
+ * @file services/server_import_service.go
+ * @description 提供服务器批量导入的业务逻辑：表头识别、逐行校验、客户名称解析以及按 (客户, IP) 进行 upsert。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [Webhook]：导入成功提交后，为每个新建的服务器发布 `server.added` 事件；试运行和被回滚的导入不发布事件。
+ */
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ServerImportColumns 定义了服务器导入/导出文件中可导入的列。
+// 导入时接受任意语言的表头以及字段名本身（如 `customerName`），未知的列（如 ID、创建时间）会被忽略，
+// 因此导出的文件可以直接修改后重新导入。前三列（客户名称、服务器名称、IP地址）为必需列。
+var ServerImportColumns = []utils.ExportColumn{
+	utils.Col("customerName", "客户名称", "Customer"),
+	utils.Col("serverName", "服务器名称", "Server Name"),
+	utils.Col("ipAddress", "IP地址", "IP Address"),
+	utils.Col("role", "角色", "Role"),
+	utils.Col("deploymentType", "部署类型", "Deployment Type"),
+	utils.Col("customerNote", "客户备注", "Customer Note"),
+	utils.Col("usageNote", "使用备注", "Usage Note"),
+}
+
+// serverFieldMaxLength 对应 `servers` 表中各 varchar 列的最大长度
+var serverFieldMaxLength = map[string]int{
+	"serverName":     100,
+	"ipAddress":      50,
+	"role":           50,
+	"deploymentType": 100,
+	"customerNote":   500,
+	"usageNote":      1000,
+}
+
+// errImportRollback 是一个哨兵错误，用于在试运行或存在校验错误时主动回滚事务
+var errImportRollback = errors.New("import rolled back")
+
+// ServerImportRowError 描述了导入文件中某一行的校验错误
+type ServerImportRowError struct {
+	Row     int    `json:"row"`             // 文件中的行号（从 1 开始，表头为第 1 行）
+	Field   string `json:"field,omitempty"` // 出错的字段名，整行错误时为空
+	Message string `json:"message"`
+}
+
+// ServerImportResult 定义了批量导入的返回结构
+type ServerImportResult struct {
+	DryRun  bool                   `json:"dryRun"`
+	Applied bool                   `json:"applied"` // 数据是否已真正写入数据库
+	Total   int                    `json:"total"`   // 数据行总数（不含表头和空行）
+	Created int                    `json:"created"`
+	Updated int                    `json:"updated"`
+	Errors  []ServerImportRowError `json:"errors"`
+}
+
+// serverImportRow 是从表格中解析出的一行待导入数据
+type serverImportRow struct {
+	line       int
+	customerID uint
+	values     map[string]string
+}
+
+// ImportServers 校验并导入表格数据。rows 的第一行必须是表头。
+// 在 dryRun 模式下，所有的校验和 upsert 都会执行，但最终事务会被回滚，返回的计数代表“将会”创建和更新的数量。
+func ImportServers(ctx context.Context, rows [][]string, dryRun bool) (_ *ServerImportResult, err error) {
+	ctx, span := startSpan(ctx, "ImportServers")
+	defer endSpan(span, &err)
+
+	result := &ServerImportResult{DryRun: dryRun, Errors: make([]ServerImportRowError, 0)}
+
+	if len(rows) == 0 {
+		result.Errors = append(result.Errors, ServerImportRowError{Row: 1, Message: "文件为空，缺少表头"})
+		return result, nil
+	}
+
+	columnIndex, missing := resolveServerImportHeader(rows[0])
+	if len(missing) > 0 {
+		result.Errors = append(result.Errors, ServerImportRowError{
+			Row:     1,
+			Message: fmt.Sprintf("缺少必需的列: %s", strings.Join(missing, ", ")),
+		})
+		return result, nil
+	}
+
+	customerIDs, err := loadCustomerIDsByName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := make([]serverImportRow, 0, len(rows)-1)
+	seen := make(map[string]int) // (customer_id, ip) -> 首次出现的行号
+	for i, raw := range rows[1:] {
+		line := i + 2
+		if isBlankRow(raw) {
+			continue
+		}
+		result.Total++
+
+		row, rowErrors := validateServerImportRow(line, raw, columnIndex, customerIDs)
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+
+		key := fmt.Sprintf("%d|%s", row.customerID, row.values["ipAddress"])
+		if first, ok := seen[key]; ok {
+			result.Errors = append(result.Errors, ServerImportRowError{
+				Row:     line,
+				Field:   "ipAddress",
+				Message: fmt.Sprintf("与第 %d 行的客户和 IP 重复", first),
+			})
+			continue
+		}
+		seen[key] = line
+		parsed = append(parsed, row)
+	}
+
+	var added []models.Server
+	err = gormDB(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, row := range parsed {
+			server, created, err := upsertImportedServer(tx, row)
+			if err != nil {
+				return fmt.Errorf("第 %d 行写入失败: %w", row.line, err)
+			}
+			if created {
+				result.Created++
+				added = append(added, server)
+			} else {
+				result.Updated++
+			}
+		}
+		if dryRun || len(result.Errors) > 0 {
+			return errImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRollback) {
+		return nil, err
+	}
+
+	result.Applied = err == nil
+	if result.Applied {
+		for i := range added {
+			PublishEvent(ctx, EventServerAdded, &added[i])
+		}
+	}
+	return result, nil
+}
+
+// resolveServerImportHeader 将表头映射为 字段名 -> 列下标，并返回缺失的必需列（以中文表头表示）
+func resolveServerImportHeader(header []string) (map[string]int, []string) {
+	lookup := make(map[string]string, len(ServerImportColumns)*3)
+	for _, col := range ServerImportColumns {
+		lookup[strings.ToLower(col.Key)] = col.Key
+		for _, h := range col.Headers {
+			lookup[strings.ToLower(h)] = col.Key
+		}
+	}
+
+	index := make(map[string]int)
+	for i, name := range header {
+		field, ok := lookup[strings.ToLower(strings.TrimSpace(name))]
+		if ok {
+			if _, dup := index[field]; !dup {
+				index[field] = i
+			}
+		}
+	}
+
+	var missing []string
+	for _, col := range ServerImportColumns[:3] { // 客户名称、服务器名称、IP地址 为必需列
+		if _, ok := index[col.Key]; !ok {
+			missing = append(missing, col.Header(utils.LangZH))
+		}
+	}
+	return index, missing
+}
+
+// validateServerImportRow 校验单行数据，并解析客户名称
+func validateServerImportRow(line int, raw []string, columnIndex map[string]int, customerIDs map[string]uint) (serverImportRow, []ServerImportRowError) {
+	row := serverImportRow{line: line, values: make(map[string]string, len(columnIndex))}
+	var errs []ServerImportRowError
+
+	for field, idx := range columnIndex {
+		if idx < len(raw) {
+			row.values[field] = raw[idx]
+		}
+	}
+
+	for _, field := range []string{"customerName", "serverName", "ipAddress"} {
+		if row.values[field] == "" {
+			errs = append(errs, ServerImportRowError{Row: line, Field: field, Message: "不能为空"})
+		}
+	}
+
+	if name := row.values["customerName"]; name != "" {
+		id, ok := customerIDs[name]
+		if !ok {
+			errs = append(errs, ServerImportRowError{Row: line, Field: "customerName", Message: fmt.Sprintf("客户“%s”不存在", name)})
+		}
+		row.customerID = id
+	}
+
+	if ip := row.values["ipAddress"]; ip != "" && net.ParseIP(ip) == nil {
+		errs = append(errs, ServerImportRowError{Row: line, Field: "ipAddress", Message: "不是有效的 IP 地址"})
+	}
+
+	for field, max := range serverFieldMaxLength {
+		if utf8.RuneCountInString(row.values[field]) > max {
+			errs = append(errs, ServerImportRowError{Row: line, Field: field, Message: fmt.Sprintf("长度不能超过 %d 个字符", max)})
+		}
+	}
+
+	return row, errs
+}
+
+// upsertImportedServer 按 (customer_id, ip_address) 查找服务器：存在则更新，不存在则创建。返回新建的服务器和是否为新建。
+// 查找时包含已软删除的服务器，命中时会将其从回收站中恢复。
+func upsertImportedServer(tx *gorm.DB, row serverImportRow) (models.Server, bool, error) {
+	var existing models.Server
+	err := tx.Unscoped().Where("customer_id = ? AND ip_address = ?", row.customerID, row.values["ipAddress"]).
+		Take(&existing).Error
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		server := models.Server{
+			CustomerID:     row.customerID,
+			ServerName:     row.values["serverName"],
+			IPAddress:      row.values["ipAddress"],
+			Role:           nullString(row.values["role"]),
+			DeploymentType: nullString(row.values["deploymentType"]),
+			CustomerNote:   nullString(row.values["customerNote"]),
+			UsageNote:      nullString(row.values["usageNote"]),
+		}
+		return server, true, tx.Create(&server).Error
+	case err != nil:
+		return models.Server{}, false, err
+	}
+
+	err = tx.Unscoped().Model(&existing).Updates(map[string]interface{}{
+		"server_name":     row.values["serverName"],
+		"role":            nullString(row.values["role"]),
+		"deployment_type": nullString(row.values["deploymentType"]),
+		"customer_note":   nullString(row.values["customerNote"]),
+		"usage_note":      nullString(row.values["usageNote"]),
+		"deleted_at":      nil,
+		"version":         gorm.Expr("version + 1"),
+	}).Error
+	return models.Server{}, false, err
+}
+
+// loadCustomerIDsByName 一次性加载访问范围内的所有客户，返回 客户名称 -> customer_id 的映射
+func loadCustomerIDsByName(ctx context.Context) (map[string]uint, error) {
+	var customers []models.Customer
+	err := gormDB(ctx).Scopes(customerScope(ctx, "customers.customer_id")).Select("customer_id", "customer_name").Find(&customers).Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]uint, len(customers))
+	for _, c := range customers {
+		ids[c.CustomerName] = c.CustomerID
+	}
+	return ids, nil
+}
+
+// isBlankRow 判断一行是否所有单元格都为空
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if cell != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// nullString 将空字符串转换为数据库 NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -1,13 +1,15 @@
 // @file services/server_service.go
 // @description 提供与服务器相关的业务逻辑，使用 GORM 实现分页查询、删除和按 ID 查询操作。
 // @modification 本次提交中所做的具体修改摘要。
-//   - [依赖清理]：移除了未使用的 `gorm.io/gorm` 包导入，以修复 "imported and not used" 编译错误。该包中的错误类型（如 `gorm.ErrRecordNotFound`）已由上层 handler 处理，因此在本文件中无需导入。
+//   - [Webhook]：删除服务器后发布 `server.deleted` 事件；新增 `ReportServerOffline`，供监控工具报告服务器离线并发布 `server.offline` 事件。
 
 package services
 
 import (
-	"opsboard-backend/database"
+	"context"
 	"opsboard-backend/models"
+
+	"gorm.io/gorm"
 )
 
 // PaginatedServersResult 定义了分页查询的返回结构
@@ -16,22 +18,59 @@ type PaginatedServersResult struct {
 	Data  []models.Server `json:"data"`
 }
 
+// ServerFilter 定义了服务器列表的筛选条件，所有字段均为可选。
+// 列表查询和导出共用此结构，确保导出的数据与用户当前看到的列表一致。
+type ServerFilter struct {
+	CustomerName   string `form:"customerName"`
+	ServerName     string `form:"serverName"`
+	IP             string `form:"ip"`
+	Role           string `form:"role"`
+	DeploymentType string `form:"deploymentType"`
+}
+
+// applyServerFilter 将筛选条件应用到已 JOIN 了 customers 表（别名 c）的查询上
+func applyServerFilter(query *gorm.DB, filter ServerFilter) *gorm.DB {
+	if filter.CustomerName != "" {
+		query = query.Where("c.customer_name LIKE ?", "%"+filter.CustomerName+"%")
+	}
+	if filter.ServerName != "" {
+		query = query.Where("servers.server_name LIKE ?", "%"+filter.ServerName+"%")
+	}
+	if filter.IP != "" {
+		query = query.Where("servers.ip_address LIKE ?", filter.IP+"%")
+	}
+	if filter.Role != "" {
+		query = query.Where("servers.role = ?", filter.Role)
+	}
+	if filter.DeploymentType != "" {
+		query = query.Where("servers.deployment_type = ?", filter.DeploymentType)
+	}
+	return query
+}
+
+// serverListQuery 构造服务器列表的基础查询（包含客户表 JOIN 和筛选条件）
+func serverListQuery(ctx context.Context, filter ServerFilter) *gorm.DB {
+	query := gormDB(ctx).Model(&models.Server{}).
+		Joins("LEFT JOIN customers c ON servers.customer_id = c.customer_id").
+		Scopes(customerScope(ctx, "servers.customer_id"))
+	return applyServerFilter(query, filter)
+}
+
 // GetPaginatedServers 使用 GORM 从数据库中分页查询服务器列表。
-func GetPaginatedServers(page, pageSize int) (*PaginatedServersResult, error) {
-	db := database.GormDB
+func GetPaginatedServers(ctx context.Context, page, pageSize int, filter ServerFilter) (_ *PaginatedServersResult, err error) {
+	ctx, span := startSpan(ctx, "GetPaginatedServers")
+	defer endSpan(span, &err)
+
 	var servers []models.Server
 	var total int64
 
-	query := db.Model(&models.Server{})
-
-	if err := query.Count(&total).Error; err != nil {
+	if err := serverListQuery(ctx, filter).Count(&total).Error; err != nil {
 		return nil, err
 	}
 
 	offset := (page - 1) * pageSize
 
-	err := query.
-		Joins("LEFT JOIN customers c ON servers.customer_id = c.customer_id").
+	err = serverListQuery(ctx, filter).
 		Select("servers.*, c.customer_name").
 		Offset(offset).
 		Limit(pageSize).
@@ -54,30 +93,74 @@ func GetPaginatedServers(page, pageSize int) (*PaginatedServersResult, error) {
 	return result, nil
 }
 
+// StreamServers 按列表的排序逐行遍历所有符合筛选条件的服务器，并对每一行调用 fn。
+// 如果 fn 返回错误，遍历会立即停止并返回该错误。
+func StreamServers(ctx context.Context, filter ServerFilter, fn func(server *models.Server) error) (err error) {
+	ctx, span := startSpan(ctx, "StreamServers")
+	defer endSpan(span, &err)
+
+	query := serverListQuery(ctx, filter).
+		Select("servers.*, c.customer_name").
+		Order("servers.created_at DESC")
+	return streamQuery(query, fn)
+}
+
 // GetServerByID 使用 GORM 根据 ID 从数据库中查找一个服务器的详细信息。
-func GetServerByID(id string) (*models.Server, error) {
-	db := database.GormDB
+func GetServerByID(ctx context.Context, id string) (_ *models.Server, err error) {
+	ctx, span := startSpan(ctx, "GetServerByID")
+	defer endSpan(span, &err)
+
 	var server models.Server
 
 	// 使用 Joins 和 Select 来获取服务器数据以及关联的客户名称
 	// First 方法会在找到第一条匹配记录后停止，并将其填充到 server 变量中
-	// 如果没有找到记录，它将返回 gorm.ErrRecordNotFound 错误
-	err := db.Model(&models.Server{}).
+	err = gormDB(ctx).Model(&models.Server{}).
 		Joins("LEFT JOIN customers c ON servers.customer_id = c.customer_id").
+		Scopes(customerScope(ctx, "servers.customer_id")).
 		Select("servers.*, c.customer_name").
 		First(&server, "servers.server_id = ?", id).Error
 
 	if err != nil {
-		// 直接返回错误，让 handler 层去判断是 gorm.ErrRecordNotFound 还是其他错误
-		return nil, err
+		// 未找到记录时转换为 404 错误，其他数据库错误原样返回
+		return nil, asNotFound(err, "服务器")
 	}
 
 	return &server, nil
 }
 
-// DeleteServerByID 使用 GORM 根据 ID 从数据库中删除一个服务器。
-func DeleteServerByID(id string) error {
-	db := database.GormDB
-	result := db.Delete(&models.Server{}, id)
-	return result.Error
+// DeleteServerByID 使用 GORM 根据 ID 软删除一个服务器（模型包含 DeletedAt，GORM 会自动转换为 UPDATE）。
+// 服务器不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func DeleteServerByID(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteServerByID")
+	defer endSpan(span, &err)
+
+	server, err := GetServerByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := deleteVersioned(ctx, &models.Server{}, "服务器", "server_id", id, expectedVersion); err != nil {
+		return err
+	}
+	PublishEvent(ctx, EventServerDeleted, server)
+	return nil
+}
+
+// ServerOfflineEventData 是 `server.offline` 事件的数据
+type ServerOfflineEventData struct {
+	Server *models.Server `json:"server"`
+	Reason string         `json:"reason,omitempty"`
+}
+
+// ReportServerOffline 报告服务器离线并发布 `server.offline` 事件，服务器本身不记录在线状态。
+// 服务器不存在或不在访问范围内时返回 404。
+func ReportServerOffline(ctx context.Context, id, reason string) (err error) {
+	ctx, span := startSpan(ctx, "ReportServerOffline")
+	defer endSpan(span, &err)
+
+	server, err := GetServerByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	PublishEvent(ctx, EventServerOffline, ServerOfflineEventData{Server: server, Reason: reason})
+	return nil
 }
@@ -0,0 +1,323 @@
+/**
+ * @file settings_service.go
+ * @description 管理用户的个人偏好设置和管理员维护的系统设置，二者都是带版本号的 JSON 文档。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。文档的结构由 `UserPreferences` 和 `SystemSettings` 定义，保存前按结构体标签校验；
+ *     读取时以默认值为基础解码，新增的字段自动取默认值。读取结果缓存在内存中，本实例写入时立即失效，
+ *     其他实例的缓存最多保留 `settingsCacheTTL`。系统设置中的会话时长在加载后写入 `utils`。
+ *   - [邮件通知]：用户偏好设置新增 `emailNotifications`，默认订阅除每日摘要以外的邮件通知。
+ */
+
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"opsboard-backend/metrics"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserPreferences 是用户的个人偏好设置
+type UserPreferences struct {
+	PageSize int    `json:"pageSize" binding:"oneof=10 20 50 100"`
+	Theme    string `json:"theme" binding:"oneof=system light dark"`
+	Language string `json:"language" binding:"oneof=zh-CN en-US"`
+	// DefaultFilters 按页面保存打开列表时默认使用的筛选条件，例如 {"tickets": {"status": "挂起"}}
+	DefaultFilters map[string]map[string]string `json:"defaultFilters" binding:"dive,keys,oneof=servers changelogs maintenance tickets customers trash audit-logs,endkeys,max=20,dive,keys,min=1,max=50,endkeys,max=200"`
+	// EmailNotifications 是用户订阅的邮件通知，用户没有设置邮箱地址时不会收到任何邮件
+	EmailNotifications EmailNotificationPreferences `json:"emailNotifications"`
+}
+
+// EmailNotificationPreferences 是用户对每种邮件通知的订阅开关
+type EmailNotificationPreferences struct {
+	TicketAssigned bool `json:"ticketAssigned"` // 工单被分配给自己
+	SLABreached    bool `json:"slaBreached"`    // 自己处理的工单 SLA 超时（管理员接收所有工单的超时通知）
+	TaskFailed     bool `json:"taskFailed"`     // 可访问客户的维护任务失败
+	DailyDigest    bool `json:"dailyDigest"`    // 每日摘要：挂起的更新日志和维护任务
+}
+
+// SystemSettings 是由管理员维护的全局设置
+type SystemSettings struct {
+	ChangelogUpdateTypes []string `json:"changelogUpdateTypes" binding:"required,min=1,max=50,unique,dive,required,max=100"`
+	TicketStatuses       []string `json:"ticketStatuses" binding:"required,min=1,max=20,unique,dive,required,max=50"`
+	AccessTokenMinutes   int      `json:"accessTokenMinutes" binding:"min=5,max=60"` // 访问令牌有效期（分钟）
+	RefreshTokenHours    int      `json:"refreshTokenHours" binding:"min=1,max=720"` // 刷新令牌有效期（小时），即不操作时保持登录的时长
+}
+
+// DefaultUserPreferences 返回用户没有保存过偏好设置时使用的默认值
+func DefaultUserPreferences() UserPreferences {
+	return UserPreferences{
+		PageSize:       10,
+		Theme:          "system",
+		Language:       "zh-CN",
+		DefaultFilters: map[string]map[string]string{},
+		EmailNotifications: EmailNotificationPreferences{
+			TicketAssigned: true,
+			SLABreached:    true,
+			TaskFailed:     true,
+		},
+	}
+}
+
+// DefaultSystemSettings 返回管理员没有保存过系统设置时使用的默认值
+func DefaultSystemSettings() SystemSettings {
+	return SystemSettings{
+		ChangelogUpdateTypes: []string{"服务部署", "日常维护", "应用变更", "缓存策略更新", "文件同步"},
+		TicketStatuses:       []string{"待处理", "处理中", "挂起", "待确认", "完成"},
+		AccessTokenMinutes:   int(utils.DefaultAccessTokenTTL / time.Minute),
+		RefreshTokenHours:    int(utils.DefaultRefreshTokenTTL / time.Hour),
+	}
+}
+
+// PreferencesDocument 是一个用户的偏好设置及其版本，Version 为 0 表示尚未保存过，Preferences 为默认值
+type PreferencesDocument struct {
+	Preferences UserPreferences `json:"preferences"`
+	Version     uint            `json:"version"`
+	UpdatedAt   *time.Time      `json:"updatedAt"`
+}
+
+// SystemSettingsDocument 是系统设置及其版本，Version 为 0 表示尚未保存过，Settings 为默认值
+type SystemSettingsDocument struct {
+	Settings  SystemSettings `json:"settings"`
+	Version   uint           `json:"version"`
+	UpdatedBy *string        `json:"updatedBy"`
+	UpdatedAt *time.Time     `json:"updatedAt"`
+}
+
+// systemSettingsKey 是系统设置在 system_settings 表中的键
+const systemSettingsKey = "global"
+
+// settingsCacheTTL 是设置在内存中缓存的时长，也是其他实例的修改在本实例生效的最长延迟
+const settingsCacheTTL = time.Minute
+
+// cachedPreferences 是缓存中的一份偏好设置
+type cachedPreferences struct {
+	doc      *PreferencesDocument
+	loadedAt time.Time
+}
+
+var (
+	settingsMu             sync.Mutex
+	preferencesCache       = make(map[string]cachedPreferences) // 用户 ID -> 偏好设置
+	systemSettingsCache    *SystemSettingsDocument
+	systemSettingsLoadedAt time.Time
+)
+
+// GetUserPreferences 返回用户的偏好设置，用户没有保存过时返回默认值
+func GetUserPreferences(ctx context.Context, userID string) (_ *PreferencesDocument, err error) {
+	ctx, span := startSpan(ctx, "GetUserPreferences")
+	defer endSpan(span, &err)
+
+	settingsMu.Lock()
+	cached, ok := preferencesCache[userID]
+	settingsMu.Unlock()
+	if ok && time.Since(cached.loadedAt) < settingsCacheTTL {
+		return cached.doc, nil
+	}
+
+	var row models.UserPreference
+	err = gormDB(ctx).Where("user_id = ?", userID).Take(&row).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	doc, err := preferencesDocument(&row)
+	if err != nil {
+		return nil, err
+	}
+
+	settingsMu.Lock()
+	preferencesCache[userID] = cachedPreferences{doc: doc, loadedAt: time.Now()}
+	settingsMu.Unlock()
+	return doc, nil
+}
+
+// UpdateUserPreferences 整体替换用户的偏好设置。
+// expectedVersion 不为 nil 且与当前版本不一致时返回 ErrVersionConflict。
+func UpdateUserPreferences(ctx context.Context, userID string, prefs UserPreferences, expectedVersion *uint) (_ *PreferencesDocument, err error) {
+	ctx, span := startSpan(ctx, "UpdateUserPreferences")
+	defer endSpan(span, &err)
+
+	if err := validateSettings(&prefs); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return nil, err
+	}
+
+	row := models.UserPreference{UserID: userID, Data: "{}"}
+	err = gormDB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockSettingsRow(tx, &row, "user_id", userID, expectedVersion, func() uint { return row.Version }); err != nil {
+			return err
+		}
+		return tx.Model(&row).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"data":    string(data),
+			"version": gorm.Expr("version + 1"),
+		}).Error
+	})
+
+	settingsMu.Lock()
+	delete(preferencesCache, userID)
+	settingsMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return GetUserPreferences(ctx, userID)
+}
+
+// GetSystemSettings 返回系统设置，管理员没有保存过时返回默认值
+func GetSystemSettings(ctx context.Context) (_ *SystemSettingsDocument, err error) {
+	ctx, span := startSpan(ctx, "GetSystemSettings")
+	defer endSpan(span, &err)
+
+	settingsMu.Lock()
+	cached, loadedAt := systemSettingsCache, systemSettingsLoadedAt
+	settingsMu.Unlock()
+	if cached != nil && time.Since(loadedAt) < settingsCacheTTL {
+		return cached, nil
+	}
+	return loadSystemSettings(ctx)
+}
+
+// UpdateSystemSettings 整体替换系统设置，updatedBy 为执行修改的管理员。
+// expectedVersion 不为 nil 且与当前版本不一致时返回 ErrVersionConflict。
+func UpdateSystemSettings(ctx context.Context, settings SystemSettings, updatedBy string, expectedVersion *uint) (_ *SystemSettingsDocument, err error) {
+	ctx, span := startSpan(ctx, "UpdateSystemSettings")
+	defer endSpan(span, &err)
+
+	if err := validateSettings(&settings); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	row := models.SystemSetting{SettingKey: systemSettingsKey, Data: "{}"}
+	err = gormDB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockSettingsRow(tx, &row, "setting_key", systemSettingsKey, expectedVersion, func() uint { return row.Version }); err != nil {
+			return err
+		}
+		return tx.Model(&row).Where("setting_key = ?", systemSettingsKey).Updates(map[string]interface{}{
+			"data":       string(data),
+			"version":    gorm.Expr("version + 1"),
+			"updated_by": updatedBy,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loadSystemSettings(ctx)
+}
+
+// StartSettingsReload 启动定期重新加载系统设置的后台任务，使其他实例修改的会话时长在本实例生效。
+// 应在首次加载系统设置后调用一次。
+func StartSettingsReload() {
+	go func() {
+		ticker := time.NewTicker(settingsCacheTTL)
+		defer ticker.Stop()
+		for range ticker.C {
+			_, err := loadSystemSettings(context.Background())
+			metrics.TaskExecutionsTotal.WithLabelValues("settings_reload", metrics.Result(err)).Inc()
+			if err != nil {
+				// 加载失败时继续使用上一次的设置，下次再试
+				slog.Error("重新加载系统设置失败", "error", err)
+			}
+		}
+	}()
+}
+
+// LoadSystemSettings 从数据库加载系统设置并应用其中的会话时长，应在启动时调用一次
+func LoadSystemSettings(ctx context.Context) error {
+	_, err := loadSystemSettings(ctx)
+	return err
+}
+
+// loadSystemSettings 从数据库读取系统设置，刷新缓存并应用会话时长
+func loadSystemSettings(ctx context.Context) (*SystemSettingsDocument, error) {
+	var row models.SystemSetting
+	err := gormDB(ctx).Where("setting_key = ?", systemSettingsKey).Take(&row).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	settings := DefaultSystemSettings()
+	if err := decodeSettings(row.Data, &settings); err != nil {
+		return nil, err
+	}
+	doc := &SystemSettingsDocument{Settings: settings, Version: row.Version}
+	if row.UpdatedBy.Valid {
+		doc.UpdatedBy = &row.UpdatedBy.String
+	}
+	if !row.UpdatedAt.IsZero() {
+		doc.UpdatedAt = &row.UpdatedAt
+	}
+
+	utils.SetSessionLifetimes(
+		time.Duration(settings.AccessTokenMinutes)*time.Minute,
+		time.Duration(settings.RefreshTokenHours)*time.Hour,
+	)
+	settingsMu.Lock()
+	systemSettingsCache, systemSettingsLoadedAt = doc, time.Now()
+	settingsMu.Unlock()
+	return doc, nil
+}
+
+// preferencesDocument 把数据库中的偏好设置行转换为文档，row 为零值时返回默认值
+func preferencesDocument(row *models.UserPreference) (*PreferencesDocument, error) {
+	prefs := DefaultUserPreferences()
+	if err := decodeSettings(row.Data, &prefs); err != nil {
+		return nil, err
+	}
+	doc := &PreferencesDocument{Preferences: prefs, Version: row.Version}
+	if !row.UpdatedAt.IsZero() {
+		doc.UpdatedAt = &row.UpdatedAt
+	}
+	return doc, nil
+}
+
+// lockSettingsRow 在事务中锁定设置行（不存在时先插入一行版本为 0 的空文档），并确认当前版本与 expectedVersion 一致
+func lockSettingsRow(tx *gorm.DB, row interface{}, keyColumn, key string, expectedVersion *uint, version func() uint) error {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(row).Error; err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(keyColumn+" = ?", key).Take(row).Error; err != nil {
+		return err
+	}
+	if expectedVersion != nil && *expectedVersion != version() {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// decodeSettings 把保存的 JSON 文档解码到已填好默认值的 v 上，data 为空时保持默认值
+func decodeSettings(data string, v interface{}) error {
+	if data == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		return fmt.Errorf("设置文档格式错误: %w", err)
+	}
+	return nil
+}
+
+// validateSettings 按结构体的 binding 标签校验设置文档，并检查标签无法表达的规则
+func validateSettings(v interface{}) error {
+	if err := binding.Validator.ValidateStruct(v); err != nil {
+		return utils.BindingError(err)
+	}
+	if s, ok := v.(*SystemSettings); ok && time.Duration(s.RefreshTokenHours)*time.Hour <= time.Duration(s.AccessTokenMinutes)*time.Minute {
+		return utils.ErrValidation(utils.FieldError{Field: "refreshTokenHours", Message: "必须长于访问令牌的有效期"})
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+/**
+ * @file services/sla_evaluator.go
+ * @description 定期评估工单 SLA 的后台任务：计算截止时间、标记超时并升级。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [审计日志]：写入审计日志时记录被操作对象的实体类型和 ID。
+ */
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"opsboard-backend/metrics"
+	"opsboard-backend/models"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultSLAEvaluationInterval 是 SLA 评估任务的默认运行间隔
+const DefaultSLAEvaluationInterval = time.Minute
+
+// slaBatchSize 是 SLA 评估任务每次查询处理的工单数
+const slaBatchSize = 200
+
+// slaTarget 描述了 SLA 的一个考核目标（首次响应或解决）
+type slaTarget struct {
+	name           string // 审计日志中的目标名称
+	dueColumn      string // 截止时间列
+	breachedColumn string // 超时标记列
+	openCondition  string // 目标尚未达成的条件
+	dueAt          func(t *models.TicketRecord) time.Time
+}
+
+var slaTargets = []slaTarget{
+	{
+		name: "response", dueColumn: "response_due_at", breachedColumn: "response_breached_at",
+		openCondition: "first_response_at IS NULL",
+		dueAt:         func(t *models.TicketRecord) time.Time { return t.ResponseDueAt.Time },
+	},
+	{
+		name: "resolve", dueColumn: "resolve_due_at", breachedColumn: "resolve_breached_at",
+		openCondition: "resolved_at IS NULL AND completion_time IS NULL",
+		dueAt:         func(t *models.TicketRecord) time.Time { return t.ResolveDueAt.Time },
+	},
+}
+
+// slaRules 是一次评估中使用的 SLA 策略和工作日历
+type slaRules struct {
+	byID       map[uint]*models.SLAPolicy
+	byCustomer map[slaPolicyKey]*models.SLAPolicy
+	calendars  map[uint]*models.BusinessCalendar
+}
+
+// slaPolicyKey 是按客户和优先级匹配策略的键，CustomerID 为 0 表示适用于所有客户的策略
+type slaPolicyKey struct {
+	customerID uint
+	priority   string
+}
+
+// StartSLAEvaluator 启动定期评估工单 SLA 的后台任务，interval 不大于 0 时使用 DefaultSLAEvaluationInterval
+func StartSLAEvaluator(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSLAEvaluationInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			err := metrics.ObserveSchedulerRun("sla_evaluator", func() error {
+				return EvaluateSLA(context.Background())
+			})
+			if err != nil {
+				slog.Error("评估工单 SLA 失败", "error", err)
+			}
+		}
+	}()
+}
+
+// EvaluateSLA 计算尚未计算截止时间的工单的 SLA 截止时间，然后标记并升级超时的工单
+func EvaluateSLA(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "EvaluateSLA")
+	defer endSpan(span, &err)
+
+	// 后台任务不代表任何用户，处理所有客户的工单
+	ctx = WithAccessScope(ctx, GlobalAccess)
+	rules, err := loadSLARules(gormDB(ctx))
+	if err != nil {
+		return err
+	}
+	if err := computeSLADueTimes(ctx, rules); err != nil {
+		return err
+	}
+	for _, target := range slaTargets {
+		if err := escalateSLABreaches(ctx, rules, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSLARules 加载所有 SLA 策略和工作日历
+func loadSLARules(db *gorm.DB) (*slaRules, error) {
+	var policies []models.SLAPolicy
+	if err := db.Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	var calendars []models.BusinessCalendar
+	if err := db.Find(&calendars).Error; err != nil {
+		return nil, err
+	}
+
+	rules := &slaRules{
+		byID:       make(map[uint]*models.SLAPolicy, len(policies)),
+		byCustomer: make(map[slaPolicyKey]*models.SLAPolicy, len(policies)),
+		calendars:  make(map[uint]*models.BusinessCalendar, len(calendars)),
+	}
+	for i := range policies {
+		p := &policies[i]
+		rules.byID[p.PolicyID] = p
+		rules.byCustomer[slaPolicyKey{customerID: uint(p.CustomerID.Int64), priority: p.Priority}] = p
+	}
+	for i := range calendars {
+		rules.calendars[calendars[i].CalendarID] = &calendars[i]
+	}
+	return rules, nil
+}
+
+// match 返回适用于客户和优先级的策略：客户专属的策略优先，其次是适用于所有客户的策略；都没有时返回 nil
+func (r *slaRules) match(customerID uint, priority string) *models.SLAPolicy {
+	if p, ok := r.byCustomer[slaPolicyKey{customerID: customerID, priority: priority}]; ok {
+		return p
+	}
+	return r.byCustomer[slaPolicyKey{priority: priority}]
+}
+
+// dueTimes 按策略计算从 start 开始的首次响应和解决截止时间
+func (r *slaRules) dueTimes(policy *models.SLAPolicy, start time.Time) (response, resolve time.Time, err error) {
+	if !policy.CalendarID.Valid {
+		return start.Add(time.Duration(policy.ResponseMinutes) * time.Minute),
+			start.Add(time.Duration(policy.ResolveMinutes) * time.Minute), nil
+	}
+
+	calendar, ok := r.calendars[uint(policy.CalendarID.Int64)]
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("工作日历 %d 不存在", policy.CalendarID.Int64)
+	}
+	if response, err = addBusinessMinutes(calendar, start, policy.ResponseMinutes); err != nil {
+		return
+	}
+	resolve, err = addBusinessMinutes(calendar, start, policy.ResolveMinutes)
+	return
+}
+
+// computeSLADueTimes 为 `sla_evaluated_at` 为空的工单匹配策略并计算截止时间，同时清除之前的超时标记
+func computeSLADueTimes(ctx context.Context, rules *slaRules) error {
+	db := gormDB(ctx)
+	for {
+		var tickets []models.TicketRecord
+		err := db.Select("ticket_id", "customer_id", "priority", "created_at").
+			Where("sla_evaluated_at IS NULL").
+			Order("ticket_id ASC").
+			Limit(slaBatchSize).
+			Find(&tickets).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, t := range tickets {
+			updates := map[string]interface{}{
+				"sla_evaluated_at":     now,
+				"sla_policy_id":        nil,
+				"response_due_at":      nil,
+				"resolve_due_at":       nil,
+				"response_breached_at": nil,
+				"resolve_breached_at":  nil,
+			}
+			if policy := rules.match(t.CustomerID, t.Priority); policy != nil {
+				response, resolve, err := rules.dueTimes(policy, t.CreatedAt)
+				if err != nil {
+					// 日历中的工作时间不足以计算截止时间，视为没有适用的策略
+					slog.WarnContext(ctx, "无法计算工单的 SLA 截止时间", "ticket_id", t.TicketID, "policy_id", policy.PolicyID, "error", err)
+				} else {
+					updates["sla_policy_id"] = policy.PolicyID
+					updates["response_due_at"] = response
+					updates["resolve_due_at"] = resolve
+				}
+			}
+
+			// 计算期间优先级被修改的工单保持待计算状态，下一批次按新的优先级重新计算
+			err := db.Model(&models.TicketRecord{}).
+				Where("ticket_id = ? AND priority = ? AND sla_evaluated_at IS NULL", t.TicketID, t.Priority).
+				Updates(updates).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(tickets) < slaBatchSize {
+			return nil
+		}
+	}
+}
+
+// escalateSLABreaches 标记超过 target 截止时间仍未达成的工单，并按策略升级
+func escalateSLABreaches(ctx context.Context, rules *slaRules, target slaTarget) error {
+	db := gormDB(ctx)
+	for {
+		now := time.Now()
+		var tickets []models.TicketRecord
+		err := db.Where(target.breachedColumn+" IS NULL AND "+target.dueColumn+" < ? AND status <> ? AND "+target.openCondition,
+			now, models.TicketStatusCompleted).
+			Order("ticket_id ASC").
+			Limit(slaBatchSize).
+			Find(&tickets).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range tickets {
+			var policy *models.SLAPolicy
+			if tickets[i].SLAPolicyID.Valid {
+				policy = rules.byID[uint(tickets[i].SLAPolicyID.Int64)]
+			}
+			if err := escalateSLABreach(ctx, &tickets[i], policy, target, now); err != nil {
+				return err
+			}
+		}
+
+		if len(tickets) < slaBatchSize {
+			return nil
+		}
+	}
+}
+
+// escalateSLABreach 标记一个工单的 target 超时。策略指定了升级用户时把工单改派给该用户，并记录审计日志。
+// 其他实例已经标记过的工单不再重复升级。
+func escalateSLABreach(ctx context.Context, ticket *models.TicketRecord, policy *models.SLAPolicy, target slaTarget, now time.Time) error {
+	var escalateTo sql.NullString
+	if policy != nil && policy.EscalateToUserID.Valid && policy.EscalateToUserID != ticket.AssigneeID {
+		escalateTo = policy.EscalateToUserID
+	}
+
+	claimed := false
+	err := gormDB(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TicketRecord{}).
+			Where("ticket_id = ? AND "+target.breachedColumn+" IS NULL", ticket.TicketID).
+			Update(target.breachedColumn, now)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		claimed = true
+
+		if !escalateTo.Valid {
+			return nil
+		}
+		return tx.Model(&models.TicketRecord{}).Where("ticket_id = ?", ticket.TicketID).Updates(map[string]interface{}{
+			"assignee_id": escalateTo,
+			"version":     gorm.Expr("version + 1"),
+		}).Error
+	})
+	if err != nil || !claimed {
+		return err
+	}
+
+	details := LogDetails{
+		"ticket_id": ticket.TicketID,
+		"target":    target.name,
+		"due_at":    target.dueAt(ticket),
+	}
+	if escalateTo.Valid {
+		details["escalated_to"] = escalateTo.String
+	}
+	slog.WarnContext(ctx, "工单 SLA 超时", "ticket_id", ticket.TicketID, "target", target.name, "escalated_to", escalateTo.String)
+	CreateLog(ctx, "", string(TicketSLABreached), Target("tickets", ticket.TicketID), details)
+	id := strconv.FormatUint(uint64(ticket.TicketID), 10)
+	publishTicketEventData(ctx, EventTicketSLABreached, id, TicketEventData{SLATarget: target.name})
+	if escalateTo.Valid {
+		publishTicketEvent(ctx, EventTicketUpdated, id, "assignee")
+	}
+	return nil
+}
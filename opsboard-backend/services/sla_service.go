@@ -0,0 +1,427 @@
+/**
+ * @file services/sla_service.go
+ * @description 提供 SLA 策略和工作日历的管理，以及按策略计算工单截止时间的业务逻辑。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建此文件。策略按客户和优先级匹配（客户专属的策略优先于适用于所有客户的策略），
+ *     截止时间在工单创建或修改优先级后由 SLA 评估任务计算；修改策略或日历只影响之后计算的工单。
+ */
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrCalendarInUse 表示工作日历正在被 SLA 策略使用，不能删除
+var ErrCalendarInUse = utils.NewAppError(utils.CodeConflict, "工作日历正在被 SLA 策略使用，无法删除")
+
+// maxCalendarDays 是按工作日历计算截止时间时最多向后查找的天数
+const maxCalendarDays = 3 * 366
+
+// BusinessCalendarInput 是创建或修改工作日历时提交的内容
+type BusinessCalendarInput struct {
+	Name      string              `json:"name" binding:"required,max=100"`
+	Timezone  string              `json:"timezone" binding:"required,max=64"`
+	WorkHours []models.WorkPeriod `json:"workHours" binding:"required,min=1,max=100"`
+	Holidays  []string            `json:"holidays" binding:"max=1000"`
+}
+
+// SLAPolicyInput 是创建或修改 SLA 策略时提交的内容，CustomerID 为空表示适用于所有客户，CalendarID 为空表示按自然时间计算
+type SLAPolicyInput struct {
+	Name             string  `json:"name" binding:"required,max=100"`
+	CustomerID       *uint   `json:"customerId"`
+	Priority         string  `json:"priority" binding:"required,oneof=P1 P2 P3 P4"`
+	ResponseMinutes  uint    `json:"responseMinutes" binding:"required,min=1,max=525600"`
+	ResolveMinutes   uint    `json:"resolveMinutes" binding:"required,min=1,max=525600"`
+	CalendarID       *uint   `json:"calendarId"`
+	EscalateToUserID *string `json:"escalateToUserId" binding:"omitempty,uuid"`
+}
+
+// GetBusinessCalendars 返回所有工作日历，按名称排序
+func GetBusinessCalendars(ctx context.Context) (_ []models.BusinessCalendar, err error) {
+	ctx, span := startSpan(ctx, "GetBusinessCalendars")
+	defer endSpan(span, &err)
+
+	calendars := make([]models.BusinessCalendar, 0)
+	err = gormDB(ctx).Order("name ASC").Find(&calendars).Error
+	return calendars, err
+}
+
+// GetBusinessCalendar 根据 ID 查找一个工作日历，不存在时返回 404
+func GetBusinessCalendar(ctx context.Context, id string) (_ *models.BusinessCalendar, err error) {
+	ctx, span := startSpan(ctx, "GetBusinessCalendar")
+	defer endSpan(span, &err)
+
+	var calendar models.BusinessCalendar
+	if err := gormDB(ctx).Take(&calendar, "calendar_id = ?", id).Error; err != nil {
+		return nil, asNotFound(err, "工作日历")
+	}
+	return &calendar, nil
+}
+
+// CreateBusinessCalendar 创建一个工作日历，名称已存在时返回 409
+func CreateBusinessCalendar(ctx context.Context, input BusinessCalendarInput) (_ *models.BusinessCalendar, err error) {
+	ctx, span := startSpan(ctx, "CreateBusinessCalendar")
+	defer endSpan(span, &err)
+
+	if err := validateBusinessCalendar(&input); err != nil {
+		return nil, err
+	}
+	if err := ensureCalendarNameAvailable(gormDB(ctx), input.Name, 0); err != nil {
+		return nil, err
+	}
+
+	calendar := models.BusinessCalendar{
+		Name:      input.Name,
+		Timezone:  input.Timezone,
+		WorkHours: input.WorkHours,
+		Holidays:  input.Holidays,
+		CreatedAt: time.Now(),
+	}
+	if err := gormDB(ctx).Create(&calendar).Error; err != nil {
+		return nil, err
+	}
+	return GetBusinessCalendar(ctx, strconv.FormatUint(uint64(calendar.CalendarID), 10))
+}
+
+// UpdateBusinessCalendar 整体替换一个工作日历的内容。
+// 日历不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func UpdateBusinessCalendar(ctx context.Context, id string, input BusinessCalendarInput, expectedVersion *uint) (_ *models.BusinessCalendar, err error) {
+	ctx, span := startSpan(ctx, "UpdateBusinessCalendar")
+	defer endSpan(span, &err)
+
+	if err := validateBusinessCalendar(&input); err != nil {
+		return nil, err
+	}
+	calendarID, _ := strconv.ParseUint(id, 10, 32)
+	if err := ensureCalendarNameAvailable(gormDB(ctx), input.Name, uint(calendarID)); err != nil {
+		return nil, err
+	}
+	workHours, err := json.Marshal(input.WorkHours)
+	if err != nil {
+		return nil, err
+	}
+	holidays, err := json.Marshal(input.Holidays)
+	if err != nil {
+		return nil, err
+	}
+
+	err = updateVersioned(ctx, &models.BusinessCalendar{}, "工作日历", "calendar_id", id, expectedVersion, map[string]interface{}{
+		"name":       input.Name,
+		"timezone":   input.Timezone,
+		"work_hours": string(workHours),
+		"holidays":   string(holidays),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetBusinessCalendar(ctx, id)
+}
+
+// DeleteBusinessCalendar 删除一个工作日历，仍有 SLA 策略使用时返回 ErrCalendarInUse。
+// 日历不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func DeleteBusinessCalendar(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteBusinessCalendar")
+	defer endSpan(span, &err)
+
+	var count int64
+	if err := gormDB(ctx).Model(&models.SLAPolicy{}).Where("calendar_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCalendarInUse
+	}
+	return deleteVersioned(ctx, &models.BusinessCalendar{}, "工作日历", "calendar_id", id, expectedVersion)
+}
+
+// ensureCalendarNameAvailable 确认日历名称未被 exceptID 以外的日历使用
+func ensureCalendarNameAvailable(db *gorm.DB, name string, exceptID uint) error {
+	var count int64
+	if err := db.Model(&models.BusinessCalendar{}).Where("name = ? AND calendar_id <> ?", name, exceptID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return utils.NewAppError(utils.CodeConflict, "日历名称已存在")
+	}
+	return nil
+}
+
+// validateBusinessCalendar 校验时区、工作时段和节假日的格式，并把节假日排序去重
+func validateBusinessCalendar(input *BusinessCalendarInput) error {
+	var details []utils.FieldError
+	if _, err := time.LoadLocation(input.Timezone); err != nil {
+		details = append(details, utils.FieldError{Field: "timezone", Message: "不是有效的时区"})
+	}
+	for i, p := range input.WorkHours {
+		field := fmt.Sprintf("workHours[%d]", i)
+		start, startErr := parseClock(p.Start)
+		end, endErr := parseClock(p.End)
+		switch {
+		case p.Weekday < 0 || p.Weekday > 6:
+			details = append(details, utils.FieldError{Field: field + ".weekday", Message: "必须是 0（周日）到 6（周六）之间的整数"})
+		case startErr != nil:
+			details = append(details, utils.FieldError{Field: field + ".start", Message: "必须是 HH:MM 格式的时间"})
+		case endErr != nil:
+			details = append(details, utils.FieldError{Field: field + ".end", Message: "必须是 HH:MM 格式的时间"})
+		case start >= end:
+			details = append(details, utils.FieldError{Field: field + ".end", Message: "必须晚于开始时间"})
+		}
+	}
+	for i, day := range input.Holidays {
+		if _, err := time.Parse(time.DateOnly, day); err != nil {
+			details = append(details, utils.FieldError{Field: fmt.Sprintf("holidays[%d]", i), Message: "必须是 YYYY-MM-DD 格式的日期"})
+		}
+	}
+	if len(details) > 0 {
+		return utils.ErrValidation(details...)
+	}
+
+	if input.Holidays == nil {
+		input.Holidays = []string{}
+	}
+	slices.Sort(input.Holidays)
+	input.Holidays = slices.Compact(input.Holidays)
+	return nil
+}
+
+// parseClock 把 `HH:MM`（00:00 ~ 24:00）解析为从零点开始的分钟数
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok || len(hh) != 2 || len(mm) != 2 {
+		return 0, errors.New("invalid clock")
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, errors.New("invalid clock")
+	}
+	return h*60 + m, nil
+}
+
+// addBusinessMinutes 返回从 start 开始经过 minutes 分钟工作时间后的时刻，节假日和工作时段以外的时间不计入
+func addBusinessMinutes(calendar *models.BusinessCalendar, start time.Time, minutes uint) (time.Time, error) {
+	loc, err := time.LoadLocation(calendar.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	holidays := make(map[string]bool, len(calendar.Holidays))
+	for _, day := range calendar.Holidays {
+		holidays[day] = true
+	}
+
+	start = start.In(loc)
+	remaining := time.Duration(minutes) * time.Minute
+	for i := 0; i < maxCalendarDays; i++ {
+		day := time.Date(start.Year(), start.Month(), start.Day()+i, 0, 0, 0, 0, loc)
+		if holidays[day.Format(time.DateOnly)] {
+			continue
+		}
+
+		periods := make([]models.WorkPeriod, 0, 2)
+		for _, p := range calendar.WorkHours {
+			if time.Weekday(p.Weekday) == day.Weekday() {
+				periods = append(periods, p)
+			}
+		}
+		slices.SortFunc(periods, func(a, b models.WorkPeriod) int { return strings.Compare(a.Start, b.Start) })
+
+		for _, p := range periods {
+			from, _ := parseClock(p.Start)
+			to, _ := parseClock(p.End)
+			periodStart := time.Date(day.Year(), day.Month(), day.Day(), 0, from, 0, 0, loc)
+			periodEnd := time.Date(day.Year(), day.Month(), day.Day(), 0, to, 0, 0, loc)
+			if !periodEnd.After(start) {
+				continue
+			}
+			if periodStart.Before(start) {
+				periodStart = start
+			}
+			available := periodEnd.Sub(periodStart)
+			if remaining <= available {
+				return periodStart.Add(remaining), nil
+			}
+			remaining -= available
+		}
+	}
+	return time.Time{}, fmt.Errorf("工作日历 %q 在 %d 天内没有足够的工作时间", calendar.Name, maxCalendarDays)
+}
+
+// GetSLAPolicies 返回所有 SLA 策略，按客户（适用于所有客户的策略在前）和优先级排序
+func GetSLAPolicies(ctx context.Context) (_ []models.SLAPolicy, err error) {
+	ctx, span := startSpan(ctx, "GetSLAPolicies")
+	defer endSpan(span, &err)
+
+	policies := make([]models.SLAPolicy, 0)
+	err = slaPolicyQuery(ctx).Order("c.customer_name IS NOT NULL, c.customer_name ASC, sla_policies.priority ASC").Find(&policies).Error
+	return policies, err
+}
+
+// GetSLAPolicy 根据 ID 查找一条 SLA 策略，不存在时返回 404
+func GetSLAPolicy(ctx context.Context, id string) (_ *models.SLAPolicy, err error) {
+	ctx, span := startSpan(ctx, "GetSLAPolicy")
+	defer endSpan(span, &err)
+
+	var policy models.SLAPolicy
+	if err := slaPolicyQuery(ctx).Take(&policy, "sla_policies.policy_id = ?", id).Error; err != nil {
+		return nil, asNotFound(err, "SLA 策略")
+	}
+	return &policy, nil
+}
+
+// CreateSLAPolicy 创建一条 SLA 策略，同一客户和优先级已有策略时返回 409
+func CreateSLAPolicy(ctx context.Context, input SLAPolicyInput) (_ *models.SLAPolicy, err error) {
+	ctx, span := startSpan(ctx, "CreateSLAPolicy")
+	defer endSpan(span, &err)
+
+	var policyID uint
+	err = gormDB(ctx).Transaction(func(tx *gorm.DB) error {
+		policy := models.SLAPolicy{CreatedAt: time.Now()}
+		if err := applySLAPolicyInput(tx, &policy, input, 0); err != nil {
+			return err
+		}
+		if err := tx.Create(&policy).Error; err != nil {
+			return err
+		}
+		policyID = policy.PolicyID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetSLAPolicy(ctx, strconv.FormatUint(uint64(policyID), 10))
+}
+
+// UpdateSLAPolicy 整体替换一条 SLA 策略的内容，已经计算过截止时间的工单不受影响。
+// 策略不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func UpdateSLAPolicy(ctx context.Context, id string, input SLAPolicyInput, expectedVersion *uint) (_ *models.SLAPolicy, err error) {
+	ctx, span := startSpan(ctx, "UpdateSLAPolicy")
+	defer endSpan(span, &err)
+
+	policyID, _ := strconv.ParseUint(id, 10, 32)
+	var policy models.SLAPolicy
+	if err := applySLAPolicyInput(gormDB(ctx), &policy, input, uint(policyID)); err != nil {
+		return nil, err
+	}
+
+	err = updateVersioned(ctx, &models.SLAPolicy{}, "SLA 策略", "policy_id", id, expectedVersion, map[string]interface{}{
+		"name":                policy.Name,
+		"customer_id":         policy.CustomerID,
+		"priority":            policy.Priority,
+		"response_minutes":    policy.ResponseMinutes,
+		"resolve_minutes":     policy.ResolveMinutes,
+		"calendar_id":         policy.CalendarID,
+		"escalate_to_user_id": policy.EscalateToUserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetSLAPolicy(ctx, id)
+}
+
+// DeleteSLAPolicy 删除一条 SLA 策略，已经计算过截止时间的工单保留原来的截止时间。
+// 策略不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func DeleteSLAPolicy(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteSLAPolicy")
+	defer endSpan(span, &err)
+
+	return deleteVersioned(ctx, &models.SLAPolicy{}, "SLA 策略", "policy_id", id, expectedVersion)
+}
+
+// slaPolicyQuery 构造 SLA 策略的查询，关联 customers 表得到客户名称
+func slaPolicyQuery(ctx context.Context) *gorm.DB {
+	return gormDB(ctx).Model(&models.SLAPolicy{}).
+		Joins("LEFT JOIN customers c ON c.customer_id = sla_policies.customer_id").
+		Select("sla_policies.*, c.customer_name")
+}
+
+// applySLAPolicyInput 校验 input 引用的客户、日历和升级用户，并把 input 写入 policy；exceptID 为正在修改的策略 ID
+func applySLAPolicyInput(db *gorm.DB, policy *models.SLAPolicy, input SLAPolicyInput, exceptID uint) error {
+	var details []utils.FieldError
+	if input.ResolveMinutes < input.ResponseMinutes {
+		details = append(details, utils.FieldError{Field: "resolveMinutes", Message: "不能小于首次响应时限"})
+	}
+	if input.CustomerID != nil {
+		if exists, err := recordExists(db, &models.Customer{}, "customer_id", *input.CustomerID); err != nil {
+			return err
+		} else if !exists {
+			details = append(details, utils.FieldError{Field: "customerId", Message: "客户不存在"})
+		}
+	}
+	if input.CalendarID != nil {
+		if exists, err := recordExists(db, &models.BusinessCalendar{}, "calendar_id", *input.CalendarID); err != nil {
+			return err
+		} else if !exists {
+			details = append(details, utils.FieldError{Field: "calendarId", Message: "工作日历不存在"})
+		}
+	}
+	if input.EscalateToUserID != nil {
+		var user models.User
+		err := db.Take(&user, "user_id = ?", uuid.MustParse(*input.EscalateToUserID).String()).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			details = append(details, utils.FieldError{Field: "escalateToUserId", Message: "用户不存在"})
+		case err != nil:
+			return err
+		case !slices.Contains(models.StaffRoles, user.Role) || user.IsDisabled():
+			details = append(details, utils.FieldError{Field: "escalateToUserId", Message: "只能升级给未禁用的内部用户"})
+		}
+	}
+	if len(details) > 0 {
+		return utils.ErrValidation(details...)
+	}
+
+	var customerKey uint
+	if input.CustomerID != nil {
+		customerKey = *input.CustomerID
+	}
+	var count int64
+	err := db.Model(&models.SLAPolicy{}).
+		Where("COALESCE(customer_id, 0) = ? AND priority = ? AND policy_id <> ?", customerKey, input.Priority, exceptID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return utils.NewAppError(utils.CodeConflict, "该客户和优先级已有 SLA 策略")
+	}
+
+	policy.Name = input.Name
+	policy.CustomerID = nullInt64(input.CustomerID)
+	policy.Priority = input.Priority
+	policy.ResponseMinutes = input.ResponseMinutes
+	policy.ResolveMinutes = input.ResolveMinutes
+	policy.CalendarID = nullInt64(input.CalendarID)
+	policy.EscalateToUserID.Valid = input.EscalateToUserID != nil
+	if policy.EscalateToUserID.Valid {
+		policy.EscalateToUserID.String = uuid.MustParse(*input.EscalateToUserID).String()
+	}
+	return nil
+}
+
+// recordExists 判断 model 所在表中是否存在主键列 column 等于 id 的记录
+func recordExists(db *gorm.DB, model interface{}, column string, id uint) (bool, error) {
+	var count int64
+	err := db.Model(model).Where(column+" = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// nullInt64 把可选的 ID 转换为可空的数据库值
+func nullInt64(id *uint) sql.NullInt64 {
+	if id == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*id), Valid: true}
+}
@@ -2,14 +2,21 @@
  * @file services/ticket_service.go
  * @description 提供与工单相关的业务逻辑，使用 GORM 从 `v_tickets` 视图中进行分页查询。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [排序修正]：将 `ORDER BY` 子句的目标字段从 `created_at` 修正为 `publication_time`，以确保工单列表按照其“发布时间”进行正确的倒序排列。
+ *   - [通知中心]：工单新增评论后发布 `ticket.commented` 事件，工单事件数据新增 `comment` 字段。
  */
 
 package services
 
 import (
-	"opsboard-backend/database"
+	"context"
+	"database/sql"
+	"log/slog"
 	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 // PaginatedTicketsResult 定义了工单分页查询的返回结构
@@ -18,22 +25,46 @@ type PaginatedTicketsResult struct {
 	Data  []models.Ticket `json:"data"`
 }
 
+// TicketFilter 定义了工单列表的筛选条件，所有字段均为可选。
+type TicketFilter struct {
+	CustomerName  string `form:"customerName"`
+	Status        string `form:"status"`
+	OperationType string `form:"operationType"`
+	TimeRangeFilter
+}
+
+// ticketListQuery 构造工单列表的基础查询（基于 v_tickets 视图和筛选条件）
+func ticketListQuery(ctx context.Context, filter TicketFilter) *gorm.DB {
+	query := gormDB(ctx).Table("v_tickets").Scopes(customerScope(ctx, "customer_id"))
+
+	if filter.CustomerName != "" {
+		query = query.Where("customer_name LIKE ?", "%"+filter.CustomerName+"%")
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.OperationType != "" {
+		query = query.Where("operation_type = ?", filter.OperationType)
+	}
+	return filter.TimeRangeFilter.apply(query, "publication_time")
+}
+
 // GetPaginatedTickets 使用 GORM 从 v_tickets 视图中分页查询工单列表。
-func GetPaginatedTickets(page, pageSize int) (*PaginatedTicketsResult, error) {
-	db := database.GormDB
+func GetPaginatedTickets(ctx context.Context, page, pageSize int, filter TicketFilter) (_ *PaginatedTicketsResult, err error) {
+	ctx, span := startSpan(ctx, "GetPaginatedTickets")
+	defer endSpan(span, &err)
+
 	var tickets []models.Ticket
 	var total int64
 
-	query := db.Table("v_tickets")
-
-	if err := query.Count(&total).Error; err != nil {
+	if err := ticketListQuery(ctx, filter).Count(&total).Error; err != nil {
 		return nil, err
 	}
 
 	offset := (page - 1) * pageSize
 
 	// [核心修改] 更新排序字段
-	err := query.
+	err = ticketListQuery(ctx, filter).
 		Offset(offset).
 		Limit(pageSize).
 		Order("publication_time DESC").
@@ -54,3 +85,244 @@ func GetPaginatedTickets(page, pageSize int) (*PaginatedTicketsResult, error) {
 
 	return result, nil
 }
+
+// StreamTickets 按列表的筛选和排序逐行遍历全部工单，并对每一行调用 fn。
+func StreamTickets(ctx context.Context, filter TicketFilter, fn func(ticket *models.Ticket) error) (err error) {
+	ctx, span := startSpan(ctx, "StreamTickets")
+	defer endSpan(span, &err)
+
+	query := ticketListQuery(ctx, filter).Order("publication_time DESC")
+	return streamQuery(query, fn)
+}
+
+// GetTicketByID 使用 GORM 从 v_tickets 视图中查找一个工单。
+// 如果没有找到记录，返回 404 `NOT_FOUND` 错误。
+func GetTicketByID(ctx context.Context, id string) (_ *models.Ticket, err error) {
+	ctx, span := startSpan(ctx, "GetTicketByID")
+	defer endSpan(span, &err)
+
+	var ticket models.Ticket
+	err = gormDB(ctx).Table("v_tickets").Scopes(customerScope(ctx, "customer_id")).Where("id = ?", id).Take(&ticket).Error
+	if err != nil {
+		return nil, asNotFound(err, "工单")
+	}
+	return &ticket, nil
+}
+
+// DeleteTicketByID 使用 GORM 根据 ID 软删除一个工单。
+// 工单不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func DeleteTicketByID(ctx context.Context, id string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteTicketByID")
+	defer endSpan(span, &err)
+
+	return deleteVersioned(ctx, &models.TicketRecord{}, "工单", "ticket_id", id, expectedVersion)
+}
+
+// SetTicketStatus 修改工单状态。状态改为“完成”时记录完成时间，改为其他状态时清空完成时间。
+// 工单不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func SetTicketStatus(ctx context.Context, id, status string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "SetTicketStatus")
+	defer endSpan(span, &err)
+
+	settings, err := GetSystemSettings(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(settings.Settings.TicketStatuses, status) {
+		return utils.ErrValidation(utils.FieldError{Field: "status", Message: "不是系统设置中的工单状态"})
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":          status,
+		"completion_time": sql.NullTime{Time: now, Valid: status == models.TicketStatusCompleted},
+		"resolved_at":     nil,
+	}
+	if status != models.TicketStatusPending {
+		updates["first_response_at"] = gorm.Expr("COALESCE(first_response_at, ?)", now)
+	}
+	if status == models.TicketStatusResolving || status == models.TicketStatusCompleted {
+		updates["resolved_at"] = gorm.Expr("COALESCE(resolved_at, ?)", now)
+	}
+	if err := updateVersioned(ctx, &models.TicketRecord{}, "工单", "ticket_id", id, expectedVersion, updates); err != nil {
+		return err
+	}
+	publishTicketEvent(ctx, EventTicketUpdated, id, "status")
+	return nil
+}
+
+// SetTicketPriority 修改工单优先级，并清除已计算的 SLA 截止时间和超时标记，由 SLA 评估任务按新的优先级重新计算。
+// 工单不存在时返回 404；expectedVersion 不为 nil 且版本不一致时返回 ErrVersionConflict。
+func SetTicketPriority(ctx context.Context, id, priority string, expectedVersion *uint) (err error) {
+	ctx, span := startSpan(ctx, "SetTicketPriority")
+	defer endSpan(span, &err)
+
+	err = updateVersioned(ctx, &models.TicketRecord{}, "工单", "ticket_id", id, expectedVersion, map[string]interface{}{
+		"priority":             priority,
+		"sla_policy_id":        nil,
+		"sla_evaluated_at":     nil,
+		"response_due_at":      nil,
+		"resolve_due_at":       nil,
+		"response_breached_at": nil,
+		"resolve_breached_at":  nil,
+	})
+	if err != nil {
+		return err
+	}
+	publishTicketEvent(ctx, EventTicketUpdated, id, "priority")
+	return nil
+}
+
+// TicketEventData 是工单事件的数据，Changes 列出了 ticket.updated 事件中被修改的字段，
+// SLATarget 为 ticket.sla_breached 事件中超时的目标（response 或 resolve）
+type TicketEventData struct {
+	Ticket    *models.Ticket        `json:"ticket"`
+	Changes   []string              `json:"changes,omitempty"`
+	SLATarget string                `json:"slaTarget,omitempty"`
+	Comment   *models.TicketComment `json:"comment,omitempty"`
+}
+
+// publishTicketEvent 从 v_tickets 视图读取工单修改后的内容并发布工单事件。
+// 调用方已经确认工单在访问范围内，这里不再按访问范围过滤；读取失败时只记录日志。
+func publishTicketEvent(ctx context.Context, eventType EventType, id string, changes ...string) {
+	publishTicketEventData(ctx, eventType, id, TicketEventData{Changes: changes})
+}
+
+// publishTicketEventData 与 publishTicketEvent 相同，data 中除工单以外的字段由调用方填写
+func publishTicketEventData(ctx context.Context, eventType EventType, id string, data TicketEventData) {
+	var ticket models.Ticket
+	if err := gormDB(ctx).Table("v_tickets").Where("id = ?", id).Take(&ticket).Error; err != nil {
+		slog.WarnContext(ctx, "无法读取工单，未发布工单事件", "ticket_id", id, "event", eventType, "error", err)
+		return
+	}
+	data.Ticket = &ticket
+	PublishEvent(ctx, eventType, data)
+}
+
+// TicketStats 定义了工单统计的返回结构，SLA 状态的取值见 models 中的 SLAStatus 常量
+type TicketStats struct {
+	Total       int64            `json:"total"`
+	ByStatus    map[string]int64 `json:"byStatus"`
+	ByPriority  map[string]int64 `json:"byPriority"`
+	ResponseSLA map[string]int64 `json:"responseSla"`
+	ResolveSLA  map[string]int64 `json:"resolveSla"`
+}
+
+// GetTicketStats 按工单列表的筛选条件统计工单数量
+func GetTicketStats(ctx context.Context, filter TicketFilter) (_ *TicketStats, err error) {
+	ctx, span := startSpan(ctx, "GetTicketStats")
+	defer endSpan(span, &err)
+
+	stats := &TicketStats{}
+	for column, counts := range map[string]*map[string]int64{
+		"status":              &stats.ByStatus,
+		"priority":            &stats.ByPriority,
+		"response_sla_status": &stats.ResponseSLA,
+		"resolve_sla_status":  &stats.ResolveSLA,
+	} {
+		if *counts, err = countTicketsBy(ctx, filter, column); err != nil {
+			return nil, err
+		}
+	}
+	for _, n := range stats.ByStatus {
+		stats.Total += n
+	}
+	return stats, nil
+}
+
+// countTicketsBy 按 v_tickets 视图的 column 列分组统计符合筛选条件的工单数量
+func countTicketsBy(ctx context.Context, filter TicketFilter, column string) (map[string]int64, error) {
+	var rows []struct {
+		Value string
+		Count int64
+	}
+	err := ticketListQuery(ctx, filter).Select(column + " AS value, COUNT(*) AS count").Group(column).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Value] = row.Count
+	}
+	return counts, nil
+}
+
+// GetTicketComments 按时间顺序返回工单的评论，includeInternal 为 false 时不包含内部备注。
+// 工单不存在或不在访问范围内时返回 404。
+func GetTicketComments(ctx context.Context, ticketID string, includeInternal bool) (_ []models.TicketComment, err error) {
+	ctx, span := startSpan(ctx, "GetTicketComments")
+	defer endSpan(span, &err)
+
+	if _, err := findTicketRecord(ctx, ticketID); err != nil {
+		return nil, err
+	}
+
+	query := ticketCommentQuery(ctx).Where("ticket_comments.ticket_id = ?", ticketID)
+	if !includeInternal {
+		query = query.Where("ticket_comments.is_internal = ?", false)
+	}
+	comments := make([]models.TicketComment, 0)
+	err = query.Order("ticket_comments.created_at ASC, ticket_comments.comment_id ASC").Find(&comments).Error
+	return comments, err
+}
+
+// AddTicketComment 以处理人的身份为工单添加一条评论，internal 为 true 时添加内部备注。
+// 处理人的首次公开回复记为工单的首次响应。工单不存在或不在访问范围内时返回 404。
+func AddTicketComment(ctx context.Context, ticketID, authorID, content string, internal bool) (_ *models.TicketComment, err error) {
+	ctx, span := startSpan(ctx, "AddTicketComment")
+	defer endSpan(span, &err)
+
+	comment, err := addTicketComment(ctx, ticketID, authorID, content, internal)
+	if err != nil || internal {
+		return comment, err
+	}
+	err = gormDB(ctx).Model(&models.TicketRecord{}).
+		Where("ticket_id = ? AND first_response_at IS NULL", comment.TicketID).
+		Update("first_response_at", comment.CreatedAt).Error
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// addTicketComment 为工单添加一条评论并返回带评论人昵称的评论
+func addTicketComment(ctx context.Context, ticketID, authorID, content string, internal bool) (*models.TicketComment, error) {
+	ticket, err := findTicketRecord(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	comment := models.TicketComment{
+		TicketID:   ticket.TicketID,
+		AuthorID:   authorID,
+		IsInternal: internal,
+		Content:    content,
+		CreatedAt:  time.Now(),
+	}
+	if err := gormDB(ctx).Create(&comment).Error; err != nil {
+		return nil, err
+	}
+
+	var created models.TicketComment
+	if err := ticketCommentQuery(ctx).Take(&created, "ticket_comments.comment_id = ?", comment.CommentID).Error; err != nil {
+		return nil, err
+	}
+	publishTicketEventData(ctx, EventTicketCommented, ticketID, TicketEventData{Comment: &created})
+	return &created, nil
+}
+
+// findTicketRecord 查找未被删除且在 ctx 的访问范围内的工单，否则返回 404
+func findTicketRecord(ctx context.Context, id string) (*models.TicketRecord, error) {
+	var ticket models.TicketRecord
+	err := gormDB(ctx).Scopes(recordScope(ctx, &ticket)).Take(&ticket, "ticket_id = ?", id).Error
+	if err != nil {
+		return nil, asNotFound(err, "工单")
+	}
+	return &ticket, nil
+}
+
+// ticketCommentQuery 构造工单评论的查询，关联 users 表得到评论人的昵称
+func ticketCommentQuery(ctx context.Context) *gorm.DB {
+	return gormDB(ctx).Model(&models.TicketComment{}).
+		Joins("LEFT JOIN users u ON u.user_id = ticket_comments.author_id").
+		Select("ticket_comments.*, COALESCE(u.nickname, u.username) AS author_name")
+}
@@ -0,0 +1,52 @@
+/**
+ * @file services/tracing.go
+ * @description 提供服务层共用的 OpenTelemetry 链路追踪辅助函数。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [新文件]：创建 `startSpan`/`endSpan` 和 `gormDB`。每个导出的服务函数都会创建一个子 span，并通过 `gormDB(ctx)` 让其中的 SQL span 挂在该 span 之下。
+ */
+
+package services
+
+import (
+	"context"
+	"errors"
+	"opsboard-backend/database"
+	"opsboard-backend/utils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// tracer 是服务层使用的 Tracer
+var tracer = otel.Tracer("opsboard-backend/services")
+
+// startSpan 为服务函数开始一个名为 `services.<name>` 的 span
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "services."+name)
+}
+
+// endSpan 结束 span，应以 `defer endSpan(span, &err)` 的形式调用（err 为命名返回值）。
+// 未知错误和 5xx 错误会被记录为 span 错误；404、412 等预期内的业务错误只记录错误码。
+func endSpan(span trace.Span, errp *error) {
+	defer span.End()
+
+	err := *errp
+	if err == nil {
+		return
+	}
+	var appErr *utils.AppError
+	if errors.As(err, &appErr) && appErr.Status() < 500 {
+		span.SetAttributes(attribute.String("error.code", string(appErr.Code)))
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// gormDB 返回绑定了 ctx 的 GORM 会话，使 SQL span 成为当前 span 的子 span
+func gormDB(ctx context.Context) *gorm.DB {
+	return database.GormDB.WithContext(ctx)
+}
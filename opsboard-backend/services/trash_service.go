@@ -0,0 +1,162 @@
+/**
+ * @file services/trash_service.go
+ * @description 提供回收站相关的业务逻辑：列出已软删除的记录、恢复记录以及彻底清除（物理删除）记录。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [客户范围]：列表、恢复和清除按请求的访问范围过滤，非管理员只能看到和恢复分配给自己的客户的记录。
+ */
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownTrashEntity 表示请求了一个不支持回收站的实体类型
+var ErrUnknownTrashEntity = utils.ErrBadRequest("不支持的实体类型")
+
+// errTrashItemNotFound 表示回收站中不存在指定的记录（记录不存在或未被删除）
+var errTrashItemNotFound = utils.ErrNotFound("回收站中不存在该记录")
+
+// trashEntity 描述了一种支持软删除的实体
+type trashEntity struct {
+	table      string
+	primaryKey string
+	nameExpr   string             // 用于在回收站中展示的名称的 SQL 表达式
+	newModel   func() interface{} // 创建一个新的 GORM 模型实例，用于恢复和清除操作
+}
+
+// TrashEntityNames 定义了支持回收站的实体名称（同时也是 URL 中的 `:entity` 参数）
+var TrashEntityNames = []string{"customers", "servers", "changelogs", "maintenance", "tickets"}
+
+var trashEntities = map[string]trashEntity{
+	"customers":   {table: "customers", primaryKey: "customer_id", nameExpr: "customer_name", newModel: func() interface{} { return &models.Customer{} }},
+	"servers":     {table: "servers", primaryKey: "server_id", nameExpr: "CONCAT(server_name, ' (', ip_address, ')')", newModel: func() interface{} { return &models.Server{} }},
+	"changelogs":  {table: "changelogs", primaryKey: "log_id", nameExpr: "CONCAT(update_type, ': ', LEFT(update_content, 100))", newModel: func() interface{} { return &models.Changelog{} }},
+	"maintenance": {table: "maintenance", primaryKey: "task_id", nameExpr: "task_name", newModel: func() interface{} { return &models.MaintenanceTask{} }},
+	"tickets":     {table: "tickets", primaryKey: "ticket_id", nameExpr: "LEFT(operation_content, 100)", newModel: func() interface{} { return &models.TicketRecord{} }},
+}
+
+// TrashItem 定义了回收站中的一条记录
+type TrashItem struct {
+	Entity    string    `json:"entity"`
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// PaginatedTrashResult 定义了回收站分页查询的返回结构
+type PaginatedTrashResult struct {
+	Total int64       `json:"total"`
+	Data  []TrashItem `json:"data"`
+}
+
+// lookupTrashEntity 根据名称查找实体定义
+func lookupTrashEntity(entity string) (trashEntity, error) {
+	def, ok := trashEntities[entity]
+	if !ok {
+		return trashEntity{}, ErrUnknownTrashEntity
+	}
+	return def, nil
+}
+
+// GetPaginatedTrash 分页查询回收站中的记录，按删除时间倒序排列。
+// entity 为空时返回所有实体的已删除记录，否则只返回指定实体的记录。
+func GetPaginatedTrash(ctx context.Context, page, pageSize int, entity string) (_ *PaginatedTrashResult, err error) {
+	ctx, span := startSpan(ctx, "GetPaginatedTrash")
+	defer endSpan(span, &err)
+
+	names := TrashEntityNames
+	if entity != "" {
+		if _, err := lookupTrashEntity(entity); err != nil {
+			return nil, err
+		}
+		names = []string{entity}
+	}
+
+	// 表名、列名和名称表达式均来自上方的静态注册表，不包含任何用户输入；访问范围的参数通过占位符传入
+	parts := make([]string, 0, len(names))
+	var args []interface{}
+	for _, name := range names {
+		def := trashEntities[name]
+		part := fmt.Sprintf(
+			"SELECT '%s' AS entity, %s AS id, %s AS name, deleted_at FROM %s WHERE deleted_at IS NOT NULL",
+			name, def.primaryKey, def.nameExpr, def.table,
+		)
+		if cond, condArgs := recordCondition(ctx, def.newModel()); cond != "" {
+			part += " AND " + cond
+			args = append(args, condArgs...)
+		}
+		parts = append(parts, part)
+	}
+	union := strings.Join(parts, " UNION ALL ")
+
+	db := gormDB(ctx)
+	var total int64
+	if err := db.Raw("SELECT COUNT(*) FROM ("+union+") AS trash", args...).Scan(&total).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]TrashItem, 0)
+	err = db.Raw("SELECT * FROM ("+union+") AS trash ORDER BY deleted_at DESC LIMIT ? OFFSET ?",
+		append(args, pageSize, (page-1)*pageSize)...).Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginatedTrashResult{Total: total, Data: items}, nil
+}
+
+// RestoreTrashItem 将回收站中的一条记录恢复（清空 `deleted_at`）。
+// 如果记录不存在或未被删除，返回 404 `NOT_FOUND` 错误。
+func RestoreTrashItem(ctx context.Context, entity, id string) (err error) {
+	ctx, span := startSpan(ctx, "RestoreTrashItem")
+	defer endSpan(span, &err)
+
+	def, err := lookupTrashEntity(entity)
+	if err != nil {
+		return err
+	}
+
+	model := def.newModel()
+	result := gormDB(ctx).Unscoped().Model(model).Scopes(recordScope(ctx, model)).
+		Where(def.primaryKey+" = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errTrashItemNotFound
+	}
+	return nil
+}
+
+// PurgeTrashItem 彻底删除回收站中的一条记录。只有已被软删除的记录才能被清除。
+// 注意：物理删除会触发数据库外键的级联删除（例如清除服务器会同时删除其维护任务）。
+func PurgeTrashItem(ctx context.Context, entity, id string) (err error) {
+	ctx, span := startSpan(ctx, "PurgeTrashItem")
+	defer endSpan(span, &err)
+
+	def, err := lookupTrashEntity(entity)
+	if err != nil {
+		return err
+	}
+
+	model := def.newModel()
+	result := gormDB(ctx).Unscoped().Scopes(recordScope(ctx, model)).
+		Where(def.primaryKey+" = ? AND deleted_at IS NOT NULL", id).
+		Delete(model)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errTrashItemNotFound
+	}
+	return nil
+}
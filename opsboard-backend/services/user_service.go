@@ -2,74 +2,442 @@
  * @file user_service.go
  * @description 封装与用户相关的数据库操作。
  * @modification
- *   - [UUID Migration]: 导入了 `github.com/google/uuid` 包。
- *   - [UUID Migration]: `GetUserByID` 函数的参数类型从 `int64` 修改为 `uuid.UUID`，以接受 UUID 类型的用户 ID。
+ *   - [Password Policy]: 创建用户和重置密码时检查密码策略，密码改为以 bcrypt 哈希保存；重置密码时检查并记录密码历史。
+ *   - [Self Service]: 新增 `ChangeOwnPassword`，校验当前密码后修改密码。
+ *   - [Email]: 创建用户时可以设置邮箱地址；`UpdateUserNickname` 改为 `UpdateUserProfile`，同时修改昵称和邮箱地址。
  */
 
 package services
 
 import (
+	"context"
 	"database/sql"
-	"opsboard-backend/database"
+	"errors"
 	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// GetUserByUsername 通过用户名从数据库中查询用户 (逻辑保持不变)
-func GetUserByUsername(username string) (*models.User, error) {
-	query := `SELECT user_id, username, password, nickname, role, created_at, updated_at FROM users WHERE username = ?`
-	row := database.DB.QueryRow(query, username)
+// 用户列表的状态筛选
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+)
+
+var (
+	// ErrUserDisabled 表示账户已被管理员禁用
+	ErrUserDisabled = utils.ErrForbidden("账户已被禁用，请联系管理员")
+	// ErrLastAdmin 表示操作会使系统中不再有可用的管理员
+	ErrLastAdmin = utils.NewAppError(utils.CodeConflict, "系统中至少需要保留一个未禁用的管理员")
+	// ErrExternalUser 表示该操作只适用于本地账户，外部认证源的账户由目录或 IdP 管理
+	ErrExternalUser = utils.NewAppError(utils.CodeConflict, "该用户由外部认证源管理，密码和角色以目录或 IdP 为准")
+	// ErrInvalidCurrentPassword 表示修改密码时提供的当前密码不正确
+	ErrInvalidCurrentPassword = utils.ErrValidation(utils.FieldError{Field: "currentPassword", Message: "当前密码不正确"})
+	// ErrSessionRevoked 表示令牌签发后用户修改了密码，该会话已失效
+	ErrSessionRevoked = utils.ErrUnauthorized("登录会话已失效，请重新登录")
+)
+
+// UserFilter 是用户列表的筛选条件，空值表示不筛选
+type UserFilter struct {
+	Keyword    string `form:"keyword"` // 按用户名或昵称模糊搜索
+	Role       string `form:"role"`
+	Status     string `form:"status" binding:"omitempty,oneof=active disabled"`
+	AuthSource string `form:"authSource"`
+}
+
+// PaginatedUsersResult 定义了用户分页查询的返回结构
+type PaginatedUsersResult struct {
+	Total int64         `json:"total"`
+	Data  []models.User `json:"data"`
+}
+
+// GetUserByUsername 通过用户名从数据库中查询用户
+func GetUserByUsername(ctx context.Context, username string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "GetUserByUsername")
+	defer endSpan(span, &err)
 
 	var user models.User
-	var updatedAt sql.NullTime
-
-	err := row.Scan(
-		&user.UserID,
-		&user.Username,
-		&user.Password,
-		&user.Nickname,
-		&user.Role,
-		&user.CreatedAt,
-		&updatedAt,
-	)
+	if err := gormDB(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
 
+// GetUserByID 通过用户 ID 从数据库中查询用户
+func GetUserByID(ctx context.Context, userID uuid.UUID) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "GetUserByID")
+	defer endSpan(span, &err)
+
+	var user models.User
+	if err := gormDB(ctx).Where("user_id = ?", userID.String()).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CheckUserActive 确认令牌中的用户仍然存在且未被禁用并返回该用户：用户不存在（包括已删除）时返回 401，已被禁用时返回 ErrUserDisabled
+func CheckUserActive(ctx context.Context, userID string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "CheckUserActive")
+	defer endSpan(span, &err)
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, utils.ErrUnauthorized("认证凭证中的用户 ID 格式错误")
+	}
+	user, err := GetUserByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrUnauthorized("用户不存在")
+	}
 	if err != nil {
 		return nil, err
 	}
+	if user.IsDisabled() {
+		return nil, ErrUserDisabled
+	}
+	return user, nil
+}
 
-	if updatedAt.Valid {
-		user.UpdatedAt = updatedAt.Time
+// CheckUserSession 在 CheckUserActive 的基础上确认令牌签发后用户没有修改过密码，否则返回 ErrSessionRevoked
+func CheckUserSession(ctx context.Context, userID string, sessionVersion uint) (_ *models.User, err error) {
+	user, err := CheckUserActive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.SessionVersion != sessionVersion {
+		return nil, ErrSessionRevoked
 	}
+	return user, nil
+}
 
-	return &user, nil
+// GetPaginatedUsers 分页查询用户列表，按用户名排序
+func GetPaginatedUsers(ctx context.Context, page, pageSize int, filter UserFilter) (_ *PaginatedUsersResult, err error) {
+	ctx, span := startSpan(ctx, "GetPaginatedUsers")
+	defer endSpan(span, &err)
+
+	query := gormDB(ctx).Model(&models.User{})
+	if filter.Keyword != "" {
+		like := "%" + filter.Keyword + "%"
+		query = query.Where("username LIKE ? OR nickname LIKE ?", like, like)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.AuthSource != "" {
+		query = query.Where("auth_source = ?", filter.AuthSource)
+	}
+	switch filter.Status {
+	case UserStatusActive:
+		query = query.Where("disabled_at IS NULL")
+	case UserStatusDisabled:
+		query = query.Where("disabled_at IS NOT NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	users := make([]models.User, 0)
+	err = query.
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Order("username ASC").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return &PaginatedUsersResult{Total: total, Data: users}, nil
 }
 
-// GetUserByID 通过用户 ID 从数据库中查询用户
-func GetUserByID(userID uuid.UUID) (*models.User, error) { // [核心修改] 参数类型变为 uuid.UUID
-	query := `SELECT user_id, username, password, nickname, role, created_at, updated_at FROM users WHERE user_id = ?`
-	row := database.DB.QueryRow(query, userID.String()) // 查询时将 UUID 转为字符串
+// CreateLocalUser 创建一个使用本地密码登录的用户，email 为空时用户不接收邮件通知。用户名已被使用（包括已删除的用户）时返回 409。
+func CreateLocalUser(ctx context.Context, username, nickname, email, password, role string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "CreateLocalUser")
+	defer endSpan(span, &err)
 
-	var user models.User
-	var updatedAt sql.NullTime
-
-	err := row.Scan(
-		&user.UserID,
-		&user.Username,
-		&user.Password,
-		&user.Nickname,
-		&user.Role,
-		&user.CreatedAt,
-		&updatedAt,
-	)
+	if err := checkPasswordStrength("password", password); err != nil {
+		return nil, err
+	}
+	if err := ensureUsernameAvailable(gormDB(ctx), username); err != nil {
+		return nil, err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	if nickname == "" {
+		nickname = username
+	}
+	user := &models.User{
+		UserID:     uuid.New(),
+		Username:   username,
+		Password:   hash,
+		Nickname:   nickname,
+		Email:      sql.NullString{String: email, Valid: email != ""},
+		Role:       role,
+		AuthSource: models.AuthSourceLocal,
+	}
+	if err := gormDB(ctx).Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
 
+// ProvisionExternalUser 为外部认证源（例如 LDAP）认证通过的用户创建或同步本地账户（即时开通）。
+// 用户不存在时以 source 为认证源创建，密码留空（本地密码登录会拒绝空密码）；已存在时更新昵称和角色，使目录中的变更在下次登录时生效。
+// 同名用户属于其他认证源时返回 409，避免外部目录中的同名账户接管本地账户；账户已被删除时返回 AuthFailureDisabled，不会重新开通。
+func ProvisionExternalUser(ctx context.Context, source, username, nickname, role string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "ProvisionExternalUser")
+	defer endSpan(span, &err)
+
+	user := &models.User{}
+	err = gormDB(ctx).Unscoped().Where("username = ?", username).First(user).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		user = &models.User{
+			UserID:     uuid.New(),
+			Username:   username,
+			Nickname:   nickname,
+			Role:       role,
+			AuthSource: source,
+		}
+		if err := gormDB(ctx).Create(user).Error; err != nil {
+			return nil, err
+		}
+		return user, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if user.AuthSource != source {
+		return nil, utils.NewAppError(utils.CodeConflict, "用户名已被其他认证方式的账户使用")
+	}
+	if user.DeletedAt.Valid {
+		return nil, &AuthFailure{Reason: AuthFailureDisabled, UserID: user.UserID.String()}
+	}
+	if user.Nickname == nickname && user.Role == role {
+		return user, nil
+	}
+
+	err = gormDB(ctx).Model(user).Updates(map[string]interface{}{"nickname": nickname, "role": role}).Error
 	if err != nil {
 		return nil, err
 	}
+	user.Nickname = nickname
+	user.Role = role
+	return user, nil
+}
+
+// UpdateUserProfile 修改用户的昵称和邮箱地址。email 为 nil 时邮箱保持不变，为空字符串时清除邮箱。
+func UpdateUserProfile(ctx context.Context, id uuid.UUID, nickname string, email *string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UpdateUserProfile")
+	defer endSpan(span, &err)
+
+	return modifyUser(ctx, id, func(_ *gorm.DB, _ *models.User) (map[string]interface{}, error) {
+		updates := map[string]interface{}{"nickname": nickname}
+		if email != nil {
+			updates["email"] = sql.NullString{String: *email, Valid: *email != ""}
+		}
+		return updates, nil
+	})
+}
+
+// SetUserRole 修改本地用户的角色。外部认证源的用户角色由组映射决定，返回 ErrExternalUser；
+// 把最后一个未禁用的管理员降级时返回 ErrLastAdmin。
+func SetUserRole(ctx context.Context, id uuid.UUID, role string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "SetUserRole")
+	defer endSpan(span, &err)
+
+	return modifyUser(ctx, id, func(tx *gorm.DB, user *models.User) (map[string]interface{}, error) {
+		if user.AuthSource != models.AuthSourceLocal {
+			return nil, ErrExternalUser
+		}
+		if user.Role == models.RoleAdmin && role != models.RoleAdmin {
+			if err := ensureOtherActiveAdmin(tx, user); err != nil {
+				return nil, err
+			}
+		}
+		return map[string]interface{}{"role": role}, nil
+	})
+}
 
-	if updatedAt.Valid {
-		user.UpdatedAt = updatedAt.Time
+// SetUserDisabled 禁用或启用用户。禁用后用户无法登录，已签发的令牌和 API 令牌也会被 AuthMiddleware 拒绝；
+// 禁用最后一个未禁用的管理员时返回 ErrLastAdmin。
+func SetUserDisabled(ctx context.Context, id uuid.UUID, disabled bool) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "SetUserDisabled")
+	defer endSpan(span, &err)
+
+	return modifyUser(ctx, id, func(tx *gorm.DB, user *models.User) (map[string]interface{}, error) {
+		if !disabled {
+			return map[string]interface{}{"disabled_at": nil}, nil
+		}
+		if user.IsDisabled() {
+			return nil, nil
+		}
+		if err := ensureOtherActiveAdmin(tx, user); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"disabled_at": time.Now()}, nil
+	})
+}
+
+// ResetUserPassword 由管理员为本地用户设置新密码，该用户所有已登录的会话随即失效；外部认证源的用户返回 ErrExternalUser
+func ResetUserPassword(ctx context.Context, id uuid.UUID, password string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "ResetUserPassword")
+	defer endSpan(span, &err)
+
+	if err := checkPasswordStrength("password", password); err != nil {
+		return nil, err
 	}
+	return modifyUser(ctx, id, func(tx *gorm.DB, user *models.User) (map[string]interface{}, error) {
+		if user.AuthSource != models.AuthSourceLocal {
+			return nil, ErrExternalUser
+		}
+		return replacePassword(tx, "password", user, password)
+	})
+}
+
+// ChangeOwnPassword 校验当前密码后为本地用户设置新密码，返回会话版本已递增的用户，调用方需要为当前会话重新签发令牌。
+// 当前密码错误时返回 ErrInvalidCurrentPassword；外部认证源的用户返回 ErrExternalUser。
+func ChangeOwnPassword(ctx context.Context, id uuid.UUID, currentPassword, newPassword string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "ChangeOwnPassword")
+	defer endSpan(span, &err)
 
+	return modifyUser(ctx, id, func(tx *gorm.DB, user *models.User) (map[string]interface{}, error) {
+		if user.AuthSource != models.AuthSourceLocal {
+			return nil, ErrExternalUser
+		}
+		if !verifyPassword(user.Password, currentPassword) {
+			return nil, ErrInvalidCurrentPassword
+		}
+		if err := checkPasswordStrength("newPassword", newPassword); err != nil {
+			return nil, err
+		}
+		return replacePassword(tx, "newPassword", user, newPassword)
+	})
+}
+
+// replacePassword 检查密码历史、记录被替换的密码，返回写入新密码哈希并递增会话版本的更新
+func replacePassword(tx *gorm.DB, field string, user *models.User, password string) (map[string]interface{}, error) {
+	if err := checkPasswordReuse(tx, field, user, password); err != nil {
+		return nil, err
+	}
+	if err := recordPasswordHistory(tx, user); err != nil {
+		return nil, err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"password":        hash,
+		"session_version": gorm.Expr("session_version + 1"),
+	}, nil
+}
+
+// DeleteUser 软删除用户并吊销其 API 令牌。用户名不会被释放，审计日志、工单等历史记录中的引用保持有效；
+// 删除最后一个未禁用的管理员时返回 ErrLastAdmin。
+func DeleteUser(ctx context.Context, id uuid.UUID) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "DeleteUser")
+	defer endSpan(span, &err)
+
+	var deleted *models.User
+	err = gormDB(ctx).Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := ensureOtherActiveAdmin(tx, user); err != nil {
+			return err
+		}
+		if err := tx.Delete(user).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&models.APIToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.UserID.String()).
+			Update("revoked_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		deleted = user
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// modifyUser 在事务中锁定用户，交给 fn 计算需要更新的列并写回，返回更新后的用户；fn 返回空的更新时不写入
+func modifyUser(ctx context.Context, id uuid.UUID, fn func(tx *gorm.DB, user *models.User) (map[string]interface{}, error)) (*models.User, error) {
+	var updated *models.User
+	err := gormDB(ctx).Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, id)
+		if err != nil {
+			return err
+		}
+		updates, err := fn(tx, user)
+		if err != nil {
+			return err
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(user).Updates(updates).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("user_id = ?", id.String()).First(user).Error; err != nil {
+				return err
+			}
+		}
+		updated = user
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// lockUser 在事务中查询并锁定用户，用户不存在时返回 404
+func lockUser(tx *gorm.DB, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", id.String()).First(&user).Error
+	if err != nil {
+		return nil, asNotFound(err, "用户")
+	}
 	return &user, nil
 }
+
+// ensureOtherActiveAdmin 在 user 是未禁用的管理员时，确认系统中还有其他未禁用的管理员。
+// 锁定所有未禁用的管理员，避免两个管理员同时互相禁用后系统中不再有管理员。
+func ensureOtherActiveAdmin(tx *gorm.DB, user *models.User) error {
+	if user.Role != models.RoleAdmin || user.IsDisabled() {
+		return nil
+	}
+	var ids []string
+	err := tx.Model(&models.User{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ? AND disabled_at IS NULL AND user_id <> ?", models.RoleAdmin, user.UserID.String()).
+		Pluck("user_id", &ids).Error
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// ensureUsernameAvailable 确认用户名未被使用，已删除的用户同样占用用户名
+func ensureUsernameAvailable(db *gorm.DB, username string) error {
+	var count int64
+	if err := db.Unscoped().Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return utils.NewAppError(utils.CodeConflict, "用户名已存在")
+	}
+	return nil
+}
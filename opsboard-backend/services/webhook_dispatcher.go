@@ -0,0 +1,267 @@
+/**
+ * @file services/webhook_dispatcher.go
+ * @description 投递 webhook 的后台任务：从投递记录表中取出到期的投递，签名后 POST 给订阅方，失败时按指数退避重试。
+ * @modification 本次提交中所做的具体修改摘要。
+ *   - [邮件通知]：`webhookRetryDelay` 改为通用的 `retryDelay`，邮件发件箱使用同样的退避算法。
+ */
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"opsboard-backend/metrics"
+	"opsboard-backend/models"
+	"opsboard-backend/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// WebhookPolicy 定义了 webhook 投递的超时和重试策略
+type WebhookPolicy struct {
+	Timeout     time.Duration // 每次投递的 HTTP 超时时间
+	MaxAttempts int           // 最多尝试的总次数，达到后投递记录标记为 failed
+	RetryBase   time.Duration // 第一次失败后的重试间隔，之后每次失败翻倍
+	RetryMax    time.Duration // 重试间隔的上限
+}
+
+// DefaultWebhookPolicy 是未配置时使用的默认策略：最多尝试 8 次，重试间隔从 30 秒翻倍到最多 6 小时
+var DefaultWebhookPolicy = WebhookPolicy{
+	Timeout:     10 * time.Second,
+	MaxAttempts: 8,
+	RetryBase:   30 * time.Second,
+	RetryMax:    6 * time.Hour,
+}
+
+// webhookPollInterval 是投递任务在没有新事件时检查到期重试的间隔
+const webhookPollInterval = 15 * time.Second
+
+// webhookBatchSize 是投递任务每次查询处理的投递记录数
+const webhookBatchSize = 50
+
+// 投递记录中保存的响应体和错误信息的最大字符数，与表结构中的列长度一致
+const (
+	maxWebhookResponseBody = 2000
+	maxWebhookError        = 1000
+)
+
+var (
+	webhookMu     sync.RWMutex
+	webhookPolicy = DefaultWebhookPolicy
+
+	// webhookWake 用于在写入新的投递记录后立即唤醒投递任务
+	webhookWake = make(chan struct{}, 1)
+)
+
+// ConfigureWebhooks 设置 webhook 投递策略，应在启动时调用一次
+func ConfigureWebhooks(policy WebhookPolicy) {
+	webhookMu.Lock()
+	defer webhookMu.Unlock()
+	webhookPolicy = policy
+}
+
+// currentWebhookPolicy 返回当前的投递策略
+func currentWebhookPolicy() WebhookPolicy {
+	webhookMu.RLock()
+	defer webhookMu.RUnlock()
+	return webhookPolicy
+}
+
+// StartWebhookDispatcher 订阅事件总线并启动投递 webhook 的后台任务。应在数据库初始化后调用一次。
+func StartWebhookDispatcher() {
+	SubscribeEvents("webhooks", enqueueWebhookDeliveries)
+	go func() {
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-webhookWake:
+			}
+			err := metrics.ObserveSchedulerRun("webhook_dispatcher", func() error {
+				return DeliverDueWebhooks(context.Background())
+			})
+			if err != nil {
+				slog.Error("投递 webhook 失败", "error", err)
+			}
+		}
+	}()
+}
+
+// wakeWebhookDispatcher 唤醒投递任务；投递任务已经被唤醒时不重复唤醒
+func wakeWebhookDispatcher() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// DeliverDueWebhooks 投递所有到期的投递记录，直到没有到期的记录为止
+func DeliverDueWebhooks(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "DeliverDueWebhooks")
+	defer endSpan(span, &err)
+
+	policy := currentWebhookPolicy()
+	client := &http.Client{
+		Timeout: policy.Timeout,
+		// 不跟随重定向，订阅方应直接配置最终的 URL
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	webhooks := make(map[uint]*models.Webhook)
+
+	for {
+		var deliveries []models.WebhookDelivery
+		err := gormDB(ctx).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+			Order("next_attempt_at ASC").
+			Limit(webhookBatchSize).
+			Find(&deliveries).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range deliveries {
+			claimed, err := claimWebhookDelivery(ctx, &deliveries[i], policy)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+
+			webhook, ok := webhooks[deliveries[i].WebhookID]
+			if !ok {
+				webhook = new(models.Webhook)
+				err := gormDB(ctx).Take(webhook, "webhook_id = ?", deliveries[i].WebhookID).Error
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue // 订阅刚被删除，投递记录也已随之删除
+				}
+				if err != nil {
+					return err
+				}
+				webhooks[deliveries[i].WebhookID] = webhook
+			}
+			if err := attemptWebhookDelivery(ctx, client, webhook, &deliveries[i], policy); err != nil {
+				return err
+			}
+		}
+
+		if len(deliveries) < webhookBatchSize {
+			return nil
+		}
+	}
+}
+
+// claimWebhookDelivery 把投递记录的下一次尝试时间推迟到租约到期时间，返回是否由本实例取得了这条投递
+func claimWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery, policy WebhookPolicy) (bool, error) {
+	now := time.Now()
+	result := gormDB(ctx).Model(&models.WebhookDelivery{}).
+		Where("delivery_id = ? AND status = ? AND next_attempt_at <= ?", delivery.DeliveryID, models.WebhookDeliveryPending, now).
+		Update("next_attempt_at", now.Add(2*policy.Timeout))
+	return result.RowsAffected == 1, result.Error
+}
+
+// attemptWebhookDelivery 发送一次投递并记录结果：2xx 响应视为成功，其他情况在未达到最大尝试次数时安排重试
+func attemptWebhookDelivery(ctx context.Context, client *http.Client, webhook *models.Webhook, delivery *models.WebhookDelivery, policy WebhookPolicy) error {
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"last_attempt_at": time.Now(),
+	}
+
+	var status int
+	var body string
+	var sendErr error
+	if webhook.Enabled {
+		status, body, sendErr = sendWebhook(ctx, client, webhook, delivery)
+	} else {
+		sendErr = errors.New("webhook 已停用")
+		attempts = uint(policy.MaxAttempts) // 停用的订阅不再重试
+	}
+
+	updates["response_status"] = nil
+	updates["response_body"] = nil
+	if status != 0 {
+		updates["response_status"] = status
+		updates["response_body"] = truncateRunes(body, maxWebhookResponseBody)
+	}
+	if sendErr == nil && (status < 200 || status > 299) {
+		sendErr = fmt.Errorf("订阅方返回 HTTP %d", status)
+	}
+
+	switch {
+	case sendErr == nil:
+		updates["status"] = models.WebhookDeliverySucceeded
+		updates["delivered_at"] = time.Now()
+		updates["last_error"] = nil
+	case int(attempts) >= policy.MaxAttempts:
+		updates["status"] = models.WebhookDeliveryFailed
+		updates["last_error"] = truncateRunes(sendErr.Error(), maxWebhookError)
+	default:
+		updates["next_attempt_at"] = time.Now().Add(retryDelay(policy.RetryBase, policy.RetryMax, attempts))
+		updates["last_error"] = truncateRunes(sendErr.Error(), maxWebhookError)
+	}
+	metrics.TaskExecutionsTotal.WithLabelValues("webhook", metrics.Result(sendErr)).Inc()
+	if sendErr != nil {
+		slog.WarnContext(ctx, "webhook 投递失败", "webhook_id", webhook.WebhookID, "delivery_id", delivery.DeliveryID,
+			"event", delivery.EventType, "attempts", attempts, "error", sendErr)
+	}
+
+	return gormDB(ctx).Model(&models.WebhookDelivery{}).Where("delivery_id = ?", delivery.DeliveryID).Updates(updates).Error
+}
+
+// sendWebhook 把投递记录的请求体签名后 POST 给订阅方，返回响应状态码和响应体（最多读取 maxWebhookResponseBody 字节）
+func sendWebhook(ctx context.Context, client *http.Client, webhook *models.Webhook, delivery *models.WebhookDelivery) (_ int, _ string, err error) {
+	ctx, span := startSpan(ctx, "sendWebhook")
+	defer endSpan(span, &err)
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "opsboard-webhook/1.0")
+	req.Header.Set("X-Opsboard-Event", delivery.EventType)
+	req.Header.Set("X-Opsboard-Event-Id", delivery.EventID)
+	req.Header.Set("X-Opsboard-Delivery", strconv.FormatUint(delivery.DeliveryID, 10))
+	req.Header.Set("X-Opsboard-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Opsboard-Signature", utils.SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	return resp.StatusCode, string(respBody), nil
+}
+
+// retryDelay 返回第 attempts 次尝试失败后的重试间隔：base * 2^(attempts-1)，不超过 limit
+func retryDelay(base, limit time.Duration, attempts uint) time.Duration {
+	delay := base
+	for i := uint(1); i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// truncateRunes 把 s 中无效的 UTF-8 序列替换为 U+FFFD，并截断为最多 n 个字符
+func truncateRunes(s string, n int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
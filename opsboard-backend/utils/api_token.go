@@ -0,0 +1,44 @@
+/**
+ * @file api_token.go
+ * @description 提供个人 API 令牌明文的生成和哈希。
+ * @modification
+ *   - [New File]: 创建此文件。令牌格式为 `opb_<32 字节随机数的 base64url 编码>`，固定前缀便于 `AuthMiddleware` 将其与 JWT 区分，
+ *     也便于密钥扫描工具识别泄露的令牌。令牌本身是高熵随机值，因此只保存其 SHA-256 哈希即可。
+ */
+
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// APITokenPrefix 是所有个人 API 令牌的固定前缀
+const APITokenPrefix = "opb_"
+
+// apiTokenDisplayLength 是列表中展示的令牌前缀长度（含 APITokenPrefix）
+const apiTokenDisplayLength = 12
+
+// GenerateAPIToken 生成一个新的 API 令牌，返回明文及用于展示的前缀
+func GenerateAPIToken() (token, displayPrefix string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = APITokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return token, token[:apiTokenDisplayLength], nil
+}
+
+// IsAPIToken 判断 Bearer 凭证是否为 API 令牌（而不是 JWT）
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// HashAPIToken 返回 API 令牌明文的 SHA-256 哈希（十六进制）
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
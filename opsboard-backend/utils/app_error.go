@@ -0,0 +1,172 @@
+/**
+ * @file app_error.go
+ * @description 定义统一的 API 错误类型 `AppError`、机器可读的错误码及其 HTTP 状态码映射，并提供统一的错误响应写入函数。
+ * @modification
+ *   - [New File]: 创建此文件，取代各处理器中零散的 `gin.H{"message": ...}` 错误响应。
+ *   - [Envelope]: 所有错误响应均为 `{"code", "message", "requestId", "details"}` 结构；保留顶层 `message` 字段以兼容现有前端。
+ *   - [BadGateway]: 新增 `BAD_GATEWAY` 错误码（502），用于调用的外部服务（如群机器人）返回错误的情况。
+ */
+
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ErrorCode 是机器可读的错误码
+type ErrorCode string
+
+const (
+	CodeBadRequest           ErrorCode = "BAD_REQUEST"
+	CodeValidationFailed     ErrorCode = "VALIDATION_FAILED"
+	CodeUnauthorized         ErrorCode = "UNAUTHORIZED"
+	CodeTokenExpired         ErrorCode = "TOKEN_EXPIRED"
+	CodeForbidden            ErrorCode = "FORBIDDEN"
+	CodeNotFound             ErrorCode = "NOT_FOUND"
+	CodeMethodNotAllowed     ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeConflict             ErrorCode = "CONFLICT"
+	CodePreconditionFailed   ErrorCode = "PRECONDITION_FAILED"
+	CodePreconditionRequired ErrorCode = "PRECONDITION_REQUIRED"
+	CodePayloadTooLarge      ErrorCode = "PAYLOAD_TOO_LARGE"
+	CodeUnprocessable        ErrorCode = "UNPROCESSABLE_ENTITY"
+	CodeTooManyRequests      ErrorCode = "TOO_MANY_REQUESTS"
+	CodeInternal             ErrorCode = "INTERNAL_ERROR"
+	CodeBadGateway           ErrorCode = "BAD_GATEWAY"
+)
+
+// codeStatus 定义了错误码到 HTTP 状态码的映射
+var codeStatus = map[ErrorCode]int{
+	CodeBadRequest:           http.StatusBadRequest,
+	CodeValidationFailed:     http.StatusBadRequest,
+	CodeUnauthorized:         http.StatusUnauthorized,
+	CodeTokenExpired:         http.StatusUnauthorized,
+	CodeForbidden:            http.StatusForbidden,
+	CodeNotFound:             http.StatusNotFound,
+	CodeMethodNotAllowed:     http.StatusMethodNotAllowed,
+	CodeConflict:             http.StatusConflict,
+	CodePreconditionFailed:   http.StatusPreconditionFailed,
+	CodePreconditionRequired: http.StatusPreconditionRequired,
+	CodePayloadTooLarge:      http.StatusRequestEntityTooLarge,
+	CodeUnprocessable:        http.StatusUnprocessableEntity,
+	CodeTooManyRequests:      http.StatusTooManyRequests,
+	CodeInternal:             http.StatusInternalServerError,
+	CodeBadGateway:           http.StatusBadGateway,
+}
+
+// FieldError 描述了单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// AppError 是统一的应用错误类型。
+// Message 面向用户展示；Err 是内部原因，只用于日志，不会出现在响应中。
+type AppError struct {
+	Code    ErrorCode
+	Message string
+	Details []FieldError
+	Err     error
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Status 返回错误码对应的 HTTP 状态码
+func (e *AppError) Status() int {
+	if status, ok := codeStatus[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// NewAppError 创建一个新的 AppError
+func NewAppError(code ErrorCode, message string) *AppError {
+	return &AppError{Code: code, Message: message}
+}
+
+// ErrBadRequest 创建一个 400 错误
+func ErrBadRequest(message string) *AppError {
+	return NewAppError(CodeBadRequest, message)
+}
+
+// ErrUnauthorized 创建一个 401 错误
+func ErrUnauthorized(message string) *AppError {
+	return NewAppError(CodeUnauthorized, message)
+}
+
+// ErrForbidden 创建一个 403 错误
+func ErrForbidden(message string) *AppError {
+	return NewAppError(CodeForbidden, message)
+}
+
+// ErrNotFound 创建一个 404 错误
+func ErrNotFound(message string) *AppError {
+	return NewAppError(CodeNotFound, message)
+}
+
+// ErrValidation 创建一个带字段级详情的校验错误
+func ErrValidation(details ...FieldError) *AppError {
+	return &AppError{Code: CodeValidationFailed, Message: "请求参数校验失败", Details: details}
+}
+
+// ErrInternal 创建一个 500 错误，并保留内部原因用于日志
+func ErrInternal(message string, err error) *AppError {
+	return &AppError{Code: CodeInternal, Message: message, Err: err}
+}
+
+// ToAppError 将任意错误转换为 AppError：
+//   - 已经是（或包装了）AppError 的，原样返回；
+//   - gorm.ErrRecordNotFound / sql.ErrNoRows 转换为 404；
+//   - 其余错误转换为 500，并使用 fallback 作为对外消息。
+func ToAppError(err error, fallback string) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, sql.ErrNoRows) {
+		return &AppError{Code: CodeNotFound, Message: "记录不存在", Err: err}
+	}
+	if fallback == "" {
+		fallback = "服务器内部错误"
+	}
+	return ErrInternal(fallback, err)
+}
+
+// errorEnvelope 是错误响应的 JSON 结构
+type errorEnvelope struct {
+	Code      ErrorCode    `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"requestId,omitempty"`
+	Details   []FieldError `json:"details,omitempty"`
+}
+
+// RequestIDKey 是请求 ID 在 gin.Context 中的键名
+const RequestIDKey = "request_id"
+
+// RespondError 将错误转换为 AppError 并写入统一格式的错误响应，同时中止后续处理器。
+// fallback 是未知错误（500）时对外展示的消息。
+func RespondError(c *gin.Context, err error, fallback string) {
+	appErr := ToAppError(err, fallback)
+	if appErr.Code == CodeInternal || appErr.Err != nil {
+		_ = c.Error(err) // 记录内部原因，供日志中间件输出
+	}
+	c.AbortWithStatusJSON(appErr.Status(), errorEnvelope{
+		Code:      appErr.Code,
+		Message:   appErr.Message,
+		RequestID: c.GetString(RequestIDKey),
+		Details:   appErr.Details,
+	})
+}
@@ -0,0 +1,29 @@
+/**
+ * @file chat_signature.go
+ * @description 提供钉钉和飞书群机器人“加签”安全设置要求的签名算法。
+ * @modification
+ *   - [New File]: 创建此文件。两者都使用 HMAC-SHA256 和 base64 编码，但密钥和签名内容不同：
+ *     钉钉以 secret 为密钥对 `<毫秒时间戳>\n<secret>` 签名，飞书以 `<秒级时间戳>\n<secret>` 为密钥对空内容签名。
+ */
+
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+)
+
+// DingTalkSign 返回钉钉机器人请求的 sign 参数（未经 URL 编码），timestampMillis 为毫秒时间戳
+func DingTalkSign(secret string, timestampMillis int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestampMillis, 10) + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// FeishuSign 返回飞书机器人请求体中的 sign 字段，timestampSeconds 为秒级时间戳
+func FeishuSign(secret string, timestampSeconds int64) string {
+	mac := hmac.New(sha256.New, []byte(strconv.FormatInt(timestampSeconds, 10)+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
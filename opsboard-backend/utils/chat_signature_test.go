@@ -0,0 +1,42 @@
+/**
+ * @file chat_signature_test.go
+ * @description 用固定的测试向量校验钉钉和飞书群机器人的加签算法。
+ * @modification
+ *   - [New File]: 创建此文件。期望值按两个平台文档描述的算法用 Python 的 hmac 模块独立计算得到。
+ */
+
+package utils
+
+import "testing"
+
+func TestDingTalkSign(t *testing.T) {
+	tests := []struct {
+		secret    string
+		timestamp int64
+		want      string
+	}{
+		{"SEC5b1c4d2e3f", 1700000000000, "lBf+tbpooNE7faa/777k7FelbZkoWSvMkqYmpHlX4/I="},
+		{"SECtest", 1600000000000, "J1ROuI0lRhdAs5lXpASksT0u9NwWl4DNkvcHISJRBoY="},
+	}
+	for _, tt := range tests {
+		if got := DingTalkSign(tt.secret, tt.timestamp); got != tt.want {
+			t.Errorf("DingTalkSign(%q, %d) = %q，期望 %q", tt.secret, tt.timestamp, got, tt.want)
+		}
+	}
+}
+
+func TestFeishuSign(t *testing.T) {
+	tests := []struct {
+		secret    string
+		timestamp int64
+		want      string
+	}{
+		{"demo", 1700000000, "8oT2n3SMKFfEnDoiwer8BUM/SjKLwe9SqoEIHlhDTKo="},
+		{"JRt2J4bbd4Q9C3e7iC8fFh", 1599360473, "qoYD6+5z3CftxbgBKoSWbRv4KumDFXHwFPQtnMMaHBE="},
+	}
+	for _, tt := range tests {
+		if got := FeishuSign(tt.secret, tt.timestamp); got != tt.want {
+			t.Errorf("FeishuSign(%q, %d) = %q，期望 %q", tt.secret, tt.timestamp, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+/**
+ * @file etag.go
+ * @description 提供基于实体版本号的 ETag 生成和解析工具函数。
+ * @modification
+ *   - [New File]: 创建此文件，ETag 的取值为实体 `version` 列的十进制字符串（带双引号），例如 `"3"`。
+ */
+
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FormatETag 将实体版本号格式化为强 ETag
+func FormatETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// ParseETag 从 ETag 字符串中解析出版本号，兼容弱 ETag 前缀 `W/`。
+// 如果字符串不是由 FormatETag 生成的格式，返回 false。
+func ParseETag(etag string) (uint, bool) {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	if len(etag) < 2 || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(etag[1:len(etag)-1], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(version), true
+}
@@ -0,0 +1,201 @@
+/**
+ * @file export.go
+ * @description 提供通用的列表导出写入器，支持 CSV / XLSX / NDJSON 三种格式以及多语言表头。
+ * @modification
+ *   - [New File]: 创建此文件，将服务器导出中的表格写入逻辑抽象为 `Exporter`，供更新日志、维护任务、工单和审计日志等所有列表复用。
+ *   - [Localization]: 列定义 `ExportColumn` 为每种语言提供表头，NDJSON 则始终使用稳定的字段名作为键。
+ */
+
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatNDJSON 表示以换行分隔的 JSON（每行一个对象）
+const FormatNDJSON = "ndjson"
+
+// 支持的表头语言
+const (
+	LangZH = "zh"
+	LangEN = "en"
+)
+
+// exportTimeLayout 是 CSV/XLSX 中时间列的输出格式
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+// ExportColumn 定义了导出文件中的一列
+type ExportColumn struct {
+	Key     string            // 稳定的字段名，NDJSON 中作为对象键
+	Headers map[string]string // 语言 -> 表头文字
+}
+
+// Col 是构造 ExportColumn 的简写：依次传入字段名、中文表头和英文表头
+func Col(key, zh, en string) ExportColumn {
+	return ExportColumn{Key: key, Headers: map[string]string{LangZH: zh, LangEN: en}}
+}
+
+// Header 返回指定语言的表头，缺失时回退到中文表头，最后回退到字段名
+func (c ExportColumn) Header(lang string) string {
+	if h, ok := c.Headers[lang]; ok && h != "" {
+		return h
+	}
+	if h, ok := c.Headers[LangZH]; ok && h != "" {
+		return h
+	}
+	return c.Key
+}
+
+// IsExportFormat 判断给定格式是否为受支持的导出格式
+func IsExportFormat(format string) bool {
+	return format == FormatCSV || format == FormatXLSX || format == FormatNDJSON
+}
+
+// ExportContentType 返回导出格式对应的 Content-Type
+func ExportContentType(format string) string {
+	switch format {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatNDJSON:
+		return "application/x-ndjson; charset=utf-8"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// ResolveExportLang 将 `lang` 查询参数或 Accept-Language 请求头解析为受支持的表头语言，默认中文
+func ResolveExportLang(lang, acceptLanguage string) string {
+	for _, candidate := range []string{lang, acceptLanguage} {
+		candidate = strings.ToLower(strings.TrimSpace(candidate))
+		switch {
+		case strings.HasPrefix(candidate, LangEN):
+			return LangEN
+		case strings.HasPrefix(candidate, LangZH):
+			return LangZH
+		}
+	}
+	return LangZH
+}
+
+// Exporter 是一个逐行写入导出数据的流式写入器
+type Exporter struct {
+	format  string
+	columns []ExportColumn
+	sheet   SpreadsheetWriter // CSV / XLSX 时使用
+	encoder *json.Encoder     // NDJSON 时使用
+}
+
+// NewExporter 创建一个导出写入器。对于 CSV/XLSX，表头会立即按指定语言写入。
+func NewExporter(w io.Writer, format, lang string, columns []ExportColumn) (*Exporter, error) {
+	e := &Exporter{format: format, columns: columns}
+
+	if format == FormatNDJSON {
+		e.encoder = json.NewEncoder(w)
+		e.encoder.SetEscapeHTML(false)
+		return e, nil
+	}
+
+	sheet, err := NewSpreadsheetWriter(w, format)
+	if err != nil {
+		return nil, err
+	}
+	e.sheet = sheet
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Header(lang)
+	}
+	if err := sheet.WriteRow(header); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Write 写入一行数据，values 的顺序必须与列定义一致
+func (e *Exporter) Write(values ...interface{}) error {
+	if len(values) != len(e.columns) {
+		return fmt.Errorf("导出列数不匹配: 期望 %d 列，实际 %d 列", len(e.columns), len(values))
+	}
+
+	if e.encoder != nil {
+		record := make(map[string]interface{}, len(values))
+		for i, col := range e.columns {
+			record[col.Key] = exportJSONValue(values[i])
+		}
+		return e.encoder.Encode(record)
+	}
+
+	row := make([]string, len(values))
+	for i, v := range values {
+		row[i] = exportCellValue(v)
+	}
+	return e.sheet.WriteRow(row)
+}
+
+// Close 刷新并结束导出
+func (e *Exporter) Close() error {
+	if e.sheet != nil {
+		return e.sheet.Close()
+	}
+	return nil
+}
+
+// exportCellValue 将任意值格式化为表格单元格文本
+func exportCellValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case time.Time:
+		if val.IsZero() {
+			return ""
+		}
+		return val.Format(exportTimeLayout)
+	case sql.NullString:
+		return val.String
+	case sql.NullTime:
+		if !val.Valid {
+			return ""
+		}
+		return val.Time.Format(exportTimeLayout)
+	case sql.NullInt64:
+		if !val.Valid {
+			return ""
+		}
+		return strconv.FormatInt(val.Int64, 10)
+	case fmt.Stringer:
+		return val.String()
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// exportJSONValue 将 sql.Null* 类型转换为 JSON 友好的值（无效时为 null）
+func exportJSONValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case sql.NullString:
+		if !val.Valid {
+			return nil
+		}
+		return val.String
+	case sql.NullTime:
+		if !val.Valid {
+			return nil
+		}
+		return val.Time
+	case sql.NullInt64:
+		if !val.Valid {
+			return nil
+		}
+		return val.Int64
+	default:
+		return val
+	}
+}
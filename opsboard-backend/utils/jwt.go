@@ -2,73 +2,161 @@
  * @file jwt.go
  * @description 提供 JWT 的生成和验证功能，支持访问令牌和刷新令牌。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [架构重构]：将原有的 `GenerateToken` 函数重命名为 `GenerateAccessToken`，并显著缩短其有效期（例如15分钟），以提高安全性。
- *   - [新增功能]：新增了 `GenerateRefreshToken` 函数，用于生成一个长生命周期的刷新令牌（例如7天）。
- *   - [职责明确]：现在此文件明确支持两种令牌的生成，为实现标准的刷新令牌（Refresh Token）认证流程提供了基础。
+ *   - [兼容旧令牌]：HS256 旧令牌的密钥改为启动时通过 `ConfigureLegacyHS256` 设置一次，不再在每次校验时重新加载配置。
  */
 
 package utils
 
 import (
+	"errors"
 	"fmt"
-	"opsboard-backend/config"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// GenerateAccessToken 为指定用户 ID (UUID 字符串) 生成一个短生命周期的访问令牌
-func GenerateAccessToken(userID string) (string, error) {
-	cfg, _ := config.LoadConfig()
-	secretKey := []byte(cfg.JWTSecret)
+// 令牌类型，写入 `token_type` 声明
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeMFA     = "mfa"
+)
+
+// 各类令牌的有效期，访问令牌和刷新令牌的默认值可以通过 SetSessionLifetimes 修改
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 7 * 24 * time.Hour
+	MFATokenTTL            = 5 * time.Minute // 两步验证挑战令牌
+)
+
+var (
+	legacyHS256Mu  sync.RWMutex
+	legacyHS256Key []byte // 为空表示不接受 HS256 旧令牌
+)
+
+// ConfigureLegacyHS256 设置校验升级前 HS256 令牌的密钥，应在启动时调用一次；secret 为空表示不再接受旧令牌
+func ConfigureLegacyHS256(secret string) {
+	legacyHS256Mu.Lock()
+	defer legacyHS256Mu.Unlock()
+	legacyHS256Key = []byte(secret)
+}
+
+var (
+	sessionLifetimesMu sync.RWMutex
+	accessTokenTTL     = DefaultAccessTokenTTL
+	refreshTokenTTL    = DefaultRefreshTokenTTL
+)
 
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		// [核心修改] 将有效期缩短为15分钟
-		"exp": time.Now().Add(time.Minute * 15).Unix(),
-		"iat": time.Now().Unix(),
+// SetSessionLifetimes 设置之后签发的访问令牌和刷新令牌的有效期，已签发的令牌仍按签发时的有效期过期
+func SetSessionLifetimes(access, refresh time.Duration) {
+	sessionLifetimesMu.Lock()
+	defer sessionLifetimesMu.Unlock()
+	accessTokenTTL = access
+	refreshTokenTTL = refresh
+}
+
+// ErrWrongTokenType 表示令牌的用途与预期不符
+var ErrWrongTokenType = errors.New("令牌类型不匹配")
+
+// tokenKeySets 记录每种令牌使用的密钥组
+var tokenKeySets = map[string]string{
+	TokenTypeAccess:  JWTKeySetAccess,
+	TokenTypeRefresh: JWTKeySetRefresh,
+	TokenTypeMFA:     JWTKeySetRefresh,
+}
+
+// JWTKeySetMaxTTL 返回使用该密钥组签发的令牌的最长有效期，即一把密钥停止签名后还需要保留多久用于校验
+func JWTKeySetMaxTTL(set string) time.Duration {
+	var ttl time.Duration
+	for tokenType, s := range tokenKeySets {
+		if s == set {
+			ttl = max(ttl, tokenTTL(tokenType))
+		}
 	}
+	return ttl
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(secretKey)
-	if err != nil {
-		return "", err
+// tokenTTL 返回令牌类型对应的有效期
+func tokenTTL(tokenType string) time.Duration {
+	sessionLifetimesMu.RLock()
+	defer sessionLifetimesMu.RUnlock()
+	switch tokenType {
+	case TokenTypeAccess:
+		return accessTokenTTL
+	case TokenTypeRefresh:
+		return refreshTokenTTL
+	case TokenTypeMFA:
+		return MFATokenTTL
+	default:
+		return 0
 	}
+}
 
-	return tokenString, nil
+// GenerateAccessToken 为指定用户 ID (UUID 字符串) 生成一个短生命周期的访问令牌，sessionVersion 为用户当前的会话版本
+func GenerateAccessToken(userID string, sessionVersion uint) (string, error) {
+	claims := newClaims(userID, TokenTypeAccess)
+	claims["sv"] = sessionVersion
+	return signWithKeySet(JWTKeySetAccess, claims)
 }
 
-// GenerateRefreshToken 为指定用户 ID (UUID 字符串) 生成一个长生命周期的刷新令牌
-func GenerateRefreshToken(userID string) (string, error) {
-	cfg, _ := config.LoadConfig()
-	secretKey := []byte(cfg.JWTSecret) // 在生产环境中，刷新令牌应该使用不同的密钥
+// GenerateRefreshToken 为指定用户 ID (UUID 字符串) 生成一个长生命周期的刷新令牌，sessionVersion 为用户当前的会话版本
+func GenerateRefreshToken(userID string, sessionVersion uint) (string, error) {
+	claims := newClaims(userID, TokenTypeRefresh)
+	claims["sv"] = sessionVersion
+	return signWithKeySet(JWTKeySetRefresh, claims)
+}
 
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		// [核心修改] 将有效期延长为7天
-		"exp": time.Now().Add(time.Hour * 24 * 7).Unix(),
-		"iat": time.Now().Unix(),
-	}
+// GenerateMFAToken 在密码校验通过后签发两步验证挑战令牌，只能用于提交 TOTP 验证码或完成强制的两步验证绑定
+func GenerateMFAToken(userID string) (string, error) {
+	return signWithKeySet(JWTKeySetRefresh, newClaims(userID, TokenTypeMFA))
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(secretKey)
-	if err != nil {
-		return "", err
+// newClaims 返回各类令牌共有的声明
+func newClaims(userID, tokenType string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"user_id":    userID,
+		"token_type": tokenType,
+		"exp":        now.Add(tokenTTL(tokenType)).Unix(),
+		"iat":        now.Unix(),
 	}
+}
 
-	return tokenString, nil
+// SessionVersion 返回令牌签发时用户的会话版本，没有 `sv` 声明的旧令牌返回 0
+func SessionVersion(claims jwt.MapClaims) uint {
+	sv, _ := claims["sv"].(float64)
+	return uint(sv)
 }
 
-// ValidateToken 验证 JWT 并返回 claims (逻辑保持不变)
-func ValidateToken(tokenString string) (jwt.MapClaims, error) {
-	cfg, _ := config.LoadConfig()
-	secretKey := []byte(cfg.JWTSecret)
+// ValidateTokenType 验证 JWT 并确认其用途为 expected。
+// 升级前签发的访问令牌和刷新令牌没有 `token_type` 声明，为了不让已登录用户全部掉线，仍按原方式接受；两步验证挑战令牌必须带有该声明。
+func ValidateTokenType(tokenString, expected string) (jwt.MapClaims, error) {
+	set, ok := tokenKeySets[expected]
+	if !ok {
+		return nil, ErrWrongTokenType
+	}
+	claims, err := validateToken(set, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	tokenType, ok := claims["token_type"].(string)
+	if !ok && expected != TokenTypeMFA {
+		return claims, nil
+	}
+	if tokenType != expected {
+		return nil, ErrWrongTokenType
+	}
+	return claims, nil
+}
 
+// validateToken 使用密钥组中的校验密钥验证 JWT 并返回 claims。没有 `kid` 的令牌按升级前的 HS256 令牌处理。
+func validateToken(set, tokenString string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("非预期的签名算法: %v", token.Header["alg"])
+		if _, ok := token.Header["kid"]; ok {
+			return verificationKey(set, token)
 		}
-		return secretKey, nil
+		return legacyHS256Secret(token)
 	})
 
 	if err != nil {
@@ -81,3 +169,17 @@ func ValidateToken(tokenString string) (jwt.MapClaims, error) {
 
 	return nil, fmt.Errorf("无效的 token")
 }
+
+// legacyHS256Secret 返回校验升级前 HS256 令牌的密钥，未开启兼容时拒绝
+func legacyHS256Secret(token *jwt.Token) (interface{}, error) {
+	legacyHS256Mu.RLock()
+	key := legacyHS256Key
+	legacyHS256Mu.RUnlock()
+	if len(key) == 0 {
+		return nil, errors.New("缺少签名密钥标识 (kid)")
+	}
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("非预期的签名算法: %v", token.Header["alg"])
+	}
+	return key, nil
+}
@@ -0,0 +1,242 @@
+/**
+ * @file jwt_keys.go
+ * @description 管理签发和校验 JWT 使用的非对称密钥（RS256 / EdDSA），并将访问令牌的公钥导出为 JWKS。
+ * @modification
+ *   - [Refactor]: JWKS 的缓存时长移到这里作为 `JWKSMaxAge`，供 `services` 校验新密钥的提前发布时长。
+ */
+
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWT 密钥组
+const (
+	JWTKeySetAccess  = "access"  // 访问令牌，公钥通过 /.well-known/jwks.json 公开，供其他内部服务校验
+	JWTKeySetRefresh = "refresh" // 刷新令牌和两步验证挑战令牌，只由 OpsBoard 自己校验，公钥不公开
+)
+
+// JWTKeySets 列出了所有密钥组
+var JWTKeySets = []string{JWTKeySetAccess, JWTKeySetRefresh}
+
+// 支持的签名算法，取值与 JWT 头部的 `alg` 一致
+const (
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// rsaKeyBits 是生成 RS256 密钥的长度
+const rsaKeyBits = 2048
+
+// ErrJWTKeysNotLoaded 表示密钥组尚未加载，通常说明启动时没有调用 services.RotateJWTKeys
+var ErrJWTKeysNotLoaded = errors.New("JWT 签名密钥尚未加载")
+
+// JWTKey 是一把 JWT 签名密钥
+type JWTKey struct {
+	ID         string        // 写入 JWT 头部的 `kid`
+	Algorithm  string        // JWTAlgRS256 或 JWTAlgEdDSA
+	PrivateKey crypto.Signer // *rsa.PrivateKey 或 ed25519.PrivateKey
+}
+
+// JWK 是 RFC 7517 中的公钥表示，只包含 RSA 和 Ed25519 公钥用到的字段
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// IsValidJWTAlgorithm 判断 alg 是否为支持的签名算法
+func IsValidJWTAlgorithm(alg string) bool {
+	return alg == JWTAlgRS256 || alg == JWTAlgEdDSA
+}
+
+// GenerateJWTKey 生成一把新的签名密钥，kid 为 16 字节随机数的 base64url 编码
+func GenerateJWTKey(algorithm string) (*JWTKey, error) {
+	var signer crypto.Signer
+	switch algorithm {
+	case JWTAlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	case JWTAlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	default:
+		return nil, fmt.Errorf("不支持的 JWT 签名算法: %s", algorithm)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &JWTKey{ID: base64.RawURLEncoding.EncodeToString(id), Algorithm: algorithm, PrivateKey: signer}, nil
+}
+
+// EncodeJWTPrivateKey 将私钥编码为 PKCS #8 PEM，用于持久化
+func EncodeJWTPrivateKey(key *JWTKey) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParseJWTKey 解析 EncodeJWTPrivateKey 编码的私钥，并确认密钥类型与算法一致
+func ParseJWTKey(id, algorithm, pemData string) (*JWTKey, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, fmt.Errorf("JWT 密钥 %s 不是有效的 PEM", id)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析 JWT 密钥 %s 失败: %w", id, err)
+	}
+
+	key := &JWTKey{ID: id, Algorithm: algorithm}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.PrivateKey = k
+	case ed25519.PrivateKey:
+		key.PrivateKey = k
+	}
+	if key.PrivateKey == nil || key.signingMethod() == nil {
+		return nil, fmt.Errorf("JWT 密钥 %s 的类型与算法 %s 不匹配", id, algorithm)
+	}
+	return key, nil
+}
+
+// signingMethod 返回密钥对应的 JWT 签名方法，密钥类型与算法不匹配时返回 nil
+func (k *JWTKey) signingMethod() jwt.SigningMethod {
+	switch k.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		if k.Algorithm == JWTAlgRS256 {
+			return jwt.SigningMethodRS256
+		}
+	case ed25519.PrivateKey:
+		if k.Algorithm == JWTAlgEdDSA {
+			return jwt.SigningMethodEdDSA
+		}
+	}
+	return nil
+}
+
+// PublicJWK 返回密钥的公钥部分
+func (k *JWTKey) PublicJWK() JWK {
+	jwk := JWK{KeyID: k.ID, Algorithm: k.Algorithm, Use: "sig"}
+	switch pub := k.PrivateKey.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// jwtKeyRing 是一个密钥组当前的签名密钥和校验密钥
+type jwtKeyRing struct {
+	signing      *JWTKey
+	verification map[string]*JWTKey // 按 kid 索引，包含签名密钥
+}
+
+var (
+	jwtKeysMu    sync.RWMutex
+	jwtKeyRings  = map[string]*jwtKeyRing{}
+	jwtPublished []JWK
+)
+
+// SetJWTKeys 替换一个密钥组：signing 用于签发新令牌，verification 中的密钥和 signing 都可以用于校验
+func SetJWTKeys(set string, signing *JWTKey, verification []*JWTKey) {
+	ring := &jwtKeyRing{signing: signing, verification: make(map[string]*JWTKey, len(verification)+1)}
+	for _, key := range verification {
+		ring.verification[key.ID] = key
+	}
+	if signing != nil {
+		ring.verification[signing.ID] = signing
+	}
+
+	jwtKeysMu.Lock()
+	defer jwtKeysMu.Unlock()
+	jwtKeyRings[set] = ring
+	if set == JWTKeySetAccess {
+		published := make([]JWK, 0, len(ring.verification))
+		for _, key := range ring.verification {
+			published = append(published, key.PublicJWK())
+		}
+		sort.Slice(published, func(i, j int) bool { return published[i].KeyID < published[j].KeyID })
+		jwtPublished = published
+	}
+}
+
+// JWKSMaxAge 是 JWKS 响应允许被缓存的时长。新密钥的提前发布时长必须超过它，
+// 否则其他服务缓存的 JWKS 过期前新密钥就可能开始签名
+const JWKSMaxAge = 5 * time.Minute
+
+// JWKS 返回公开的公钥，即访问令牌密钥组中所有可用于校验的密钥
+func JWKS() []JWK {
+	jwtKeysMu.RLock()
+	defer jwtKeysMu.RUnlock()
+	return jwtPublished
+}
+
+// signWithKeySet 使用密钥组当前的签名密钥签发令牌，头部带有 `kid`
+func signWithKeySet(set string, claims jwt.Claims) (string, error) {
+	jwtKeysMu.RLock()
+	ring := jwtKeyRings[set]
+	jwtKeysMu.RUnlock()
+	if ring == nil || ring.signing == nil {
+		return "", ErrJWTKeysNotLoaded
+	}
+
+	token := jwt.NewWithClaims(ring.signing.signingMethod(), claims)
+	token.Header["kid"] = ring.signing.ID
+	return token.SignedString(ring.signing.PrivateKey)
+}
+
+// verificationKey 按 `kid` 查找密钥组中的校验密钥，并确认令牌的签名算法与密钥一致，防止算法混淆攻击
+func verificationKey(set string, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	jwtKeysMu.RLock()
+	ring := jwtKeyRings[set]
+	jwtKeysMu.RUnlock()
+	if ring == nil {
+		return nil, ErrJWTKeysNotLoaded
+	}
+
+	key, ok := ring.verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("未知的签名密钥: %s", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("非预期的签名算法: %v", token.Header["alg"])
+	}
+	return key.PrivateKey.Public(), nil
+}
@@ -0,0 +1,159 @@
+/**
+ * @file logger.go
+ * @description 提供基于 `log/slog` 的结构化日志：初始化全局 logger、在 context 中携带请求 ID / 用户 ID，以及敏感信息脱敏。
+ * @modification
+ *   - [Tracing]: 使用 `slog.*Context` 记录日志时，若 context 中存在有效的 OpenTelemetry span，会附加 `trace_id` 和 `span_id`。
+ *   - [Tracing]: 新增 `RequestIDAttribute`，用于在 span 上记录请求 ID。
+ */
+
+package utils
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// redactedValue 是被脱敏字段的替换值
+const redactedValue = "[REDACTED]"
+
+// sensitiveKeyParts 列出了需要脱敏的字段名片段（不区分大小写）
+var sensitiveKeyParts = []string{"password", "passwd", "secret", "token", "authorization", "cookie", "dsn", "connection_string"}
+
+// dsnPasswordPattern 匹配 MySQL DSN 中的 `user:password@` 部分
+var dsnPasswordPattern = regexp.MustCompile(`([^\s:/@]+):(\S*)@(tcp|unix|udp)?\(`)
+
+// contextKey 是存入 context 的键类型，避免与其他包冲突
+type contextKey string
+
+const (
+	requestIDContextKey contextKey = "request_id"
+	userIDContextKey    contextKey = "user_id"
+)
+
+// WithRequestID 返回携带请求 ID 的新 context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext 返回 context 中的请求 ID，不存在时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// RequestIDAttribute 返回在 span 上记录请求 ID 的属性
+func RequestIDAttribute(requestID string) attribute.KeyValue {
+	return attribute.String("http.request.id", requestID)
+}
+
+// WithUserID 返回携带当前用户 ID 的新 context
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDContextKey, userID)
+}
+
+// UserIDFromContext 返回 context 中的用户 ID，不存在时返回空字符串
+func UserIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(userIDContextKey).(string)
+	return id
+}
+
+// InitLogger 创建并设置全局默认 logger。
+// level 取值 debug|info|warn|error（默认 info）；format 取值 json|text（默认 json）。
+func InitLogger(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       parseLogLevel(level),
+		ReplaceAttr: redactAttr,
+	}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	logger := slog.New(&contextHandler{Handler: handler})
+	slog.SetDefault(logger)
+	return logger
+}
+
+// parseLogLevel 将配置中的日志级别字符串转换为 slog.Level
+func parseLogLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// RedactDSN 遮盖数据库连接字符串中的密码，例如 `root:secret@tcp(db:3306)/ops` -> `root:***@tcp(db:3306)/ops`
+func RedactDSN(dsn string) string {
+	return dsnPasswordPattern.ReplaceAllString(dsn, "$1:***@$3(")
+}
+
+// isSensitiveKey 判断字段名是否疑似包含敏感信息
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr 是 slog 的 ReplaceAttr 回调：敏感字段整体替换，其余字符串和错误中的 DSN 密码会被遮盖
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, redactedValue)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(RedactDSN(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(RedactDSN(err.Error()))
+		}
+	}
+	return a
+}
+
+// contextHandler 包装一个 slog.Handler，在每条日志中附加 context 里的请求 ID、用户 ID 以及 trace ID / span ID
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestIDFromContext(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if id := UserIDFromContext(ctx); id != "" {
+			r.AddAttrs(slog.String("user_id", id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,101 @@
+/**
+ * @file mail_test.go
+ * @description 测试 MIME 邮件的构造：头部编码、multipart/alternative 结构和 quoted-printable 正文。
+ * @modification
+ *   - [New File]: 创建此文件。用标准库的 net/mail、mime/multipart 解析生成的邮件，校验解码后与输入一致。
+ */
+
+package utils
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMailMessage(t *testing.T) {
+	from := &mail.Address{Name: "OpsBoard", Address: "noreply@ops.example.com"}
+	msg := &MailMessage{
+		To:      "张三 <zhangsan@example.com>",
+		Subject: "工单 #42 已分配给您",
+		// 超过 76 字节的长行和非 ASCII 字符都需要 quoted-printable 编码
+		TextBody: "您好，\n工单 #42 已分配给您。" + strings.Repeat("长内容", 40) + "\n=结束",
+		HTMLBody: `<p>您好，</p><p><a href="https://ops.example.com/tickets/42">工单 #42</a> 已分配给您。</p>`,
+	}
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.FixedZone("CST", 8*3600))
+
+	raw, err := BuildMailMessage(from, msg, now)
+	if err != nil {
+		t.Fatalf("BuildMailMessage 返回错误: %v", err)
+	}
+	for i, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("第 %d 行长度 %d 超过 RFC 5322 的限制", i+1, len(line))
+		}
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("生成的邮件无法解析: %v", err)
+	}
+	header := parsed.Header
+	if got, _ := header.AddressList("From"); len(got) != 1 || got[0].Address != from.Address || got[0].Name != from.Name {
+		t.Errorf("From = %v", got)
+	}
+	if got, _ := header.AddressList("To"); len(got) != 1 || got[0].Address != "zhangsan@example.com" || got[0].Name != "张三" {
+		t.Errorf("To = %v", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject 解码为 %q（错误 %v），期望 %q", subject, err, msg.Subject)
+	}
+	if date, err := header.Date(); err != nil || !date.Equal(now) {
+		t.Errorf("Date = %v（错误 %v），期望 %v", date, err, now)
+	}
+	if id := header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@ops.example.com>") {
+		t.Errorf("Message-ID = %q，期望使用发件人的域名", id)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q（错误 %v）", header.Get("Content-Type"), err)
+	}
+	// multipart.Reader 会自动解码 quoted-printable，依次为纯文本和 HTML 正文
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", msg.TextBody},
+		{"text/html; charset=UTF-8", msg.HTMLBody},
+	}
+	for i, w := range want {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("读取第 %d 个部分失败: %v", i+1, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != w.contentType {
+			t.Errorf("第 %d 个部分的 Content-Type = %q，期望 %q", i+1, got, w.contentType)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("读取第 %d 个部分的正文失败: %v", i+1, err)
+		}
+		// 文本正文中的换行按 MIME 规范写为 CRLF
+		if wantBody := strings.ReplaceAll(w.body, "\n", "\r\n"); string(body) != wantBody {
+			t.Errorf("第 %d 个部分的正文 = %q，期望 %q", i+1, body, wantBody)
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("期望只有两个部分，读取第三个部分返回 %v", err)
+	}
+}
+
+func TestBuildMailMessageInvalidRecipient(t *testing.T) {
+	from := &mail.Address{Address: "noreply@example.com"}
+	if _, err := BuildMailMessage(from, &MailMessage{To: "not an address"}, time.Now()); err == nil {
+		t.Fatal("收件人地址无效时应返回错误")
+	}
+}
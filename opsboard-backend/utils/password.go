@@ -3,17 +3,27 @@
  * @file password.go
  * @description 提供密码哈希和验证的工具函数。
  * @modification
- *   - [New File]: 创建此文件以封装密码处理逻辑。
- *   - [Security]: 使用 bcrypt 算法，这是当前密码哈希的标准实践。
+ *   - [Cost]: bcrypt 代价从 14 降为 12。修改密码时需要逐个比对最近使用过的密码哈希，代价 14 会让一次修改耗时数秒；已有哈希中记录了各自的代价，不受影响。
+ *   - [Legacy]: 新增 `IsPasswordHash`，用于区分 bcrypt 哈希和升级前以明文保存的密码。
  */
 
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordHashCost 是 bcrypt 的代价参数
+const passwordHashCost = 12
+
+// MaxPasswordBytes 是 bcrypt 能处理的最长密码（字节）
+const MaxPasswordBytes = 72
 
 // HashPassword 使用 bcrypt 对密码进行哈希处理
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
 	return string(bytes), err
 }
 
@@ -22,3 +32,8 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// IsPasswordHash 判断 stored 是否为 bcrypt 哈希
+func IsPasswordHash(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") || strings.HasPrefix(stored, "$2b$") || strings.HasPrefix(stored, "$2y$")
+}
@@ -0,0 +1,79 @@
+/**
+ * @file signature.go
+ * @description 提供基于 HMAC-SHA256 的通用签名，用于需要交给客户端保管、但不能被篡改的短期数据（表单令牌、OIDC 登录状态 Cookie 等）。
+ * @modification
+ *   - [Security]: 签名密钥改为独立的必填配置 `FORM_SIGNING_SECRET`，启动时通过 `ConfigureSigningKey` 设置一次，
+ *     不再在每次签名时重新加载配置并借用可以为空的 `JWT_SECRET`；未设置密钥时签名和校验都会失败。
+ */
+
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidSignature 表示签名格式错误或与内容不匹配
+var ErrInvalidSignature = errors.New("签名无效")
+
+// ErrSigningKeyMissing 表示还没有设置签名密钥
+var ErrSigningKeyMissing = errors.New("未设置签名密钥")
+
+var (
+	signingKeyMu sync.RWMutex
+	signingKey   []byte // 由 ConfigureSigningKey 在启动时设置
+)
+
+// ConfigureSigningKey 设置签名密钥，应在启动时调用一次；密钥为空时返回错误
+func ConfigureSigningKey(secret string) error {
+	if secret == "" {
+		return ErrSigningKeyMissing
+	}
+	signingKeyMu.Lock()
+	defer signingKeyMu.Unlock()
+	signingKey = []byte(secret)
+	return nil
+}
+
+// SignValue 返回 `<payload>.<签名>`，purpose 区分不同用途
+func SignValue(purpose, payload string) (string, error) {
+	sig, err := hmacSignature(purpose, payload)
+	if err != nil {
+		return "", err
+	}
+	return payload + "." + sig, nil
+}
+
+// VerifySignedValue 校验 SignValue 生成的值并返回其中的 payload
+func VerifySignedValue(purpose, signed string) (string, error) {
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return "", ErrInvalidSignature
+	}
+	payload, sig := signed[:i], signed[i+1:]
+	expected, err := hmacSignature(purpose, payload)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return "", ErrInvalidSignature
+	}
+	return payload, nil
+}
+
+// hmacSignature 计算 payload 在 purpose 用途下的签名（base64url 编码）
+func hmacSignature(purpose, payload string) (string, error) {
+	signingKeyMu.RLock()
+	key := signingKey
+	signingKeyMu.RUnlock()
+	if len(key) == 0 {
+		return "", ErrSigningKeyMissing
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("opsboard-" + purpose + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
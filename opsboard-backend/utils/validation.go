@@ -0,0 +1,80 @@
+/**
+ * @file validation.go
+ * @description 提供请求绑定/校验错误到 `AppError` 字段级详情的转换工具。
+ * @modification
+ *   - [Messages]: 新增 `unique` 校验标签的错误消息。
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// RegisterValidatorTagNames 让校验错误中的字段名使用 `json`（其次 `form`）标签，而不是 Go 结构体字段名。
+// 应在应用启动时调用一次。
+func RegisterValidatorTagNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// BindingError 将 ShouldBindJSON / ShouldBindQuery 返回的错误转换为 AppError。
+func BindingError(err error) *AppError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		details := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			details = append(details, FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+		}
+		return ErrValidation(details...)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return ErrValidation(FieldError{Field: typeErr.Field, Message: fmt.Sprintf("类型错误，期望 %s", typeErr.Type)})
+	}
+
+	return &AppError{Code: CodeBadRequest, Message: "无效的请求参数", Err: err}
+}
+
+// validationMessage 根据校验标签生成可读的错误消息
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "不能为空"
+	case "min":
+		return fmt.Sprintf("不能小于 %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("不能大于 %s", fe.Param())
+	case "len":
+		return fmt.Sprintf("长度必须为 %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("必须是以下值之一: %s", fe.Param())
+	case "unique":
+		return "不能包含重复的值"
+	case "email":
+		return "不是有效的邮箱地址"
+	case "url":
+		return "不是有效的 URL"
+	default:
+		return fmt.Sprintf("未通过 %s 校验", fe.Tag())
+	}
+}
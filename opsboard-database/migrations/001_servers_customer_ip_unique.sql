@@ -0,0 +1,34 @@
+-- 为 servers 增加 (customer_id, ip_address) 唯一键 uk_servers_customer_ip。
+-- 服务器批量导入按客户和 IP 查找已有服务器进行更新，唯一键保证并发导入时不会重复创建。
+--
+-- 添加唯一键前先合并已有的重复记录：同一客户下 IP 相同的服务器只保留一条，
+-- 优先保留未移入回收站的记录，其次保留 server_id 最小（最早创建）的记录。
+-- 维护任务的外键为 on delete cascade，删除重复记录前先把它们的维护任务改为指向保留的记录。
+
+start transaction;
+
+create temporary table server_duplicates as
+select server_id, keep_id
+from (select server_id,
+             first_value(server_id) over (
+                 partition by customer_id, ip_address
+                 order by deleted_at is not null, server_id
+                 ) as keep_id
+      from servers) s
+where server_id <> keep_id;
+
+update maintenance m
+    join server_duplicates d on m.target_server_id = d.server_id
+set m.target_server_id = d.keep_id;
+
+delete s
+from servers s
+         join server_duplicates d on s.server_id = d.server_id;
+
+drop temporary table server_duplicates;
+
+commit;
+
+alter table servers
+    add constraint uk_servers_customer_ip
+        unique (customer_id, ip_address);
@@ -0,0 +1,51 @@
+-- 软删除：客户、服务器、维护任务、更新日志和工单增加 deleted_at，删除时先移入回收站，管理员可以恢复或彻底删除。
+-- 工单增加完成时间 completion_time，已完成的工单以最后更新时间作为完成时间；并新增只包含未删除工单的列表视图 v_tickets。
+
+alter table customers
+    add column deleted_at datetime(6) null comment '软删除时间，非空表示已移入回收站' after created_at;
+
+alter table servers
+    add column deleted_at datetime(6) null comment '软删除时间，非空表示已移入回收站' after updated_at;
+
+alter table maintenance
+    add column deleted_at datetime(6) null comment '软删除时间，非空表示已移入回收站' after created_at;
+
+alter table changelogs
+    add column deleted_at datetime(6) null comment '软删除时间，非空表示已移入回收站' after created_at;
+
+alter table tickets
+    add column completion_time datetime(6) null comment '工单完成时间' after updated_at,
+    add column deleted_at      datetime(6) null comment '软删除时间，非空表示已移入回收站' after completion_time;
+
+update tickets
+set completion_time = coalesce(updated_at, created_at),
+    updated_at      = updated_at -- 保持原值，不触发 on update
+where status = '完成';
+
+create index idx_customers_deleted_at
+    on customers (deleted_at);
+
+create index idx_servers_deleted_at
+    on servers (deleted_at);
+
+create index idx_maintenance_deleted_at
+    on maintenance (deleted_at);
+
+create index idx_changelogs_deleted_at
+    on changelogs (deleted_at);
+
+create index idx_tickets_deleted_at
+    on tickets (deleted_at);
+
+-- 工单列表视图：只包含未被软删除的工单
+create or replace view v_tickets as
+select cast(t.ticket_id as char) as id,
+       c.customer_name,
+       t.status,
+       t.operation_type,
+       t.operation_content,
+       t.created_at                as publication_time,
+       t.completion_time
+from tickets t
+         left join customers c on t.customer_id = c.customer_id
+where t.deleted_at is null;
@@ -0,0 +1,31 @@
+-- 乐观锁：可编辑的实体增加 version，每次修改递增，接口通过 ETag / If-Match 检测并发修改。
+-- 已有记录的版本号从 1 开始。
+
+alter table customers
+    add column version int unsigned default 1 not null comment '乐观锁版本号，每次修改递增' after deleted_at;
+
+alter table servers
+    add column version int unsigned default 1 not null comment '乐观锁版本号，每次修改递增' after deleted_at;
+
+alter table maintenance
+    add column version int unsigned default 1 not null comment '乐观锁版本号，每次修改递增' after deleted_at;
+
+alter table changelogs
+    add column version int unsigned default 1 not null comment '乐观锁版本号，每次修改递增' after deleted_at;
+
+alter table tickets
+    add column version int unsigned default 1 not null comment '乐观锁版本号，每次修改递增' after deleted_at;
+
+-- 工单列表视图：只包含未被软删除的工单
+create or replace view v_tickets as
+select cast(t.ticket_id as char) as id,
+       c.customer_name,
+       t.status,
+       t.operation_type,
+       t.operation_content,
+       t.created_at                as publication_time,
+       t.completion_time,
+       t.version
+from tickets t
+         left join customers c on t.customer_id = c.customer_id
+where t.deleted_at is null;
@@ -0,0 +1,15 @@
+-- 登录暴力破解防护：新增登录失败计数表 login_attempts，多实例部署时共享退避和锁定状态。
+-- 未知用户的登录失败也会写入审计日志，audit_logs.user_id 改为可空。
+
+alter table audit_logs
+    modify user_id char(36) null comment '执行操作的用户ID (外键)，未知用户的登录失败等匿名事件为空';
+
+create table login_attempts
+(
+    attempt_key     varchar(191)  not null comment '限制维度和值，例如 user:admin 或 ip:10.0.0.1'
+        primary key,
+    failures        int unsigned  not null comment '统计窗口内连续失败的次数',
+    last_failure_at datetime(6)   not null comment '最近一次失败的时间',
+    locked_until    datetime(6)   null comment '锁定截止时间，为空表示未锁定'
+)
+    comment '登录失败计数表 (多实例部署时共享登录限制状态)';
@@ -0,0 +1,40 @@
+-- TOTP 两步验证：新增用户两步验证、一次性恢复码和按角色强制两步验证的策略表。
+
+create table user_mfa
+(
+    user_id        char(36)                                 not null comment '用户ID (主键, 外键)'
+        primary key,
+    totp_secret    varchar(64)                              not null comment 'TOTP 密钥 (Base32)',
+    enabled        tinyint(1)  default 0                    not null comment '是否已启用；开始绑定但尚未确认时为 0',
+    last_used_step bigint      default 0                    not null comment '最近一次使用的 TOTP 时间步，用于拒绝重放',
+    confirmed_at   datetime(6)                              null comment '确认绑定的时间',
+    created_at     datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint fk_user_mfa_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '用户两步验证表';
+
+create table user_recovery_codes
+(
+    code_id    bigint unsigned auto_increment comment '恢复码唯一标识符 (主键)'
+        primary key,
+    user_id    char(36)                                 not null comment '所属用户ID (外键)',
+    code_hash  char(64)                                 not null comment '恢复码的 SHA-256 哈希 (十六进制)',
+    used_at    datetime(6)                              null comment '使用时间，非空表示已使用',
+    created_at datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint uk_user_recovery_codes_hash
+        unique (user_id, code_hash),
+    constraint fk_user_recovery_codes_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '两步验证一次性恢复码表';
+
+create table mfa_role_policies
+(
+    role       varchar(20)                              not null comment '必须启用两步验证的角色 (主键)'
+        primary key,
+    created_at datetime(6) default current_timestamp(6) not null comment '记录创建时间'
+)
+    comment '按角色强制两步验证的策略表';
@@ -0,0 +1,27 @@
+-- 个人 API 令牌：新增 api_tokens 表，只保存令牌的哈希和前缀。
+
+create table api_tokens
+(
+    token_id     char(36)                                 not null comment '令牌唯一标识符 (主键)'
+        primary key,
+    user_id      char(36)                                 not null comment '所属用户ID (外键)',
+    name         varchar(100)                             not null comment '令牌名称，例如 CI 部署脚本',
+    token_prefix varchar(16)                              not null comment '令牌明文的前几位，用于在列表中辨认令牌',
+    token_hash   char(64)                                 not null comment '令牌明文的 SHA-256 哈希 (十六进制)',
+    scopes       longtext collate utf8mb4_bin             not null comment '允许访问的路由分组列表 (JSON 数组)'
+        check (json_valid(`scopes`)),
+    expires_at   datetime(6)                              not null comment '过期时间',
+    last_used_at datetime(6)                              null comment '最近一次使用的时间',
+    last_used_ip varchar(45)                              null comment '最近一次使用的客户端 IP',
+    revoked_at   datetime(6)                              null comment '吊销时间，非空表示已吊销',
+    created_at   datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint uk_api_tokens_hash
+        unique (token_hash),
+    constraint fk_api_tokens_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '个人 API 令牌表';
+
+create index idx_api_tokens_user
+    on api_tokens (user_id, revoked_at);
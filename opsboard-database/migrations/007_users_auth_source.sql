@@ -0,0 +1,7 @@
+-- 可插拔的认证源：用户增加 auth_source，记录账户来自本地密码、LDAP 目录还是 OIDC 单点登录。
+-- 已有用户均为本地账户。外部认证源即时开通的账户没有本地密码，password 保存空字符串。
+
+alter table users
+    modify password varchar(255) not null comment '密码，外部认证源的账户为空',
+    add column auth_source varchar(20) default 'local' not null
+        comment '认证源 (local: 本地密码, ldap: LDAP 目录, oidc: OIDC 单点登录)' after role;
@@ -0,0 +1,17 @@
+-- 非对称 JWT 签名密钥：新增 jwt_signing_keys 表，多实例共享按周期轮换的 RS256 / EdDSA 密钥。
+-- 表为空时服务启动会自动生成第一组密钥。
+
+create table jwt_signing_keys
+(
+    key_id       varchar(64)                              not null comment '密钥标识，即 JWT 头部的 kid (主键)'
+        primary key,
+    key_set      varchar(20)                              not null comment '密钥组：access (访问令牌) | refresh (刷新令牌和两步验证挑战令牌)',
+    algorithm    varchar(10)                              not null comment '签名算法：RS256 | EdDSA',
+    private_key  text                                     not null comment 'PKCS #8 PEM 编码的私钥',
+    activates_at datetime(6)                              not null comment '开始用于签名的时间，之前只发布、用于校验',
+    created_at   datetime(6) default current_timestamp(6) not null comment '记录创建时间'
+)
+    comment 'JWT 签名密钥表 (非对称密钥，按周期轮换)';
+
+create index idx_jwt_signing_keys_set
+    on jwt_signing_keys (key_set, activates_at);
@@ -0,0 +1,9 @@
+-- 用户管理：用户增加禁用时间 disabled_at 和软删除时间 deleted_at。
+-- 删除用户只做软删除，保留工单、审计日志等历史记录中的引用。
+
+alter table users
+    add column disabled_at datetime(6) null comment '禁用时间，非空表示账户已被禁用' after auth_source,
+    add column deleted_at  datetime(6) null comment '软删除时间，非空表示已删除 (保留历史记录中的引用)' after updated_at;
+
+create index idx_users_deleted_at
+    on users (deleted_at);
@@ -0,0 +1,25 @@
+-- 自助修改密码：用户增加会话版本 session_version，修改密码时递增使之前签发的令牌失效；
+-- 新增密码历史表，禁止重复使用最近的密码。
+-- 升级前保存的明文密码无需处理，用户下次登录成功时会自动转换为 bcrypt 哈希。
+
+alter table users
+    modify password varchar(255) not null
+        comment 'bcrypt 密码哈希 (升级前的明文密码在下次登录时转换)，外部认证源的账户为空',
+    add column session_version int unsigned default 0 not null
+        comment '会话版本，修改密码时递增，使之前签发的令牌失效' after disabled_at;
+
+create table user_password_history
+(
+    history_id    bigint unsigned auto_increment comment '记录唯一标识符 (主键)'
+        primary key,
+    user_id       char(36)                                 not null comment '用户ID (外键)',
+    password_hash varchar(255)                             not null comment '曾经使用过的密码的 bcrypt 哈希',
+    created_at    datetime(6) default current_timestamp(6) not null comment '该密码被替换的时间',
+    constraint fk_user_password_history_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '用户密码历史表 (用于禁止重复使用最近的密码)';
+
+create index idx_user_password_history_user
+    on user_password_history (user_id, created_at);
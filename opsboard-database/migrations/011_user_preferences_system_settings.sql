@@ -0,0 +1,31 @@
+-- 用户偏好和系统设置：新增 user_preferences 和 system_settings 表，均为带版本号的 JSON 文档。
+-- 系统设置没有记录时使用内置默认值，无需初始化数据。
+
+create table user_preferences
+(
+    user_id    char(36)                                 not null comment '用户ID (主键，外键)'
+        primary key,
+    data       longtext collate utf8mb4_bin             not null comment '偏好设置 JSON 文档 (每页条数、主题、语言、默认筛选条件等)'
+        check (json_valid(`data`)),
+    version    int unsigned default 0                   not null comment '乐观锁版本号，每次保存递增',
+    updated_at datetime(6) default current_timestamp(6) not null comment '最后保存时间',
+    constraint fk_user_preferences_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '用户偏好设置表';
+
+create table system_settings
+(
+    setting_key varchar(50)                              not null comment '设置组的键 (主键)，目前只有 global'
+        primary key,
+    data        longtext collate utf8mb4_bin             not null comment '设置 JSON 文档 (更新类型、工单状态、会话时长等)'
+        check (json_valid(`data`)),
+    version     int unsigned default 0                   not null comment '乐观锁版本号，每次保存递增',
+    updated_by  char(36)                                 null comment '最后修改人 (外键，关联用户表)',
+    updated_at  datetime(6) default current_timestamp(6) not null comment '最后保存时间',
+    constraint fk_system_settings_user
+        foreign key (updated_by) references users (user_id)
+            on delete set null
+)
+    comment '系统设置表';
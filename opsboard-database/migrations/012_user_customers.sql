@@ -0,0 +1,45 @@
+-- 按客户划分数据访问范围：新增 user_customers 表，非管理员只能访问分配给自己的客户的服务器、维护任务、更新日志和工单。
+-- 工单列表视图增加 customer_id，用于按可访问的客户过滤。
+--
+-- 升级前普通用户 (USER) 可以访问所有客户。没有分配记录的普通用户在升级后看不到任何数据，
+-- 因此把已有的每个普通用户分配给当前的所有客户，保持升级前的可见范围；之后新建的客户需要管理员单独分配。
+
+create table user_customers
+(
+    user_id     char(36)                                 not null comment '用户ID (主键，外键)',
+    customer_id int unsigned                             not null comment '客户ID (主键，外键)',
+    created_at  datetime(6) default current_timestamp(6) not null comment '分配时间',
+    primary key (user_id, customer_id),
+    constraint fk_user_customers_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade,
+    constraint fk_user_customers_customer
+        foreign key (customer_id) references customers (customer_id)
+            on delete cascade
+)
+    comment '用户可访问的客户表 (非管理员只能访问分配给自己的客户的数据)';
+
+create index idx_user_customers_customer
+    on user_customers (customer_id);
+
+insert into user_customers (user_id, customer_id)
+select u.user_id, c.customer_id
+from users u
+         cross join customers c
+where u.role = 'USER'
+  and u.deleted_at is null;
+
+-- 工单列表视图：只包含未被软删除的工单
+create or replace view v_tickets as
+select cast(t.ticket_id as char) as id,
+       t.customer_id,
+       c.customer_name,
+       t.status,
+       t.operation_type,
+       t.operation_content,
+       t.created_at                as publication_time,
+       t.completion_time,
+       t.version
+from tickets t
+         left join customers c on t.customer_id = c.customer_id
+where t.deleted_at is null;
@@ -0,0 +1,31 @@
+-- 客户门户：新增 CUSTOMER 角色 (role 为字符串，结构不变，只更新注释)，工单增加客户评价，新增工单评论表。
+
+alter table users
+    modify role varchar(20) not null comment '用户角色 (ADMIN, USER, CUSTOMER: 客户门户用户)';
+
+alter table tickets
+    add column rating         tinyint unsigned null comment '客户对服务的评分 (1-5)，为空表示未评价'
+        check (`rating` between 1 and 5) after completion_time,
+    add column rating_comment varchar(500)     null comment '客户的评价内容' after rating,
+    add column rated_at       datetime(6)      null comment '客户评价时间' after rating_comment;
+
+create table ticket_comments
+(
+    comment_id  bigint unsigned auto_increment comment '评论唯一标识符 (主键)'
+        primary key,
+    ticket_id   int unsigned                             not null comment '外键，关联到工单表',
+    author_id   char(36)                                 not null comment '外键，评论人，关联用户表 (UUID)',
+    is_internal tinyint(1)  default 0                    not null comment '是否为内部备注 (内部备注对客户门户用户不可见)',
+    content     text                                     not null comment '评论内容',
+    created_at  datetime(6) default current_timestamp(6) not null comment '评论时间',
+    constraint fk_ticket_comments_ticket
+        foreign key (ticket_id) references tickets (ticket_id)
+            on delete cascade,
+    constraint fk_ticket_comments_author
+        foreign key (author_id) references users (user_id)
+            on delete cascade
+)
+    comment '工单评论表 (包括对客户公开的回复和内部备注)';
+
+create index idx_ticket_comments_ticket
+    on ticket_comments (ticket_id, created_at);
@@ -0,0 +1,104 @@
+-- 工单优先级和 SLA：新增工作日历和 SLA 策略表，工单增加优先级、匹配的策略、截止时间和超时升级记录。
+-- 已有工单的优先级为 P3，并且不追溯 SLA：把它们标记为已计算 (没有截止时间)，
+-- 否则后台任务会按创建时间为历史工单计算截止时间，把它们全部判定为超时并升级。修改优先级后会按新策略重新计算。
+-- 工单列表视图增加优先级和 SLA 状态。
+
+create table business_calendars
+(
+    calendar_id int unsigned auto_increment comment '工作日历唯一标识符 (主键)'
+        primary key,
+    name        varchar(100)                             not null comment '日历名称',
+    timezone    varchar(64) default 'Asia/Shanghai'      not null comment '工作时间所在的时区 (IANA 时区名)',
+    work_hours  longtext collate utf8mb4_bin             not null comment '每周的工作时段 JSON 数组，例如 [{"weekday":1,"start":"09:00","end":"18:00"}]，weekday 0 为周日'
+        check (json_valid(`work_hours`)),
+    holidays    longtext collate utf8mb4_bin             not null comment '节假日 JSON 数组 (YYYY-MM-DD)，当天不计入工作时间'
+        check (json_valid(`holidays`)),
+    created_at  datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    updated_at  datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    version     int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
+    constraint uk_business_calendars_name
+        unique (name)
+)
+    comment '工作日历表 (SLA 截止时间只按工作时间计算)';
+
+create table sla_policies
+(
+    policy_id           int unsigned auto_increment comment 'SLA 策略唯一标识符 (主键)'
+        primary key,
+    name                varchar(100)                             not null comment '策略名称',
+    customer_id         int unsigned                             null comment '外键，适用的客户，为空表示适用于所有客户 (客户专属策略优先)',
+    customer_key        int unsigned as (coalesce(`customer_id`, 0)) stored comment '用于唯一约束的客户 ID，默认策略为 0',
+    priority            varchar(10)                              not null comment '适用的工单优先级',
+    response_minutes    int unsigned                             not null comment '首次响应时限 (分钟)',
+    resolve_minutes     int unsigned                             not null comment '解决时限 (分钟)',
+    calendar_id         int unsigned                             null comment '外键，计算时限使用的工作日历，为空表示按自然时间 (7x24) 计算',
+    escalate_to_user_id char(36)                                 null comment '外键，超时后工单改派给的用户，为空表示只记录升级不改派',
+    created_at          datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    updated_at          datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    version             int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
+    constraint uk_sla_policies_customer_priority
+        unique (customer_key, priority),
+    constraint fk_sla_policies_customer
+        foreign key (customer_id) references customers (customer_id)
+            on delete cascade,
+    constraint fk_sla_policies_calendar
+        foreign key (calendar_id) references business_calendars (calendar_id),
+    constraint fk_sla_policies_escalate_to
+        foreign key (escalate_to_user_id) references users (user_id)
+            on delete set null
+)
+    comment 'SLA 策略表 (按客户和优先级定义响应和解决时限)';
+
+alter table tickets
+    add column priority             varchar(10) default 'P3' not null comment '优先级 (P1 紧急, P2 高, P3 中, P4 低)' after completion_time,
+    add column sla_policy_id        int unsigned null comment '外键，计算截止时间时匹配到的 SLA 策略，为空表示没有适用的策略' after priority,
+    add column sla_evaluated_at     datetime(6)  null comment 'SLA 截止时间的计算时间，为空表示等待后台任务 (重新) 计算' after sla_policy_id,
+    add column response_due_at      datetime(6)  null comment '首次响应截止时间' after sla_evaluated_at,
+    add column resolve_due_at       datetime(6)  null comment '解决截止时间' after response_due_at,
+    add column first_response_at    datetime(6)  null comment '首次响应时间 (处理人首次公开回复或开始处理)' after resolve_due_at,
+    add column resolved_at          datetime(6)  null comment '解决时间 (进入待确认或完成状态)' after first_response_at,
+    add column response_breached_at datetime(6)  null comment '检测到首次响应超时并升级的时间' after resolved_at,
+    add column resolve_breached_at  datetime(6)  null comment '检测到解决超时并升级的时间' after response_breached_at,
+    add constraint fk_tickets_sla_policy
+        foreign key (sla_policy_id) references sla_policies (policy_id)
+            on delete set null;
+
+update tickets
+set sla_evaluated_at = now(6),
+    updated_at       = updated_at; -- 保持原值，不触发 on update
+
+create index idx_tickets_sla_evaluated_at
+    on tickets (sla_evaluated_at);
+
+-- 工单列表视图：只包含未被软删除的工单
+create or replace view v_tickets as
+select cast(t.ticket_id as char) as id,
+       t.customer_id,
+       c.customer_name,
+       t.status,
+       t.operation_type,
+       t.operation_content,
+       t.created_at                as publication_time,
+       t.completion_time,
+       t.priority,
+       t.response_due_at,
+       t.resolve_due_at,
+       t.first_response_at,
+       t.resolved_at,
+       case
+           when t.response_due_at is null then 'none'
+           when t.first_response_at is not null then if(t.first_response_at <= t.response_due_at, 'met', 'breached')
+           when t.response_due_at < now(6) then 'breached'
+           else 'on_track'
+           end                     as response_sla_status,
+       case
+           when t.resolve_due_at is null then 'none'
+           when coalesce(t.resolved_at, t.completion_time) is not null
+               then if(coalesce(t.resolved_at, t.completion_time) <= t.resolve_due_at, 'met', 'breached')
+           when t.resolve_due_at < now(6) then 'breached'
+           else 'on_track'
+           end                     as resolve_sla_status,
+       t.version
+from tickets t
+         left join customers c on t.customer_id = c.customer_id
+where t.deleted_at is null;
@@ -0,0 +1,51 @@
+-- webhook 订阅：新增订阅表和投递记录表，投递记录同时作为待投递队列。
+
+create table webhooks
+(
+    webhook_id int unsigned auto_increment comment 'webhook 订阅唯一标识符 (主键)'
+        primary key,
+    name       varchar(100)                             not null comment '订阅名称',
+    url        varchar(500)                             not null comment '接收事件的 URL (http 或 https)',
+    secret     varchar(100)                             not null comment '对请求体进行 HMAC-SHA256 签名的密钥',
+    events     longtext collate utf8mb4_bin             not null comment '订阅的事件类型列表 (JSON 数组)'
+        check (json_valid(`events`)),
+    enabled    tinyint(1)  default 1                    not null comment '是否启用，停用的订阅不生成新的投递',
+    created_by char(36)                                 null comment '外键，创建订阅的用户',
+    created_at datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    updated_at datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    version    int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
+    constraint fk_webhooks_created_by
+        foreign key (created_by) references users (user_id)
+            on delete set null
+)
+    comment 'webhook 订阅表';
+
+create table webhook_deliveries
+(
+    delivery_id     bigint unsigned auto_increment comment '投递记录唯一标识符 (主键)'
+        primary key,
+    webhook_id      int unsigned                             not null comment '外键，所属的 webhook 订阅',
+    event_id        char(36)                                 not null comment '事件 ID，同一事件投递给不同订阅时相同',
+    event_type      varchar(50)                              not null comment '事件类型',
+    payload         longtext                                 not null comment '发送的请求体 (JSON)',
+    status          varchar(20) default 'pending'            not null comment '投递状态 (pending: 等待投递或重试, succeeded: 成功, failed: 已放弃)',
+    attempts        int unsigned default 0                   not null comment '已尝试的次数',
+    next_attempt_at datetime(6) default current_timestamp(6) not null comment '下一次尝试的时间，投递进行中时为租约的到期时间',
+    last_attempt_at datetime(6)                              null comment '最近一次尝试的时间',
+    response_status smallint unsigned                        null comment '最近一次尝试的 HTTP 状态码',
+    response_body   varchar(2000)                            null comment '最近一次尝试的响应体 (截断)',
+    last_error      varchar(1000)                            null comment '最近一次尝试的错误信息',
+    delivered_at    datetime(6)                              null comment '投递成功的时间',
+    redelivery_of   bigint unsigned                          null comment '手动重新投递时为原投递记录的 ID',
+    created_at      datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint fk_webhook_deliveries_webhook
+        foreign key (webhook_id) references webhooks (webhook_id)
+            on delete cascade
+)
+    comment 'webhook 投递记录表 (待投递队列和投递日志)';
+
+create index idx_webhook_deliveries_due
+    on webhook_deliveries (status, next_attempt_at);
+
+create index idx_webhook_deliveries_webhook
+    on webhook_deliveries (webhook_id, created_at);
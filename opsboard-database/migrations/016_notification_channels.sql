@@ -0,0 +1,54 @@
+-- 群机器人通知：新增钉钉、企业微信、飞书渠道表，事件到渠道的路由规则表，以及管理员修改过的消息模板表。
+
+create table notification_channels
+(
+    channel_id  int unsigned auto_increment comment '通知渠道唯一标识符 (主键)'
+        primary key,
+    name        varchar(100)                             not null comment '渠道名称',
+    type        varchar(20)                              not null comment '渠道类型 (dingtalk: 钉钉, wecom: 企业微信, feishu: 飞书)',
+    webhook_url varchar(500)                             not null comment '群机器人的 webhook 地址',
+    secret      varchar(200) default ''                  not null comment '机器人的加签密钥，为空时不签名',
+    enabled     tinyint(1)   default 1                   not null comment '是否启用，停用的渠道不发送消息',
+    created_at  datetime(6)  default current_timestamp(6) not null comment '记录创建时间',
+    updated_at  datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    version     int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增'
+)
+    comment '群机器人通知渠道表';
+
+create table notification_rules
+(
+    rule_id     int unsigned auto_increment comment '路由规则唯一标识符 (主键)'
+        primary key,
+    name        varchar(100)                             not null comment '规则名称',
+    channel_id  int unsigned                             not null comment '外键，消息发送到的渠道',
+    events      longtext collate utf8mb4_bin             not null comment '匹配的事件类型列表 (JSON 数组)'
+        check (json_valid(`events`)),
+    customer_id int unsigned                             null comment '外键，只匹配该客户相关的事件，为空时匹配所有事件',
+    enabled     tinyint(1)   default 1                   not null comment '是否启用',
+    created_at  datetime(6)  default current_timestamp(6) not null comment '记录创建时间',
+    updated_at  datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    version     int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
+    constraint fk_notification_rules_channel
+        foreign key (channel_id) references notification_channels (channel_id)
+            on delete cascade,
+    constraint fk_notification_rules_customer
+        foreign key (customer_id) references customers (customer_id)
+            on delete cascade
+)
+    comment '通知路由规则表 (事件到渠道的映射)';
+
+create table notification_templates
+(
+    kind       varchar(20)                              not null comment '模板种类 (chat: 群机器人消息)',
+    event_type varchar(50)                              not null comment '事件类型',
+    title      varchar(200)                             not null comment '标题模板 (Go text/template)',
+    body       text                                     not null comment '正文模板 (Go text/template)',
+    updated_by char(36)                                 null comment '外键，最后修改模板的用户',
+    updated_at datetime(6) default current_timestamp(6) not null comment '最后保存时间',
+    version    int unsigned default 0                   not null comment '乐观锁版本号，每次保存递增',
+    primary key (kind, event_type),
+    constraint fk_notification_templates_updated_by
+        foreign key (updated_by) references users (user_id)
+            on delete set null
+)
+    comment '通知消息模板表 (只保存管理员修改过的模板，其余使用内置默认模板)';
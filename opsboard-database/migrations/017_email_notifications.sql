@@ -0,0 +1,41 @@
+-- 邮件通知：用户增加邮箱地址，新增发件箱 email_outbox，消息模板表增加邮件的纯文本正文模板。
+-- 已有用户的邮箱为空，需要用户在个人资料中填写后才会收到邮件。
+
+alter table users
+    add column email varchar(254) null comment '接收邮件通知的邮箱地址' after nickname;
+
+create table email_outbox
+(
+    email_id        bigint unsigned auto_increment comment '邮件唯一标识符 (主键)'
+        primary key,
+    dedupe_key      varchar(100)                             not null comment '去重键 (event:<事件ID>:<用户ID> 或 digest:<日期>:<用户ID>)',
+    user_id         char(36)                                 null comment '外键，收件用户',
+    to_address      varchar(254)                             not null comment '收件地址',
+    template        varchar(50)                              not null comment '使用的邮件模板名称',
+    subject         varchar(255)                             not null comment '邮件主题',
+    text_body       mediumtext                               not null comment '纯文本正文',
+    html_body       mediumtext                               not null comment 'HTML 正文',
+    status          varchar(20) default 'pending'            not null comment '发送状态 (pending: 等待发送或重试, sent: 已发送, failed: 已放弃)',
+    attempts        int unsigned default 0                   not null comment '已尝试的次数',
+    next_attempt_at datetime(6) default current_timestamp(6) not null comment '下一次尝试的时间，发送进行中时为租约的到期时间',
+    last_attempt_at datetime(6)                              null comment '最近一次尝试的时间',
+    last_error      varchar(1000)                            null comment '最近一次尝试的错误信息',
+    sent_at         datetime(6)                              null comment '发送成功的时间',
+    created_at      datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint uk_email_outbox_dedupe
+        unique (dedupe_key),
+    constraint fk_email_outbox_user
+        foreign key (user_id) references users (user_id)
+            on delete set null
+)
+    comment '邮件发件箱 (待发送队列和发送日志)';
+
+create index idx_email_outbox_due
+    on email_outbox (status, next_attempt_at);
+
+alter table notification_templates
+    modify kind       varchar(20)  not null comment '模板种类 (chat: 群机器人消息, email: 邮件)',
+    modify event_type varchar(50)  not null comment '模板名称，群消息为事件类型，邮件为通知类型 (如 digest.daily)',
+    modify title      varchar(200) not null comment '标题模板，邮件为主题 (Go 模板)',
+    modify body       text         not null comment '正文模板，邮件为 HTML 正文 (Go 模板)',
+    add column text_body text not null default '' comment '邮件的纯文本正文模板，群消息不使用' after body;
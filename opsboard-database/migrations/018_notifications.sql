@@ -0,0 +1,25 @@
+-- 站内通知：新增 notifications 表，同一事件对同一用户只生成一条通知。
+
+create table notifications
+(
+    notification_id bigint unsigned auto_increment comment '通知唯一标识符 (主键)'
+        primary key,
+    user_id         char(36)                                 not null comment '外键，接收通知的用户',
+    type            varchar(50)                              not null comment '通知类型 (ticket.assigned, ticket.mentioned, ticket.sla_breached, task.failed)',
+    title           varchar(200)                             not null comment '通知标题',
+    body            varchar(1000) default ''                 not null comment '通知正文',
+    resource_type   varchar(20)                              not null comment '关联的资源类型 (ticket: 工单, task: 维护任务)',
+    resource_id     varchar(50)                              not null comment '关联的资源 ID',
+    event_id        char(36)                                 not null comment '生成通知的事件 ID',
+    read_at         datetime(6)                              null comment '已读时间，为空表示未读',
+    created_at      datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint uk_notifications_event_user
+        unique (event_id, user_id),
+    constraint fk_notifications_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '站内通知表';
+
+create index idx_notifications_user_unread
+    on notifications (user_id, read_at);
@@ -24,6 +24,8 @@ create or replace table customers
     contact_person varchar(100)                             null comment '主要联系人姓名',
     contact_phone  varchar(50)                              null comment '联系电话',
     created_at     datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    deleted_at     datetime(6)                              null comment '软删除时间，非空表示已移入回收站',
+    version        int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
     constraint uk_customers_name
         unique (customer_name),
     constraint fk_customers_region
@@ -45,6 +47,10 @@ create or replace table servers
     usage_note      varchar(1000)                            null comment '使用备注',
     created_at      datetime(6) default current_timestamp(6) not null comment '记录创建时间',
     updated_at      datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    deleted_at      datetime(6)                              null comment '软删除时间，非空表示已移入回收站',
+    version         int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
+    constraint uk_servers_customer_ip
+        unique (customer_id, ip_address),
     constraint fk_servers_customer
         foreign key (customer_id) references customers (customer_id)
             on delete cascade
@@ -62,6 +68,8 @@ create or replace table maintenance
     execution_time   datetime(6)                              null comment '任务实际执行的时间',
     log_output       longtext                                 null comment '任务执行的详细日志输出',
     created_at       datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    deleted_at       datetime(6)                              null comment '软删除时间，非空表示已移入回收站',
+    version          int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
     constraint fk_maintenance_server
         foreign key (target_server_id) references servers (server_id)
             on delete cascade
@@ -70,24 +78,92 @@ create or replace table maintenance
 
 create or replace table users
 (
-    user_id    char(36)                                 not null comment '用户唯一标识符 (UUID)'
+    user_id         char(36)                                 not null comment '用户唯一标识符 (UUID)'
         primary key,
-    username   varchar(50)                              not null comment '用户登录名',
-    password   varchar(255)                             not null comment '密码',
-    nickname   varchar(100)                             null comment '用户昵称',
-    role       varchar(20)                              not null comment '用户角色 (例如 ADMIN, USER)',
-    created_at datetime(6) default current_timestamp(6) not null comment '记录创建时间',
-    updated_at datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    username        varchar(50)                              not null comment '用户登录名',
+    password        varchar(255)                             not null comment 'bcrypt 密码哈希 (升级前的明文密码在下次登录时转换)，外部认证源的账户为空',
+    nickname        varchar(100)                             null comment '用户昵称',
+    email           varchar(254)                             null comment '接收邮件通知的邮箱地址',
+    role            varchar(20)                              not null comment '用户角色 (ADMIN, USER, CUSTOMER: 客户门户用户)',
+    auth_source     varchar(20) default 'local'              not null comment '认证源 (local: 本地密码, ldap: LDAP 目录, oidc: OIDC 单点登录)',
+    disabled_at     datetime(6)                              null comment '禁用时间，非空表示账户已被禁用',
+    session_version int unsigned default 0                   not null comment '会话版本，修改密码时递增，使之前签发的令牌失效',
+    created_at      datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    updated_at      datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    deleted_at      datetime(6)                              null comment '软删除时间，非空表示已删除 (保留历史记录中的引用)',
     constraint uk_users_username
         unique (username)
 )
     comment '用户表';
 
+create or replace table user_password_history
+(
+    history_id    bigint unsigned auto_increment comment '记录唯一标识符 (主键)'
+        primary key,
+    user_id       char(36)                                 not null comment '用户ID (外键)',
+    password_hash varchar(255)                             not null comment '曾经使用过的密码的 bcrypt 哈希',
+    created_at    datetime(6) default current_timestamp(6) not null comment '该密码被替换的时间',
+    constraint fk_user_password_history_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '用户密码历史表 (用于禁止重复使用最近的密码)';
+
+create or replace index idx_user_password_history_user
+    on user_password_history (user_id, created_at);
+
+create or replace table user_customers
+(
+    user_id     char(36)                                 not null comment '用户ID (主键，外键)',
+    customer_id int unsigned                             not null comment '客户ID (主键，外键)',
+    created_at  datetime(6) default current_timestamp(6) not null comment '分配时间',
+    primary key (user_id, customer_id),
+    constraint fk_user_customers_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade,
+    constraint fk_user_customers_customer
+        foreign key (customer_id) references customers (customer_id)
+            on delete cascade
+)
+    comment '用户可访问的客户表 (非管理员只能访问分配给自己的客户的数据)';
+
+create or replace index idx_user_customers_customer
+    on user_customers (customer_id);
+
+create or replace table user_preferences
+(
+    user_id    char(36)                                 not null comment '用户ID (主键，外键)'
+        primary key,
+    data       longtext collate utf8mb4_bin             not null comment '偏好设置 JSON 文档 (每页条数、主题、语言、默认筛选条件等)'
+        check (json_valid(`data`)),
+    version    int unsigned default 0                   not null comment '乐观锁版本号，每次保存递增',
+    updated_at datetime(6) default current_timestamp(6) not null comment '最后保存时间',
+    constraint fk_user_preferences_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '用户偏好设置表';
+
+create or replace table system_settings
+(
+    setting_key varchar(50)                              not null comment '设置组的键 (主键)，目前只有 global'
+        primary key,
+    data        longtext collate utf8mb4_bin             not null comment '设置 JSON 文档 (更新类型、工单状态、会话时长等)'
+        check (json_valid(`data`)),
+    version     int unsigned default 0                   not null comment '乐观锁版本号，每次保存递增',
+    updated_by  char(36)                                 null comment '最后修改人 (外键，关联用户表)',
+    updated_at  datetime(6) default current_timestamp(6) not null comment '最后保存时间',
+    constraint fk_system_settings_user
+        foreign key (updated_by) references users (user_id)
+            on delete set null
+)
+    comment '系统设置表';
+
 create or replace table audit_logs
 (
     log_id        bigint unsigned auto_increment comment '日志唯一标识符 (主键)'
         primary key,
-    user_id       char(36)                                 not null comment '执行操作的用户ID (外键)',
+    user_id       char(36)                                 null comment '执行操作的用户ID (外键)，未知用户的登录失败等匿名事件为空',
     action        varchar(100)                             not null comment '操作类型 (例如: USER_LOGIN_SUCCESS, TICKET_CREATED)',
     target_entity varchar(50)                              null comment '被操作的实体类型 (例如: users, tickets)',
     target_id     varchar(255)                             null comment '被操作的实体ID',
@@ -106,6 +182,96 @@ create or replace index idx_audit_logs_action
 create or replace index idx_audit_logs_user_id
     on audit_logs (user_id);
 
+create or replace table user_mfa
+(
+    user_id        char(36)                                 not null comment '用户ID (主键, 外键)'
+        primary key,
+    totp_secret    varchar(64)                              not null comment 'TOTP 密钥 (Base32)',
+    enabled        tinyint(1)  default 0                    not null comment '是否已启用；开始绑定但尚未确认时为 0',
+    last_used_step bigint      default 0                    not null comment '最近一次使用的 TOTP 时间步，用于拒绝重放',
+    confirmed_at   datetime(6)                              null comment '确认绑定的时间',
+    created_at     datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint fk_user_mfa_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '用户两步验证表';
+
+create or replace table user_recovery_codes
+(
+    code_id    bigint unsigned auto_increment comment '恢复码唯一标识符 (主键)'
+        primary key,
+    user_id    char(36)                                 not null comment '所属用户ID (外键)',
+    code_hash  char(64)                                 not null comment '恢复码的 SHA-256 哈希 (十六进制)',
+    used_at    datetime(6)                              null comment '使用时间，非空表示已使用',
+    created_at datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint uk_user_recovery_codes_hash
+        unique (user_id, code_hash),
+    constraint fk_user_recovery_codes_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '两步验证一次性恢复码表';
+
+create or replace table mfa_role_policies
+(
+    role       varchar(20)                              not null comment '必须启用两步验证的角色 (主键)'
+        primary key,
+    created_at datetime(6) default current_timestamp(6) not null comment '记录创建时间'
+)
+    comment '按角色强制两步验证的策略表';
+
+create or replace table api_tokens
+(
+    token_id     char(36)                                 not null comment '令牌唯一标识符 (主键)'
+        primary key,
+    user_id      char(36)                                 not null comment '所属用户ID (外键)',
+    name         varchar(100)                             not null comment '令牌名称，例如 CI 部署脚本',
+    token_prefix varchar(16)                              not null comment '令牌明文的前几位，用于在列表中辨认令牌',
+    token_hash   char(64)                                 not null comment '令牌明文的 SHA-256 哈希 (十六进制)',
+    scopes       longtext collate utf8mb4_bin             not null comment '允许访问的路由分组列表 (JSON 数组)'
+        check (json_valid(`scopes`)),
+    expires_at   datetime(6)                              not null comment '过期时间',
+    last_used_at datetime(6)                              null comment '最近一次使用的时间',
+    last_used_ip varchar(45)                              null comment '最近一次使用的客户端 IP',
+    revoked_at   datetime(6)                              null comment '吊销时间，非空表示已吊销',
+    created_at   datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint uk_api_tokens_hash
+        unique (token_hash),
+    constraint fk_api_tokens_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '个人 API 令牌表';
+
+create or replace index idx_api_tokens_user
+    on api_tokens (user_id, revoked_at);
+
+create or replace table jwt_signing_keys
+(
+    key_id       varchar(64)                              not null comment '密钥标识，即 JWT 头部的 kid (主键)'
+        primary key,
+    key_set      varchar(20)                              not null comment '密钥组：access (访问令牌) | refresh (刷新令牌和两步验证挑战令牌)',
+    algorithm    varchar(10)                              not null comment '签名算法：RS256 | EdDSA',
+    private_key  text                                     not null comment 'PKCS #8 PEM 编码的私钥',
+    activates_at datetime(6)                              not null comment '开始用于签名的时间，之前只发布、用于校验',
+    created_at   datetime(6) default current_timestamp(6) not null comment '记录创建时间'
+)
+    comment 'JWT 签名密钥表 (非对称密钥，按周期轮换)';
+
+create or replace index idx_jwt_signing_keys_set
+    on jwt_signing_keys (key_set, activates_at);
+
+create or replace table login_attempts
+(
+    attempt_key     varchar(191)  not null comment '限制维度和值，例如 user:admin 或 ip:10.0.0.1'
+        primary key,
+    failures        int unsigned  not null comment '统计窗口内连续失败的次数',
+    last_failure_at datetime(6)   not null comment '最近一次失败的时间',
+    locked_until    datetime(6)   null comment '锁定截止时间，为空表示未锁定'
+)
+    comment '登录失败计数表 (多实例部署时共享登录限制状态)';
+
 create or replace table changelogs
 (
     log_id         int unsigned auto_increment comment '日志唯一标识符 (主键)'
@@ -116,6 +282,8 @@ create or replace table changelogs
     update_type    varchar(100)                             not null comment '更新类型',
     update_content text                                     not null comment '详细的更新内容描述',
     created_at     datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    deleted_at     datetime(6)                              null comment '软删除时间，非空表示已移入回收站',
+    version        int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
     constraint fk_changelogs_customer
         foreign key (customer_id) references customers (customer_id)
             on delete cascade,
@@ -125,18 +293,80 @@ create or replace table changelogs
 )
     comment '更新日志表';
 
+create or replace table business_calendars
+(
+    calendar_id int unsigned auto_increment comment '工作日历唯一标识符 (主键)'
+        primary key,
+    name        varchar(100)                             not null comment '日历名称',
+    timezone    varchar(64) default 'Asia/Shanghai'      not null comment '工作时间所在的时区 (IANA 时区名)',
+    work_hours  longtext collate utf8mb4_bin             not null comment '每周的工作时段 JSON 数组，例如 [{"weekday":1,"start":"09:00","end":"18:00"}]，weekday 0 为周日'
+        check (json_valid(`work_hours`)),
+    holidays    longtext collate utf8mb4_bin             not null comment '节假日 JSON 数组 (YYYY-MM-DD)，当天不计入工作时间'
+        check (json_valid(`holidays`)),
+    created_at  datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    updated_at  datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    version     int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
+    constraint uk_business_calendars_name
+        unique (name)
+)
+    comment '工作日历表 (SLA 截止时间只按工作时间计算)';
+
+create or replace table sla_policies
+(
+    policy_id           int unsigned auto_increment comment 'SLA 策略唯一标识符 (主键)'
+        primary key,
+    name                varchar(100)                             not null comment '策略名称',
+    customer_id         int unsigned                             null comment '外键，适用的客户，为空表示适用于所有客户 (客户专属策略优先)',
+    customer_key        int unsigned as (coalesce(`customer_id`, 0)) stored comment '用于唯一约束的客户 ID，默认策略为 0',
+    priority            varchar(10)                              not null comment '适用的工单优先级',
+    response_minutes    int unsigned                             not null comment '首次响应时限 (分钟)',
+    resolve_minutes     int unsigned                             not null comment '解决时限 (分钟)',
+    calendar_id         int unsigned                             null comment '外键，计算时限使用的工作日历，为空表示按自然时间 (7x24) 计算',
+    escalate_to_user_id char(36)                                 null comment '外键，超时后工单改派给的用户，为空表示只记录升级不改派',
+    created_at          datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    updated_at          datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    version             int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
+    constraint uk_sla_policies_customer_priority
+        unique (customer_key, priority),
+    constraint fk_sla_policies_customer
+        foreign key (customer_id) references customers (customer_id)
+            on delete cascade,
+    constraint fk_sla_policies_calendar
+        foreign key (calendar_id) references business_calendars (calendar_id),
+    constraint fk_sla_policies_escalate_to
+        foreign key (escalate_to_user_id) references users (user_id)
+            on delete set null
+)
+    comment 'SLA 策略表 (按客户和优先级定义响应和解决时限)';
+
 create or replace table tickets
 (
-    ticket_id         int unsigned auto_increment comment '工单唯一标识符 (主键)'
+    ticket_id            int unsigned auto_increment comment '工单唯一标识符 (主键)'
         primary key,
-    customer_id       int unsigned                             not null comment '外键，关联到客户表',
-    submitter_id      char(36)                                 not null comment '外键，工单提交人，关联用户表 (UUID)',
-    assignee_id       char(36)                                 null comment '外键，被指派的处理人，关联用户表 (UUID)',
-    status            varchar(50)                              not null comment '工单状态',
-    operation_type    varchar(100)                             null comment '操作类别',
-    operation_content text                                     not null comment '工单的核心内容描述',
-    created_at        datetime(6) default current_timestamp(6) not null comment '记录创建时间',
-    updated_at        datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    customer_id          int unsigned                             not null comment '外键，关联到客户表',
+    submitter_id         char(36)                                 not null comment '外键，工单提交人，关联用户表 (UUID)',
+    assignee_id          char(36)                                 null comment '外键，被指派的处理人，关联用户表 (UUID)',
+    status               varchar(50)                              not null comment '工单状态',
+    operation_type       varchar(100)                             null comment '操作类别',
+    operation_content    text                                     not null comment '工单的核心内容描述',
+    created_at           datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    updated_at           datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    completion_time      datetime(6)                              null comment '工单完成时间',
+    priority             varchar(10) default 'P3'                 not null comment '优先级 (P1 紧急, P2 高, P3 中, P4 低)',
+    sla_policy_id        int unsigned                             null comment '外键，计算截止时间时匹配到的 SLA 策略，为空表示没有适用的策略',
+    sla_evaluated_at     datetime(6)                              null comment 'SLA 截止时间的计算时间，为空表示等待后台任务 (重新) 计算',
+    response_due_at      datetime(6)                              null comment '首次响应截止时间',
+    resolve_due_at       datetime(6)                              null comment '解决截止时间',
+    first_response_at    datetime(6)                              null comment '首次响应时间 (处理人首次公开回复或开始处理)',
+    resolved_at          datetime(6)                              null comment '解决时间 (进入待确认或完成状态)',
+    response_breached_at datetime(6)                              null comment '检测到首次响应超时并升级的时间',
+    resolve_breached_at  datetime(6)                              null comment '检测到解决超时并升级的时间',
+    rating               tinyint unsigned                         null comment '客户对服务的评分 (1-5)，为空表示未评价'
+        check (`rating` between 1 and 5),
+    rating_comment       varchar(500)                             null comment '客户的评价内容',
+    rated_at             datetime(6)                              null comment '客户评价时间',
+    deleted_at           datetime(6)                              null comment '软删除时间，非空表示已移入回收站',
+    version              int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
     constraint fk_tickets_assignee
         foreign key (assignee_id) references users (user_id)
             on delete set null,
@@ -145,7 +375,241 @@ create or replace table tickets
             on delete cascade,
     constraint fk_tickets_submitter
         foreign key (submitter_id) references users (user_id)
-            on delete cascade
+            on delete cascade,
+    constraint fk_tickets_sla_policy
+        foreign key (sla_policy_id) references sla_policies (policy_id)
+            on delete set null
 )
     comment '工单表';
 
+create or replace index idx_tickets_sla_evaluated_at
+    on tickets (sla_evaluated_at);
+
+create or replace table ticket_comments
+(
+    comment_id  bigint unsigned auto_increment comment '评论唯一标识符 (主键)'
+        primary key,
+    ticket_id   int unsigned                             not null comment '外键，关联到工单表',
+    author_id   char(36)                                 not null comment '外键，评论人，关联用户表 (UUID)',
+    is_internal tinyint(1)  default 0                    not null comment '是否为内部备注 (内部备注对客户门户用户不可见)',
+    content     text                                     not null comment '评论内容',
+    created_at  datetime(6) default current_timestamp(6) not null comment '评论时间',
+    constraint fk_ticket_comments_ticket
+        foreign key (ticket_id) references tickets (ticket_id)
+            on delete cascade,
+    constraint fk_ticket_comments_author
+        foreign key (author_id) references users (user_id)
+            on delete cascade
+)
+    comment '工单评论表 (包括对客户公开的回复和内部备注)';
+
+create or replace index idx_ticket_comments_ticket
+    on ticket_comments (ticket_id, created_at);
+
+create or replace table webhooks
+(
+    webhook_id int unsigned auto_increment comment 'webhook 订阅唯一标识符 (主键)'
+        primary key,
+    name       varchar(100)                             not null comment '订阅名称',
+    url        varchar(500)                             not null comment '接收事件的 URL (http 或 https)',
+    secret     varchar(100)                             not null comment '对请求体进行 HMAC-SHA256 签名的密钥',
+    events     longtext collate utf8mb4_bin             not null comment '订阅的事件类型列表 (JSON 数组)'
+        check (json_valid(`events`)),
+    enabled    tinyint(1)  default 1                    not null comment '是否启用，停用的订阅不生成新的投递',
+    created_by char(36)                                 null comment '外键，创建订阅的用户',
+    created_at datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    updated_at datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    version    int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
+    constraint fk_webhooks_created_by
+        foreign key (created_by) references users (user_id)
+            on delete set null
+)
+    comment 'webhook 订阅表';
+
+create or replace table webhook_deliveries
+(
+    delivery_id     bigint unsigned auto_increment comment '投递记录唯一标识符 (主键)'
+        primary key,
+    webhook_id      int unsigned                             not null comment '外键，所属的 webhook 订阅',
+    event_id        char(36)                                 not null comment '事件 ID，同一事件投递给不同订阅时相同',
+    event_type      varchar(50)                              not null comment '事件类型',
+    payload         longtext                                 not null comment '发送的请求体 (JSON)',
+    status          varchar(20) default 'pending'            not null comment '投递状态 (pending: 等待投递或重试, succeeded: 成功, failed: 已放弃)',
+    attempts        int unsigned default 0                   not null comment '已尝试的次数',
+    next_attempt_at datetime(6) default current_timestamp(6) not null comment '下一次尝试的时间，投递进行中时为租约的到期时间',
+    last_attempt_at datetime(6)                              null comment '最近一次尝试的时间',
+    response_status smallint unsigned                        null comment '最近一次尝试的 HTTP 状态码',
+    response_body   varchar(2000)                            null comment '最近一次尝试的响应体 (截断)',
+    last_error      varchar(1000)                            null comment '最近一次尝试的错误信息',
+    delivered_at    datetime(6)                              null comment '投递成功的时间',
+    redelivery_of   bigint unsigned                          null comment '手动重新投递时为原投递记录的 ID',
+    created_at      datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint fk_webhook_deliveries_webhook
+        foreign key (webhook_id) references webhooks (webhook_id)
+            on delete cascade
+)
+    comment 'webhook 投递记录表 (待投递队列和投递日志)';
+
+create or replace index idx_webhook_deliveries_due
+    on webhook_deliveries (status, next_attempt_at);
+
+create or replace index idx_webhook_deliveries_webhook
+    on webhook_deliveries (webhook_id, created_at);
+
+create or replace table email_outbox
+(
+    email_id        bigint unsigned auto_increment comment '邮件唯一标识符 (主键)'
+        primary key,
+    dedupe_key      varchar(100)                             not null comment '去重键 (event:<事件ID>:<用户ID> 或 digest:<日期>:<用户ID>)',
+    user_id         char(36)                                 null comment '外键，收件用户',
+    to_address      varchar(254)                             not null comment '收件地址',
+    template        varchar(50)                              not null comment '使用的邮件模板名称',
+    subject         varchar(255)                             not null comment '邮件主题',
+    text_body       mediumtext                               not null comment '纯文本正文',
+    html_body       mediumtext                               not null comment 'HTML 正文',
+    status          varchar(20) default 'pending'            not null comment '发送状态 (pending: 等待发送或重试, sent: 已发送, failed: 已放弃)',
+    attempts        int unsigned default 0                   not null comment '已尝试的次数',
+    next_attempt_at datetime(6) default current_timestamp(6) not null comment '下一次尝试的时间，发送进行中时为租约的到期时间',
+    last_attempt_at datetime(6)                              null comment '最近一次尝试的时间',
+    last_error      varchar(1000)                            null comment '最近一次尝试的错误信息',
+    sent_at         datetime(6)                              null comment '发送成功的时间',
+    created_at      datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint uk_email_outbox_dedupe
+        unique (dedupe_key),
+    constraint fk_email_outbox_user
+        foreign key (user_id) references users (user_id)
+            on delete set null
+)
+    comment '邮件发件箱 (待发送队列和发送日志)';
+
+create or replace index idx_email_outbox_due
+    on email_outbox (status, next_attempt_at);
+
+create or replace table notifications
+(
+    notification_id bigint unsigned auto_increment comment '通知唯一标识符 (主键)'
+        primary key,
+    user_id         char(36)                                 not null comment '外键，接收通知的用户',
+    type            varchar(50)                              not null comment '通知类型 (ticket.assigned, ticket.mentioned, ticket.sla_breached, task.failed)',
+    title           varchar(200)                             not null comment '通知标题',
+    body            varchar(1000) default ''                 not null comment '通知正文',
+    resource_type   varchar(20)                              not null comment '关联的资源类型 (ticket: 工单, task: 维护任务)',
+    resource_id     varchar(50)                              not null comment '关联的资源 ID',
+    event_id        char(36)                                 not null comment '生成通知的事件 ID',
+    read_at         datetime(6)                              null comment '已读时间，为空表示未读',
+    created_at      datetime(6) default current_timestamp(6) not null comment '记录创建时间',
+    constraint uk_notifications_event_user
+        unique (event_id, user_id),
+    constraint fk_notifications_user
+        foreign key (user_id) references users (user_id)
+            on delete cascade
+)
+    comment '站内通知表';
+
+create or replace index idx_notifications_user_unread
+    on notifications (user_id, read_at);
+
+create or replace table notification_channels
+(
+    channel_id  int unsigned auto_increment comment '通知渠道唯一标识符 (主键)'
+        primary key,
+    name        varchar(100)                             not null comment '渠道名称',
+    type        varchar(20)                              not null comment '渠道类型 (dingtalk: 钉钉, wecom: 企业微信, feishu: 飞书)',
+    webhook_url varchar(500)                             not null comment '群机器人的 webhook 地址',
+    secret      varchar(200) default ''                  not null comment '机器人的加签密钥，为空时不签名',
+    enabled     tinyint(1)   default 1                   not null comment '是否启用，停用的渠道不发送消息',
+    created_at  datetime(6)  default current_timestamp(6) not null comment '记录创建时间',
+    updated_at  datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    version     int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增'
+)
+    comment '群机器人通知渠道表';
+
+create or replace table notification_rules
+(
+    rule_id     int unsigned auto_increment comment '路由规则唯一标识符 (主键)'
+        primary key,
+    name        varchar(100)                             not null comment '规则名称',
+    channel_id  int unsigned                             not null comment '外键，消息发送到的渠道',
+    events      longtext collate utf8mb4_bin             not null comment '匹配的事件类型列表 (JSON 数组)'
+        check (json_valid(`events`)),
+    customer_id int unsigned                             null comment '外键，只匹配该客户相关的事件，为空时匹配所有事件',
+    enabled     tinyint(1)   default 1                   not null comment '是否启用',
+    created_at  datetime(6)  default current_timestamp(6) not null comment '记录创建时间',
+    updated_at  datetime(6)                              null on update current_timestamp(6) comment '记录最后更新时间',
+    version     int unsigned default 1                   not null comment '乐观锁版本号，每次修改递增',
+    constraint fk_notification_rules_channel
+        foreign key (channel_id) references notification_channels (channel_id)
+            on delete cascade,
+    constraint fk_notification_rules_customer
+        foreign key (customer_id) references customers (customer_id)
+            on delete cascade
+)
+    comment '通知路由规则表 (事件到渠道的映射)';
+
+create or replace table notification_templates
+(
+    kind       varchar(20)                              not null comment '模板种类 (chat: 群机器人消息, email: 邮件)',
+    event_type varchar(50)                              not null comment '模板名称，群消息为事件类型，邮件为通知类型 (如 digest.daily)',
+    title      varchar(200)                             not null comment '标题模板，邮件为主题 (Go 模板)',
+    body       text                                     not null comment '正文模板，邮件为 HTML 正文 (Go 模板)',
+    text_body  text                                     not null default '' comment '邮件的纯文本正文模板，群消息不使用',
+    updated_by char(36)                                 null comment '外键，最后修改模板的用户',
+    updated_at datetime(6) default current_timestamp(6) not null comment '最后保存时间',
+    version    int unsigned default 0                   not null comment '乐观锁版本号，每次保存递增',
+    primary key (kind, event_type),
+    constraint fk_notification_templates_updated_by
+        foreign key (updated_by) references users (user_id)
+            on delete set null
+)
+    comment '通知消息模板表 (只保存管理员修改过的模板，其余使用内置默认模板)';
+
+create or replace index idx_users_deleted_at
+    on users (deleted_at);
+
+create or replace index idx_customers_deleted_at
+    on customers (deleted_at);
+
+create or replace index idx_servers_deleted_at
+    on servers (deleted_at);
+
+create or replace index idx_maintenance_deleted_at
+    on maintenance (deleted_at);
+
+create or replace index idx_changelogs_deleted_at
+    on changelogs (deleted_at);
+
+create or replace index idx_tickets_deleted_at
+    on tickets (deleted_at);
+
+-- 工单列表视图：只包含未被软删除的工单
+create or replace view v_tickets as
+select cast(t.ticket_id as char) as id,
+       t.customer_id,
+       c.customer_name,
+       t.status,
+       t.operation_type,
+       t.operation_content,
+       t.created_at                as publication_time,
+       t.completion_time,
+       t.priority,
+       t.response_due_at,
+       t.resolve_due_at,
+       t.first_response_at,
+       t.resolved_at,
+       case
+           when t.response_due_at is null then 'none'
+           when t.first_response_at is not null then if(t.first_response_at <= t.response_due_at, 'met', 'breached')
+           when t.response_due_at < now(6) then 'breached'
+           else 'on_track'
+           end                     as response_sla_status,
+       case
+           when t.resolve_due_at is null then 'none'
+           when coalesce(t.resolved_at, t.completion_time) is not null
+               then if(coalesce(t.resolved_at, t.completion_time) <= t.resolve_due_at, 'met', 'breached')
+           when t.resolve_due_at < now(6) then 'breached'
+           else 'on_track'
+           end                     as resolve_sla_status,
+       t.version
+from tickets t
+         left join customers c on t.customer_id = c.customer_id
+where t.deleted_at is null;
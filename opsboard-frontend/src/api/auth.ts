@@ -2,14 +2,22 @@
  * @file src/api/auth.ts
  * @description 提供了认证相关的 API 函数，例如登录。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [最终修复]：在调用 `api` 客户端时，为登录请求明确传递了 `skipTokenRefresh: true` 选项。
- *   - [原因]：此修改是为了告知 API 拦截器，如果登录请求本身返回 401 错误，这应被视为“凭据错误”而非“令牌过期”，因此不应触发自动的令牌刷新流程。这是解决登录时报错问题的关键一步。
+ *   - [机器人验证]：新增 `getFormToken`，获取后端签发的一次性登录表单令牌；`Credentials` 新增 `formToken` 和蜜罐字段 `website`。
  */
 import api from './index';
 
 export interface Credentials {
     username: string;
     password: string;
+    formToken?: string;
+    website?: string; // 蜜罐字段，真实用户不会填写
+}
+
+export interface FormTokenResponse {
+    formToken: string;
+    honeypotField: string;
+    issuedAt: string;
+    expiresAt: string;
 }
 
 export interface LoginResponse {
@@ -24,4 +32,10 @@ export const login = (credentials: Credentials): Promise<LoginResponse> => {
         // [核心修改] 告诉 api 客户端，如果此请求失败，不要尝试刷新令牌
         skipTokenRefresh: true,
     });
-};
\ No newline at end of file
+};
+export const getFormToken = (): Promise<FormTokenResponse> => {
+    return api<FormTokenResponse>('/auth/form-token', {
+        method: 'GET',
+        skipTokenRefresh: true,
+    });
+};
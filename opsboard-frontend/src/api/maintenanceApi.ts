@@ -12,7 +12,7 @@ export interface MaintenanceTaskRow {
     id: string;
     taskName: string;
     type: string;
-    status: '完成' | '挂起';
+    status: '完成' | '挂起' | '失败';
     publicationTime: string;
     completionTime: GoNullTime;
     target: GoNullString;
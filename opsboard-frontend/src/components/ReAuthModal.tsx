@@ -2,13 +2,12 @@
  * @file src/components/ReAuthModal.tsx
  * @description 一个模态框组件，用于在用户会话超时后请求重新进行身份认证。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [Hook 修正]：将用于重置组件内部状态的 `useState` Hook 调用修正为正确的 `useEffect` Hook。
- *   - [原因]：修复了因错误使用 Hook 导致的 TypeScript 编译错误 (TS2554)。`useEffect` 是在组件渲染后根据依赖变化执行副作用的正确方式，而 `useState` 的初始化函数不接受依赖数组。
- *   - [依赖导入]：在 `react` 的导入语句中添加了 `useEffect`。
+ *   - [机器人验证]：模态框打开时通过 `useFormToken` 获取一次性登录表单令牌并随登录请求提交；登录失败后重新获取。
  */
 import { useState, useEffect } from 'react';
 import { Box, Button, Dialog, DialogActions, DialogContent, DialogContentText, DialogTitle, TextField, CircularProgress, Alert } from '@mui/material';
 import { useAuth } from '@/hooks/useAuth';
+import { useFormToken } from '@/hooks/useFormToken';
 
 interface ReAuthModalProps {
     open: boolean;
@@ -18,6 +17,7 @@ interface ReAuthModalProps {
 
 export const ReAuthModal = ({ open, onLoginSuccess, onLogout }: ReAuthModalProps) => {
     const { login } = useAuth();
+    const { formToken, refresh: refreshFormToken } = useFormToken(open);
     const [username, setUsername] = useState('');
     const [password, setPassword] = useState('');
     const [isLoading, setIsLoading] = useState(false);
@@ -27,12 +27,13 @@ export const ReAuthModal = ({ open, onLoginSuccess, onLogout }: ReAuthModalProps
         setIsLoading(true);
         setError(null);
         try {
-            await login({ username, password });
+            await login({ username, password, formToken });
             setUsername('');
             setPassword('');
             onLoginSuccess();
         } catch (err) {
             setError('登录失败，请检查您的凭据。');
+            refreshFormToken();
         } finally {
             setIsLoading(false);
         }
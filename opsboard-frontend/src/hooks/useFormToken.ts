@@ -0,0 +1,31 @@
+/**
+ * @file src/hooks/useFormToken.ts
+ * @description 一个自定义钩子，在登录表单显示时获取后端签发的一次性表单令牌。
+ * @modification
+ *   - [New File]: 创建此文件。令牌在 `active` 变为 true 时获取；每个令牌只能提交一次，因此登录失败后应调用 `refresh` 重新获取。
+ */
+import { useCallback, useEffect, useState } from 'react';
+import { getFormToken } from '@/api/auth';
+
+export const useFormToken = (active: boolean = true) => {
+    const [formToken, setFormToken] = useState<string | undefined>(undefined);
+
+    const refresh = useCallback(async () => {
+        try {
+            const result = await getFormToken();
+            setFormToken(result.formToken);
+        } catch (err) {
+            // 获取失败时不阻塞表单，提交后后端会返回“表单已失效”，届时再次获取
+            console.error('Failed to fetch form token:', err);
+            setFormToken(undefined);
+        }
+    }, []);
+
+    useEffect(() => {
+        if (active) {
+            refresh();
+        }
+    }, [active, refresh]);
+
+    return { formToken, refresh };
+};
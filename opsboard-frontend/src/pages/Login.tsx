@@ -2,9 +2,8 @@
  * @file src/pages/Login.tsx
  * @description 此文件定义了应用的登录页面。
  * @modification
- *   - [认证重构]: 移除了旧的 `onFakeLogin` 逻辑，现在使用 `useAuth` 钩子来访问全局的 `login` 方法。
- *   - [状态解耦]: 组件本身不再管理认证状态、token 或导航逻辑。它只负责收集用户输入并调用 `authContext.login`。
- *   - [用户反馈]: 登录按钮的加载状态和错误信息的显示现在都由 `useAuth` 钩子提供的状态驱动，确保了 UI 与认证过程的实时同步。
+ *   - [Bot Check]: 渲染表单时通过 `useFormToken` 获取一次性表单令牌，并添加对用户隐藏的蜜罐字段 `website`，两者随登录请求一起提交。
+ *   - [Bot Check]: 表单令牌只能使用一次，登录失败后会重新获取。
  */
 import { useState, useRef, useEffect, type JSX } from 'react';
 import {
@@ -16,18 +15,21 @@ import { useTheme } from '@mui/material/styles';
 import LoginIcon from '@mui/icons-material/Login';
 import ReportProblemIcon from '@mui/icons-material/ReportProblem';
 import { useAuth } from '@/hooks/useAuth'; // 引入 useAuth 钩子
+import { useFormToken } from '@/hooks/useFormToken';
 import HoneypotInfo from './HoneypotInfo';
 import logoSrc from '../assets/logo.svg';
 
 const Login = (): JSX.Element => {
     const [username, setUsername] = useState('');
     const [password, setPassword] = useState('');
+    const [website, setWebsite] = useState(''); // 蜜罐字段
     const [isInfoDialogOpen, setIsInfoDialogOpen] = useState(false);
     const cardRef = useRef<HTMLDivElement>(null);
     const theme = useTheme();
 
     // 从 AuthContext 获取登录函数和状态
     const { login, isLoading, error } = useAuth();
+    const { formToken, refresh: refreshFormToken } = useFormToken();
 
     useEffect(() => {
         cardRef.current?.focus();
@@ -36,11 +38,12 @@ const Login = (): JSX.Element => {
     const handleSubmit = async (e: React.FormEvent) => {
         e.preventDefault();
         try {
-            await login({ username, password });
+            await login({ username, password, formToken, website });
             // 导航逻辑现在由 AuthProvider 处理
         } catch (err) {
             // 错误已在 AuthProvider 中设置，UI 将自动更新
             console.error('Login failed:', err);
+            refreshFormToken();
         }
     };
 
@@ -107,6 +110,14 @@ const Login = (): JSX.Element => {
                                 value={password} onChange={e => setPassword(e.target.value)} sx={tfSX}
                                 disabled={isLoading}
                             />
+                            {/* 蜜罐字段，使用 CSS 对用户隐藏 */}
+                            <Box aria-hidden="true" sx={{ opacity: 0, position: 'absolute', top: '-9999px', left: '-9999px' }}>
+                                <label htmlFor="website">请勿填写此字段</label>
+                                <input
+                                    type="text" id="website" name="website" tabIndex={-1} autoComplete="off"
+                                    value={website} onChange={e => setWebsite(e.target.value)}
+                                />
+                            </Box>
                             <Box sx={{ display: 'flex', gap: 1, mt: 2 }}>
                                 <Button
                                     fullWidth
@@ -121,7 +132,7 @@ const Login = (): JSX.Element => {
                                 </Button>
                                 <Button
                                     variant="contained" color="error" onClick={handleOpenInfoDialog}
-                                    sx={{ flexShrink: 0, px: 2 }} aria-label="查看待办的安全实现"
+                                    sx={{ flexShrink: 0, px: 2 }} aria-label="查看机器人验证机制说明"
                                     disabled={isLoading}
                                 >
                                     <ReportProblemIcon />
@@ -133,7 +144,7 @@ const Login = (): JSX.Element => {
             </Box>
 
             <Dialog open={isInfoDialogOpen} onClose={handleCloseInfoDialog} maxWidth="md" fullWidth scroll="paper">
-                <DialogTitle fontWeight="bold">TODO: 实施本地化机器人验证机制</DialogTitle>
+                <DialogTitle fontWeight="bold">本地化机器人验证机制</DialogTitle>
                 <DialogContent dividers>
                     <HoneypotInfo />
                 </DialogContent>
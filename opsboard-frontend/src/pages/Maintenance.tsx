@@ -2,8 +2,8 @@
  * @file src/pages/Maintenance.tsx
  * @description 此文件是“维护任务”功能的主页面，已集成后端分页功能。
  * @modification 本次提交中所做的具体修改摘要。
- *   - [最终修复]：修正了 `PaginatedResponse` 类型的导入路径，从 `@/api/maintenanceApi` 更改为 `@/api`。
- *   - [原因]：此修改解决了因错误的导入路径而导致的 TypeScript 编译失败问题 (TS2459)。通过从全局 API 入口文件 (`api/index.ts`) 导入通用的 `PaginatedResponse` 类型，我们确保了类型引用的正确性和一致性。
+ *   - [失败任务]：操作列按任务状态提供对应的操作：“完成”的任务可以取消完成，“失败”的任务可以重新挂起，
+ *     只有“挂起”的任务才能标记为完成，避免失败的任务被直接当作完成处理。
  */
 import {useEffect, useCallback, lazy, Suspense, type JSX, useState, useMemo, type ChangeEvent} from 'react';
 import {
@@ -23,6 +23,7 @@ import {useQuery, useQueryClient, useMutation} from '@tanstack/react-query';
 import DeleteIcon from '@mui/icons-material/Delete';
 import CheckCircleIcon from '@mui/icons-material/CheckCircle';
 import CancelIcon from '@mui/icons-material/Cancel';
+import ReplayIcon from '@mui/icons-material/Replay';
 import {useLayoutState, useLayoutDispatch} from '@/contexts/LayoutContext.tsx';
 import {type InspectionBackupSearchValues} from '@/components/forms/InspectionBackupSearchForm.tsx';
 import { maintenanceApi, type MaintenanceTaskRow } from '@/api/maintenanceApi';
@@ -44,9 +45,10 @@ const columns: ColumnConfig<MaintenanceTaskRow>[] = [
     { id: 'target', label: '目标对象', sx: { width: '20%' }, renderCell: (r: MaintenanceTaskRow) => <TooltipCell>{r.target?.Valid ? r.target.String : '无'}</TooltipCell> },
     { id: 'type', label: '类型', sx: { width: '10%' }, renderCell: (r: MaintenanceTaskRow) => <Chip label={r.type} size="small" /> },
     { id: 'status', label: '状态', sx: { width: '10%' }, renderCell: (r: MaintenanceTaskRow) => {
-            const colorMap: { [key in MaintenanceTaskRow['status']]: 'success' | 'warning' } = {
+            const colorMap: { [key in MaintenanceTaskRow['status']]: 'success' | 'warning' | 'error' } = {
                 '完成': 'success',
                 '挂起': 'warning',
+                '失败': 'error',
             };
             return <Chip label={r.status} size="small" color={colorMap[r.status]} />;
         }},
@@ -58,6 +60,13 @@ const columns: ColumnConfig<MaintenanceTaskRow>[] = [
 const MAINTENANCE_QUERY_KEY_BASE = ['maintenance'];
 const ANIMATION_DELAY = 300;
 
+// 各状态的任务在操作列中可以执行的状态变更
+const statusActionText: Record<MaintenanceTaskRow['status'], string> = {
+    '完成': '取消完成',
+    '失败': '重新挂起',
+    '挂起': '标记为完成',
+};
+
 const Maintenance = (): JSX.Element => {
     const {isPanelOpen} = useLayoutState();
     const {togglePanel, setPanelContent, setPanelTitle, setPanelWidth} = useLayoutDispatch();
@@ -86,15 +95,15 @@ const Maintenance = (): JSX.Element => {
     const totalRows = data?.total || 0;
 
     const updateStatusMutation = useMutation({
-        mutationFn: async ({ id, status }: { id: string, status: '完成' | '挂起' }) => {
-            if (status === '完成') {
+        mutationFn: async ({ id, status }: { id: string, status: MaintenanceTaskRow['status'] }) => {
+            // “完成”和“失败”的任务都回到“挂起”，只有“挂起”的任务才标记为完成
+            if (status === '完成' || status === '失败') {
                 return maintenanceApi.markAsPending(id);
             }
             return maintenanceApi.markAsCompleted(id);
         },
         onSuccess: (_, variables) => {
-            const actionText = variables.status === '完成' ? '取消完成' : '标记为完成';
-            showNotification(`操作成功: ${actionText}`, 'success');
+            showNotification(`操作成功: ${statusActionText[variables.status]}`, 'success');
             queryClient.invalidateQueries({ queryKey: [MAINTENANCE_QUERY_KEY_BASE] });
         },
         onError: (err) => {
@@ -173,14 +182,22 @@ const Maintenance = (): JSX.Element => {
                         onClick={() => { /* 暂时无点击交互 */ }}
                         actions={
                             <>
-                                {r.status === '完成' ? (
-                                    <Tooltip title="取消完成">
+                                {r.status === '完成' && (
+                                    <Tooltip title={statusActionText[r.status]}>
                                         <IconButton size="small" color="warning" onClick={(e) => { e.stopPropagation(); updateStatusMutation.mutate({ id: r.id, status: r.status }); }}>
                                             <CancelIcon fontSize="small" />
                                         </IconButton>
                                     </Tooltip>
-                                ) : (
-                                    <Tooltip title="完成">
+                                )}
+                                {r.status === '失败' && (
+                                    <Tooltip title={statusActionText[r.status]}>
+                                        <IconButton size="small" color="warning" onClick={(e) => { e.stopPropagation(); updateStatusMutation.mutate({ id: r.id, status: r.status }); }}>
+                                            <ReplayIcon fontSize="small" />
+                                        </IconButton>
+                                    </Tooltip>
+                                )}
+                                {r.status === '挂起' && (
+                                    <Tooltip title={statusActionText[r.status]}>
                                         <IconButton size="small" color="success" onClick={(e) => { e.stopPropagation(); updateStatusMutation.mutate({ id: r.id, status: r.status }); }}>
                                             <CheckCircleIcon fontSize="small" />
                                         </IconButton>